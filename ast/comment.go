@@ -0,0 +1,66 @@
+package ast
+
+import (
+	"github.com/rhysd/locerr"
+	"goscilla/token"
+	"strings"
+)
+
+// Comment is a `(* ... *)` comment in source.
+type Comment struct {
+	Token *token.Token
+}
+
+// CommentGroup is a sequence of comments with no other tokens and no empty line between them.
+// The parser sets a comment group placed just above a declaration to its Doc field, and a comment
+// group following a declaration on the same line to its Comment field.
+type CommentGroup struct {
+	List []*Comment
+}
+
+func (c *Comment) Pos() locerr.Pos {
+	return c.Token.Start
+}
+func (c *Comment) End() locerr.Pos {
+	return c.Token.End
+}
+
+func (g *CommentGroup) Pos() locerr.Pos {
+	return g.List[0].Pos()
+}
+func (g *CommentGroup) End() locerr.Pos {
+	return g.List[len(g.List)-1].End()
+}
+
+// Text returns the text of the comment without `(*` and `*)`. Spaces around each line and
+// empty lines at the beginning and the end are removed.
+func (c *Comment) Text() string {
+	s := c.Token.Value()
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "(*"), "*)")
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimSpace(l)
+	}
+	for len(lines) > 0 && lines[0] == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Text returns texts of all comments in the group joined with newlines. It returns an empty
+// string for nil.
+func (g *CommentGroup) Text() string {
+	if g == nil {
+		return ""
+	}
+	texts := make([]string, 0, len(g.List))
+	for _, c := range g.List {
+		if t := c.Text(); t != "" {
+			texts = append(texts, t)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
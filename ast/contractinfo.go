@@ -0,0 +1,262 @@
+package ast
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/rhysd/locerr"
+	"io"
+	"strconv"
+)
+
+// ContractInfo is the interface of a contract in the shape of `contract_info` output by
+// `scilla-checker -contractinfo`. Block explorers and wallets read it to know how to deploy and
+// call the contract.
+type ContractInfo struct {
+	ScillaMajorVersion string                  `json:"scilla_major_version"`
+	Name               string                  `json:"vname"`
+	Params             []ContractInfoParam     `json:"params"`
+	Fields             []ContractInfoField     `json:"fields"`
+	Transitions        []ContractInfoComponent `json:"transitions"`
+	Procedures         []ContractInfoComponent `json:"procedures"`
+	Events             []ContractInfoEvent     `json:"events"`
+	ADTs               []ContractInfoADT       `json:"ADTs"`
+	Warnings           []ContractInfoWarning   `json:"-"`
+}
+
+// ContractInfoParam is a parameter of contract, transition, procedure or event.
+type ContractInfoParam struct {
+	Name string `json:"vname"`
+	Type string `json:"type"`
+}
+
+// ContractInfoField is a mutable field of contract. Depth is the number of nested maps in its type.
+type ContractInfoField struct {
+	Name  string `json:"vname"`
+	Type  string `json:"type"`
+	Depth int    `json:"depth"`
+}
+
+type ContractInfoComponent struct {
+	Name   string              `json:"vname"`
+	Params []ContractInfoParam `json:"params"`
+}
+
+// ContractInfoEvent is an event emitted by `event` statements. Name is the value of `_eventname`
+// and params are the other entries of the message.
+type ContractInfoEvent struct {
+	Name   string              `json:"vname"`
+	Params []ContractInfoParam `json:"params"`
+}
+
+type ContractInfoADT struct {
+	Name    string             `json:"tname"`
+	TParams []string           `json:"tparams"`
+	TMap    []ContractInfoCtor `json:"tmap"`
+}
+
+type ContractInfoCtor struct {
+	Name     string   `json:"cname"`
+	ArgTypes []string `json:"argtypes"`
+}
+
+type ContractInfoLocation struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+}
+
+// ContractInfoWarning is a warning in the shape of scilla-checker's `warnings` output.
+type ContractInfoWarning struct {
+	Message string               `json:"warning_message"`
+	Start   ContractInfoLocation `json:"start_location"`
+	End     ContractInfoLocation `json:"end_location"`
+	ID      int                  `json:"warning_id"`
+}
+
+// ADTs defined in every Scilla program. They are listed before user-defined ones as scilla-checker does.
+var builtinADTs = []ContractInfoADT{
+	{"Bool", []string{}, []ContractInfoCtor{{"True", []string{}}, {"False", []string{}}}},
+	{"Option", []string{"'A"}, []ContractInfoCtor{{"Some", []string{"'A"}}, {"None", []string{}}}},
+	{"List", []string{"'A"}, []ContractInfoCtor{{"Cons", []string{"'A", "List ('A)"}}, {"Nil", []string{}}}},
+	{"Pair", []string{"'A", "'B"}, []ContractInfoCtor{{"Pair", []string{"'A", "'B"}}}},
+	{"Nat", []string{}, []ContractInfoCtor{{"Zero", []string{}}, {"Succ", []string{"Nat"}}}},
+}
+
+// TypeOf returns the type of the expression in the format of scilla-checker like `Option (Uint32)`.
+// Since the AST does not know types, it is given by the type checker.
+type TypeOf func(e Expr) string
+
+// infoCollector collects events emitted in components. Each event must be bound to a message literal
+// in the same component so that its parameters are known.
+type infoCollector struct {
+	info   *ContractInfo
+	typeOf TypeOf
+	events map[string]bool     // Names of events already collected
+	msgs   map[string]*Message // Message literals bound in the current component
+}
+
+func (c *infoCollector) warn(msg string, n Node) {
+	loc := func(p locerr.Pos) ContractInfoLocation {
+		l := ContractInfoLocation{Line: p.Line, Column: p.Column}
+		if p.File != nil {
+			l.File = p.File.Path
+		}
+		return l
+	}
+	c.info.Warnings = append(c.info.Warnings, ContractInfoWarning{msg, loc(n.Pos()), loc(n.End()), 1})
+}
+
+func (c *infoCollector) event(s *Event) {
+	if s.Event == nil {
+		return
+	}
+	name := s.Event.Symbol.DisplayName
+	msg, ok := c.msgs[s.Event.Symbol.Name]
+	if !ok {
+		c.warn(fmt.Sprintf("Event %s is not bound to a message literal in the same component. Its parameters are unknown", name), s)
+		return
+	}
+	ev := ContractInfoEvent{Params: []ContractInfoParam{}}
+	for _, ent := range msg.Entries {
+		key := ent.Key.Value()
+		if key == "_eventname" {
+			lit, ok := ent.Value.(*StringLit)
+			if !ok {
+				c.warn("Value of _eventname must be a string literal", ent.Value)
+				return
+			}
+			ev.Name = unquote(lit.Token.Value())
+			continue
+		}
+		ev.Params = append(ev.Params, ContractInfoParam{key, c.typeOf(ent.Value)})
+	}
+	if ev.Name == "" {
+		c.warn(fmt.Sprintf("Event %s has no _eventname", name), s)
+		return
+	}
+	if _, ok := c.events[ev.Name]; ok {
+		return
+	}
+	c.events[ev.Name] = true
+	c.info.Events = append(c.info.Events, ev)
+}
+
+func (c *infoCollector) stmts(stmts []Stmt) {
+	for _, s := range stmts {
+		c.stmt(s)
+	}
+}
+
+func (c *infoCollector) stmt(s Stmt) {
+	switch s := s.(type) {
+	case *Bind:
+		if m, ok := s.Value.(*Message); ok {
+			c.msgs[s.Ident.Symbol.Name] = m
+		}
+	case *Event:
+		c.event(s)
+	case *MatchStmt:
+		for _, arm := range s.Arms {
+			c.stmts(arm.Body)
+		}
+	}
+}
+
+func infoParams(params []*Param) []ContractInfoParam {
+	ps := make([]ContractInfoParam, 0, len(params))
+	for _, p := range params {
+		ps = append(ps, ContractInfoParam{p.Ident.Symbol.DisplayName, ScillaTypeString(p.Type)})
+	}
+	return ps
+}
+
+func mapDepth(t Type) int {
+	d := 0
+	for {
+		m, ok := t.(*MapType)
+		if !ok {
+			return d
+		}
+		d++
+		t = m.Value
+	}
+}
+
+// NewContractInfo collects the interface of the contract in the type checked AST. Types of event
+// parameters are given by typeOf. It returns an error when the AST has no contract.
+func NewContractInfo(a *AST, typeOf TypeOf) (*ContractInfo, error) {
+	if a.Contract == nil {
+		return nil, locerr.NewError("No contract is defined in the source")
+	}
+	contr := a.Contract
+	version := 0
+	if a.Version != nil {
+		version = a.Version.Value
+	}
+	info := &ContractInfo{
+		ScillaMajorVersion: strconv.Itoa(version),
+		Name:               contr.Ident.Symbol.DisplayName,
+		Params:             infoParams(contr.Params),
+		Fields:             make([]ContractInfoField, 0, len(contr.Fields)),
+		Transitions:        []ContractInfoComponent{},
+		Procedures:         []ContractInfoComponent{},
+		Events:             []ContractInfoEvent{},
+		ADTs:               append([]ContractInfoADT{}, builtinADTs...),
+		Warnings:           []ContractInfoWarning{},
+	}
+	c := &infoCollector{
+		info:   info,
+		typeOf: typeOf,
+		events: map[string]bool{},
+	}
+
+	if a.Library != nil {
+		for _, e := range a.Library.Entries {
+			d, ok := e.(*TypeDecl)
+			if !ok {
+				continue
+			}
+			adt := ContractInfoADT{d.Ident.Symbol.DisplayName, []string{}, make([]ContractInfoCtor, 0, len(d.Ctors))}
+			for _, ctor := range d.Ctors {
+				args := make([]string, 0, len(ctor.Types))
+				for _, t := range ctor.Types {
+					args = append(args, ScillaTypeString(t))
+				}
+				adt.TMap = append(adt.TMap, ContractInfoCtor{ctor.Ident.Symbol.DisplayName, args})
+			}
+			info.ADTs = append(info.ADTs, adt)
+		}
+	}
+	for _, f := range contr.Fields {
+		info.Fields = append(info.Fields, ContractInfoField{f.Ident.Symbol.DisplayName, ScillaTypeString(f.Type), mapDepth(f.Type)})
+	}
+
+	for _, comp := range contr.Components {
+		ci := ContractInfoComponent{comp.Ident.Symbol.DisplayName, infoParams(comp.Params)}
+		if comp.IsProcedure() {
+			info.Procedures = append(info.Procedures, ci)
+		} else {
+			info.Transitions = append(info.Transitions, ci)
+		}
+		c.msgs = map[string]*Message{}
+		c.stmts(comp.Body)
+	}
+
+	return info, nil
+}
+
+// FprintContractInfo outputs the interface of the contract in the type checked AST as JSON in the
+// shape of `scilla-checker -contractinfo` output to given io.Writer object.
+func FprintContractInfo(out io.Writer, a *AST, typeOf TypeOf) error {
+	info, err := NewContractInfo(a, typeOf)
+	if err != nil {
+		return err
+	}
+	obj := jsonObject{{"contract_info", info}, {"warnings", info.Warnings}}
+	b, err := json.MarshalIndent(obj, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(append(b, '\n'))
+	return err
+}
@@ -12,59 +12,54 @@ type printPath struct {
 }
 
 // VisitTopdown method is called before children are visited
-func (v *printPath) VisitTopdown(e Expr) Visitor {
-	fmt.Printf("\n -> %s (topdown)", e.Name())
+func (v *printPath) VisitTopdown(n Node) Visitor {
+	fmt.Printf("\n -> %s (topdown)", n.Name())
 	return v
 }
 
 // VisitBottomup method is called after children were visited
-func (v *printPath) VisitBottomup(e Expr) {
-	fmt.Printf("\n -> %s (bottomup)", e.Name())
+func (v *printPath) VisitBottomup(n Node) {
+	fmt.Printf("\n -> %s (bottomup)", n.Name())
 }
 
 func Example() {
 	src := locerr.NewDummySource("")
 
 	// AST which usually comes from syntax.Parse() function.
-	rootOfAST := &Let{
+	// let one = Uint32 1
+	entry := &LetDecl{
 		LetToken: &token.Token{File: src},
-		Symbol:   NewSymbol("test"),
-		Bound: &Int{
-			Token: &token.Token{File: src},
-			Value: 42,
-		},
-		Body: &Add{
-			Left: &VarRef{
-				Token:  &token.Token{File: src},
-				Symbol: NewSymbol("test"),
-			},
-			Right: &Float{
-				Token: &token.Token{File: src},
-				Value: 3.14,
-			},
+		Ident:    &Ident{&token.Token{File: src}, NewSymbol("one")},
+		Bound: &IntLit{
+			TypeToken:  token.NewOrphanToken(token.INT_TYPE, "Uint32"),
+			ValueToken: token.NewOrphanToken(token.NUM_LIT, "1"),
 		},
 	}
 
-	ast := &AST{Root: rootOfAST}
+	ast := &AST{
+		Library: &Library{
+			LibraryToken: &token.Token{File: src},
+			Ident:        &Ident{&token.Token{File: src}, NewSymbol("Example")},
+			Entries:      []LibEntry{entry},
+		},
+		Source: src,
+	}
 
 	// Apply visitor to root node of AST
 	v := &printPath{0}
-	fmt.Println("ROOT")
+	fmt.Print("ROOT")
 
-	Visit(v, ast.Root)
+	Visit(v, ast.Library)
 	// Output:
 	// ROOT
-	//  -> Let (test) (topdown)
-	//  -> Int (topdown)
-	//  -> Int (bottomup)
-	//  -> Add (topdown)
-	//  -> VarRef (test) (topdown)
-	//  -> VarRef (test) (bottomup)
-	//  -> Float (topdown)
-	//  -> Float (bottomup)
-	//  -> Add (bottomup)
-	//  -> Let (test) (bottomup)
-
-	// Print AST
-	Println(ast)
+	//  -> Library (Example) (topdown)
+	//  -> Ident (Example) (topdown)
+	//  -> Ident (Example) (bottomup)
+	//  -> LetDecl (one) (topdown)
+	//  -> Ident (one) (topdown)
+	//  -> Ident (one) (bottomup)
+	//  -> IntLit (Uint32 1) (topdown)
+	//  -> IntLit (Uint32 1) (bottomup)
+	//  -> LetDecl (one) (bottomup)
+	//  -> Library (Example) (bottomup)
 }
@@ -0,0 +1,732 @@
+package ast
+
+import (
+	"fmt"
+	"github.com/rhysd/locerr"
+	"goscilla/token"
+	"io"
+	"strings"
+)
+
+// formatError is a panic payload which aborts formatting
+type formatError struct {
+	err *locerr.Error
+}
+
+// formatter converts AST into Scilla source code in canonical layout.
+type formatter struct {
+	buf    strings.Builder
+	indent int
+}
+
+func (f *formatter) fail(n Node) {
+	panic(formatError{locerr.ErrorIn(n.Pos(), n.End(), fmt.Sprintf("%s cannot be formatted as source code since it contains syntax errors", n.Name()))})
+}
+
+func (f *formatter) write(ss ...string) {
+	for _, s := range ss {
+		f.buf.WriteString(s)
+	}
+}
+
+func (f *formatter) newline() {
+	f.buf.WriteByte('\n')
+	for i := 0; i < f.indent; i++ {
+		f.buf.WriteString("  ")
+	}
+}
+
+// nested writes lines written by fn with one deeper indentation
+func (f *formatter) nested(fn func()) {
+	f.indent++
+	fn()
+	f.indent--
+}
+
+// doc writes each comment in the group on its own line. Newline is put after the last comment.
+func (f *formatter) doc(g *CommentGroup) {
+	if g == nil {
+		return
+	}
+	for _, c := range g.List {
+		f.write(c.Token.Value())
+		f.newline()
+	}
+}
+
+// comment writes the group after the current line
+func (f *formatter) comment(g *CommentGroup) {
+	if g == nil {
+		return
+	}
+	for _, c := range g.List {
+		f.write(" ", c.Token.Value())
+	}
+}
+
+func (f *formatter) ident(i *Ident) {
+	f.write(i.Symbol.DisplayName)
+}
+
+func (f *formatter) varRef(v *VarRef) {
+	f.write(v.Symbol.DisplayName)
+}
+
+func (f *formatter) varRefs(vs []*VarRef) {
+	for _, v := range vs {
+		f.write(" ")
+		f.varRef(v)
+	}
+}
+
+func (f *formatter) mapKeys(keys []*MapKey) {
+	for _, k := range keys {
+		f.write("[")
+		f.varRef(k.Key)
+		f.write("]")
+	}
+}
+
+func (f *formatter) typ(t Type) {
+	switch t := t.(type) {
+	case *PrimType:
+		f.write(t.Token.Value())
+	case *TypeVar:
+		f.write(t.Token.Value())
+	case *MapType:
+		f.write("Map ")
+		f.targ(t.Key)
+		f.write(" ")
+		f.targ(t.Value)
+	case *ADTType:
+		f.ident(t.Ident)
+		for _, a := range t.Args {
+			f.write(" ")
+			f.targ(a)
+		}
+	case *FunType:
+		switch t.Param.(type) {
+		case *FunType, *PolyType:
+			f.write("(")
+			f.typ(t.Param)
+			f.write(")")
+		default:
+			f.typ(t.Param)
+		}
+		f.write(" -> ")
+		f.typ(t.Ret)
+	case *PolyType:
+		f.write("forall ")
+		f.ident(t.TVar)
+		f.write(". ")
+		f.typ(t.Body)
+	case *AddressType:
+		f.write(t.ByStrToken.Value(), " with")
+		if t.KindToken != nil {
+			if t.KindToken.Kind == token.CONTRACT {
+				f.write(" contract")
+				for i, fld := range t.Fields {
+					if i > 0 {
+						f.write(",")
+					}
+					f.write(" ")
+					f.addressField(fld)
+				}
+			} else {
+				f.write(" library")
+			}
+		}
+		f.write(" end")
+	default:
+		panic(fmt.Sprintf("FATAL: Unknown type node %T", t))
+	}
+}
+
+// targ writes a type at argument position. Types consisting of multiple words are parenthesized.
+func (f *formatter) targ(t Type) {
+	switch t := t.(type) {
+	case *FunType, *PolyType, *MapType, *AddressType:
+	case *ADTType:
+		if len(t.Args) == 0 {
+			f.typ(t)
+			return
+		}
+	default:
+		f.typ(t)
+		return
+	}
+	f.write("(")
+	f.typ(t)
+	f.write(")")
+}
+
+func (f *formatter) addressField(fld *AddressField) {
+	f.write("field ")
+	f.ident(fld.Ident)
+	f.write(" : ")
+	f.typ(fld.Type)
+}
+
+func (f *formatter) pattern(p Pattern) {
+	switch p := p.(type) {
+	case *WildcardPattern:
+		f.write("_")
+	case *BinderPattern:
+		f.ident(p.Ident)
+	case *ConstrPattern:
+		f.ident(p.Ctor)
+		for _, a := range p.Args {
+			f.write(" ")
+			if c, ok := a.(*ConstrPattern); ok && len(c.Args) > 0 {
+				f.write("(")
+				f.pattern(a)
+				f.write(")")
+			} else {
+				f.pattern(a)
+			}
+		}
+	case *BadPattern:
+		f.fail(p)
+	default:
+		panic(fmt.Sprintf("FATAL: Unknown pattern node %T", p))
+	}
+}
+
+// isOneLine returns true when the expression is written in one line
+func isOneLine(e Expr) bool {
+	switch e.(type) {
+	case *Let, *Fun, *TFun, *Match:
+		return false
+	}
+	return true
+}
+
+// bound writes the expression after `=`. An expression taking multiple lines starts at the next
+// line with deeper indentation.
+func (f *formatter) bound(e Expr) {
+	if isOneLine(e) {
+		f.write(" ")
+		f.expr(e)
+		return
+	}
+	f.nested(func() {
+		f.newline()
+		f.expr(e)
+	})
+}
+
+func (f *formatter) literal(e Expr) bool {
+	switch e := e.(type) {
+	case *StringLit:
+		f.write(e.Token.Value())
+	case *HexLit:
+		f.write(e.Token.Value())
+	case *IntLit:
+		f.write(e.TypeToken.Value(), " ", e.ValueToken.Value())
+	case *BNumLit:
+		f.write("BNum ", e.ValueToken.Value())
+	case *EmpLit:
+		f.write("Emp ")
+		f.targ(e.Key)
+		f.write(" ")
+		f.targ(e.Value)
+	default:
+		return false
+	}
+	return true
+}
+
+func (f *formatter) expr(e Expr) {
+	if f.literal(e) {
+		return
+	}
+	switch e := e.(type) {
+	case *VarRef:
+		f.varRef(e)
+	case *Let:
+		f.write("let ")
+		f.ident(e.Ident)
+		if e.Type != nil {
+			f.write(" : ")
+			f.typ(e.Type)
+		}
+		f.write(" =")
+		if isOneLine(e.Bound) {
+			f.bound(e.Bound)
+			f.write(" in")
+		} else {
+			f.bound(e.Bound)
+			f.newline()
+			f.write("in")
+		}
+		f.newline()
+		f.expr(e.Body)
+	case *Fun:
+		f.write("fun (")
+		f.ident(e.Param.Ident)
+		f.write(" : ")
+		f.typ(e.Param.Type)
+		f.write(") =>")
+		f.newline()
+		f.expr(e.Body)
+	case *TFun:
+		f.write("tfun ")
+		f.ident(e.TVar)
+		f.write(" =>")
+		f.newline()
+		f.expr(e.Body)
+	case *App:
+		f.varRef(e.Func)
+		f.varRefs(e.Args)
+	case *TApp:
+		f.write("@")
+		f.varRef(e.Func)
+		for _, t := range e.Types {
+			f.write(" ")
+			f.targ(t)
+		}
+	case *Builtin:
+		f.write("builtin ")
+		f.ident(e.Ident)
+		if len(e.Args) == 0 {
+			f.write(" ()")
+		}
+		f.varRefs(e.Args)
+	case *Constr:
+		f.ident(e.Ident)
+		if e.LBraceToken != nil || len(e.TypeArgs) > 0 {
+			f.write(" {")
+			for i, t := range e.TypeArgs {
+				if i > 0 {
+					f.write(" ")
+				}
+				f.targ(t)
+			}
+			f.write("}")
+		}
+		f.varRefs(e.Args)
+	case *Message:
+		f.write("{")
+		for i, ent := range e.Entries {
+			if i > 0 {
+				f.write("; ")
+			}
+			f.write(ent.Key.Value(), " : ")
+			f.expr(ent.Value)
+		}
+		f.write("}")
+	case *Match:
+		f.write("match ")
+		f.varRef(e.Target)
+		f.write(" with")
+		for _, arm := range e.Arms {
+			f.newline()
+			f.write("| ")
+			f.pattern(arm.Pattern)
+			f.write(" =>")
+			f.bound(arm.Body)
+		}
+		f.newline()
+		f.write("end")
+	case *BadExpr:
+		f.fail(e)
+	default:
+		panic(fmt.Sprintf("FATAL: Unknown expression node %T", e))
+	}
+}
+
+// stmts writes each statement on its own line with deeper indentation
+func (f *formatter) stmts(stmts []Stmt) {
+	f.nested(func() {
+		for i, s := range stmts {
+			f.newline()
+			f.stmt(s)
+			if i < len(stmts)-1 {
+				f.write(";")
+			}
+		}
+	})
+}
+
+func (f *formatter) stmt(s Stmt) {
+	switch s := s.(type) {
+	case *Load:
+		f.ident(s.Ident)
+		f.write(" <- ")
+		f.varRef(s.Field)
+	case *RemoteLoad:
+		f.ident(s.Ident)
+		f.write(" <- & ")
+		f.varRef(s.Addr)
+		f.write(".")
+		f.ident(s.Field)
+	case *Store:
+		f.varRef(s.Field)
+		f.write(" := ")
+		f.varRef(s.Value)
+	case *Bind:
+		f.ident(s.Ident)
+		f.write(" =")
+		f.bound(s.Value)
+	case *MapUpdate:
+		f.varRef(s.Map)
+		f.mapKeys(s.Keys)
+		f.write(" := ")
+		f.varRef(s.Value)
+	case *MapDelete:
+		f.write("delete ")
+		f.varRef(s.Map)
+		f.mapKeys(s.Keys)
+	case *MapGet:
+		f.ident(s.Ident)
+		f.write(" <- ")
+		if s.ExistsToken != nil {
+			f.write("exists ")
+		}
+		f.varRef(s.Map)
+		f.mapKeys(s.Keys)
+	case *RemoteMapGet:
+		f.ident(s.Ident)
+		f.write(" <- & ")
+		if s.ExistsToken != nil {
+			f.write("exists ")
+		}
+		f.varRef(s.Addr)
+		f.write(".")
+		f.ident(s.Map)
+		f.mapKeys(s.Keys)
+	case *ReadFromBC:
+		f.ident(s.Ident)
+		f.write(" <- & ", s.Query.Value())
+	case *Accept:
+		f.write("accept")
+	case *Send:
+		f.write("send ")
+		f.varRef(s.Msgs)
+	case *Event:
+		f.write("event ")
+		f.varRef(s.Event)
+	case *Throw:
+		f.write("throw")
+		if s.Exception != nil {
+			f.write(" ")
+			f.varRef(s.Exception)
+		}
+	case *MatchStmt:
+		f.write("match ")
+		f.varRef(s.Target)
+		f.write(" with")
+		for _, arm := range s.Arms {
+			f.newline()
+			f.write("| ")
+			f.pattern(arm.Pattern)
+			f.write(" =>")
+			f.stmts(arm.Body)
+		}
+		f.newline()
+		f.write("end")
+	case *CallProc:
+		f.varRef(s.Proc)
+		f.varRefs(s.Args)
+	case *Iterate:
+		f.write("forall ")
+		f.varRef(s.List)
+		f.write(" ")
+		f.varRef(s.Proc)
+	case *BadStmt:
+		f.fail(s)
+	default:
+		panic(fmt.Sprintf("FATAL: Unknown statement node %T", s))
+	}
+}
+
+func (f *formatter) param(p *Param) {
+	f.ident(p.Ident)
+	f.write(" : ")
+	f.typ(p.Type)
+}
+
+// params writes parameters in parentheses. They are written in one line unless multiline is true
+// or some parameter has comments.
+func (f *formatter) params(params []*Param, multiline bool) {
+	for _, p := range params {
+		if p.Doc != nil || p.Comment != nil {
+			multiline = true
+		}
+	}
+	if !multiline || len(params) == 0 {
+		f.write("(")
+		for i, p := range params {
+			if i > 0 {
+				f.write(", ")
+			}
+			f.param(p)
+		}
+		f.write(")")
+		return
+	}
+	f.write("(")
+	f.nested(func() {
+		for i, p := range params {
+			f.newline()
+			f.doc(p.Doc)
+			f.param(p)
+			if i < len(params)-1 {
+				f.write(",")
+			}
+			f.comment(p.Comment)
+		}
+	})
+	f.newline()
+	f.write(")")
+}
+
+func (f *formatter) letDecl(d *LetDecl) {
+	f.doc(d.Doc)
+	f.write("let ")
+	f.ident(d.Ident)
+	if d.Type != nil {
+		f.write(" : ")
+		f.typ(d.Type)
+	}
+	f.write(" =")
+	f.bound(d.Bound)
+	f.comment(d.Comment)
+}
+
+func (f *formatter) ctorDecl(c *CtorDecl) {
+	f.doc(c.Doc)
+	f.write("| ")
+	f.ident(c.Ident)
+	if len(c.Types) > 0 {
+		f.write(" of")
+		for _, t := range c.Types {
+			f.write(" ")
+			f.targ(t)
+		}
+	}
+	f.comment(c.Comment)
+}
+
+func (f *formatter) typeDecl(d *TypeDecl) {
+	f.doc(d.Doc)
+	f.write("type ")
+	f.ident(d.Ident)
+	if len(d.Ctors) == 0 {
+		return
+	}
+	f.write(" =")
+	for _, c := range d.Ctors {
+		f.newline()
+		f.ctorDecl(c)
+	}
+}
+
+func (f *formatter) libEntry(e LibEntry) {
+	switch e := e.(type) {
+	case *LetDecl:
+		f.letDecl(e)
+	case *TypeDecl:
+		f.typeDecl(e)
+	case *BadDecl:
+		f.fail(e)
+	default:
+		panic(fmt.Sprintf("FATAL: Unknown library entry %T", e))
+	}
+}
+
+func (f *formatter) library(l *Library) {
+	f.doc(l.Doc)
+	f.write("library ")
+	f.ident(l.Ident)
+	for _, e := range l.Entries {
+		f.newline()
+		f.newline()
+		f.libEntry(e)
+	}
+}
+
+func (f *formatter) importName(n *ImportName) {
+	f.ident(n.Lib)
+	if n.Alias != nil {
+		f.write(" as ")
+		f.ident(n.Alias)
+	}
+}
+
+func (f *formatter) imports(i *Import) {
+	f.write("import")
+	for _, n := range i.Names {
+		f.write(" ")
+		f.importName(n)
+	}
+}
+
+func (f *formatter) field(fld *Field) {
+	f.doc(fld.Doc)
+	f.write("field ")
+	f.ident(fld.Ident)
+	f.write(" : ")
+	f.typ(fld.Type)
+	f.write(" =")
+	f.bound(fld.Init)
+	f.comment(fld.Comment)
+}
+
+func (f *formatter) component(c *Component) {
+	f.doc(c.Doc)
+	if c.IsProcedure() {
+		f.write("procedure ")
+	} else {
+		f.write("transition ")
+	}
+	f.ident(c.Ident)
+	f.params(c.Params, false)
+	f.stmts(c.Body)
+	f.newline()
+	f.write("end")
+}
+
+func (f *formatter) contract(c *Contract) {
+	f.doc(c.Doc)
+	f.write("contract ")
+	f.ident(c.Ident)
+	f.newline()
+	f.params(c.Params, true)
+	if c.Constraint != nil {
+		f.newline()
+		f.write("with")
+		f.nested(func() {
+			f.newline()
+			f.expr(c.Constraint)
+		})
+		f.newline()
+		f.write("=>")
+	}
+	for _, fld := range c.Fields {
+		f.newline()
+		f.newline()
+		f.field(fld)
+	}
+	for _, comp := range c.Components {
+		f.newline()
+		f.newline()
+		f.component(comp)
+	}
+}
+
+func (f *formatter) module(a *AST) {
+	if len(a.BadDecls) > 0 {
+		f.fail(a.BadDecls[0])
+	}
+	var decls []func()
+	if a.Version != nil {
+		decls = append(decls, func() { f.write("scilla_version ", fmt.Sprint(a.Version.Value)) })
+	}
+	if len(a.Imports) > 0 {
+		decls = append(decls, func() {
+			for i, imp := range a.Imports {
+				if i > 0 {
+					f.newline()
+				}
+				f.imports(imp)
+			}
+		})
+	}
+	if a.Library != nil {
+		decls = append(decls, func() { f.library(a.Library) })
+	}
+	if a.Contract != nil {
+		decls = append(decls, func() { f.contract(a.Contract) })
+	}
+	for _, c := range a.Components {
+		c := c
+		decls = append(decls, func() { f.component(c) })
+	}
+	for i, d := range decls {
+		if i > 0 {
+			f.newline()
+			f.newline()
+		}
+		d()
+	}
+}
+
+func (f *formatter) node(n interface{}) {
+	switch n := n.(type) {
+	case *AST:
+		f.module(n)
+	case *Version:
+		f.write("scilla_version ", fmt.Sprint(n.Value))
+	case *Import:
+		f.imports(n)
+	case *ImportName:
+		f.importName(n)
+	case *Library:
+		f.library(n)
+	case LibEntry:
+		f.libEntry(n)
+	case *CtorDecl:
+		f.ctorDecl(n)
+	case *Contract:
+		f.contract(n)
+	case *Param:
+		f.param(n)
+	case *Field:
+		f.field(n)
+	case *Component:
+		f.component(n)
+	case *Ident:
+		f.ident(n)
+	case Expr:
+		f.expr(n)
+	case *MessageEntry:
+		f.write(n.Key.Value(), " : ")
+		f.expr(n.Value)
+	case *MatchArm:
+		f.write("| ")
+		f.pattern(n.Pattern)
+		f.write(" =>")
+		f.bound(n.Body)
+	case Stmt:
+		f.stmt(n)
+	case *MapKey:
+		f.mapKeys([]*MapKey{n})
+	case *StmtArm:
+		f.write("| ")
+		f.pattern(n.Pattern)
+		f.write(" =>")
+		f.stmts(n.Body)
+	case Pattern:
+		f.pattern(n)
+	case Type:
+		f.typ(n)
+	case *AddressField:
+		f.addressField(n)
+	default:
+		panic(fmt.Sprintf("FATAL: Cannot format %T", n))
+	}
+}
+
+// Format writes the node as Scilla source code in canonical layout to given io.Writer object.
+// node is *AST or any AST node. Doc comments and line comments attached to declarations are also
+// written. Parsing the output yields the equivalent AST. When the node contains Bad* nodes, it
+// cannot be formatted and an error located at the broken part is returned.
+func Format(out io.Writer, node interface{}) (err error) {
+	f := &formatter{}
+	defer func() {
+		if r := recover(); r != nil {
+			e, ok := r.(formatError)
+			if !ok {
+				panic(r)
+			}
+			err = e.err
+		}
+	}()
+	f.node(node)
+	if _, ok := node.(*AST); ok {
+		f.buf.WriteByte('\n')
+	}
+	_, err = io.WriteString(out, f.buf.String())
+	return
+}
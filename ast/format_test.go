@@ -0,0 +1,82 @@
+package ast
+
+import (
+	"bytes"
+	"github.com/rhysd/locerr"
+	"goscilla/token"
+	"strings"
+	"testing"
+)
+
+func TestFormatNode(t *testing.T) {
+	tok := token.NewOrphanToken
+	ident := func(name string) *Ident { return &Ident{tok(token.ID, name), NewSymbol(name)} }
+	ref := func(name string) *VarRef { return &VarRef{tok(token.ID, name), NewSymbol(name)} }
+	prim := func(name string) Type { return &PrimType{Token: tok(token.CID, name)} }
+	adt := func(name string, args ...Type) Type { return &ADTType{Ident: ident(name), Args: args} }
+	tvar := &TypeVar{Token: tok(token.TID, "'A")}
+
+	for _, tc := range []struct {
+		node interface{}
+		want string
+	}{
+		{&MapType{Key: prim("ByStr20"), Value: &MapType{Key: prim("ByStr20"), Value: adt("Option", prim("Uint128"))}}, "Map ByStr20 (Map ByStr20 (Option Uint128))"},
+		{&FunType{Param: &FunType{Param: tvar, Ret: adt("Bool")}, Ret: adt("List", tvar)}, "('A -> Bool) -> List 'A"},
+		{&PolyType{TVar: ident("'A"), Body: &FunType{Param: tvar, Ret: tvar}}, "forall 'A. 'A -> 'A"},
+		{
+			&AddressType{ByStrToken: tok(token.BYSTR_TYPE, "ByStr20"), KindToken: tok(token.CONTRACT, "contract"), Fields: []*AddressField{
+				{nil, ident("admin"), prim("ByStr20")},
+				{nil, ident("paused"), adt("Bool")},
+			}},
+			"ByStr20 with contract field admin : ByStr20, field paused : Bool end",
+		},
+		{&ConstrPattern{Ctor: ident("Cons"), Args: []Pattern{&ConstrPattern{Ctor: ident("Pair"), Args: []Pattern{&BinderPattern{Ident: ident("a")}, &WildcardPattern{}}}, &ConstrPattern{Ctor: ident("Nil")}}}, "Cons (Pair a _) Nil"},
+		{&Builtin{nil, ident("blt"), nil, nil}, "builtin blt ()"},
+		{&Constr{ident("Nil"), nil, []Type{adt("Option", prim("Int32"))}, nil, nil}, "Nil {(Option Int32)}"},
+		{&TApp{nil, ref("list_map"), []Type{prim("Int32"), adt("List", prim("Int32"))}}, "@list_map Int32 (List Int32)"},
+		{&Message{nil, []*MessageEntry{{tok(token.SPID, "_tag"), &StringLit{tok(token.STRING_LIT, `"Foo"`)}}, {tok(token.ID, "x"), ref("x")}}, nil}, `{_tag : "Foo"; x : x}`},
+		{&Let{nil, ident("x"), prim("Uint32"), &IntLit{tok(token.INT_TYPE, "Uint32"), tok(token.NUM_LIT, "1")}, ref("x")}, "let x : Uint32 = Uint32 1 in\nx"},
+		{&Match{nil, ref("o"), []*MatchArm{
+			{nil, &ConstrPattern{Ctor: ident("Some"), Args: []Pattern{&BinderPattern{Ident: ident("v")}}}, ref("v")},
+			{nil, &ConstrPattern{Ctor: ident("None")}, &Fun{nil, &Param{Ident: ident("y"), Type: prim("Int32")}, ref("y")}},
+		}, nil}, "match o with\n| Some v => v\n| None =>\n  fun (y : Int32) =>\n  y\nend"},
+		{&MatchStmt{nil, ref("b"), []*StmtArm{
+			{nil, &ConstrPattern{Ctor: ident("True")}, nil},
+			{nil, &ConstrPattern{Ctor: ident("False")}, []Stmt{&Accept{}, &Throw{}}},
+		}, nil}, "match b with\n| True =>\n| False =>\n  accept;\n  throw\nend"},
+		{&RemoteMapGet{ident("b"), nil, tok(token.EXISTS, "exists"), ref("a"), ident("balances"), []*MapKey{{nil, ref("k"), nil}}}, "b <- & exists a.balances[k]"},
+	} {
+		var buf bytes.Buffer
+		if err := Format(&buf, tc.node); err != nil {
+			t.Fatal(err)
+		}
+		if have := buf.String(); have != tc.want {
+			t.Errorf("Wanted:\n%s\nbut got:\n%s", tc.want, have)
+		}
+	}
+}
+
+func TestFormatBadNode(t *testing.T) {
+	s := locerr.NewDummySource("transition Foo() x + end")
+	from := locerr.Pos{Offset: 17, Line: 1, Column: 18, File: s}
+	to := locerr.Pos{Offset: 20, Line: 1, Column: 21, File: s}
+	a := &AST{
+		Components: []*Component{{
+			Token: token.NewOrphanToken(token.TRANSITION, "transition"),
+			Ident: &Ident{token.NewOrphanToken(token.CID, "Foo"), NewSymbol("Foo")},
+			Body:  []Stmt{&BadStmt{from, to}},
+		}},
+		Source: s,
+	}
+	var buf bytes.Buffer
+	err := Format(&buf, a)
+	if err == nil {
+		t.Fatal("Error did not occur")
+	}
+	if !strings.Contains(err.Error(), "BadStmt cannot be formatted") {
+		t.Fatal("Unexpected error:", err)
+	}
+	if buf.Len() != 0 {
+		t.Fatal("Nothing should be written on error but got", buf.String())
+	}
+}
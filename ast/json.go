@@ -0,0 +1,294 @@
+package ast
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/rhysd/locerr"
+	"io"
+	"reflect"
+)
+
+// JSONSchema is the name of JSON schema emitted by FprintJSON.
+const JSONSchema = "goscilla.ast"
+
+// JSONSchemaVersion is the version of JSON schema emitted by FprintJSON. It is incremented on
+// every incompatible change of the schema. Adding a new field is not an incompatible change.
+const JSONSchemaVersion = 1
+
+// jsonField is a key-value pair of JSON object.
+type jsonField struct {
+	key   string
+	value interface{}
+}
+
+// jsonObject is a JSON object which preserves the order of keys.
+type jsonObject []jsonField
+
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, err := json.Marshal(f.key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(f.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func jsonPos(p locerr.Pos) jsonObject {
+	return jsonObject{{"line", p.Line}, {"column", p.Column}, {"offset", p.Offset}}
+}
+
+func jsonDoc(g *CommentGroup) interface{} {
+	if g == nil {
+		return nil
+	}
+	return g.Text()
+}
+
+// jsonNodes converts a slice of nodes such as []Stmt into JSON array. It is never null.
+func jsonNodes(nodes interface{}) []interface{} {
+	v := reflect.ValueOf(nodes)
+	arr := make([]interface{}, 0, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		arr = append(arr, jsonNode(v.Index(i).Interface().(Node)))
+	}
+	return arr
+}
+
+// jsonNode converts the node into JSON object. Every object has "kind", "start" and "end" keys
+// followed by fields specific to the kind. nil node is converted into null.
+func jsonNode(n Node) interface{} {
+	if isNilNode(n) {
+		return nil
+	}
+
+	var fields jsonObject
+	switch n := n.(type) {
+	case *Version:
+		fields = jsonObject{{"value", n.Value}}
+	case *Import:
+		fields = jsonObject{{"names", jsonNodes(n.Names)}}
+	case *ImportName:
+		fields = jsonObject{{"lib", jsonNode(n.Lib)}, {"alias", jsonNode(n.Alias)}}
+	case *Library:
+		fields = jsonObject{{"doc", jsonDoc(n.Doc)}, {"name", jsonNode(n.Ident)}, {"entries", jsonNodes(n.Entries)}}
+	case *LetDecl:
+		fields = jsonObject{
+			{"doc", jsonDoc(n.Doc)},
+			{"name", jsonNode(n.Ident)},
+			{"type", jsonNode(n.Type)},
+			{"bound", jsonNode(n.Bound)},
+			{"comment", jsonDoc(n.Comment)},
+		}
+	case *TypeDecl:
+		fields = jsonObject{{"doc", jsonDoc(n.Doc)}, {"name", jsonNode(n.Ident)}, {"ctors", jsonNodes(n.Ctors)}}
+	case *CtorDecl:
+		fields = jsonObject{
+			{"doc", jsonDoc(n.Doc)},
+			{"name", jsonNode(n.Ident)},
+			{"types", jsonNodes(n.Types)},
+			{"comment", jsonDoc(n.Comment)},
+		}
+	case *Contract:
+		fields = jsonObject{
+			{"doc", jsonDoc(n.Doc)},
+			{"name", jsonNode(n.Ident)},
+			{"params", jsonNodes(n.Params)},
+			{"constraint", jsonNode(n.Constraint)},
+			{"fields", jsonNodes(n.Fields)},
+			{"components", jsonNodes(n.Components)},
+		}
+	case *Param:
+		fields = jsonObject{
+			{"doc", jsonDoc(n.Doc)},
+			{"name", jsonNode(n.Ident)},
+			{"type", jsonNode(n.Type)},
+			{"comment", jsonDoc(n.Comment)},
+		}
+	case *Field:
+		fields = jsonObject{
+			{"doc", jsonDoc(n.Doc)},
+			{"name", jsonNode(n.Ident)},
+			{"type", jsonNode(n.Type)},
+			{"init", jsonNode(n.Init)},
+			{"comment", jsonDoc(n.Comment)},
+		}
+	case *Component:
+		fields = jsonObject{
+			{"doc", jsonDoc(n.Doc)},
+			{"component", n.Token.Value()},
+			{"name", jsonNode(n.Ident)},
+			{"params", jsonNodes(n.Params)},
+			{"body", jsonNodes(n.Body)},
+		}
+	case *Ident:
+		fields = jsonObject{{"name", n.Symbol.DisplayName}}
+	case *StringLit:
+		fields = jsonObject{{"literal", n.Token.Value()}}
+	case *IntLit:
+		fields = jsonObject{{"type", n.TypeToken.Value()}, {"literal", n.ValueToken.Value()}}
+	case *BNumLit:
+		fields = jsonObject{{"literal", n.ValueToken.Value()}}
+	case *HexLit:
+		fields = jsonObject{{"literal", n.Token.Value()}}
+	case *EmpLit:
+		fields = jsonObject{{"key_type", jsonNode(n.Key)}, {"value_type", jsonNode(n.Value)}}
+	case *VarRef:
+		fields = jsonObject{{"name", n.Symbol.DisplayName}}
+	case *Let:
+		fields = jsonObject{
+			{"name", jsonNode(n.Ident)},
+			{"type", jsonNode(n.Type)},
+			{"bound", jsonNode(n.Bound)},
+			{"body", jsonNode(n.Body)},
+		}
+	case *Fun:
+		fields = jsonObject{{"param", jsonNode(n.Param)}, {"body", jsonNode(n.Body)}}
+	case *TFun:
+		fields = jsonObject{{"tvar", jsonNode(n.TVar)}, {"body", jsonNode(n.Body)}}
+	case *App:
+		fields = jsonObject{{"func", jsonNode(n.Func)}, {"args", jsonNodes(n.Args)}}
+	case *TApp:
+		fields = jsonObject{{"func", jsonNode(n.Func)}, {"types", jsonNodes(n.Types)}}
+	case *Builtin:
+		fields = jsonObject{{"name", jsonNode(n.Ident)}, {"args", jsonNodes(n.Args)}}
+	case *Constr:
+		fields = jsonObject{{"name", jsonNode(n.Ident)}, {"type_args", jsonNodes(n.TypeArgs)}, {"args", jsonNodes(n.Args)}}
+	case *Message:
+		fields = jsonObject{{"entries", jsonNodes(n.Entries)}}
+	case *MessageEntry:
+		fields = jsonObject{{"key", n.Key.Value()}, {"value", jsonNode(n.Value)}}
+	case *Match:
+		fields = jsonObject{{"target", jsonNode(n.Target)}, {"arms", jsonNodes(n.Arms)}}
+	case *MatchArm:
+		fields = jsonObject{{"pattern", jsonNode(n.Pattern)}, {"body", jsonNode(n.Body)}}
+	case *Load:
+		fields = jsonObject{{"lhs", jsonNode(n.Ident)}, {"field", jsonNode(n.Field)}}
+	case *RemoteLoad:
+		fields = jsonObject{{"lhs", jsonNode(n.Ident)}, {"addr", jsonNode(n.Addr)}, {"field", jsonNode(n.Field)}}
+	case *Store:
+		fields = jsonObject{{"field", jsonNode(n.Field)}, {"value", jsonNode(n.Value)}}
+	case *Bind:
+		fields = jsonObject{{"lhs", jsonNode(n.Ident)}, {"value", jsonNode(n.Value)}}
+	case *MapKey:
+		fields = jsonObject{{"key", jsonNode(n.Key)}}
+	case *MapUpdate:
+		fields = jsonObject{{"map", jsonNode(n.Map)}, {"keys", jsonNodes(n.Keys)}, {"value", jsonNode(n.Value)}}
+	case *MapDelete:
+		fields = jsonObject{{"map", jsonNode(n.Map)}, {"keys", jsonNodes(n.Keys)}}
+	case *MapGet:
+		fields = jsonObject{
+			{"lhs", jsonNode(n.Ident)},
+			{"exists", n.ExistsToken != nil},
+			{"map", jsonNode(n.Map)},
+			{"keys", jsonNodes(n.Keys)},
+		}
+	case *RemoteMapGet:
+		fields = jsonObject{
+			{"lhs", jsonNode(n.Ident)},
+			{"exists", n.ExistsToken != nil},
+			{"addr", jsonNode(n.Addr)},
+			{"map", jsonNode(n.Map)},
+			{"keys", jsonNodes(n.Keys)},
+		}
+	case *ReadFromBC:
+		fields = jsonObject{{"lhs", jsonNode(n.Ident)}, {"query", n.Query.Value()}}
+	case *Accept:
+	case *Send:
+		fields = jsonObject{{"msgs", jsonNode(n.Msgs)}}
+	case *Event:
+		fields = jsonObject{{"event", jsonNode(n.Event)}}
+	case *Throw:
+		fields = jsonObject{{"exception", jsonNode(n.Exception)}}
+	case *MatchStmt:
+		fields = jsonObject{{"target", jsonNode(n.Target)}, {"arms", jsonNodes(n.Arms)}}
+	case *StmtArm:
+		fields = jsonObject{{"pattern", jsonNode(n.Pattern)}, {"body", jsonNodes(n.Body)}}
+	case *CallProc:
+		fields = jsonObject{{"proc", jsonNode(n.Proc)}, {"args", jsonNodes(n.Args)}}
+	case *Iterate:
+		fields = jsonObject{{"list", jsonNode(n.List)}, {"proc", jsonNode(n.Proc)}}
+	case *WildcardPattern:
+	case *BinderPattern:
+		fields = jsonObject{{"name", jsonNode(n.Ident)}}
+	case *ConstrPattern:
+		fields = jsonObject{{"ctor", jsonNode(n.Ctor)}, {"args", jsonNodes(n.Args)}}
+	case *PrimType:
+		fields = jsonObject{{"name", n.Token.Value()}}
+	case *MapType:
+		fields = jsonObject{{"key", jsonNode(n.Key)}, {"value", jsonNode(n.Value)}}
+	case *FunType:
+		fields = jsonObject{{"param", jsonNode(n.Param)}, {"ret", jsonNode(n.Ret)}}
+	case *PolyType:
+		fields = jsonObject{{"tvar", jsonNode(n.TVar)}, {"body", jsonNode(n.Body)}}
+	case *TypeVar:
+		fields = jsonObject{{"name", n.Token.Value()}}
+	case *ADTType:
+		fields = jsonObject{{"name", jsonNode(n.Ident)}, {"args", jsonNodes(n.Args)}}
+	case *AddressType:
+		kind := "any"
+		if n.KindToken != nil {
+			kind = n.KindToken.Value()
+		}
+		fields = jsonObject{{"bystr", n.ByStrToken.Value()}, {"address_kind", kind}, {"fields", jsonNodes(n.Fields)}}
+	case *AddressField:
+		fields = jsonObject{{"name", jsonNode(n.Ident)}, {"type", jsonNode(n.Type)}}
+	case *BadDecl, *BadExpr, *BadStmt, *BadPattern:
+	default:
+		panic(fmt.Sprintf("FATAL: Unknown node %T", n))
+	}
+
+	kind := fmt.Sprintf("%T", n)[len("*ast."):]
+	obj := jsonObject{{"kind", kind}, {"start", jsonPos(n.Pos())}, {"end", jsonPos(n.End())}}
+	return append(obj, fields...)
+}
+
+// isNilNode returns true when n is nil or a nil pointer stored in an interface.
+func isNilNode(n Node) bool {
+	if n == nil {
+		return true
+	}
+	v := reflect.ValueOf(n)
+	return v.Kind() == reflect.Ptr && v.IsNil()
+}
+
+// FprintJSON outputs the AST as JSON to given io.Writer object. The root object has "schema",
+// "version", "file" and "module" keys. Each node is an object which has "kind" (the node type
+// name such as "LetDecl"), "start" and "end" positions and child fields of the node.
+func FprintJSON(out io.Writer, a *AST) error {
+	module := jsonObject{
+		{"version", jsonNode(a.Version)},
+		{"imports", jsonNodes(a.Imports)},
+		{"library", jsonNode(a.Library)},
+		{"contract", jsonNode(a.Contract)},
+		{"components", jsonNodes(a.Components)},
+		{"bad_decls", jsonNodes(a.BadDecls)},
+	}
+	root := jsonObject{
+		{"schema", JSONSchema},
+		{"version", JSONSchemaVersion},
+		{"file", a.File().Path},
+		{"module", module},
+	}
+	b, err := json.MarshalIndent(root, "", "  ")
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	_, err = out.Write(b)
+	return err
+}
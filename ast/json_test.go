@@ -0,0 +1,471 @@
+package ast
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/rhysd/locerr"
+	"goscilla/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFprintJSON(t *testing.T) {
+	s := locerr.NewDummySource("")
+	pos := func(line, col int) locerr.Pos {
+		return locerr.Pos{Offset: col - 1, Line: line, Column: col, File: s}
+	}
+	tok := func(kind token.Kind, v string, line, col int) *token.Token {
+		t := &token.Token{Kind: kind, Start: pos(line, col), End: pos(line, col+len(v)), File: s}
+		t.SetLiteral(v)
+		return t
+	}
+
+	// library Foo
+	// (* The one *)
+	// let one = Uint32 1
+	one := tok(token.ID, "one", 3, 5)
+	a := &AST{
+		Library: &Library{
+			LibraryToken: tok(token.LIBRARY, "library", 1, 1),
+			Ident:        &Ident{tok(token.CID, "Foo", 1, 9), NewSymbol("Foo")},
+			Entries: []LibEntry{
+				&LetDecl{
+					Doc:      &CommentGroup{[]*Comment{{tok(token.COMMENT, "(* The one *)", 2, 1)}}},
+					LetToken: tok(token.LET, "let", 3, 1),
+					Ident:    &Ident{one, NewSymbol("one")},
+					Bound:    &IntLit{tok(token.INT_TYPE, "Uint32", 3, 11), tok(token.NUM_LIT, "1", 3, 18)},
+				},
+			},
+		},
+		Source: s,
+	}
+
+	var buf bytes.Buffer
+	if err := FprintJSON(&buf, a); err != nil {
+		t.Fatal(err)
+	}
+
+	var have map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &have); err != nil {
+		t.Fatal(err, buf.String())
+	}
+
+	p := func(line, col int) map[string]interface{} {
+		return map[string]interface{}{"line": float64(line), "column": float64(col), "offset": float64(col - 1)}
+	}
+	want := map[string]interface{}{
+		"schema":  JSONSchema,
+		"version": float64(JSONSchemaVersion),
+		"file":    "<dummy>",
+		"module": map[string]interface{}{
+			"version": nil,
+			"imports": []interface{}{},
+			"library": map[string]interface{}{
+				"kind":  "Library",
+				"start": p(1, 1),
+				"end":   p(3, 19),
+				"doc":   nil,
+				"name":  map[string]interface{}{"kind": "Ident", "start": p(1, 9), "end": p(1, 12), "name": "Foo"},
+				"entries": []interface{}{
+					map[string]interface{}{
+						"kind":  "LetDecl",
+						"start": p(3, 1),
+						"end":   p(3, 19),
+						"doc":   "The one",
+						"name":  map[string]interface{}{"kind": "Ident", "start": p(3, 5), "end": p(3, 8), "name": "one"},
+						"type":  nil,
+						"bound": map[string]interface{}{
+							"kind":    "IntLit",
+							"start":   p(3, 11),
+							"end":     p(3, 19),
+							"type":    "Uint32",
+							"literal": "1",
+						},
+						"comment": nil,
+					},
+				},
+			},
+			"contract":   nil,
+			"components": []interface{}{},
+			"bad_decls":  []interface{}{},
+		},
+	}
+
+	if !reflect.DeepEqual(have, want) {
+		t.Fatalf("Unexpected JSON output:\n%s", buf.String())
+	}
+
+	// Keys are emitted in stable order
+	if !bytes.HasPrefix(buf.Bytes(), []byte("{\n  \"schema\": \"goscilla.ast\",\n  \"version\": 1,\n")) {
+		t.Fatalf("Unexpected header of JSON output:\n%s", buf.String())
+	}
+}
+
+func TestScillaTypeString(t *testing.T) {
+	tok := func(kind token.Kind, v string) *token.Token {
+		t := &token.Token{Kind: kind}
+		t.SetLiteral(v)
+		return t
+	}
+	prim := func(name string) Type { return &PrimType{Token: tok(token.CID, name)} }
+	adt := func(name string, args ...Type) Type {
+		return &ADTType{Ident: &Ident{tok(token.CID, name), NewSymbol(name)}, Args: args}
+	}
+	tvar := &TypeVar{Token: tok(token.TID, "'A")}
+
+	for _, tc := range []struct {
+		ty   Type
+		want string
+	}{
+		{prim("Uint128"), "Uint128"},
+		{&MapType{Key: prim("ByStr20"), Value: &MapType{Key: prim("ByStr20"), Value: prim("Uint128")}}, "Map (ByStr20) (Map (ByStr20) (Uint128))"},
+		{adt("List", adt("Option", prim("Int32"))), "List (Option (Int32))"},
+		{adt("Bool"), "Bool"},
+		{&FunType{Param: &FunType{Param: tvar, Ret: prim("Bool")}, Ret: adt("List", tvar)}, "('A -> Bool) -> List ('A)"},
+		{&PolyType{TVar: &Ident{tok(token.TID, "'A"), NewSymbol("'A")}, Body: &FunType{Param: tvar, Ret: tvar}}, "forall 'A. 'A -> 'A"},
+		{&AddressType{ByStrToken: tok(token.CID, "ByStr20")}, "ByStr20 with end"},
+		{&AddressType{ByStrToken: tok(token.CID, "ByStr20"), KindToken: tok(token.LIBRARY, "library")}, "ByStr20 with library end"},
+		{
+			&AddressType{ByStrToken: tok(token.CID, "ByStr20"), KindToken: tok(token.CONTRACT, "contract"), Fields: []*AddressField{
+				{nil, &Ident{tok(token.ID, "admin"), NewSymbol("admin")}, prim("ByStr20")},
+				{nil, &Ident{tok(token.ID, "paused"), NewSymbol("paused")}, adt("Bool")},
+			}},
+			"ByStr20 with contract field admin : ByStr20, field paused : Bool end",
+		},
+	} {
+		if have := ScillaTypeString(tc.ty); have != tc.want {
+			t.Errorf("Wanted %q but got %q", tc.want, have)
+		}
+	}
+}
+
+// contract Counter(owner : ByStr20)
+// field counts : Map ByStr20 Uint32 = Emp ByStr20 Uint32
+// transition Count(by : Uint32)
+//
+//	c <- counts[_sender];
+//	e = {_eventname : "Counted"; who : _sender; by : by; current : c; raw : raw};
+//	event e
+//
+// end
+func counterContract() *AST {
+	s := locerr.NewDummySource("")
+	tok := func(kind token.Kind, v string) *token.Token {
+		t := &token.Token{Kind: kind, File: s}
+		t.SetLiteral(v)
+		return t
+	}
+	ident := func(name string) *Ident { return &Ident{tok(token.ID, name), NewSymbol(name)} }
+	ref := func(name string) *VarRef { return &VarRef{tok(token.ID, name), NewSymbol(name)} }
+	prim := func(name string) Type { return &PrimType{Token: tok(token.CID, name)} }
+	counts := &MapType{MapToken: tok(token.MAP, "Map"), Key: prim("ByStr20"), Value: prim("Uint32")}
+	entry := func(k string, v Expr) *MessageEntry { return &MessageEntry{tok(token.ID, k), v} }
+
+	return &AST{
+		Version: &Version{tok(token.SCILLA_VERSION, "scilla_version"), tok(token.NUM_LIT, "0"), 0},
+		Contract: &Contract{
+			ContractToken: tok(token.CONTRACT, "contract"),
+			Ident:         &Ident{tok(token.CID, "Counter"), NewSymbol("Counter")},
+			Params:        []*Param{{Ident: ident("owner"), Type: prim("ByStr20")}},
+			Fields: []*Field{{
+				FieldToken: tok(token.FIELD, "field"),
+				Ident:      ident("counts"),
+				Type:       counts,
+				Init:       &EmpLit{tok(token.EMP, "Emp"), prim("ByStr20"), prim("Uint32")},
+			}},
+			Components: []*Component{{
+				Token:  tok(token.TRANSITION, "transition"),
+				Ident:  &Ident{tok(token.CID, "Count"), NewSymbol("Count")},
+				Params: []*Param{{Ident: ident("by"), Type: prim("Uint32")}},
+				Body: []Stmt{
+					&MapGet{Ident: ident("c"), Map: ref("counts"), Keys: []*MapKey{{Key: ref("_sender")}}},
+					&Bind{ident("e"), &Message{Entries: []*MessageEntry{
+						entry("_eventname", &StringLit{tok(token.STRING_LIT, `"Counted"`)}),
+						entry("who", ref("_sender")),
+						entry("by", ref("by")),
+						entry("current", ref("c")),
+						entry("raw", ref("raw")),
+					}}},
+					&Event{tok(token.EVENT, "event"), ref("e")},
+				},
+			}},
+		},
+		Source: s,
+	}
+}
+
+func TestFprintContractInfo(t *testing.T) {
+	var buf bytes.Buffer
+	// Types given by the type checker
+	typeOf := func(e Expr) string {
+		return map[string]string{
+			"_sender": "ByStr20",
+			"by":      "Uint32",
+			"c":       "Option (Uint32)",
+			"raw":     "ByStr",
+		}[e.(*VarRef).Symbol.Name]
+	}
+	if err := FprintContractInfo(&buf, counterContract(), typeOf); err != nil {
+		t.Fatal(err)
+	}
+
+	var have struct {
+		Info     ContractInfo          `json:"contract_info"`
+		Warnings []ContractInfoWarning `json:"warnings"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &have); err != nil {
+		t.Fatal(err, buf.String())
+	}
+	info := have.Info
+
+	if info.ScillaMajorVersion != "0" || info.Name != "Counter" {
+		t.Fatalf("Unexpected header of contract info:\n%s", buf.String())
+	}
+	if want := []ContractInfoParam{{"owner", "ByStr20"}}; !reflect.DeepEqual(info.Params, want) {
+		t.Errorf("Wanted params %v but got %v", want, info.Params)
+	}
+	if want := []ContractInfoField{{"counts", "Map (ByStr20) (Uint32)", 1}}; !reflect.DeepEqual(info.Fields, want) {
+		t.Errorf("Wanted fields %v but got %v", want, info.Fields)
+	}
+	if want := []ContractInfoComponent{{"Count", []ContractInfoParam{{"by", "Uint32"}}}}; !reflect.DeepEqual(info.Transitions, want) {
+		t.Errorf("Wanted transitions %v but got %v", want, info.Transitions)
+	}
+	if len(info.Procedures) != 0 {
+		t.Errorf("Wanted no procedure but got %v", info.Procedures)
+	}
+	want := []ContractInfoEvent{{"Counted", []ContractInfoParam{
+		{"who", "ByStr20"},
+		{"by", "Uint32"},
+		{"current", "Option (Uint32)"},
+		{"raw", "ByStr"},
+	}}}
+	if !reflect.DeepEqual(info.Events, want) {
+		t.Errorf("Wanted events %v but got %v", want, info.Events)
+	}
+	if len(info.ADTs) != len(builtinADTs) || info.ADTs[0].Name != "Bool" {
+		t.Errorf("Unexpected ADTs: %v", info.ADTs)
+	}
+	if len(have.Warnings) != 0 {
+		t.Errorf("Unexpected warnings: %v", have.Warnings)
+	}
+
+	if _, err := NewContractInfo(&AST{}, typeOf); err == nil {
+		t.Error("Error should occur when no contract is defined")
+	}
+}
+
+func TestFprintScillaJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := FprintScillaJSON(&buf, counterContract()); err != nil {
+		t.Fatal(err)
+	}
+
+	var have struct {
+		SMVer int             `json:"smver"`
+		Libs  interface{}     `json:"libs"`
+		ELibs []interface{}   `json:"elibs"`
+		Contr json.RawMessage `json:"contr"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &have); err != nil {
+		t.Fatal(err, buf.String())
+	}
+	if have.SMVer != 0 || have.Libs != nil || len(have.ELibs) != 0 {
+		t.Fatalf("Unexpected module header:\n%s", buf.String())
+	}
+
+	var contr struct {
+		Params     [][]interface{} `json:"cparams"`
+		Constraint []interface{}   `json:"cconstraint"`
+		Fields     [][]interface{} `json:"cfields"`
+		Comps      []struct {
+			Type []string        `json:"comp_type"`
+			Body [][]interface{} `json:"comp_body"`
+		} `json:"ccomps"`
+	}
+	if err := json.Unmarshal(have.Contr, &contr); err != nil {
+		t.Fatal(err)
+	}
+	if len(contr.Params) != 1 || !reflect.DeepEqual(contr.Params[0][1], []interface{}{"PrimType", []interface{}{"Bystrx_typ", float64(20)}}) {
+		t.Errorf("Unexpected contract params: %v", contr.Params)
+	}
+	if contr.Constraint[0] != "Constr" {
+		t.Errorf("Missing constraint should be True but got %v", contr.Constraint)
+	}
+	if len(contr.Fields) != 1 || contr.Fields[0][2].([]interface{})[0] != "Literal" {
+		t.Errorf("Unexpected fields: %v", contr.Fields)
+	}
+	if len(contr.Comps) != 1 || !reflect.DeepEqual(contr.Comps[0].Type, []string{"CompTrans"}) {
+		t.Fatalf("Unexpected components: %v", contr.Comps)
+	}
+	var stmts []interface{}
+	for _, s := range contr.Comps[0].Body {
+		stmts = append(stmts, s[0])
+	}
+	if want := []interface{}{"MapGet", "Bind", "CreateEvnt"}; !reflect.DeepEqual(stmts, want) {
+		t.Errorf("Wanted statements %v but got %v", want, stmts)
+	}
+}
+
+func TestReadScillaJSON(t *testing.T) {
+	var want bytes.Buffer
+	if err := FprintScillaJSON(&want, counterContract()); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := ReadScillaJSON(bytes.NewReader(want.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Contract == nil || a.Contract.Constraint != nil || len(a.Contract.Components) != 1 {
+		t.Fatalf("Unexpected contract: %v", a.Contract)
+	}
+	body := a.Contract.Components[0].Body
+	if g, ok := body[0].(*MapGet); !ok || g.ExistsToken != nil || len(g.Keys) != 1 {
+		t.Errorf("Unexpected first statement: %#v", body[0])
+	}
+
+	var have bytes.Buffer
+	if err := FprintScillaJSON(&have, a); err != nil {
+		t.Fatal(err)
+	}
+	if have.String() != want.String() {
+		t.Fatalf("Imported AST is different from original.\nwant:\n%s\nhave:\n%s", want.String(), have.String())
+	}
+}
+
+func TestReadScillaJSONFile(t *testing.T) {
+	// HelloWorld contract of Scilla documentation (testdata/hello_world.scilla) written by hand after
+	// Syntax.ml of Zilliqa/scilla in the shape of ppx_deriving_yojson. It is not captured from
+	// scilla-checker so it is not a reference output. Types, literals and builtins are OCaml variants
+	// and identifiers have locations
+	f, err := os.Open(filepath.Join("testdata", "hello_world.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	a, err := ReadScillaJSON(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if a.Version == nil || a.Version.Value != 0 || len(a.Imports) != 0 {
+		t.Errorf("Unexpected header: %v %v", a.Version, a.Imports)
+	}
+	if a.Library == nil || a.Library.Ident.Symbol.DisplayName != "HelloWorld" || len(a.Library.Entries) != 2 {
+		t.Fatalf("Unexpected library: %v", a.Library)
+	}
+	code := a.Library.Entries[1].(*LetDecl)
+	if lit := code.Bound.(*IntLit); code.Type != nil || lit.TypeToken.Value() != "Int32" || lit.ValueToken.Value() != "2" {
+		t.Errorf("Unexpected library entry %s: %v", code.Ident.Symbol.DisplayName, code.Bound)
+	}
+
+	c := a.Contract
+	if c == nil || c.Ident.Symbol.DisplayName != "HelloWorld" || c.Constraint != nil {
+		t.Fatalf("Unexpected contract: %v", c)
+	}
+	if p := c.Params[0]; p.Ident.Symbol.DisplayName != "owner" || ScillaTypeString(p.Type) != "ByStr20" {
+		t.Errorf("Unexpected contract parameter: %v", p)
+	}
+	if f := c.Fields[0]; ScillaTypeString(f.Type) != "String" || f.Init.(*StringLit).Token.Value() != `""` {
+		t.Errorf("Unexpected field: %v", f)
+	}
+	if pos := c.Fields[0].Ident.Pos(); pos.Line != 10 || pos.Column != 7 || pos.File.Path != "hello_world.scilla" {
+		t.Errorf("Unexpected location of field: %v", pos)
+	}
+
+	if len(c.Components) != 2 {
+		t.Fatalf("Wanted 2 transitions but got %d", len(c.Components))
+	}
+	set := c.Components[0].Body
+	if b := set[0].(*Bind).Value.(*Builtin); b.Ident.Symbol.DisplayName != "eq" || len(b.Args) != 2 {
+		t.Errorf("Unexpected builtin: %v", b)
+	}
+	m := set[1].(*MatchStmt)
+	if len(m.Arms) != 2 || m.Arms[1].Pattern.(*ConstrPattern).Ctor.Symbol.DisplayName != "True" {
+		t.Fatalf("Unexpected match: %v", m)
+	}
+	if p := m.Arms[1].Pattern.Pos(); p.Line != 18 || p.Column != 5 {
+		t.Errorf("Unexpected position of constructor pattern: %v", p)
+	}
+	arm := m.Arms[1].Body
+	if s, ok := arm[0].(*Store); !ok || s.Field.Symbol.DisplayName != "welcome_msg" || s.Value.Symbol.DisplayName != "msg" {
+		t.Errorf("Unexpected store: %#v", arm[0])
+	}
+	msg := arm[1].(*Bind).Value.(*Message)
+	if len(msg.Entries) != 2 || msg.Entries[0].Key.Value() != "_eventname" || msg.Entries[1].Value.(*VarRef).Symbol.DisplayName != "set_hello_code" {
+		t.Errorf("Unexpected message: %v", msg)
+	}
+	if _, ok := arm[2].(*Event); !ok {
+		t.Errorf("Unexpected event: %#v", arm[2])
+	}
+
+	get := c.Components[1]
+	if len(get.Params) != 0 || len(get.Body) != 3 {
+		t.Fatalf("Unexpected transition %s: %v", get.Ident.Symbol.DisplayName, get.Body)
+	}
+	if l, ok := get.Body[0].(*Load); !ok || l.Ident.Symbol.DisplayName != "r" || l.Field.Symbol.DisplayName != "welcome_msg" {
+		t.Errorf("Unexpected load: %#v", get.Body[0])
+	}
+}
+
+func TestReadScillaJSONOCamlVariants(t *testing.T) {
+	// Primitive types, integer literals and builtins in the shape of ppx_deriving_yojson
+	in := `{
+	  "smver": 0,
+	  "libs": {"lname": {"vname": "Lib"}, "lentries": [
+	    ["LibVar", {"vname": "one"}, ["PrimType", ["Uint_typ", ["Bits32"]]], ["Literal", ["UintLit", ["Uint32L", "1"]]]],
+	    ["LibVar", {"vname": "two"}, null, ["Builtin", ["Builtin_add"], [{"vname": "one"}, {"vname": "one"}]]],
+	    ["LibVar", {"vname": "h"}, null, ["Builtin", ["Builtin_to_bystrx", 20], [{"vname": "one"}]]]
+	  ]},
+	  "elibs": [],
+	  "contr": null
+	}`
+	a, err := ReadScillaJSON(bytes.NewReader([]byte(in)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	one := a.Library.Entries[0].(*LetDecl)
+	if ty := ScillaTypeString(one.Type); ty != "Uint32" {
+		t.Errorf("Wanted Uint32 but got %q", ty)
+	}
+	if lit := one.Bound.(*IntLit); lit.TypeToken.Value() != "Uint32" || lit.ValueToken.Value() != "1" {
+		t.Errorf("Unexpected literal: %s %s", lit.TypeToken.Value(), lit.ValueToken.Value())
+	}
+	two := a.Library.Entries[1].(*LetDecl).Bound.(*Builtin)
+	if two.Ident.Symbol.DisplayName != "add" || len(two.Args) != 2 {
+		t.Errorf("Unexpected builtin: %s %v", two.Ident.Symbol.DisplayName, two.Args)
+	}
+	if h := a.Library.Entries[2].(*LetDecl).Bound.(*Builtin); h.Ident.Symbol.DisplayName != "to_bystr20" {
+		t.Errorf("Unexpected builtin: %s", h.Ident.Symbol.DisplayName)
+	}
+}
+
+func TestReadScillaJSONError(t *testing.T) {
+	for _, tc := range []struct {
+		what string
+		in   string
+		want string
+	}{
+		{"broken JSON", `{"smver": `, "unexpected EOF"},
+		{"not object", `[]`, "module must be an object"},
+		{"unknown statement", `{"contr": {"cname": {"vname": "C"}, "cparams": [], "cfields": [], "ccomps": [
+			{"comp_type": ["CompTrans"], "comp_name": {"vname": "T"}, "comp_params": [], "comp_body": [["Foo"]]}]}}`, `unknown statement "Foo"`},
+		{"wrong arity", `{"libs": {"lname": {"vname": "L"}, "lentries": [["LibVar", {"vname": "x"}]]}}`, "LibVar must have 3 arguments but got 1"},
+		{"plain string identifier", `{"libs": {"lname": "L", "lentries": []}}`, `identifier must be an object but got "L"`},
+		{"plain string type", `{"libs": {"lname": {"vname": "L"}, "lentries": [
+			["LibVar", {"vname": "x"}, ["PrimType", "Uint32"], ["Literal", ["StringLit", ""]]]]}}`, `primitive type must be an array but got "Uint32"`},
+	} {
+		t.Run(tc.what, func(t *testing.T) {
+			_, err := ReadScillaJSON(bytes.NewReader([]byte(tc.in)))
+			if err == nil {
+				t.Fatal("Error did not occur")
+			}
+			if !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("Wanted %q in error message but got %q", tc.want, err.Error())
+			}
+		})
+	}
+}
@@ -8,52 +8,96 @@ import (
 	"strings"
 )
 
-// Type t =
-//   | Unit
-//   | Bool of bool
-//   | Int of int
-//   | Float of float
-//   | Not of t
-//   | Neg of t
-//   | Add of t * t
-//   | Sub of t * t
-//   | FNeg of t
-//   | FAdd of t * t
-//   | FSub of t * t
-//   | FMul of t * t
-//   | FDiv of t * t
-//   | Eq of t * t
-//   | LE of t * t
-//   | If of t * t * t
-//   | Let of (Id.t * Type.t) * t * t
-//   | Var of Id.t
-//   | LetRec of fundef * t
-//   | App of t * t list
-//   | Tuple of t list
-//   | LetTuple of (Id.t * Type.t) list * t * t
-//   | Array of t * t
-//   | Get of t * t
-//   | Put of t * t * t
-// and fundef = { name : Id.t * Type.t; args : (Id.t * Type.t) list; body : t }
-
+// Structure of a Scilla module (see ScillaParser.mly of Zilliqa/scilla):
+//
+//   scilla_version 0
+//   import ListUtils IntUtils as I
+//   library Foo
+//     let x : T = e
+//     type T = | A of T1 T2 | B
+//   contract Foo(p : T) with e =>
+//     field f : T = e
+//     transition t(p : T) stmts end
+//     procedure p(p : T) stmts end
+
+// AST is a root of parsed Scilla source.
+// Components is only filled when the source is a snippet which contains
+// components without a contract header (e.g. a single procedure).
+// BadDecls holds module level declarations which could not be parsed.
 type AST struct {
-	Root      Expr
-	TypeDecls []*TypeDecl
-	Externals []*External
+	Version    *Version // Maybe nil
+	Imports    []*Import
+	Library    *Library  // Maybe nil
+	Contract   *Contract // Maybe nil
+	Components []*Component
+	BadDecls   []*BadDecl
+	Source     *locerr.Source
+	EOFToken   *token.Token // Maybe nil when the AST was not parsed from source
 }
 
 func (a *AST) File() *locerr.Source {
-	return a.Root.Pos().File
+	return a.Source
+}
+
+// Pos returns the start of the source. AST is a node so that the whole module can be visited
+// and rewritten.
+func (a *AST) Pos() locerr.Pos {
+	return locerr.Pos{Offset: 0, Line: 1, Column: 1, File: a.Source}
+}
+
+// End returns the end of the source.
+func (a *AST) End() locerr.Pos {
+	if a.EOFToken == nil {
+		return a.Pos()
+	}
+	return a.EOFToken.End
 }
 
-// Expr is an interface for node of GoScilla AST.
+func (a *AST) Name() string {
+	if a.Source == nil {
+		return "AST"
+	}
+	return fmt.Sprintf("AST (%s)", a.Source.Path)
+}
+
+// Node is an interface for node of GoScilla AST.
 // All nodes have its position and name.
-type Expr interface {
+type Node interface {
 	Pos() locerr.Pos
 	End() locerr.Pos
 	Name() string
 }
 
+// Expr is a node of Scilla expression (right hand side of `let` and `=`).
+type Expr interface {
+	Node
+	exprNode()
+}
+
+// Stmt is a node of Scilla statement in body of transitions and procedures.
+type Stmt interface {
+	Node
+	stmtNode()
+}
+
+// Type is a node of type annotation.
+type Type interface {
+	Node
+	typeNode()
+}
+
+// Pattern is a node of pattern in match arms.
+type Pattern interface {
+	Node
+	patternNode()
+}
+
+// LibEntry is a `let` or `type` declaration in library.
+type LibEntry interface {
+	Node
+	libEntryNode()
+}
+
 // Note:
 // This struct cannot be replaced with string because there may be the
 // same name symbol.
@@ -79,649 +123,1170 @@ func (s *Symbol) IsIgnored() bool {
 	return strings.HasPrefix(s.Name, "$unused")
 }
 
-type Param struct {
-	Ident *Symbol
-	Type  Expr
-}
+// Module level nodes
+type (
+	// scilla_version 0
+	Version struct {
+		VersionToken *token.Token
+		NumToken     *token.Token
+		Value        int
+	}
 
-type FuncDef struct {
-	Symbol  *Symbol
-	Params  []Param
-	Body    Expr
-	RetType Expr
-}
+	// import ListUtils IntUtils as I
+	Import struct {
+		ImportToken *token.Token
+		Names       []*ImportName
+	}
 
-func (d *FuncDef) ParamSymbols() []*Symbol {
-	syms := make([]*Symbol, 0, len(d.Params))
-	for _, p := range d.Params {
-		syms = append(syms, p.Ident)
+	// ListUtils, or `IntUtils as I`
+	ImportName struct {
+		Lib   *Ident
+		Alias *Ident // Maybe nil
 	}
-	return syms
-}
 
-// AST node which meets Expr interface
-type (
-	Unit struct {
-		LParenToken *token.Token
+	Library struct {
+		Doc          *CommentGroup // Maybe nil
+		LibraryToken *token.Token
+		Ident        *Ident
+		Entries      []LibEntry
+	}
+
+	// let x : T = e
+	LetDecl struct {
+		Doc      *CommentGroup // Maybe nil
+		LetToken *token.Token
+		Ident    *Ident
+		Type     Type // Maybe nil
+		Bound    Expr
+		Comment  *CommentGroup // Maybe nil
+	}
+
+	// type T = | A of T1 T2 | B
+	TypeDecl struct {
+		Doc       *CommentGroup // Maybe nil
+		TypeToken *token.Token
+		Ident     *Ident
+		Ctors     []*CtorDecl
+	}
+
+	// | A of T1 T2
+	CtorDecl struct {
+		Doc      *CommentGroup // Maybe nil
+		BarToken *token.Token
+		Ident    *Ident
+		Types    []Type
+		Comment  *CommentGroup // Maybe nil
+	}
+
+	// contract Foo(p : T) with e => fields components
+	Contract struct {
+		Doc           *CommentGroup // Maybe nil
+		ContractToken *token.Token
+		Ident         *Ident
+		Params        []*Param
+		RParenToken   *token.Token
+		Constraint    Expr // Maybe nil
+		Fields        []*Field
+		Components    []*Component
+	}
+
+	// x : T
+	Param struct {
+		Doc     *CommentGroup // Maybe nil
+		Ident   *Ident
+		Type    Type
+		Comment *CommentGroup // Maybe nil
+	}
+
+	// field f : T = e
+	Field struct {
+		Doc        *CommentGroup // Maybe nil
+		FieldToken *token.Token
+		Ident      *Ident
+		Type       Type
+		Init       Expr
+		Comment    *CommentGroup // Maybe nil
+	}
+
+	// transition/procedure name(params) body end
+	Component struct {
+		Doc         *CommentGroup // Maybe nil
+		Token       *token.Token
+		Ident       *Ident
+		Params      []*Param
 		RParenToken *token.Token
+		Body        []Stmt
+		EndToken    *token.Token
 	}
 
-	Bool struct {
-		Token *token.Token
-		Value bool
+	// Ident is a name introduced by declarations or binders, e.g. `x` in `let x = e`.
+	Ident struct {
+		Token  *token.Token
+		Symbol *Symbol
 	}
+)
 
-	Int struct {
+// Expression nodes which meet Expr interface
+type (
+	// "foo"
+	StringLit struct {
 		Token *token.Token
-		Value int64
 	}
 
-	Float struct {
-		Token *token.Token
-		Value float64
+	// Uint128 42, Int32 -1
+	IntLit struct {
+		TypeToken  *token.Token
+		ValueToken *token.Token
+	}
+
+	// BNum 100
+	BNumLit struct {
+		TypeToken  *token.Token
+		ValueToken *token.Token
 	}
 
-	String struct {
+	// 0x1234abcd
+	HexLit struct {
 		Token *token.Token
-		Value string
 	}
 
-	Not struct {
-		OpToken *token.Token
-		Child   Expr
+	// Emp K V
+	EmpLit struct {
+		EmpToken *token.Token
+		Key      Type
+		Value    Type
 	}
 
-	Neg struct {
-		MinusToken *token.Token
-		Child      Expr
+	VarRef struct {
+		Token  *token.Token
+		Symbol *Symbol
+	}
+
+	// let x : T = e in e
+	Let struct {
+		LetToken    *token.Token
+		Ident       *Ident
+		Type        Type // Maybe nil
+		Bound, Body Expr
 	}
 
-	Add struct {
-		Left, Right Expr
+	// fun (x : T) => e
+	Fun struct {
+		FunToken *token.Token
+		Param    *Param
+		Body     Expr
 	}
 
-	Sub struct {
-		Left, Right Expr
+	// tfun 'A => e
+	TFun struct {
+		TFunToken *token.Token
+		TVar      *Ident
+		Body      Expr
 	}
 
-	Mul struct {
-		Left, Right Expr
+	// f x y
+	App struct {
+		Func *VarRef
+		Args []*VarRef
 	}
 
-	Div struct {
-		Left, Right Expr
+	// @f T1 T2
+	TApp struct {
+		AtToken *token.Token
+		Func    *VarRef
+		Types   []Type
 	}
 
-	Mod struct {
-		Left, Right Expr
+	// builtin add x y, builtin blt ()
+	Builtin struct {
+		BuiltinToken *token.Token
+		Ident        *Ident
+		Args         []*VarRef
+		RParenToken  *token.Token // Only set when arguments are `()`
 	}
 
-	FNeg struct {
-		MinusToken *token.Token
-		Child      Expr
+	// Cons {T} x xs
+	Constr struct {
+		Ident       *Ident
+		LBraceToken *token.Token // Maybe nil
+		TypeArgs    []Type
+		RBraceToken *token.Token // Maybe nil
+		Args        []*VarRef
 	}
 
-	FAdd struct {
-		Left, Right Expr
+	// {_tag : "Foo"; x : y}
+	Message struct {
+		LBraceToken *token.Token
+		Entries     []*MessageEntry
+		RBraceToken *token.Token
 	}
 
-	FSub struct {
-		Left, Right Expr
+	// _tag : "Foo"
+	MessageEntry struct {
+		Key   *token.Token
+		Value Expr
 	}
 
-	FMul struct {
-		Left, Right Expr
+	// match x with | p => e end
+	Match struct {
+		MatchToken *token.Token
+		Target     *VarRef
+		Arms       []*MatchArm
+		EndToken   *token.Token
 	}
 
-	FDiv struct {
-		Left, Right Expr
+	MatchArm struct {
+		BarToken *token.Token
+		Pattern  Pattern
+		Body     Expr
 	}
+)
 
-	Eq struct {
-		Left, Right Expr
+// Statement nodes which meet Stmt interface
+type (
+	// x <- f
+	Load struct {
+		Ident *Ident
+		Field *VarRef
 	}
 
-	NotEq struct {
-		Left, Right Expr
+	// x <- & addr.f
+	RemoteLoad struct {
+		Ident    *Ident
+		AndToken *token.Token
+		Addr     *VarRef
+		Field    *Ident
 	}
 
-	Less struct {
-		Left, Right Expr
+	// f := x
+	Store struct {
+		Field *VarRef
+		Value *VarRef
 	}
 
-	LessEq struct {
-		Left, Right Expr
+	// x = e
+	Bind struct {
+		Ident *Ident
+		Value Expr
 	}
 
-	Greater struct {
-		Left, Right Expr
+	// [k]
+	MapKey struct {
+		LSQBToken *token.Token
+		Key       *VarRef
+		RSQBToken *token.Token
 	}
 
-	GreaterEq struct {
-		Left, Right Expr
+	// m[k1][k2] := v
+	MapUpdate struct {
+		Map   *VarRef
+		Keys  []*MapKey
+		Value *VarRef
 	}
 
-	And struct {
-		Left, Right Expr
+	// delete m[k1][k2]
+	MapDelete struct {
+		DeleteToken *token.Token
+		Map         *VarRef
+		Keys        []*MapKey
 	}
 
-	Or struct {
-		Left, Right Expr
+	// x <- m[k], x <- exists m[k]
+	MapGet struct {
+		Ident       *Ident
+		ExistsToken *token.Token // Maybe nil
+		Map         *VarRef
+		Keys        []*MapKey
 	}
 
-	If struct {
-		IfToken          *token.Token
-		Cond, Then, Else Expr
+	// x <- & addr.m[k], x <- & exists addr.m[k]
+	RemoteMapGet struct {
+		Ident       *Ident
+		AndToken    *token.Token
+		ExistsToken *token.Token // Maybe nil
+		Addr        *VarRef
+		Map         *Ident
+		Keys        []*MapKey
 	}
 
-	Let struct {
-		LetToken    *token.Token
-		Symbol      *Symbol
-		Bound, Body Expr
-		Type        Expr // Maybe nil
+	// x <- & BLOCKNUMBER
+	ReadFromBC struct {
+		Ident    *Ident
+		AndToken *token.Token
+		Query    *token.Token
 	}
 
-	VarRef struct {
-		Token  *token.Token
-		Symbol *Symbol
+	Accept struct {
+		Token *token.Token
 	}
 
-	LetRec struct {
-		LetToken *token.Token
-		Func     *FuncDef
-		Body     Expr
+	// send msgs
+	Send struct {
+		Token *token.Token
+		Msgs  *VarRef
 	}
 
-	Apply struct {
-		Callee Expr
-		Args   []Expr
+	// event e
+	Event struct {
+		Token *token.Token
+		Event *VarRef
 	}
 
-	Tuple struct {
-		Elems []Expr
+	// throw, throw e
+	Throw struct {
+		Token     *token.Token
+		Exception *VarRef // Maybe nil
 	}
 
-	LetTuple struct {
-		LetToken    *token.Token
-		Symbols     []*Symbol
-		Bound, Body Expr
-		Type        Expr // Maybe nil
+	// match x with | p => stmts end
+	MatchStmt struct {
+		MatchToken *token.Token
+		Target     *VarRef
+		Arms       []*StmtArm
+		EndToken   *token.Token
 	}
 
-	ArrayMake struct {
-		ArrayToken *token.Token
-		Size, Elem Expr
+	StmtArm struct {
+		BarToken *token.Token
+		Pattern  Pattern
+		Body     []Stmt
 	}
 
-	ArraySize struct {
-		ArrayToken *token.Token
-		Target     Expr
+	// proc x y
+	CallProc struct {
+		Proc *VarRef
+		Args []*VarRef
 	}
 
-	ArrayGet struct {
-		Array, Index Expr
+	// forall xs proc
+	Iterate struct {
+		ForallToken *token.Token
+		List        *VarRef
+		Proc        *VarRef
 	}
+)
 
-	ArrayPut struct {
-		Array, Index, Assignee Expr
+// Pattern nodes which meet Pattern interface
+type (
+	// _
+	WildcardPattern struct {
+		Token *token.Token
+		Parens
 	}
 
-	Match struct {
-		StartToken     *token.Token
-		Target         Expr
-		IfSome, IfNone Expr
-		SomeIdent      *Symbol
-		EndPos         locerr.Pos
+	// x
+	BinderPattern struct {
+		Ident *Ident
+		Parens
 	}
 
-	Some struct {
-		StartToken *token.Token
-		Child      Expr
+	// Some x, Cons (Pair a b) _
+	ConstrPattern struct {
+		Ctor *Ident
+		Args []Pattern
+		Parens
 	}
+)
 
-	None struct {
+// Type nodes which meet Type interface
+type (
+	// Uint128, String, BNum, ByStr20, Message, Event
+	PrimType struct {
 		Token *token.Token
+		Parens
 	}
 
-	ArrayLit struct {
-		StartToken *token.Token
-		EndToken   *token.Token
-		Elems      []Expr
+	// Map K V
+	MapType struct {
+		MapToken *token.Token
+		Key      Type
+		Value    Type
+		Parens
 	}
 
-	FuncType struct {
-		ParamTypes []Expr
-		RetType    Expr
+	// T1 -> T2
+	FunType struct {
+		Param Type
+		Ret   Type
+		Parens
 	}
 
-	TupleType struct {
-		ElemTypes []Expr
+	// forall 'A. T
+	PolyType struct {
+		ForallToken *token.Token
+		TVar        *Ident
+		Body        Type
+		Parens
 	}
 
-	// Note: `int` has no param
-	CtorType struct {
-		StartToken *token.Token // Maybe nil
+	// 'A
+	TypeVar struct {
+		Token *token.Token
+		Parens
+	}
+
+	// Option Uint32, Bool, MyType
+	ADTType struct {
+		Ident *Ident
+		Args  []Type
+		Parens
+	}
+
+	// ByStr20 with end, ByStr20 with contract field f : T end, ByStr20 with library end
+	AddressType struct {
+		ByStrToken *token.Token
+		WithToken  *token.Token
+		KindToken  *token.Token // `contract`, `library` or nil
+		Fields     []*AddressField
 		EndToken   *token.Token
-		ParamTypes []Expr
-		Ctor       *Symbol
+		Parens
 	}
 
-	Typed struct {
-		Child Expr
-		Type  Expr
+	// field f : T
+	AddressField struct {
+		FieldToken *token.Token
+		Ident      *Ident
+		Type       Type
 	}
+)
 
-	TypeDecl struct {
-		Token *token.Token
-		Ident *Symbol
-		Type  Expr
+// Parens is embedded in type and pattern nodes to keep the parentheses around them such as
+// `(Option Uint32)` and `(Some x)`. For nested parentheses only the outermost pair is kept.
+// Both tokens are nil when the node is not parenthesized.
+type Parens struct {
+	LParenToken *token.Token
+	RParenToken *token.Token
+}
+
+// SetParens records the parentheses around the node.
+func (p *Parens) SetParens(lparen, rparen *token.Token) {
+	p.LParenToken = lparen
+	p.RParenToken = rparen
+}
+
+// Nodes which represent source ranges containing syntax errors. The parser
+// puts them where a well-formed node could not be built and continues.
+// [From, To) is the broken range. It may be empty.
+type (
+	BadDecl struct {
+		From, To locerr.Pos
 	}
 
-	External struct {
-		StartToken *token.Token
-		EndToken   *token.Token
-		Ident      *Symbol
-		Type       Expr
-		C          string
+	BadExpr struct {
+		From, To locerr.Pos
+	}
+
+	BadStmt struct {
+		From, To locerr.Pos
+	}
+
+	BadPattern struct {
+		From, To locerr.Pos
 	}
 )
 
-func (e *Unit) Pos() locerr.Pos {
-	return e.LParenToken.Start
+func (n *Version) Pos() locerr.Pos {
+	return n.VersionToken.Start
 }
-func (e *Unit) End() locerr.Pos {
-	return e.RParenToken.End
+func (n *Version) End() locerr.Pos {
+	return n.NumToken.End
 }
 
-func (e *Bool) Pos() locerr.Pos {
-	return e.Token.Start
+func (n *Import) Pos() locerr.Pos {
+	return n.ImportToken.Start
 }
-func (e *Bool) End() locerr.Pos {
-	return e.Token.End
+func (n *Import) End() locerr.Pos {
+	if len(n.Names) == 0 {
+		return n.ImportToken.End
+	}
+	return n.Names[len(n.Names)-1].End()
+}
+
+func (n *ImportName) Pos() locerr.Pos {
+	return n.Lib.Pos()
+}
+func (n *ImportName) End() locerr.Pos {
+	if n.Alias != nil {
+		return n.Alias.End()
+	}
+	return n.Lib.End()
+}
+
+func (n *Library) Pos() locerr.Pos {
+	return n.LibraryToken.Start
+}
+func (n *Library) End() locerr.Pos {
+	if len(n.Entries) == 0 {
+		return n.Ident.End()
+	}
+	return n.Entries[len(n.Entries)-1].End()
 }
 
-func (e *Int) Pos() locerr.Pos {
+func (n *LetDecl) Pos() locerr.Pos {
+	return n.LetToken.Start
+}
+func (n *LetDecl) End() locerr.Pos {
+	return n.Bound.End()
+}
+
+func (n *TypeDecl) Pos() locerr.Pos {
+	return n.TypeToken.Start
+}
+func (n *TypeDecl) End() locerr.Pos {
+	if len(n.Ctors) == 0 {
+		return n.Ident.End()
+	}
+	return n.Ctors[len(n.Ctors)-1].End()
+}
+
+func (n *CtorDecl) Pos() locerr.Pos {
+	return n.BarToken.Start
+}
+func (n *CtorDecl) End() locerr.Pos {
+	if len(n.Types) == 0 {
+		return n.Ident.End()
+	}
+	return n.Types[len(n.Types)-1].End()
+}
+
+func (n *Contract) Pos() locerr.Pos {
+	return n.ContractToken.Start
+}
+func (n *Contract) End() locerr.Pos {
+	switch {
+	case len(n.Components) > 0:
+		return n.Components[len(n.Components)-1].End()
+	case len(n.Fields) > 0:
+		return n.Fields[len(n.Fields)-1].End()
+	case n.Constraint != nil:
+		return n.Constraint.End()
+	case n.RParenToken != nil:
+		return n.RParenToken.End
+	case len(n.Params) > 0:
+		return n.Params[len(n.Params)-1].End()
+	default:
+		return n.Ident.End()
+	}
+}
+
+func (n *Param) Pos() locerr.Pos {
+	return n.Ident.Pos()
+}
+func (n *Param) End() locerr.Pos {
+	return n.Type.End()
+}
+
+func (n *Field) Pos() locerr.Pos {
+	return n.FieldToken.Start
+}
+func (n *Field) End() locerr.Pos {
+	return n.Init.End()
+}
+
+func (n *Component) Pos() locerr.Pos {
+	return n.Token.Start
+}
+func (n *Component) End() locerr.Pos {
+	return n.EndToken.End
+}
+
+func (n *Ident) Pos() locerr.Pos {
+	return n.Token.Start
+}
+func (n *Ident) End() locerr.Pos {
+	return n.Token.End
+}
+
+func (e *StringLit) Pos() locerr.Pos {
 	return e.Token.Start
 }
-func (e *Int) End() locerr.Pos {
+func (e *StringLit) End() locerr.Pos {
 	return e.Token.End
 }
 
-func (e *Float) Pos() locerr.Pos {
+func (e *IntLit) Pos() locerr.Pos {
+	return e.TypeToken.Start
+}
+func (e *IntLit) End() locerr.Pos {
+	return e.ValueToken.End
+}
+
+func (e *BNumLit) Pos() locerr.Pos {
+	return e.TypeToken.Start
+}
+func (e *BNumLit) End() locerr.Pos {
+	return e.ValueToken.End
+}
+
+func (e *HexLit) Pos() locerr.Pos {
 	return e.Token.Start
 }
-func (e *Float) End() locerr.Pos {
+func (e *HexLit) End() locerr.Pos {
 	return e.Token.End
 }
 
-func (e *String) Pos() locerr.Pos {
+func (e *EmpLit) Pos() locerr.Pos {
+	return e.EmpToken.Start
+}
+func (e *EmpLit) End() locerr.Pos {
+	return e.Value.End()
+}
+
+func (e *VarRef) Pos() locerr.Pos {
 	return e.Token.Start
 }
-func (e *String) End() locerr.Pos {
+func (e *VarRef) End() locerr.Pos {
 	return e.Token.End
 }
 
-func (e *Not) Pos() locerr.Pos {
-	return e.OpToken.Start
+func (e *Let) Pos() locerr.Pos {
+	return e.LetToken.Start
 }
-func (e *Not) End() locerr.Pos {
-	return e.Child.End()
+func (e *Let) End() locerr.Pos {
+	return e.Body.End()
 }
 
-func (e *Neg) Pos() locerr.Pos {
-	return e.MinusToken.Start
+func (e *Fun) Pos() locerr.Pos {
+	return e.FunToken.Start
 }
-func (e *Neg) End() locerr.Pos {
-	return e.Child.End()
+func (e *Fun) End() locerr.Pos {
+	return e.Body.End()
 }
 
-func (e *Add) Pos() locerr.Pos {
-	return e.Left.Pos()
+func (e *TFun) Pos() locerr.Pos {
+	return e.TFunToken.Start
 }
-func (e *Add) End() locerr.Pos {
-	return e.Right.End()
+func (e *TFun) End() locerr.Pos {
+	return e.Body.End()
 }
 
-func (e *Sub) Pos() locerr.Pos {
-	return e.Left.Pos()
+func (e *App) Pos() locerr.Pos {
+	return e.Func.Pos()
 }
-func (e *Sub) End() locerr.Pos {
-	return e.Right.End()
+func (e *App) End() locerr.Pos {
+	if len(e.Args) == 0 {
+		return e.Func.End()
+	}
+	return e.Args[len(e.Args)-1].End()
 }
 
-func (e *Mul) Pos() locerr.Pos {
-	return e.Left.Pos()
+func (e *TApp) Pos() locerr.Pos {
+	return e.AtToken.Start
 }
-func (e *Mul) End() locerr.Pos {
-	return e.Right.End()
+func (e *TApp) End() locerr.Pos {
+	if len(e.Types) == 0 {
+		return e.Func.End()
+	}
+	return e.Types[len(e.Types)-1].End()
 }
 
-func (e *Div) Pos() locerr.Pos {
-	return e.Left.Pos()
+func (e *Builtin) Pos() locerr.Pos {
+	return e.BuiltinToken.Start
 }
-func (e *Div) End() locerr.Pos {
-	return e.Right.End()
+func (e *Builtin) End() locerr.Pos {
+	if e.RParenToken != nil {
+		return e.RParenToken.End
+	}
+	if len(e.Args) == 0 {
+		return e.Ident.End()
+	}
+	return e.Args[len(e.Args)-1].End()
 }
 
-func (e *Mod) Pos() locerr.Pos {
-	return e.Left.Pos()
+func (e *Constr) Pos() locerr.Pos {
+	return e.Ident.Pos()
 }
-func (e *Mod) End() locerr.Pos {
-	return e.Right.End()
+func (e *Constr) End() locerr.Pos {
+	switch {
+	case len(e.Args) > 0:
+		return e.Args[len(e.Args)-1].End()
+	case e.RBraceToken != nil:
+		return e.RBraceToken.End
+	default:
+		return e.Ident.End()
+	}
 }
 
-func (e *FNeg) Pos() locerr.Pos {
-	return e.MinusToken.Start
+func (e *Message) Pos() locerr.Pos {
+	return e.LBraceToken.Start
 }
-func (e *FNeg) End() locerr.Pos {
-	return e.Child.End()
+func (e *Message) End() locerr.Pos {
+	return e.RBraceToken.End
 }
 
-func (e *FAdd) Pos() locerr.Pos {
-	return e.Left.Pos()
+func (e *MessageEntry) Pos() locerr.Pos {
+	return e.Key.Start
 }
-func (e *FAdd) End() locerr.Pos {
-	return e.Right.End()
+func (e *MessageEntry) End() locerr.Pos {
+	return e.Value.End()
 }
 
-func (e *FSub) Pos() locerr.Pos {
-	return e.Left.Pos()
+func (e *Match) Pos() locerr.Pos {
+	return e.MatchToken.Start
 }
-func (e *FSub) End() locerr.Pos {
-	return e.Right.End()
+func (e *Match) End() locerr.Pos {
+	return e.EndToken.End
 }
 
-func (e *FMul) Pos() locerr.Pos {
-	return e.Left.Pos()
+func (e *MatchArm) Pos() locerr.Pos {
+	return e.BarToken.Start
 }
-func (e *FMul) End() locerr.Pos {
-	return e.Right.End()
+func (e *MatchArm) End() locerr.Pos {
+	return e.Body.End()
 }
 
-func (e *FDiv) Pos() locerr.Pos {
-	return e.Left.Pos()
+func (s *Load) Pos() locerr.Pos {
+	return s.Ident.Pos()
 }
-func (e *FDiv) End() locerr.Pos {
-	return e.Right.End()
+func (s *Load) End() locerr.Pos {
+	return s.Field.End()
 }
 
-func (e *Eq) Pos() locerr.Pos {
-	return e.Left.Pos()
+func (s *RemoteLoad) Pos() locerr.Pos {
+	return s.Ident.Pos()
 }
-func (e *Eq) End() locerr.Pos {
-	return e.Right.End()
+func (s *RemoteLoad) End() locerr.Pos {
+	return s.Field.End()
 }
 
-func (e *NotEq) Pos() locerr.Pos {
-	return e.Left.Pos()
+func (s *Store) Pos() locerr.Pos {
+	return s.Field.Pos()
 }
-func (e *NotEq) End() locerr.Pos {
-	return e.Right.End()
+func (s *Store) End() locerr.Pos {
+	return s.Value.End()
 }
 
-func (e *Less) Pos() locerr.Pos {
-	return e.Left.Pos()
+func (s *Bind) Pos() locerr.Pos {
+	return s.Ident.Pos()
 }
-func (e *Less) End() locerr.Pos {
-	return e.Right.End()
+func (s *Bind) End() locerr.Pos {
+	return s.Value.End()
 }
 
-func (e *LessEq) Pos() locerr.Pos {
-	return e.Left.Pos()
+func (s *MapKey) Pos() locerr.Pos {
+	return s.LSQBToken.Start
 }
-func (e *LessEq) End() locerr.Pos {
-	return e.Right.End()
+func (s *MapKey) End() locerr.Pos {
+	return s.RSQBToken.End
 }
 
-func (e *Greater) Pos() locerr.Pos {
-	return e.Left.Pos()
+func (s *MapUpdate) Pos() locerr.Pos {
+	return s.Map.Pos()
 }
-func (e *Greater) End() locerr.Pos {
-	return e.Right.End()
+func (s *MapUpdate) End() locerr.Pos {
+	return s.Value.End()
 }
 
-func (e *GreaterEq) Pos() locerr.Pos {
-	return e.Left.Pos()
+func (s *MapDelete) Pos() locerr.Pos {
+	return s.DeleteToken.Start
 }
-func (e *GreaterEq) End() locerr.Pos {
-	return e.Right.End()
+func (s *MapDelete) End() locerr.Pos {
+	return s.Keys[len(s.Keys)-1].End()
 }
 
-func (e *And) Pos() locerr.Pos {
-	return e.Left.Pos()
+func (s *MapGet) Pos() locerr.Pos {
+	return s.Ident.Pos()
 }
-func (e *And) End() locerr.Pos {
-	return e.Right.End()
+func (s *MapGet) End() locerr.Pos {
+	return s.Keys[len(s.Keys)-1].End()
 }
 
-func (e *Or) Pos() locerr.Pos {
-	return e.Left.Pos()
+func (s *RemoteMapGet) Pos() locerr.Pos {
+	return s.Ident.Pos()
 }
-func (e *Or) End() locerr.Pos {
-	return e.Right.End()
+func (s *RemoteMapGet) End() locerr.Pos {
+	return s.Keys[len(s.Keys)-1].End()
 }
 
-func (e *If) Pos() locerr.Pos {
-	return e.IfToken.Start
+func (s *ReadFromBC) Pos() locerr.Pos {
+	return s.Ident.Pos()
 }
-func (e *If) End() locerr.Pos {
-	return e.Else.End()
+func (s *ReadFromBC) End() locerr.Pos {
+	return s.Query.End
 }
 
-func (e *Let) Pos() locerr.Pos {
-	return e.LetToken.Start
+func (s *Accept) Pos() locerr.Pos {
+	return s.Token.Start
 }
-func (e *Let) End() locerr.Pos {
-	return e.Body.End()
+func (s *Accept) End() locerr.Pos {
+	return s.Token.End
 }
 
-func (e *VarRef) Pos() locerr.Pos {
-	return e.Token.Start
+func (s *Send) Pos() locerr.Pos {
+	return s.Token.Start
 }
-func (e *VarRef) End() locerr.Pos {
-	return e.Token.End
+func (s *Send) End() locerr.Pos {
+	return s.Msgs.End()
 }
 
-func (e *LetRec) Pos() locerr.Pos {
-	return e.LetToken.Start
+func (s *Event) Pos() locerr.Pos {
+	return s.Token.Start
 }
-func (e *LetRec) End() locerr.Pos {
-	return e.Body.End()
+func (s *Event) End() locerr.Pos {
+	return s.Event.End()
 }
 
-func (e *Apply) Pos() locerr.Pos {
-	return e.Callee.Pos()
+func (s *Throw) Pos() locerr.Pos {
+	return s.Token.Start
 }
-func (e *Apply) End() locerr.Pos {
-	if len(e.Args) == 0 {
-		return e.Callee.End()
+func (s *Throw) End() locerr.Pos {
+	if s.Exception == nil {
+		return s.Token.End
 	}
-	return e.Args[len(e.Args)-1].End()
+	return s.Exception.End()
 }
 
-func (e *Tuple) Pos() locerr.Pos {
-	return e.Elems[0].Pos()
+func (s *MatchStmt) Pos() locerr.Pos {
+	return s.MatchToken.Start
 }
-func (e *Tuple) End() locerr.Pos {
-	return e.Elems[len(e.Elems)-1].End()
+func (s *MatchStmt) End() locerr.Pos {
+	return s.EndToken.End
 }
 
-func (e *LetTuple) Pos() locerr.Pos {
-	return e.LetToken.Start
+func (s *StmtArm) Pos() locerr.Pos {
+	return s.BarToken.Start
 }
-func (e *LetTuple) End() locerr.Pos {
-	return e.Body.End()
+func (s *StmtArm) End() locerr.Pos {
+	if len(s.Body) == 0 {
+		return s.Pattern.End()
+	}
+	return s.Body[len(s.Body)-1].End()
 }
 
-func (e *ArrayMake) Pos() locerr.Pos {
-	return e.ArrayToken.Start
+func (s *CallProc) Pos() locerr.Pos {
+	return s.Proc.Pos()
 }
-func (e *ArrayMake) End() locerr.Pos {
-	return e.Elem.End()
+func (s *CallProc) End() locerr.Pos {
+	if len(s.Args) == 0 {
+		return s.Proc.End()
+	}
+	return s.Args[len(s.Args)-1].End()
 }
 
-func (e *ArraySize) Pos() locerr.Pos {
-	return e.ArrayToken.Start
+func (s *Iterate) Pos() locerr.Pos {
+	return s.ForallToken.Start
 }
-func (e *ArraySize) End() locerr.Pos {
-	return e.Target.End()
+func (s *Iterate) End() locerr.Pos {
+	return s.Proc.End()
 }
 
-func (e *ArrayGet) Pos() locerr.Pos {
-	return e.Array.Pos()
+func (p *WildcardPattern) Pos() locerr.Pos {
+	if p.LParenToken != nil {
+		return p.LParenToken.Start
+	}
+	return p.Token.Start
 }
-func (e *ArrayGet) End() locerr.Pos {
-	return e.Index.End()
+func (p *WildcardPattern) End() locerr.Pos {
+	if p.RParenToken != nil {
+		return p.RParenToken.End
+	}
+	return p.Token.End
 }
 
-func (e *ArrayPut) Pos() locerr.Pos {
-	return e.Array.Pos()
+func (p *BinderPattern) Pos() locerr.Pos {
+	if p.LParenToken != nil {
+		return p.LParenToken.Start
+	}
+	return p.Ident.Pos()
 }
-func (e *ArrayPut) End() locerr.Pos {
-	return e.Assignee.End()
+func (p *BinderPattern) End() locerr.Pos {
+	if p.RParenToken != nil {
+		return p.RParenToken.End
+	}
+	return p.Ident.End()
 }
 
-func (e *Match) Pos() locerr.Pos {
-	return e.StartToken.Start
+func (p *ConstrPattern) Pos() locerr.Pos {
+	if p.LParenToken != nil {
+		return p.LParenToken.Start
+	}
+	return p.Ctor.Pos()
 }
-func (e *Match) End() locerr.Pos {
-	return e.EndPos
+func (p *ConstrPattern) End() locerr.Pos {
+	if p.RParenToken != nil {
+		return p.RParenToken.End
+	}
+	if len(p.Args) == 0 {
+		return p.Ctor.End()
+	}
+	return p.Args[len(p.Args)-1].End()
 }
 
-func (e *Some) Pos() locerr.Pos {
-	return e.StartToken.Start
+func (t *PrimType) Pos() locerr.Pos {
+	if t.LParenToken != nil {
+		return t.LParenToken.Start
+	}
+	return t.Token.Start
 }
-func (e *Some) End() locerr.Pos {
-	return e.Child.End()
+func (t *PrimType) End() locerr.Pos {
+	if t.RParenToken != nil {
+		return t.RParenToken.End
+	}
+	return t.Token.End
 }
 
-func (e *None) Pos() locerr.Pos {
-	return e.Token.Start
+func (t *MapType) Pos() locerr.Pos {
+	if t.LParenToken != nil {
+		return t.LParenToken.Start
+	}
+	return t.MapToken.Start
 }
-func (e *None) End() locerr.Pos {
-	return e.Token.End
+func (t *MapType) End() locerr.Pos {
+	if t.RParenToken != nil {
+		return t.RParenToken.End
+	}
+	return t.Value.End()
 }
 
-func (e *ArrayLit) Pos() locerr.Pos {
-	return e.StartToken.Start
+func (t *FunType) Pos() locerr.Pos {
+	if t.LParenToken != nil {
+		return t.LParenToken.Start
+	}
+	return t.Param.Pos()
 }
-func (e *ArrayLit) End() locerr.Pos {
-	return e.EndToken.End
+func (t *FunType) End() locerr.Pos {
+	if t.RParenToken != nil {
+		return t.RParenToken.End
+	}
+	return t.Ret.End()
 }
 
-func (e *FuncType) Pos() locerr.Pos {
-	return e.ParamTypes[0].Pos()
+func (t *PolyType) Pos() locerr.Pos {
+	if t.LParenToken != nil {
+		return t.LParenToken.Start
+	}
+	return t.ForallToken.Start
 }
-func (e *FuncType) End() locerr.Pos {
-	return e.RetType.End()
+func (t *PolyType) End() locerr.Pos {
+	if t.RParenToken != nil {
+		return t.RParenToken.End
+	}
+	return t.Body.End()
 }
 
-func (e *TupleType) Pos() locerr.Pos {
-	return e.ElemTypes[0].Pos()
+func (t *TypeVar) Pos() locerr.Pos {
+	if t.LParenToken != nil {
+		return t.LParenToken.Start
+	}
+	return t.Token.Start
 }
-func (e *TupleType) End() locerr.Pos {
-	return e.ElemTypes[len(e.ElemTypes)-1].End()
+func (t *TypeVar) End() locerr.Pos {
+	if t.RParenToken != nil {
+		return t.RParenToken.End
+	}
+	return t.Token.End
 }
 
-func (e *CtorType) Pos() locerr.Pos {
-	switch len(e.ParamTypes) {
-	case 0:
-		// foo
-		return e.EndToken.Start
-	case 1:
-		// a foo
-		return e.ParamTypes[0].Pos()
-	default:
-		// (a, b) foo
-		return e.StartToken.Start
+func (t *ADTType) Pos() locerr.Pos {
+	if t.LParenToken != nil {
+		return t.LParenToken.Start
 	}
+	return t.Ident.Pos()
 }
-func (e *CtorType) End() locerr.Pos {
-	return e.EndToken.End
+func (t *ADTType) End() locerr.Pos {
+	if t.RParenToken != nil {
+		return t.RParenToken.End
+	}
+	if len(t.Args) == 0 {
+		return t.Ident.End()
+	}
+	return t.Args[len(t.Args)-1].End()
 }
 
-func (e *Typed) Pos() locerr.Pos {
-	return e.Child.Pos()
+func (t *AddressType) Pos() locerr.Pos {
+	if t.LParenToken != nil {
+		return t.LParenToken.Start
+	}
+	return t.ByStrToken.Start
 }
-func (e *Typed) End() locerr.Pos {
-	return e.Type.End()
+func (t *AddressType) End() locerr.Pos {
+	if t.RParenToken != nil {
+		return t.RParenToken.End
+	}
+	return t.EndToken.End
 }
 
-func (e *TypeDecl) Pos() locerr.Pos {
-	return e.Token.Start
+func (t *AddressField) Pos() locerr.Pos {
+	return t.FieldToken.Start
 }
-func (e *TypeDecl) End() locerr.Pos {
-	return e.Type.End()
+func (t *AddressField) End() locerr.Pos {
+	return t.Type.End()
 }
 
-func (e *External) Pos() locerr.Pos {
-	return e.StartToken.Start
+func (n *BadDecl) Pos() locerr.Pos {
+	return n.From
 }
-func (e *External) End() locerr.Pos {
-	return e.EndToken.End
+func (n *BadDecl) End() locerr.Pos {
+	return n.To
 }
 
-func (e *Unit) Name() string      { return "Unit" }
-func (e *Bool) Name() string      { return "Bool" }
-func (e *Int) Name() string       { return "Int" }
-func (e *Float) Name() string     { return "Float" }
-func (e *String) Name() string    { return fmt.Sprintf("String (%s)", e.Token.Value()) }
-func (e *Not) Name() string       { return "Not" }
-func (e *Neg) Name() string       { return "Neg" }
-func (e *Add) Name() string       { return "Add" }
-func (e *Sub) Name() string       { return "Sub" }
-func (e *Mul) Name() string       { return "Mul" }
-func (e *Div) Name() string       { return "Div" }
-func (e *Mod) Name() string       { return "Mod" }
-func (e *FNeg) Name() string      { return "FNeg" }
-func (e *FAdd) Name() string      { return "FAdd" }
-func (e *FSub) Name() string      { return "FSub" }
-func (e *FMul) Name() string      { return "FMul" }
-func (e *FDiv) Name() string      { return "FDiv" }
-func (e *Eq) Name() string        { return "Eq" }
-func (e *NotEq) Name() string     { return "NotEq" }
-func (e *Less) Name() string      { return "Less" }
-func (e *LessEq) Name() string    { return "LessEq" }
-func (e *Greater) Name() string   { return "Greater" }
-func (e *GreaterEq) Name() string { return "GreaterEq" }
-func (e *And) Name() string       { return "And" }
-func (e *Or) Name() string        { return "Or" }
-func (e *If) Name() string        { return "If" }
-func (e *Let) Name() string       { return fmt.Sprintf("Let (%s)", e.Symbol.DisplayName) }
-func (e *VarRef) Name() string    { return fmt.Sprintf("VarRef (%s)", e.Symbol.DisplayName) }
-func (e *LetRec) Name() string {
-	params := e.Func.Params[0].Ident.DisplayName
-	for _, p := range e.Func.Params[1:] {
-		params = fmt.Sprintf("%s, %s", params, p.Ident.DisplayName)
-	}
-	return fmt.Sprintf("LetRec (fun %s %s)", e.Func.Symbol.DisplayName, params)
-}
-func (e *Apply) Name() string { return "Apply" }
-func (e *Tuple) Name() string { return "Tuple" }
-func (e *LetTuple) Name() string {
-	vars := e.Symbols[0].DisplayName
-	for _, s := range e.Symbols[1:] {
-		vars = fmt.Sprintf("%s, %s", vars, s.DisplayName)
-	}
-	return fmt.Sprintf("LetTuple (%s)", vars)
-}
-func (e *ArrayMake) Name() string { return "ArrayCreate" }
-func (e *ArraySize) Name() string { return "ArraySize" }
-func (e *ArrayGet) Name() string  { return "ArrayGet" }
-func (e *ArrayPut) Name() string  { return "ArrayPut" }
-func (e *Match) Name() string     { return fmt.Sprintf("Match (%s)", e.SomeIdent.DisplayName) }
-func (e *Some) Name() string      { return "Some" }
-func (e *None) Name() string      { return "None" }
-func (e *ArrayLit) Name() string  { return fmt.Sprintf("ArrayLit (%d)", len(e.Elems)) }
-func (e *FuncType) Name() string  { return "FuncType" }
-func (e *TupleType) Name() string { return fmt.Sprintf("TupleType (%d)", len(e.ElemTypes)) }
-func (e *CtorType) Name() string {
-	len := len(e.ParamTypes)
-	if len == 0 {
-		return fmt.Sprintf("CtorType (%s)", e.Ctor.Name)
-	}
-	return fmt.Sprintf("CtorType (%s (%d))", e.Ctor.Name, len)
-}
-func (e *Typed) Name() string    { return "Typed" }
-func (e *TypeDecl) Name() string { return fmt.Sprintf("TypeDecl (%s)", e.Ident.Name) }
-func (e *External) Name() string { return fmt.Sprintf("External (%s => %s)", e.Ident.Name, e.C) }
+func (e *BadExpr) Pos() locerr.Pos {
+	return e.From
+}
+func (e *BadExpr) End() locerr.Pos {
+	return e.To
+}
+
+func (s *BadStmt) Pos() locerr.Pos {
+	return s.From
+}
+func (s *BadStmt) End() locerr.Pos {
+	return s.To
+}
+
+func (p *BadPattern) Pos() locerr.Pos {
+	return p.From
+}
+func (p *BadPattern) End() locerr.Pos {
+	return p.To
+}
+
+func (n *Version) Name() string { return fmt.Sprintf("Version (%d)", n.Value) }
+func (n *Import) Name() string  { return fmt.Sprintf("Import (%d)", len(n.Names)) }
+func (n *ImportName) Name() string {
+	if n.Alias != nil {
+		return fmt.Sprintf("ImportName (%s as %s)", n.Lib.Symbol.DisplayName, n.Alias.Symbol.DisplayName)
+	}
+	return fmt.Sprintf("ImportName (%s)", n.Lib.Symbol.DisplayName)
+}
+func (n *Library) Name() string  { return fmt.Sprintf("Library (%s)", n.Ident.Symbol.DisplayName) }
+func (n *LetDecl) Name() string  { return fmt.Sprintf("LetDecl (%s)", n.Ident.Symbol.DisplayName) }
+func (n *TypeDecl) Name() string { return fmt.Sprintf("TypeDecl (%s)", n.Ident.Symbol.DisplayName) }
+func (n *CtorDecl) Name() string { return fmt.Sprintf("CtorDecl (%s)", n.Ident.Symbol.DisplayName) }
+func (n *Contract) Name() string { return fmt.Sprintf("Contract (%s)", n.Ident.Symbol.DisplayName) }
+func (n *Param) Name() string    { return fmt.Sprintf("Param (%s)", n.Ident.Symbol.DisplayName) }
+func (n *Field) Name() string    { return fmt.Sprintf("Field (%s)", n.Ident.Symbol.DisplayName) }
+func (n *Component) Name() string {
+	kind := "Transition"
+	if n.IsProcedure() {
+		kind = "Procedure"
+	}
+	return fmt.Sprintf("%s (%s)", kind, n.Ident.Symbol.DisplayName)
+}
+func (n *Ident) Name() string     { return fmt.Sprintf("Ident (%s)", n.Symbol.DisplayName) }
+func (e *StringLit) Name() string { return fmt.Sprintf("StringLit (%s)", e.Token.Value()) }
+func (e *IntLit) Name() string {
+	return fmt.Sprintf("IntLit (%s %s)", e.TypeToken.Value(), e.ValueToken.Value())
+}
+func (e *BNumLit) Name() string      { return fmt.Sprintf("BNumLit (%s)", e.ValueToken.Value()) }
+func (e *HexLit) Name() string       { return fmt.Sprintf("HexLit (%s)", e.Token.Value()) }
+func (e *EmpLit) Name() string       { return "EmpLit" }
+func (e *VarRef) Name() string       { return fmt.Sprintf("VarRef (%s)", e.Symbol.DisplayName) }
+func (e *Let) Name() string          { return fmt.Sprintf("Let (%s)", e.Ident.Symbol.DisplayName) }
+func (e *Fun) Name() string          { return fmt.Sprintf("Fun (%s)", e.Param.Ident.Symbol.DisplayName) }
+func (e *TFun) Name() string         { return fmt.Sprintf("TFun (%s)", e.TVar.Symbol.DisplayName) }
+func (e *App) Name() string          { return fmt.Sprintf("App (%d)", len(e.Args)) }
+func (e *TApp) Name() string         { return fmt.Sprintf("TApp (%d)", len(e.Types)) }
+func (e *Builtin) Name() string      { return fmt.Sprintf("Builtin (%s)", e.Ident.Symbol.DisplayName) }
+func (e *Constr) Name() string       { return fmt.Sprintf("Constr (%s)", e.Ident.Symbol.DisplayName) }
+func (e *Message) Name() string      { return fmt.Sprintf("Message (%d)", len(e.Entries)) }
+func (e *MessageEntry) Name() string { return fmt.Sprintf("MessageEntry (%s)", e.Key.Value()) }
+func (e *Match) Name() string        { return fmt.Sprintf("Match (%d)", len(e.Arms)) }
+func (e *MatchArm) Name() string     { return "MatchArm" }
+func (s *Load) Name() string         { return "Load" }
+func (s *RemoteLoad) Name() string   { return "RemoteLoad" }
+func (s *Store) Name() string        { return "Store" }
+func (s *Bind) Name() string         { return "Bind" }
+func (s *MapKey) Name() string       { return "MapKey" }
+func (s *MapUpdate) Name() string    { return fmt.Sprintf("MapUpdate (%d)", len(s.Keys)) }
+func (s *MapDelete) Name() string    { return fmt.Sprintf("MapDelete (%d)", len(s.Keys)) }
+func (s *MapGet) Name() string {
+	if s.ExistsToken != nil {
+		return fmt.Sprintf("MapGet (exists %d)", len(s.Keys))
+	}
+	return fmt.Sprintf("MapGet (%d)", len(s.Keys))
+}
+func (s *RemoteMapGet) Name() string {
+	if s.ExistsToken != nil {
+		return fmt.Sprintf("RemoteMapGet (exists %d)", len(s.Keys))
+	}
+	return fmt.Sprintf("RemoteMapGet (%d)", len(s.Keys))
+}
+func (s *ReadFromBC) Name() string      { return fmt.Sprintf("ReadFromBC (%s)", s.Query.Value()) }
+func (s *Accept) Name() string          { return "Accept" }
+func (s *Send) Name() string            { return "Send" }
+func (s *Event) Name() string           { return "Event" }
+func (s *Throw) Name() string           { return "Throw" }
+func (s *MatchStmt) Name() string       { return fmt.Sprintf("MatchStmt (%d)", len(s.Arms)) }
+func (s *StmtArm) Name() string         { return fmt.Sprintf("StmtArm (%d)", len(s.Body)) }
+func (s *CallProc) Name() string        { return fmt.Sprintf("CallProc (%s)", s.Proc.Symbol.DisplayName) }
+func (s *Iterate) Name() string         { return "Iterate" }
+func (p *WildcardPattern) Name() string { return "WildcardPattern" }
+func (p *BinderPattern) Name() string {
+	return fmt.Sprintf("BinderPattern (%s)", p.Ident.Symbol.DisplayName)
+}
+func (p *ConstrPattern) Name() string {
+	return fmt.Sprintf("ConstrPattern (%s)", p.Ctor.Symbol.DisplayName)
+}
+func (t *PrimType) Name() string    { return fmt.Sprintf("PrimType (%s)", t.Token.Value()) }
+func (t *MapType) Name() string     { return "MapType" }
+func (t *FunType) Name() string     { return "FunType" }
+func (t *PolyType) Name() string    { return fmt.Sprintf("PolyType (%s)", t.TVar.Symbol.DisplayName) }
+func (t *TypeVar) Name() string     { return fmt.Sprintf("TypeVar (%s)", t.Token.Value()) }
+func (t *ADTType) Name() string     { return fmt.Sprintf("ADTType (%s)", t.Ident.Symbol.DisplayName) }
+func (t *AddressType) Name() string { return fmt.Sprintf("AddressType (%d)", len(t.Fields)) }
+func (t *AddressField) Name() string {
+	return fmt.Sprintf("AddressField (%s)", t.Ident.Symbol.DisplayName)
+}
+
+func (n *BadDecl) Name() string    { return "BadDecl" }
+func (e *BadExpr) Name() string    { return "BadExpr" }
+func (s *BadStmt) Name() string    { return "BadStmt" }
+func (p *BadPattern) Name() string { return "BadPattern" }
+
+// IsProcedure returns true when the component is declared with `procedure`.
+func (n *Component) IsProcedure() bool {
+	return n.Token.Kind == token.PROCEDURE
+}
+
+func (*StringLit) exprNode() {}
+func (*IntLit) exprNode()    {}
+func (*BNumLit) exprNode()   {}
+func (*HexLit) exprNode()    {}
+func (*EmpLit) exprNode()    {}
+func (*VarRef) exprNode()    {}
+func (*Let) exprNode()       {}
+func (*Fun) exprNode()       {}
+func (*TFun) exprNode()      {}
+func (*App) exprNode()       {}
+func (*TApp) exprNode()      {}
+func (*Builtin) exprNode()   {}
+func (*Constr) exprNode()    {}
+func (*Message) exprNode()   {}
+func (*Match) exprNode()     {}
+func (*BadExpr) exprNode()   {}
+
+func (*Load) stmtNode()         {}
+func (*RemoteLoad) stmtNode()   {}
+func (*Store) stmtNode()        {}
+func (*Bind) stmtNode()         {}
+func (*MapUpdate) stmtNode()    {}
+func (*MapDelete) stmtNode()    {}
+func (*MapGet) stmtNode()       {}
+func (*RemoteMapGet) stmtNode() {}
+func (*ReadFromBC) stmtNode()   {}
+func (*Accept) stmtNode()       {}
+func (*Send) stmtNode()         {}
+func (*Event) stmtNode()        {}
+func (*Throw) stmtNode()        {}
+func (*MatchStmt) stmtNode()    {}
+func (*CallProc) stmtNode()     {}
+func (*Iterate) stmtNode()      {}
+func (*BadStmt) stmtNode()      {}
+
+func (*WildcardPattern) patternNode() {}
+func (*BinderPattern) patternNode()   {}
+func (*ConstrPattern) patternNode()   {}
+func (*BadPattern) patternNode()      {}
+
+func (*PrimType) typeNode()    {}
+func (*MapType) typeNode()     {}
+func (*FunType) typeNode()     {}
+func (*PolyType) typeNode()    {}
+func (*TypeVar) typeNode()     {}
+func (*ADTType) typeNode()     {}
+func (*AddressType) typeNode() {}
+
+func (*LetDecl) libEntryNode()  {}
+func (*TypeDecl) libEntryNode() {}
+func (*BadDecl) libEntryNode()  {}
@@ -13,12 +13,12 @@ type Printer struct {
 	out    io.Writer
 }
 
-func (p Printer) VisitTopdown(e Expr) Visitor {
-	_, _ = fmt.Fprintf(p.out, "\n%s%s (%d:%d-%d:%d)", strings.Repeat("-   ", p.indent), e.Name(), e.Pos().Line, e.Pos().Column, e.End().Line, e.End().Column)
+func (p Printer) VisitTopdown(n Node) Visitor {
+	_, _ = fmt.Fprintf(p.out, "\n%s%s (%d:%d-%d:%d)", strings.Repeat("-   ", p.indent), n.Name(), n.Pos().Line, n.Pos().Column, n.End().Line, n.End().Column)
 	return Printer{p.indent + 1, p.out}
 }
 
-func (p Printer) VisitBottomup(Expr) {
+func (p Printer) VisitBottomup(Node) {
 	return
 }
 
@@ -26,13 +26,24 @@ func (p Printer) VisitBottomup(Expr) {
 func Fprint(out io.Writer, a *AST) {
 	_, _ = fmt.Fprintf(out, "AST for %s:", a.File().Path)
 	p := Printer{1, out}
-	for _, t := range a.TypeDecls {
-		Visit(p, t)
+	if a.Version != nil {
+		Visit(p, a.Version)
 	}
-	for _, e := range a.Externals {
-		Visit(p, e)
+	for _, i := range a.Imports {
+		Visit(p, i)
+	}
+	if a.Library != nil {
+		Visit(p, a.Library)
+	}
+	if a.Contract != nil {
+		Visit(p, a.Contract)
+	}
+	for _, c := range a.Components {
+		Visit(p, c)
+	}
+	for _, d := range a.BadDecls {
+		Visit(p, d)
 	}
-	Visit(p, a.Root)
 }
 
 // Print outputs a structure of AST to stdout.
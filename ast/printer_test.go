@@ -2,8 +2,8 @@ package ast
 
 import (
 	"bytes"
-	"goscilla/token"
 	"github.com/rhysd/locerr"
+	"goscilla/token"
 	"io"
 	"os"
 	"testing"
@@ -13,266 +13,136 @@ func TestPrintAST(t *testing.T) {
 	s := locerr.NewDummySource("")
 	tok := &token.Token{
 		Kind:  token.ILLEGAL,
-		Start: locerr.Pos{0, 0, 0, s},
-		End:   locerr.Pos{0, 0, 0, s},
+		Start: locerr.Pos{Offset: 0, Line: 0, Column: 0, File: s},
+		End:   locerr.Pos{Offset: 0, Line: 0, Column: 0, File: s},
 		File:  s,
 	}
+	lit := func(kind token.Kind, v string) *token.Token {
+		t := *tok
+		t.Kind = kind
+		t.SetLiteral(v)
+		return &t
+	}
+	ident := func(name string) *Ident {
+		return &Ident{tok, NewSymbol(name)}
+	}
+	ref := func(name string) *VarRef {
+		return &VarRef{tok, NewSymbol(name)}
+	}
+	prim := func(name string) *PrimType {
+		return &PrimType{Token: lit(token.INT_TYPE, name)}
+	}
 
-	root := &Let{
+	lib := &Library{
+		nil,
 		tok,
-		NewSymbol("foo"),
-		&Add{
-			&Sub{
-				&FSub{
-					&Less{
-						&Unit{tok, tok},
-						&Not{
-							tok,
-							&Bool{tok, true},
-						},
-					},
-					&Neg{
-						tok,
-						&Mod{
-							&Int{tok, 42},
-							&Int{tok, 42},
-						},
-					},
-				},
-				&Eq{
-					&FNeg{
-						tok,
-						&Float{tok, 3.14},
-					},
-					&NotEq{
-						&VarRef{tok, NewSymbol("variable")},
-						&Int{tok, 42},
-					},
-				},
-			},
-			&FAdd{
-				&FDiv{
-					&Tuple{
-						[]Expr{
-							&Int{tok, 42},
-							&Float{tok, 3.14},
-							&ArraySize{
-								tok,
-								&VarRef{tok, NewSymbol("arr")},
-							},
-							&String{
-								tok,
-								"string literal",
-							},
-						},
-					},
-					&ArrayMake{
-						tok,
-						&Int{tok, 42},
-						&Typed{
-							&Bool{tok, false},
-							&CtorType{
-								nil,
-								tok,
-								nil,
-								NewSymbol("bool"),
-							},
-						},
-					},
-				},
-				&FMul{
-					&ArrayGet{
-						&ArrayMake{
-							tok,
-							&Int{tok, 42},
-							&Bool{tok, false},
-						},
-						&Int{tok, 1},
-					},
-					&ArrayPut{
-						&ArrayLit{
-							tok,
-							tok,
-							[]Expr{
-								&Int{tok, 100},
-								&Int{tok, 200},
-								&Int{tok, 300},
-							},
-						},
-						&Int{tok, 1},
-						&Bool{tok, true},
-					},
-				},
-			},
-		},
-		&LetTuple{
-			tok,
-			[]*Symbol{
-				NewSymbol("a"),
-				NewSymbol("b"),
-			},
-			&Tuple{
-				[]Expr{
-					&Greater{
-						&Int{tok, 1},
-						&Int{tok, 2},
-					},
-					&GreaterEq{
-						&Int{tok, 1},
-						&Int{tok, 2},
-					},
-					&Mul{
-						&Int{tok, 1},
-						&Int{tok, 2},
-					},
-					&Div{
-						&Int{tok, 1},
-						&Int{tok, 2},
-					},
-					&And{
-						&Bool{tok, true},
-						&Bool{tok, false},
-					},
-					&Or{
-						&Bool{tok, true},
-						&Bool{tok, false},
-					},
+		ident("Foo"),
+		[]LibEntry{
+			&TypeDecl{
+				nil,
+				tok,
+				ident("Error"),
+				[]*CtorDecl{
+					{nil, tok, ident("CodeNotOwner"), nil, nil},
+					{nil, tok, ident("CodeAmount"), []Type{prim("Uint128")}, nil},
 				},
 			},
-			&LetRec{
+			&LetDecl{
+				nil,
 				tok,
-				&FuncDef{
-					NewSymbol("f"),
-					[]Param{
-						{
-							NewSymbol("a"),
-							&CtorType{
-								nil,
-								tok,
-								nil,
-								NewSymbol("unit"),
-							},
-						},
-					},
-					&VarRef{tok, NewSymbol("a")},
-					&CtorType{
-						nil,
-						tok,
-						nil,
-						NewSymbol("int"),
-					},
-				},
-				&If{
+				ident("one_msg"),
+				&FunType{
+					Param: &PrimType{Token: lit(token.MESSAGE_TYPE, "Message")},
+					Ret:   &ADTType{Ident: ident("List"), Args: []Type{&PrimType{Token: lit(token.MESSAGE_TYPE, "Message")}}}},
+				&Fun{
 					tok,
-					&LessEq{
-						&Int{tok, 1},
-						&Int{tok, 2},
-					},
-					&Apply{
-						&VarRef{tok, NewSymbol("f")},
-						[]Expr{
-							&Int{tok, 42},
-						},
-					},
-					&Match{
+					&Param{nil, ident("msg"), &PrimType{Token: lit(token.MESSAGE_TYPE, "Message")}, nil},
+					&Let{
 						tok,
-						&Some{tok, &Int{tok, 1}},
-						&None{tok},
-						&None{tok},
-						NewSymbol("foo"),
-						tok.End,
-					},
-				},
-			},
-			&TupleType{
-				[]Expr{
-					&CtorType{
+						ident("nil_msg"),
 						nil,
-						tok,
-						[]Expr{
-							&CtorType{
-								nil,
-								tok,
-								nil,
-								NewSymbol("unit"),
-							},
-						},
-						NewSymbol("foo"),
+						&Constr{ident("Nil"), tok, []Type{&PrimType{Token: lit(token.MESSAGE_TYPE, "Message")}}, tok, nil},
+						&Constr{ident("Cons"), tok, []Type{&PrimType{Token: lit(token.MESSAGE_TYPE, "Message")}}, tok, []*VarRef{ref("msg"), ref("nil_msg")}},
 					},
 				},
+				nil,
 			},
-		},
-		&FuncType{
-			[]Expr{
-				&CtorType{
-					nil,
-					tok,
-					nil,
-					NewSymbol("int"),
-				},
-			},
-			&CtorType{
-				tok,
+			&LetDecl{
+				nil,
 				tok,
-				[]Expr{
-					&CtorType{
-						nil,
-						tok,
-						nil,
-						NewSymbol("bool"),
-					},
-					&CtorType{
-						nil,
-						tok,
-						nil,
-						NewSymbol("float"),
-					},
+				ident("id"),
+				&PolyType{ForallToken: tok, TVar: ident("'A"), Body: &FunType{Param: &TypeVar{Token: lit(token.TID, "'A")}, Ret: &TypeVar{Token: lit(token.TID, "'A")}}},
+				&TFun{
+					tok,
+					ident("'A"),
+					&Fun{tok, &Param{nil, ident("x"), &TypeVar{Token: lit(token.TID, "'A")}, nil}, ref("x")},
 				},
-				NewSymbol("foo"),
+				nil,
 			},
 		},
 	}
 
-	ast := &AST{
-		Root: root,
-		TypeDecls: []*TypeDecl{
-			{
-				tok,
-				NewSymbol("mytype"),
-				&CtorType{
-					nil,
-					tok,
-					nil,
-					NewSymbol("bool"),
-				},
-			},
+	contract := &Contract{
+		nil,
+		tok,
+		ident("Foo"),
+		[]*Param{{nil, ident("owner"), prim("ByStr20"), nil}},
+		tok,
+		nil,
+		[]*Field{
+			{nil, tok, ident("balances"), &MapType{MapToken: tok, Key: prim("ByStr20"), Value: prim("Uint128")}, &EmpLit{tok, prim("ByStr20"), prim("Uint128")}, nil},
 		},
-		Externals: []*External{
+		[]*Component{
 			{
+				nil,
+				lit(token.TRANSITION, "transition"),
+				ident("Transfer"),
+				[]*Param{
+					{nil, ident("to"), &AddressType{ByStrToken: tok, WithToken: tok, KindToken: tok, Fields: []*AddressField{{tok, ident("balances"), &MapType{MapToken: tok, Key: prim("ByStr20"), Value: prim("Uint128")}}}, EndToken: tok}, nil},
+					{nil, ident("amount"), prim("Uint128"), nil},
+				},
 				tok,
-				tok,
-				NewSymbol("cfun"),
-				&FuncType{
-					[]Expr{
-						&CtorType{
-							nil,
-							tok,
-							nil,
-							NewSymbol("int"),
+				[]Stmt{
+					&MapGet{ident("bal"), nil, ref("balances"), []*MapKey{{tok, ref("_sender"), tok}}},
+					&RemoteMapGet{ident("rbal"), tok, tok, ref("to"), ident("balances"), []*MapKey{{tok, ref("to"), tok}}},
+					&MatchStmt{
+						tok,
+						ref("bal"),
+						[]*StmtArm{
+							{
+								tok,
+								&ConstrPattern{Ctor: ident("Some"), Args: []Pattern{&BinderPattern{Ident: ident("b")}}},
+								[]Stmt{
+									&Bind{ident("new_bal"), &Builtin{tok, ident("sub"), []*VarRef{ref("b"), ref("amount")}, nil}},
+									&MapUpdate{ref("balances"), []*MapKey{{tok, ref("_sender"), tok}}, ref("new_bal")},
+								},
+							},
+							{
+								tok,
+								&WildcardPattern{Token: tok},
+								[]Stmt{
+									&Bind{ident("e"), &Message{tok, []*MessageEntry{{lit(token.SPID, "_exception"), &StringLit{lit(token.STRING_LIT, `"Error"`)}}}, tok}},
+									&Throw{tok, ref("e")},
+								},
+							},
 						},
-					},
-					&CtorType{
-						nil,
 						tok,
-						nil,
-						NewSymbol("bool"),
 					},
+					&Accept{tok},
 				},
-				"c_level_fun",
+				tok,
 			},
 		},
 	}
 
+	ast := &AST{
+		Version:  &Version{tok, tok, 0},
+		Imports:  []*Import{{tok, []*ImportName{{ident("BoolUtils"), nil}, {ident("IntUtils"), ident("I")}}}},
+		Library:  lib,
+		Contract: contract,
+		Source:   s,
+	}
+
 	old := os.Stdout
 	r, w, _ := os.Pipe()
 	os.Stdout = w
@@ -289,100 +159,122 @@ func TestPrintAST(t *testing.T) {
 	os.Stdout = old
 
 	expected := `AST for <dummy>:
--   TypeDecl (mytype) (0:0-0:0)
--   -   CtorType (bool) (0:0-0:0)
--   External (cfun => c_level_fun) (0:0-0:0)
--   -   FuncType (0:0-0:0)
--   -   -   CtorType (int) (0:0-0:0)
--   -   -   CtorType (bool) (0:0-0:0)
--   Let (foo) (0:0-0:0)
--   -   FuncType (0:0-0:0)
--   -   -   CtorType (int) (0:0-0:0)
--   -   -   CtorType (foo (2)) (0:0-0:0)
--   -   -   -   CtorType (bool) (0:0-0:0)
--   -   -   -   CtorType (float) (0:0-0:0)
--   -   Add (0:0-0:0)
--   -   -   Sub (0:0-0:0)
--   -   -   -   FSub (0:0-0:0)
--   -   -   -   -   Less (0:0-0:0)
--   -   -   -   -   -   Unit (0:0-0:0)
--   -   -   -   -   -   Not (0:0-0:0)
--   -   -   -   -   -   -   Bool (0:0-0:0)
--   -   -   -   -   Neg (0:0-0:0)
--   -   -   -   -   -   Mod (0:0-0:0)
--   -   -   -   -   -   -   Int (0:0-0:0)
--   -   -   -   -   -   -   Int (0:0-0:0)
--   -   -   -   Eq (0:0-0:0)
--   -   -   -   -   FNeg (0:0-0:0)
--   -   -   -   -   -   Float (0:0-0:0)
--   -   -   -   -   NotEq (0:0-0:0)
--   -   -   -   -   -   VarRef (variable) (0:0-0:0)
--   -   -   -   -   -   Int (0:0-0:0)
--   -   -   FAdd (0:0-0:0)
--   -   -   -   FDiv (0:0-0:0)
--   -   -   -   -   Tuple (0:0-0:0)
--   -   -   -   -   -   Int (0:0-0:0)
--   -   -   -   -   -   Float (0:0-0:0)
--   -   -   -   -   -   ArraySize (0:0-0:0)
--   -   -   -   -   -   -   VarRef (arr) (0:0-0:0)
--   -   -   -   -   -   String () (0:0-0:0)
--   -   -   -   -   ArrayCreate (0:0-0:0)
--   -   -   -   -   -   Int (0:0-0:0)
--   -   -   -   -   -   Typed (0:0-0:0)
--   -   -   -   -   -   -   Bool (0:0-0:0)
--   -   -   -   -   -   -   CtorType (bool) (0:0-0:0)
--   -   -   -   FMul (0:0-0:0)
--   -   -   -   -   ArrayGet (0:0-0:0)
--   -   -   -   -   -   ArrayCreate (0:0-0:0)
--   -   -   -   -   -   -   Int (0:0-0:0)
--   -   -   -   -   -   -   Bool (0:0-0:0)
--   -   -   -   -   -   Int (0:0-0:0)
--   -   -   -   -   ArrayPut (0:0-0:0)
--   -   -   -   -   -   ArrayLit (3) (0:0-0:0)
--   -   -   -   -   -   -   Int (0:0-0:0)
--   -   -   -   -   -   -   Int (0:0-0:0)
--   -   -   -   -   -   -   Int (0:0-0:0)
--   -   -   -   -   -   Int (0:0-0:0)
--   -   -   -   -   -   Bool (0:0-0:0)
--   -   LetTuple (a, b) (0:0-0:0)
--   -   -   TupleType (1) (0:0-0:0)
--   -   -   -   CtorType (foo (1)) (0:0-0:0)
--   -   -   -   -   CtorType (unit) (0:0-0:0)
--   -   -   Tuple (0:0-0:0)
--   -   -   -   Greater (0:0-0:0)
--   -   -   -   -   Int (0:0-0:0)
--   -   -   -   -   Int (0:0-0:0)
--   -   -   -   GreaterEq (0:0-0:0)
--   -   -   -   -   Int (0:0-0:0)
--   -   -   -   -   Int (0:0-0:0)
--   -   -   -   Mul (0:0-0:0)
--   -   -   -   -   Int (0:0-0:0)
--   -   -   -   -   Int (0:0-0:0)
--   -   -   -   Div (0:0-0:0)
--   -   -   -   -   Int (0:0-0:0)
--   -   -   -   -   Int (0:0-0:0)
--   -   -   -   And (0:0-0:0)
--   -   -   -   -   Bool (0:0-0:0)
--   -   -   -   -   Bool (0:0-0:0)
--   -   -   -   Or (0:0-0:0)
--   -   -   -   -   Bool (0:0-0:0)
--   -   -   -   -   Bool (0:0-0:0)
--   -   -   LetRec (fun f a) (0:0-0:0)
--   -   -   -   CtorType (unit) (0:0-0:0)
--   -   -   -   CtorType (int) (0:0-0:0)
--   -   -   -   VarRef (a) (0:0-0:0)
--   -   -   -   If (0:0-0:0)
--   -   -   -   -   LessEq (0:0-0:0)
--   -   -   -   -   -   Int (0:0-0:0)
--   -   -   -   -   -   Int (0:0-0:0)
--   -   -   -   -   Apply (0:0-0:0)
--   -   -   -   -   -   VarRef (f) (0:0-0:0)
--   -   -   -   -   -   Int (0:0-0:0)
--   -   -   -   -   Match (foo) (0:0-0:0)
--   -   -   -   -   -   Some (0:0-0:0)
--   -   -   -   -   -   -   Int (0:0-0:0)
--   -   -   -   -   -   None (0:0-0:0)
--   -   -   -   -   -   None (0:0-0:0)
+-   Version (0) (0:0-0:0)
+-   Import (2) (0:0-0:0)
+-   -   ImportName (BoolUtils) (0:0-0:0)
+-   -   -   Ident (BoolUtils) (0:0-0:0)
+-   -   ImportName (IntUtils as I) (0:0-0:0)
+-   -   -   Ident (IntUtils) (0:0-0:0)
+-   -   -   Ident (I) (0:0-0:0)
+-   Library (Foo) (0:0-0:0)
+-   -   Ident (Foo) (0:0-0:0)
+-   -   TypeDecl (Error) (0:0-0:0)
+-   -   -   Ident (Error) (0:0-0:0)
+-   -   -   CtorDecl (CodeNotOwner) (0:0-0:0)
+-   -   -   -   Ident (CodeNotOwner) (0:0-0:0)
+-   -   -   CtorDecl (CodeAmount) (0:0-0:0)
+-   -   -   -   Ident (CodeAmount) (0:0-0:0)
+-   -   -   -   PrimType (Uint128) (0:0-0:0)
+-   -   LetDecl (one_msg) (0:0-0:0)
+-   -   -   Ident (one_msg) (0:0-0:0)
+-   -   -   FunType (0:0-0:0)
+-   -   -   -   PrimType (Message) (0:0-0:0)
+-   -   -   -   ADTType (List) (0:0-0:0)
+-   -   -   -   -   Ident (List) (0:0-0:0)
+-   -   -   -   -   PrimType (Message) (0:0-0:0)
+-   -   -   Fun (msg) (0:0-0:0)
+-   -   -   -   Param (msg) (0:0-0:0)
+-   -   -   -   -   Ident (msg) (0:0-0:0)
+-   -   -   -   -   PrimType (Message) (0:0-0:0)
+-   -   -   -   Let (nil_msg) (0:0-0:0)
+-   -   -   -   -   Ident (nil_msg) (0:0-0:0)
+-   -   -   -   -   Constr (Nil) (0:0-0:0)
+-   -   -   -   -   -   Ident (Nil) (0:0-0:0)
+-   -   -   -   -   -   PrimType (Message) (0:0-0:0)
+-   -   -   -   -   Constr (Cons) (0:0-0:0)
+-   -   -   -   -   -   Ident (Cons) (0:0-0:0)
+-   -   -   -   -   -   PrimType (Message) (0:0-0:0)
+-   -   -   -   -   -   VarRef (msg) (0:0-0:0)
+-   -   -   -   -   -   VarRef (nil_msg) (0:0-0:0)
+-   -   LetDecl (id) (0:0-0:0)
+-   -   -   Ident (id) (0:0-0:0)
+-   -   -   PolyType ('A) (0:0-0:0)
+-   -   -   -   Ident ('A) (0:0-0:0)
+-   -   -   -   FunType (0:0-0:0)
+-   -   -   -   -   TypeVar ('A) (0:0-0:0)
+-   -   -   -   -   TypeVar ('A) (0:0-0:0)
+-   -   -   TFun ('A) (0:0-0:0)
+-   -   -   -   Ident ('A) (0:0-0:0)
+-   -   -   -   Fun (x) (0:0-0:0)
+-   -   -   -   -   Param (x) (0:0-0:0)
+-   -   -   -   -   -   Ident (x) (0:0-0:0)
+-   -   -   -   -   -   TypeVar ('A) (0:0-0:0)
+-   -   -   -   -   VarRef (x) (0:0-0:0)
+-   Contract (Foo) (0:0-0:0)
+-   -   Ident (Foo) (0:0-0:0)
+-   -   Param (owner) (0:0-0:0)
+-   -   -   Ident (owner) (0:0-0:0)
+-   -   -   PrimType (ByStr20) (0:0-0:0)
+-   -   Field (balances) (0:0-0:0)
+-   -   -   Ident (balances) (0:0-0:0)
+-   -   -   MapType (0:0-0:0)
+-   -   -   -   PrimType (ByStr20) (0:0-0:0)
+-   -   -   -   PrimType (Uint128) (0:0-0:0)
+-   -   -   EmpLit (0:0-0:0)
+-   -   -   -   PrimType (ByStr20) (0:0-0:0)
+-   -   -   -   PrimType (Uint128) (0:0-0:0)
+-   -   Transition (Transfer) (0:0-0:0)
+-   -   -   Ident (Transfer) (0:0-0:0)
+-   -   -   Param (to) (0:0-0:0)
+-   -   -   -   Ident (to) (0:0-0:0)
+-   -   -   -   AddressType (1) (0:0-0:0)
+-   -   -   -   -   AddressField (balances) (0:0-0:0)
+-   -   -   -   -   -   Ident (balances) (0:0-0:0)
+-   -   -   -   -   -   MapType (0:0-0:0)
+-   -   -   -   -   -   -   PrimType (ByStr20) (0:0-0:0)
+-   -   -   -   -   -   -   PrimType (Uint128) (0:0-0:0)
+-   -   -   Param (amount) (0:0-0:0)
+-   -   -   -   Ident (amount) (0:0-0:0)
+-   -   -   -   PrimType (Uint128) (0:0-0:0)
+-   -   -   MapGet (1) (0:0-0:0)
+-   -   -   -   Ident (bal) (0:0-0:0)
+-   -   -   -   VarRef (balances) (0:0-0:0)
+-   -   -   -   MapKey (0:0-0:0)
+-   -   -   -   -   VarRef (_sender) (0:0-0:0)
+-   -   -   RemoteMapGet (exists 1) (0:0-0:0)
+-   -   -   -   Ident (rbal) (0:0-0:0)
+-   -   -   -   VarRef (to) (0:0-0:0)
+-   -   -   -   Ident (balances) (0:0-0:0)
+-   -   -   -   MapKey (0:0-0:0)
+-   -   -   -   -   VarRef (to) (0:0-0:0)
+-   -   -   MatchStmt (2) (0:0-0:0)
+-   -   -   -   VarRef (bal) (0:0-0:0)
+-   -   -   -   StmtArm (2) (0:0-0:0)
+-   -   -   -   -   ConstrPattern (Some) (0:0-0:0)
+-   -   -   -   -   -   Ident (Some) (0:0-0:0)
+-   -   -   -   -   -   BinderPattern (b) (0:0-0:0)
+-   -   -   -   -   -   -   Ident (b) (0:0-0:0)
+-   -   -   -   -   Bind (0:0-0:0)
+-   -   -   -   -   -   Ident (new_bal) (0:0-0:0)
+-   -   -   -   -   -   Builtin (sub) (0:0-0:0)
+-   -   -   -   -   -   -   Ident (sub) (0:0-0:0)
+-   -   -   -   -   -   -   VarRef (b) (0:0-0:0)
+-   -   -   -   -   -   -   VarRef (amount) (0:0-0:0)
+-   -   -   -   -   MapUpdate (1) (0:0-0:0)
+-   -   -   -   -   -   VarRef (balances) (0:0-0:0)
+-   -   -   -   -   -   MapKey (0:0-0:0)
+-   -   -   -   -   -   -   VarRef (_sender) (0:0-0:0)
+-   -   -   -   -   -   VarRef (new_bal) (0:0-0:0)
+-   -   -   -   StmtArm (2) (0:0-0:0)
+-   -   -   -   -   WildcardPattern (0:0-0:0)
+-   -   -   -   -   Bind (0:0-0:0)
+-   -   -   -   -   -   Ident (e) (0:0-0:0)
+-   -   -   -   -   -   Message (1) (0:0-0:0)
+-   -   -   -   -   -   -   MessageEntry (_exception) (0:0-0:0)
+-   -   -   -   -   -   -   -   StringLit ("Error") (0:0-0:0)
+-   -   -   -   -   Throw (0:0-0:0)
+-   -   -   -   -   -   VarRef (e) (0:0-0:0)
+-   -   -   Accept (0:0-0:0)
 `
 	actual := <-ch
 	if expected != actual {
@@ -0,0 +1,350 @@
+package ast
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// ApplyFunc is called for each node visited by Apply. The return value controls the traversal.
+// See Apply for details.
+type ApplyFunc func(*Cursor) bool
+
+// Cursor describes a node encountered during Apply. It provides the parent of the node and the
+// field of the parent which holds the node, and methods to rewrite the field.
+type Cursor struct {
+	parent Node
+	name   string
+	iter   *iterator // Valid only when the node is an element of a list
+	node   Node
+}
+
+// iterator tracks the position in a list while the list is modified during traversal
+type iterator struct {
+	index, step int
+}
+
+// Node returns the current node.
+func (c *Cursor) Node() Node { return c.node }
+
+// Parent returns the parent of the current node. The parent of the root node passed to Apply is
+// a dummy node which holds the root in its field "Node".
+func (c *Cursor) Parent() Node { return c.parent }
+
+// Name returns the name of the parent's field which contains the current node, such as "Body" of
+// *Component or "Bound" of *LetDecl.
+func (c *Cursor) Name() string { return c.name }
+
+// Index returns the index of the current node in the list when the parent's field is a list such
+// as statements, match arms or library entries. Otherwise it returns a negative value.
+func (c *Cursor) Index() int {
+	if c.iter != nil {
+		return c.iter.index
+	}
+	return -1
+}
+
+func (c *Cursor) field() reflect.Value {
+	return reflect.Indirect(reflect.ValueOf(c.parent)).FieldByName(c.name)
+}
+
+// value returns the reflected node to be put in the list or field of type t. It panics when the
+// node cannot be put there, e.g. an expression in a list of statements.
+func (c *Cursor) value(n Node, t reflect.Type) reflect.Value {
+	if isNilNode(n) {
+		return reflect.Zero(t)
+	}
+	v := reflect.ValueOf(n)
+	if !v.Type().AssignableTo(t) {
+		panic(fmt.Sprintf("%T cannot be put in field %s of %T", n, c.name, c.parent))
+	}
+	return v
+}
+
+// Replace replaces the current node with n. The replacement is not walked by Apply. Replacing
+// with nil is allowed only for optional fields such as type annotation of `let`.
+func (c *Cursor) Replace(n Node) {
+	v := c.field()
+	if i := c.Index(); i >= 0 {
+		v = v.Index(i)
+	}
+	v.Set(c.value(n, v.Type()))
+	c.node = n
+}
+
+// Delete deletes the current node from the list which contains it. It panics when the current
+// node is not an element of list.
+func (c *Cursor) Delete() {
+	i := c.Index()
+	if i < 0 {
+		panic(fmt.Sprintf("Delete: %T is not contained in a list", c.node))
+	}
+	v := c.field()
+	l := v.Len()
+	reflect.Copy(v.Slice(i, l), v.Slice(i+1, l))
+	v.Index(l - 1).Set(reflect.Zero(v.Type().Elem()))
+	v.SetLen(l - 1)
+	c.iter.step--
+}
+
+// InsertAfter inserts n after the current node in the list which contains it. The inserted node
+// is not walked by Apply. It panics when the current node is not an element of list.
+func (c *Cursor) InsertAfter(n Node) {
+	i := c.Index()
+	if i < 0 {
+		panic(fmt.Sprintf("InsertAfter: %T is not contained in a list", c.node))
+	}
+	v := c.field()
+	e := c.value(n, v.Type().Elem())
+	v.Set(reflect.Append(v, reflect.Zero(v.Type().Elem())))
+	l := v.Len()
+	reflect.Copy(v.Slice(i+2, l), v.Slice(i+1, l))
+	v.Index(i + 1).Set(e)
+	c.iter.step++
+}
+
+// InsertBefore inserts n before the current node in the list which contains it. The inserted node
+// is not walked by Apply. It panics when the current node is not an element of list.
+func (c *Cursor) InsertBefore(n Node) {
+	i := c.Index()
+	if i < 0 {
+		panic(fmt.Sprintf("InsertBefore: %T is not contained in a list", c.node))
+	}
+	v := c.field()
+	e := c.value(n, v.Type().Elem())
+	v.Set(reflect.Append(v, reflect.Zero(v.Type().Elem())))
+	l := v.Len()
+	reflect.Copy(v.Slice(i+1, l), v.Slice(i, l))
+	v.Index(i).Set(e)
+	c.iter.index++
+}
+
+// applyAbort is a panic payload to stop traversal when post returned false
+type applyAbort struct{}
+
+type application struct {
+	pre, post ApplyFunc
+	cursor    Cursor
+	iter      iterator
+}
+
+// Apply traverses the AST rooted at root recursively in the same order as Visit and calls pre and
+// post for each node. root may be *AST to rewrite a whole module.
+//
+// pre is called before the children of the node are traversed. When it returns false, the
+// children and post are skipped for the node. post is called after the children are traversed.
+// When it returns false, the traversal is stopped immediately. Nil pre or post is not called.
+//
+// Through the cursor passed to pre and post, the current node can be replaced, and nodes can be
+// inserted or deleted in lists such as statements, match arms and library entries. When pre
+// replaces the node, the children of the new node are traversed. Nil optional children (e.g. nil
+// type annotation of `let`) are not visited.
+//
+// Apply returns the root which may have been replaced.
+func Apply(root Node, pre, post ApplyFunc) (result Node) {
+	parent := &struct{ Node }{root}
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(applyAbort); !ok {
+				panic(r)
+			}
+		}
+		result = parent.Node
+	}()
+	a := &application{pre: pre, post: post}
+	a.apply(parent, "Node", nil, root)
+	return
+}
+
+func (a *application) apply(parent Node, name string, iter *iterator, n Node) {
+	if isNilNode(n) {
+		return
+	}
+
+	saved := a.cursor
+	a.cursor = Cursor{parent, name, iter, n}
+	if a.pre != nil && !a.pre(&a.cursor) {
+		a.cursor = saved
+		return
+	}
+
+	switch n := a.cursor.node.(type) {
+	case *AST:
+		a.apply(n, "Version", nil, n.Version)
+		a.applyList(n, "Imports")
+		a.apply(n, "Library", nil, n.Library)
+		a.apply(n, "Contract", nil, n.Contract)
+		a.applyList(n, "Components")
+		a.applyList(n, "BadDecls")
+	case *Import:
+		a.applyList(n, "Names")
+	case *ImportName:
+		a.apply(n, "Lib", nil, n.Lib)
+		a.apply(n, "Alias", nil, n.Alias)
+	case *Library:
+		a.apply(n, "Ident", nil, n.Ident)
+		a.applyList(n, "Entries")
+	case *LetDecl:
+		a.apply(n, "Ident", nil, n.Ident)
+		a.apply(n, "Type", nil, n.Type)
+		a.apply(n, "Bound", nil, n.Bound)
+	case *TypeDecl:
+		a.apply(n, "Ident", nil, n.Ident)
+		a.applyList(n, "Ctors")
+	case *CtorDecl:
+		a.apply(n, "Ident", nil, n.Ident)
+		a.applyList(n, "Types")
+	case *Contract:
+		a.apply(n, "Ident", nil, n.Ident)
+		a.applyList(n, "Params")
+		a.apply(n, "Constraint", nil, n.Constraint)
+		a.applyList(n, "Fields")
+		a.applyList(n, "Components")
+	case *Param:
+		a.apply(n, "Ident", nil, n.Ident)
+		a.apply(n, "Type", nil, n.Type)
+	case *Field:
+		a.apply(n, "Ident", nil, n.Ident)
+		a.apply(n, "Type", nil, n.Type)
+		a.apply(n, "Init", nil, n.Init)
+	case *Component:
+		a.apply(n, "Ident", nil, n.Ident)
+		a.applyList(n, "Params")
+		a.applyList(n, "Body")
+	case *EmpLit:
+		a.apply(n, "Key", nil, n.Key)
+		a.apply(n, "Value", nil, n.Value)
+	case *Let:
+		a.apply(n, "Ident", nil, n.Ident)
+		a.apply(n, "Type", nil, n.Type)
+		a.apply(n, "Bound", nil, n.Bound)
+		a.apply(n, "Body", nil, n.Body)
+	case *Fun:
+		a.apply(n, "Param", nil, n.Param)
+		a.apply(n, "Body", nil, n.Body)
+	case *TFun:
+		a.apply(n, "TVar", nil, n.TVar)
+		a.apply(n, "Body", nil, n.Body)
+	case *App:
+		a.apply(n, "Func", nil, n.Func)
+		a.applyList(n, "Args")
+	case *TApp:
+		a.apply(n, "Func", nil, n.Func)
+		a.applyList(n, "Types")
+	case *Builtin:
+		a.apply(n, "Ident", nil, n.Ident)
+		a.applyList(n, "Args")
+	case *Constr:
+		a.apply(n, "Ident", nil, n.Ident)
+		a.applyList(n, "TypeArgs")
+		a.applyList(n, "Args")
+	case *Message:
+		a.applyList(n, "Entries")
+	case *MessageEntry:
+		a.apply(n, "Value", nil, n.Value)
+	case *Match:
+		a.apply(n, "Target", nil, n.Target)
+		a.applyList(n, "Arms")
+	case *MatchArm:
+		a.apply(n, "Pattern", nil, n.Pattern)
+		a.apply(n, "Body", nil, n.Body)
+	case *Load:
+		a.apply(n, "Ident", nil, n.Ident)
+		a.apply(n, "Field", nil, n.Field)
+	case *RemoteLoad:
+		a.apply(n, "Ident", nil, n.Ident)
+		a.apply(n, "Addr", nil, n.Addr)
+		a.apply(n, "Field", nil, n.Field)
+	case *Store:
+		a.apply(n, "Field", nil, n.Field)
+		a.apply(n, "Value", nil, n.Value)
+	case *Bind:
+		a.apply(n, "Ident", nil, n.Ident)
+		a.apply(n, "Value", nil, n.Value)
+	case *MapKey:
+		a.apply(n, "Key", nil, n.Key)
+	case *MapUpdate:
+		a.apply(n, "Map", nil, n.Map)
+		a.applyList(n, "Keys")
+		a.apply(n, "Value", nil, n.Value)
+	case *MapDelete:
+		a.apply(n, "Map", nil, n.Map)
+		a.applyList(n, "Keys")
+	case *MapGet:
+		a.apply(n, "Ident", nil, n.Ident)
+		a.apply(n, "Map", nil, n.Map)
+		a.applyList(n, "Keys")
+	case *RemoteMapGet:
+		a.apply(n, "Ident", nil, n.Ident)
+		a.apply(n, "Addr", nil, n.Addr)
+		a.apply(n, "Map", nil, n.Map)
+		a.applyList(n, "Keys")
+	case *ReadFromBC:
+		a.apply(n, "Ident", nil, n.Ident)
+	case *Send:
+		a.apply(n, "Msgs", nil, n.Msgs)
+	case *Event:
+		a.apply(n, "Event", nil, n.Event)
+	case *Throw:
+		a.apply(n, "Exception", nil, n.Exception)
+	case *MatchStmt:
+		a.apply(n, "Target", nil, n.Target)
+		a.applyList(n, "Arms")
+	case *StmtArm:
+		a.apply(n, "Pattern", nil, n.Pattern)
+		a.applyList(n, "Body")
+	case *CallProc:
+		a.apply(n, "Proc", nil, n.Proc)
+		a.applyList(n, "Args")
+	case *Iterate:
+		a.apply(n, "List", nil, n.List)
+		a.apply(n, "Proc", nil, n.Proc)
+	case *BinderPattern:
+		a.apply(n, "Ident", nil, n.Ident)
+	case *ConstrPattern:
+		a.apply(n, "Ctor", nil, n.Ctor)
+		a.applyList(n, "Args")
+	case *MapType:
+		a.apply(n, "Key", nil, n.Key)
+		a.apply(n, "Value", nil, n.Value)
+	case *FunType:
+		a.apply(n, "Param", nil, n.Param)
+		a.apply(n, "Ret", nil, n.Ret)
+	case *PolyType:
+		a.apply(n, "TVar", nil, n.TVar)
+		a.apply(n, "Body", nil, n.Body)
+	case *ADTType:
+		a.apply(n, "Ident", nil, n.Ident)
+		a.applyList(n, "Args")
+	case *AddressType:
+		a.applyList(n, "Fields")
+	case *AddressField:
+		a.apply(n, "Ident", nil, n.Ident)
+		a.apply(n, "Type", nil, n.Type)
+	}
+
+	if a.post != nil && !a.post(&a.cursor) {
+		panic(applyAbort{})
+	}
+	a.cursor = saved
+}
+
+// applyList applies each element of the list in the parent's field. The list may be modified
+// while it is traversed.
+func (a *application) applyList(parent Node, name string) {
+	saved := a.iter
+	a.iter.index = 0
+	for {
+		v := reflect.Indirect(reflect.ValueOf(parent)).FieldByName(name)
+		if a.iter.index >= v.Len() {
+			break
+		}
+		var n Node
+		if e := v.Index(a.iter.index); e.IsValid() && !(e.Kind() == reflect.Interface && e.IsNil()) {
+			n = e.Interface().(Node)
+		}
+		a.iter.step = 1
+		a.apply(parent, name, &a.iter, n)
+		a.iter.index += a.iter.step
+	}
+	a.iter = saved
+}
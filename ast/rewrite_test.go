@@ -0,0 +1,213 @@
+package ast
+
+import (
+	"bytes"
+	"goscilla/token"
+	"strings"
+	"testing"
+)
+
+// library L
+//
+// let one = Uint32 1
+//
+// let two = one
+//
+// transition T(x : Uint32)
+//
+//	accept;
+//	match x with
+//	| Some y =>
+//	  send y
+//	| None =>
+//	  throw
+//	end
+//
+// end
+func rewriteTarget() *AST {
+	tok := token.NewOrphanToken
+	ident := func(name string) *Ident { return &Ident{tok(token.ID, name), NewSymbol(name)} }
+	ref := func(name string) *VarRef { return &VarRef{tok(token.ID, name), NewSymbol(name)} }
+	return &AST{
+		Library: &Library{
+			Ident: ident("L"),
+			Entries: []LibEntry{
+				&LetDecl{Ident: ident("one"), Bound: &IntLit{tok(token.INT_TYPE, "Uint32"), tok(token.NUM_LIT, "1")}},
+				&LetDecl{Ident: ident("two"), Bound: ref("one")},
+			},
+		},
+		Components: []*Component{{
+			Token:  tok(token.TRANSITION, "transition"),
+			Ident:  ident("T"),
+			Params: []*Param{{Ident: ident("x"), Type: &PrimType{Token: tok(token.INT_TYPE, "Uint32")}}},
+			Body: []Stmt{
+				&Accept{tok(token.ACCEPT, "accept")},
+				&MatchStmt{Target: ref("x"), Arms: []*StmtArm{
+					{Pattern: &ConstrPattern{Ctor: ident("Some"), Args: []Pattern{&BinderPattern{Ident: ident("y")}}}, Body: []Stmt{&Send{Msgs: ref("y")}}},
+					{Pattern: &ConstrPattern{Ctor: ident("None")}, Body: []Stmt{&Throw{}}},
+				}},
+			},
+		}},
+	}
+}
+
+func formatted(t *testing.T, n Node) string {
+	var buf bytes.Buffer
+	if err := Format(&buf, n); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestApplyCursor(t *testing.T) {
+	type visit struct {
+		parent, name string
+		index        int
+	}
+	var have []visit
+	Apply(rewriteTarget(), func(c *Cursor) bool {
+		switch c.Node().(type) {
+		case *LetDecl, *StmtArm, *Accept, *Send:
+			have = append(have, visit{c.Parent().Name(), c.Name(), c.Index()})
+		case *Library:
+			if c.Index() >= 0 {
+				t.Error("Library is not in a list but index is", c.Index())
+			}
+		}
+		return true
+	}, nil)
+
+	want := []visit{
+		{"Library (L)", "Entries", 0},
+		{"Library (L)", "Entries", 1},
+		{"Transition (T)", "Body", 0},
+		{"MatchStmt", "Arms", 0},
+		{"StmtArm", "Body", 0},
+		{"MatchStmt", "Arms", 1},
+	}
+	if len(have) != len(want) {
+		t.Fatalf("Wanted %v but got %v", want, have)
+	}
+	for i, w := range want {
+		h := have[i]
+		if h.name != w.name || h.index != w.index || !strings.HasPrefix(h.parent, strings.Fields(w.parent)[0]) {
+			t.Errorf("Wanted %v but got %v at %d", w, h, i)
+		}
+	}
+}
+
+func TestApplyRewrite(t *testing.T) {
+	tok := token.NewOrphanToken
+	ref := func(name string) *VarRef { return &VarRef{tok(token.ID, name), NewSymbol(name)} }
+
+	a := Apply(rewriteTarget(), func(c *Cursor) bool {
+		switch n := c.Node().(type) {
+		case *VarRef:
+			// Rename variable
+			if n.Symbol.DisplayName == "one" {
+				c.Replace(ref("uno"))
+			}
+		case *LetDecl:
+			if n.Ident.Symbol.DisplayName == "one" {
+				n.Ident = &Ident{tok(token.ID, "uno"), NewSymbol("uno")}
+				c.InsertBefore(&TypeDecl{Ident: &Ident{tok(token.CID, "Unit"), NewSymbol("Unit")}})
+			}
+		case *Accept:
+			c.Delete()
+		case *Send:
+			c.InsertBefore(&Event{Event: ref("y")})
+			c.InsertAfter(&CallProc{Proc: ref("done")})
+		case *StmtArm:
+			if p, ok := n.Pattern.(*ConstrPattern); ok && p.Ctor.Symbol.DisplayName == "None" {
+				c.Delete()
+				return false
+			}
+		case *Throw:
+			t.Error("Children of deleted arm should not be visited")
+		}
+		return true
+	}, nil)
+
+	want := `library L
+
+type Unit
+
+let uno = Uint32 1
+
+let two = uno
+
+transition T(x : Uint32)
+  match x with
+  | Some y =>
+    event y;
+    send y;
+    done
+  end
+end
+`
+	if have := formatted(t, a); have != want {
+		t.Fatalf("Wanted:\n%s\nbut got:\n%s", want, have)
+	}
+}
+
+func TestApplyReplaceRoot(t *testing.T) {
+	root := &VarRef{token.NewOrphanToken(token.ID, "x"), NewSymbol("x")}
+	replaced := &VarRef{token.NewOrphanToken(token.ID, "y"), NewSymbol("y")}
+	have := Apply(root, nil, func(c *Cursor) bool {
+		c.Replace(replaced)
+		return true
+	})
+	if have != replaced {
+		t.Fatal("Root was not replaced:", have)
+	}
+}
+
+func TestApplyAbort(t *testing.T) {
+	count := 0
+	Apply(rewriteTarget(), func(c *Cursor) bool {
+		count++
+		return true
+	}, func(c *Cursor) bool {
+		_, ok := c.Node().(*LetDecl)
+		return !ok
+	})
+	// AST, Library, Ident (L), LetDecl (one), Ident (one) and IntLit
+	if count != 6 {
+		t.Fatal("Traversal should stop at the first let declaration but visited", count)
+	}
+}
+
+func TestApplyInvalidRewrite(t *testing.T) {
+	for _, tc := range []struct {
+		what string
+		edit func(c *Cursor)
+		want string
+	}{
+		{"expression into statements", func(c *Cursor) {
+			if _, ok := c.Node().(*Accept); ok {
+				c.InsertAfter(&StringLit{})
+			}
+		}, "*ast.StringLit cannot be put in field Body of *ast.Component"},
+		{"delete non-list element", func(c *Cursor) {
+			if _, ok := c.Node().(*Library); ok {
+				c.Delete()
+			}
+		}, "Delete: *ast.Library is not contained in a list"},
+	} {
+		t.Run(tc.what, func(t *testing.T) {
+			defer func() {
+				r := recover()
+				if r == nil {
+					t.Fatal("Panic did not occur")
+				}
+				if msg := r.(string); !strings.Contains(msg, tc.want) {
+					t.Fatalf("Wanted %q in %q", tc.want, msg)
+				}
+			}()
+			Apply(rewriteTarget(), func(c *Cursor) bool {
+				tc.edit(c)
+				return true
+			}, nil)
+		})
+	}
+}
@@ -0,0 +1,459 @@
+package ast
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/rhysd/locerr"
+	"goscilla/token"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// This file provides JSON output in the shape of the AST of Zilliqa's scilla-checker.
+//
+// The module is encoded in the shape of the OCaml AST in Syntax.ml of Zilliqa/scilla with the
+// conventions of ppx_deriving_yojson: a variant is an array whose first element is the constructor
+// name (e.g. ["Load", x, f] and ["Bnum_typ"]), a record is an object, an option is null or the
+// value and a tuple is an array. An identifier is an object with "vname" and "loc" like
+// identifiers in outputs of scilla-checker. ReadScillaJSON reads only this encoding.
+
+// ScillaTypeString returns the type in the format scilla-checker uses in its outputs, such as
+// "Map (ByStr20) (Uint128)" and "List (Option (Int32))".
+func ScillaTypeString(t Type) string {
+	switch t := t.(type) {
+	case *PrimType:
+		return t.Token.Value()
+	case *MapType:
+		return fmt.Sprintf("Map (%s) (%s)", ScillaTypeString(t.Key), ScillaTypeString(t.Value))
+	case *FunType:
+		param := ScillaTypeString(t.Param)
+		switch t.Param.(type) {
+		case *FunType, *PolyType:
+			param = "(" + param + ")"
+		}
+		return fmt.Sprintf("%s -> %s", param, ScillaTypeString(t.Ret))
+	case *PolyType:
+		return fmt.Sprintf("forall %s. %s", t.TVar.Symbol.DisplayName, ScillaTypeString(t.Body))
+	case *TypeVar:
+		return t.Token.Value()
+	case *ADTType:
+		elems := []string{t.Ident.Symbol.DisplayName}
+		for _, a := range t.Args {
+			elems = append(elems, "("+ScillaTypeString(a)+")")
+		}
+		return strings.Join(elems, " ")
+	case *AddressType:
+		if t.KindToken == nil {
+			return t.ByStrToken.Value() + " with end"
+		}
+		if t.KindToken.Kind != token.CONTRACT || len(t.Fields) == 0 {
+			return fmt.Sprintf("%s with %s end", t.ByStrToken.Value(), t.KindToken.Value())
+		}
+		fields := make([]string, 0, len(t.Fields))
+		for _, f := range t.Fields {
+			fields = append(fields, fmt.Sprintf("field %s : %s", f.Ident.Symbol.DisplayName, ScillaTypeString(f.Type)))
+		}
+		return fmt.Sprintf("%s with contract %s end", t.ByStrToken.Value(), strings.Join(fields, ", "))
+	}
+	return "<unknown>"
+}
+
+// scillaPrimType returns the variant of PrimType in Syntax.ml such as ["Uint_typ", ["Bits32"]] and
+// ["Bystrx_typ", 20]. It returns nil when the name is not of a primitive type.
+func scillaPrimType(name string) interface{} {
+	switch name {
+	case "String":
+		return scillaVariant("String_typ")
+	case "BNum":
+		return scillaVariant("Bnum_typ")
+	case "Message":
+		return scillaVariant("Msg_typ")
+	case "Event":
+		return scillaVariant("Event_typ")
+	case "Exception":
+		return scillaVariant("Exception_typ")
+	case "ByStr":
+		return scillaVariant("Bystr_typ")
+	}
+	for _, p := range []struct{ prefix, variant string }{{"Uint", "Uint_typ"}, {"Int", "Int_typ"}} {
+		if bits := strings.TrimPrefix(name, p.prefix); bits != name && isDigits(bits) {
+			return scillaVariant(p.variant, scillaVariant("Bits"+bits))
+		}
+	}
+	if w := strings.TrimPrefix(name, "ByStr"); w != name && isDigits(w) {
+		n, _ := strconv.Atoi(w)
+		return scillaVariant("Bystrx_typ", n)
+	}
+	return nil
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// scillaBuiltin returns the variant of builtin in Syntax.ml such as ["Builtin_eq"]. Builtins
+// parameterized by width such as to_bystr20 are ["Builtin_to_bystrx", 20].
+func scillaBuiltin(name string) interface{} {
+	for _, p := range []string{"bystr_to_bystr", "to_bystr"} {
+		if w := strings.TrimPrefix(name, p); w != name && isDigits(w) {
+			n, _ := strconv.Atoi(w)
+			return scillaVariant("Builtin_"+p+"x", n)
+		}
+	}
+	return scillaVariant("Builtin_" + name)
+}
+
+func scillaVariant(name string, args ...interface{}) []interface{} {
+	return append([]interface{}{name}, args...)
+}
+
+func scillaLoc(p locerr.Pos) jsonObject {
+	file := ""
+	if p.File != nil {
+		file = p.File.Path
+	}
+	return jsonObject{{"file", file}, {"line", p.Line}, {"column", p.Column}}
+}
+
+func scillaIdent(name string, pos locerr.Pos) jsonObject {
+	return jsonObject{{"vname", name}, {"loc", scillaLoc(pos)}}
+}
+
+// scillaDummyIdent is an identifier which scilla-checker puts without location in source
+func scillaDummyIdent(name string) jsonObject {
+	return jsonObject{{"vname", name}, {"loc", jsonObject{{"file", ""}, {"line", 0}, {"column", 0}}}}
+}
+
+func scillaOfIdent(i *Ident) interface{} {
+	if i == nil {
+		return nil
+	}
+	return scillaIdent(i.Symbol.DisplayName, i.Pos())
+}
+
+func scillaOfVar(v *VarRef) interface{} {
+	if v == nil {
+		return nil
+	}
+	return scillaIdent(v.Symbol.DisplayName, v.Pos())
+}
+
+func scillaOfVars(vs []*VarRef) []interface{} {
+	arr := make([]interface{}, 0, len(vs))
+	for _, v := range vs {
+		arr = append(arr, scillaOfVar(v))
+	}
+	return arr
+}
+
+func scillaOfKeys(keys []*MapKey) []interface{} {
+	arr := make([]interface{}, 0, len(keys))
+	for _, k := range keys {
+		arr = append(arr, scillaOfVar(k.Key))
+	}
+	return arr
+}
+
+func scillaOfTypes(ts []Type) []interface{} {
+	arr := make([]interface{}, 0, len(ts))
+	for _, t := range ts {
+		arr = append(arr, scillaOfType(t))
+	}
+	return arr
+}
+
+func scillaOfType(t Type) interface{} {
+	switch t := t.(type) {
+	case nil:
+		return nil
+	case *PrimType:
+		return scillaVariant("PrimType", scillaPrimType(t.Token.Value()))
+	case *MapType:
+		return scillaVariant("MapType", scillaOfType(t.Key), scillaOfType(t.Value))
+	case *FunType:
+		return scillaVariant("FunType", scillaOfType(t.Param), scillaOfType(t.Ret))
+	case *PolyType:
+		return scillaVariant("PolyFun", t.TVar.Symbol.DisplayName, scillaOfType(t.Body))
+	case *TypeVar:
+		return scillaVariant("TypeVar", t.Token.Value())
+	case *ADTType:
+		if p := scillaPrimType(t.Ident.Symbol.DisplayName); p != nil && len(t.Args) == 0 {
+			return scillaVariant("PrimType", p)
+		}
+		return scillaVariant("ADT", scillaOfIdent(t.Ident), scillaOfTypes(t.Args))
+	case *AddressType:
+		if t.KindToken == nil {
+			return scillaVariant("Address", scillaVariant("AnyAddr"))
+		}
+		if t.KindToken.Kind != token.CONTRACT {
+			return scillaVariant("Address", scillaVariant("LibAddr"))
+		}
+		fields := make([]interface{}, 0, len(t.Fields))
+		for _, f := range t.Fields {
+			fields = append(fields, []interface{}{scillaOfIdent(f.Ident), scillaOfType(f.Type)})
+		}
+		return scillaVariant("Address", scillaVariant("ContrAddr", fields))
+	}
+	panic(fmt.Sprintf("FATAL: Unknown type node %T", t))
+}
+
+// unquote returns the content of a string literal
+func unquote(lit string) string {
+	if s, err := strconv.Unquote(lit); err == nil {
+		return s
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(lit, `"`), `"`)
+}
+
+func scillaOfLiteral(e Expr) interface{} {
+	switch e := e.(type) {
+	case *StringLit:
+		return scillaVariant("StringLit", unquote(e.Token.Value()))
+	case *IntLit:
+		ty := e.TypeToken.Value()
+		lit := scillaVariant(ty+"L", e.ValueToken.Value())
+		if strings.HasPrefix(ty, "Uint") {
+			return scillaVariant("UintLit", lit)
+		}
+		return scillaVariant("IntLit", lit)
+	case *BNumLit:
+		return scillaVariant("BNum", e.ValueToken.Value())
+	case *HexLit:
+		return scillaVariant("ByStrX", e.Token.Value())
+	case *EmpLit:
+		return scillaVariant("Map", []interface{}{scillaOfType(e.Key), scillaOfType(e.Value)}, []interface{}{})
+	}
+	return nil
+}
+
+func scillaOfPattern(p Pattern) interface{} {
+	switch p := p.(type) {
+	case *WildcardPattern:
+		return scillaVariant("Wildcard")
+	case *BinderPattern:
+		return scillaVariant("Binder", scillaOfIdent(p.Ident))
+	case *ConstrPattern:
+		args := make([]interface{}, 0, len(p.Args))
+		for _, a := range p.Args {
+			args = append(args, scillaOfPattern(a))
+		}
+		return scillaVariant("Constructor", scillaOfIdent(p.Ctor), args)
+	}
+	panic(fmt.Sprintf("FATAL: Unknown pattern node %T", p))
+}
+
+func scillaOfExpr(e Expr) interface{} {
+	if lit := scillaOfLiteral(e); lit != nil {
+		return scillaVariant("Literal", lit)
+	}
+	switch e := e.(type) {
+	case nil:
+		return nil
+	case *VarRef:
+		return scillaVariant("Var", scillaOfVar(e))
+	case *Let:
+		return scillaVariant("Let", scillaOfIdent(e.Ident), scillaOfType(e.Type), scillaOfExpr(e.Bound), scillaOfExpr(e.Body))
+	case *Message:
+		entries := make([]interface{}, 0, len(e.Entries))
+		for _, m := range e.Entries {
+			var payload interface{}
+			if v, ok := m.Value.(*VarRef); ok {
+				payload = scillaVariant("MVar", scillaOfVar(v))
+			} else {
+				payload = scillaVariant("MLit", scillaOfLiteral(m.Value))
+			}
+			entries = append(entries, []interface{}{m.Key.Value(), payload})
+		}
+		return scillaVariant("Message", entries)
+	case *Fun:
+		return scillaVariant("Fun", scillaOfIdent(e.Param.Ident), scillaOfType(e.Param.Type), scillaOfExpr(e.Body))
+	case *TFun:
+		return scillaVariant("TFun", scillaOfIdent(e.TVar), scillaOfExpr(e.Body))
+	case *App:
+		return scillaVariant("App", scillaOfVar(e.Func), scillaOfVars(e.Args))
+	case *TApp:
+		return scillaVariant("TApp", scillaOfVar(e.Func), scillaOfTypes(e.Types))
+	case *Builtin:
+		return scillaVariant("Builtin", scillaBuiltin(e.Ident.Symbol.DisplayName), scillaOfVars(e.Args))
+	case *Constr:
+		return scillaVariant("Constr", scillaOfIdent(e.Ident), scillaOfTypes(e.TypeArgs), scillaOfVars(e.Args))
+	case *Match:
+		arms := make([]interface{}, 0, len(e.Arms))
+		for _, a := range e.Arms {
+			arms = append(arms, []interface{}{scillaOfPattern(a.Pattern), scillaOfExpr(a.Body)})
+		}
+		return scillaVariant("MatchExpr", scillaOfVar(e.Target), arms)
+	}
+	panic(fmt.Sprintf("FATAL: Unknown expression node %T", e))
+}
+
+func scillaOfStmts(stmts []Stmt) []interface{} {
+	arr := make([]interface{}, 0, len(stmts))
+	for _, s := range stmts {
+		arr = append(arr, scillaOfStmt(s))
+	}
+	return arr
+}
+
+func scillaOfStmt(s Stmt) interface{} {
+	switch s := s.(type) {
+	case *Load:
+		return scillaVariant("Load", scillaOfIdent(s.Ident), scillaOfVar(s.Field))
+	case *RemoteLoad:
+		return scillaVariant("RemoteLoad", scillaOfIdent(s.Ident), scillaOfVar(s.Addr), scillaOfIdent(s.Field))
+	case *Store:
+		return scillaVariant("Store", scillaOfVar(s.Field), scillaOfVar(s.Value))
+	case *Bind:
+		return scillaVariant("Bind", scillaOfIdent(s.Ident), scillaOfExpr(s.Value))
+	case *MapUpdate:
+		return scillaVariant("MapUpdate", scillaOfVar(s.Map), scillaOfKeys(s.Keys), scillaOfVar(s.Value))
+	case *MapDelete:
+		return scillaVariant("MapUpdate", scillaOfVar(s.Map), scillaOfKeys(s.Keys), nil)
+	case *MapGet:
+		return scillaVariant("MapGet", scillaOfIdent(s.Ident), scillaOfVar(s.Map), scillaOfKeys(s.Keys), s.ExistsToken == nil)
+	case *RemoteMapGet:
+		return scillaVariant("RemoteMapGet", scillaOfIdent(s.Ident), scillaOfVar(s.Addr), scillaOfIdent(s.Map), scillaOfKeys(s.Keys), s.ExistsToken == nil)
+	case *ReadFromBC:
+		query := scillaVariant(s.Query.Value())
+		switch s.Query.Value() {
+		case "BLOCKNUMBER":
+			query = scillaVariant("CurBlockNum")
+		case "CHAINID":
+			query = scillaVariant("ChainID")
+		}
+		return scillaVariant("ReadFromBC", scillaOfIdent(s.Ident), query)
+	case *Accept:
+		return scillaVariant("AcceptPayment")
+	case *Send:
+		return scillaVariant("SendMsgs", scillaOfVar(s.Msgs))
+	case *Event:
+		return scillaVariant("CreateEvnt", scillaOfVar(s.Event))
+	case *Throw:
+		return scillaVariant("Throw", scillaOfVar(s.Exception))
+	case *MatchStmt:
+		arms := make([]interface{}, 0, len(s.Arms))
+		for _, a := range s.Arms {
+			arms = append(arms, []interface{}{scillaOfPattern(a.Pattern), scillaOfStmts(a.Body)})
+		}
+		return scillaVariant("MatchStmt", scillaOfVar(s.Target), arms)
+	case *CallProc:
+		return scillaVariant("CallProc", scillaOfVar(s.Proc), scillaOfVars(s.Args))
+	case *Iterate:
+		return scillaVariant("Iterate", scillaOfVar(s.List), scillaOfVar(s.Proc))
+	}
+	panic(fmt.Sprintf("FATAL: Unknown statement node %T", s))
+}
+
+func scillaOfParams(params []*Param) []interface{} {
+	arr := make([]interface{}, 0, len(params))
+	for _, p := range params {
+		arr = append(arr, []interface{}{scillaOfIdent(p.Ident), scillaOfType(p.Type)})
+	}
+	return arr
+}
+
+func scillaOfComponent(c *Component) jsonObject {
+	ty := "CompTrans"
+	if c.IsProcedure() {
+		ty = "CompProc"
+	}
+	return jsonObject{
+		{"comp_type", scillaVariant(ty)},
+		{"comp_name", scillaOfIdent(c.Ident)},
+		{"comp_params", scillaOfParams(c.Params)},
+		{"comp_body", scillaOfStmts(c.Body)},
+	}
+}
+
+func scillaOfLibrary(l *Library) interface{} {
+	if l == nil {
+		return nil
+	}
+	entries := make([]interface{}, 0, len(l.Entries))
+	for _, e := range l.Entries {
+		switch e := e.(type) {
+		case *LetDecl:
+			entries = append(entries, scillaVariant("LibVar", scillaOfIdent(e.Ident), scillaOfType(e.Type), scillaOfExpr(e.Bound)))
+		case *TypeDecl:
+			ctors := make([]interface{}, 0, len(e.Ctors))
+			for _, c := range e.Ctors {
+				ctors = append(ctors, jsonObject{{"cname", scillaOfIdent(c.Ident)}, {"c_arg_types", scillaOfTypes(c.Types)}})
+			}
+			entries = append(entries, scillaVariant("LibTyp", scillaOfIdent(e.Ident), ctors))
+		}
+	}
+	return jsonObject{{"lname", scillaOfIdent(l.Ident)}, {"lentries", entries}}
+}
+
+func scillaOfContract(c *Contract) interface{} {
+	if c == nil {
+		return nil
+	}
+	fields := make([]interface{}, 0, len(c.Fields))
+	for _, f := range c.Fields {
+		fields = append(fields, []interface{}{scillaOfIdent(f.Ident), scillaOfType(f.Type), scillaOfExpr(f.Init)})
+	}
+	comps := make([]interface{}, 0, len(c.Components))
+	for _, comp := range c.Components {
+		comps = append(comps, scillaOfComponent(comp))
+	}
+	var constraint interface{}
+	if c.Constraint != nil {
+		constraint = scillaOfExpr(c.Constraint)
+	} else {
+		// scilla-checker represents a missing constraint as `True`
+		constraint = scillaVariant("Constr", scillaDummyIdent("True"), []interface{}{}, []interface{}{})
+	}
+	return jsonObject{
+		{"cname", scillaOfIdent(c.Ident)},
+		{"cparams", scillaOfParams(c.Params)},
+		{"cconstraint", constraint},
+		{"cfields", fields},
+		{"ccomps", comps},
+	}
+}
+
+// FprintScillaJSON outputs the AST as JSON in the shape of the cmodule record of scilla-checker
+// to given io.Writer object. Snippet components without a contract header and nodes with syntax
+// errors cannot be represented in the shape.
+func FprintScillaJSON(out io.Writer, a *AST) error {
+	if len(a.Components) > 0 {
+		c := a.Components[0]
+		return locerr.ErrorIn(c.Pos(), c.End(), "Components without contract cannot be output as scilla-checker JSON AST")
+	}
+	if len(a.BadDecls) > 0 {
+		d := a.BadDecls[0]
+		return locerr.ErrorIn(d.Pos(), d.End(), "Module with syntax errors cannot be output as scilla-checker JSON AST")
+	}
+	smver := 0
+	if a.Version != nil {
+		smver = a.Version.Value
+	}
+	elibs := []interface{}{}
+	for _, i := range a.Imports {
+		for _, n := range i.Names {
+			elibs = append(elibs, []interface{}{scillaOfIdent(n.Lib), scillaOfIdent(n.Alias)})
+		}
+	}
+	module := jsonObject{
+		{"smver", smver},
+		{"libs", scillaOfLibrary(a.Library)},
+		{"elibs", elibs},
+		{"contr", scillaOfContract(a.Contract)},
+	}
+	b, err := json.MarshalIndent(module, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(append(b, '\n'))
+	return err
+}
@@ -0,0 +1,746 @@
+package ast
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/rhysd/locerr"
+	"goscilla/token"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// This file reads JSON AST in the shape of scilla-checker's AST (see scilla.go) back into the AST.
+//
+// Since JSON AST does not contain tokens, every node is built with orphan tokens. Tokens of
+// identifiers are located at "loc" of the identifiers and tokens of keywords and punctuations are
+// located at the nearest identifier. Offsets are not available in JSON AST so they are always zero.
+
+// scillaImportError is a panic payload which aborts importing JSON AST
+type scillaImportError struct {
+	err *locerr.Error
+}
+
+type scillaImporter struct {
+	sources map[string]*locerr.Source
+	// Position of the identifier seen last. Tokens which have no location in JSON are put here.
+	last locerr.Pos
+}
+
+func (im *scillaImporter) fail(format string, args ...interface{}) {
+	panic(scillaImportError{locerr.NewError(fmt.Sprintf("Invalid scilla-checker JSON AST: "+format, args...))})
+}
+
+func (im *scillaImporter) array(v interface{}, what string) []interface{} {
+	arr, ok := v.([]interface{})
+	if !ok {
+		im.fail("%s must be an array but got %s", what, describeJSON(v))
+	}
+	return arr
+}
+
+func (im *scillaImporter) object(v interface{}, what string) map[string]interface{} {
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		im.fail("%s must be an object but got %s", what, describeJSON(v))
+	}
+	return obj
+}
+
+func (im *scillaImporter) str(v interface{}, what string) string {
+	s, ok := v.(string)
+	if !ok {
+		im.fail("%s must be a string but got %s", what, describeJSON(v))
+	}
+	return s
+}
+
+func (im *scillaImporter) int(v interface{}, what string) int {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := strconv.Atoi(v.String()); err == nil {
+			return i
+		}
+	case string:
+		if i, err := strconv.Atoi(v); err == nil {
+			return i
+		}
+	}
+	im.fail("%s must be an integer but got %s", what, describeJSON(v))
+	return 0
+}
+
+// variant splits a variant into its constructor name and arguments.
+func (im *scillaImporter) variant(v interface{}, what string) (string, []interface{}) {
+	arr := im.array(v, what)
+	if len(arr) == 0 {
+		im.fail("%s must not be an empty array", what)
+	}
+	return im.str(arr[0], what+" constructor"), arr[1:]
+}
+
+func (im *scillaImporter) args(name string, args []interface{}, n int) {
+	if len(args) != n {
+		im.fail("%s must have %d arguments but got %d", name, n, len(args))
+	}
+}
+
+func describeJSON(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil || len(b) > 40 {
+		return fmt.Sprintf("%T", v)
+	}
+	return string(b)
+}
+
+func (im *scillaImporter) source(path string) *locerr.Source {
+	if s, ok := im.sources[path]; ok {
+		return s
+	}
+	s := &locerr.Source{Path: path}
+	im.sources[path] = s
+	return s
+}
+
+func (im *scillaImporter) loc(v interface{}) locerr.Pos {
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return im.last
+	}
+	pos := locerr.Pos{}
+	if f, ok := obj["file"].(string); ok {
+		pos.File = im.source(f)
+	}
+	if l, ok := obj["line"]; ok {
+		pos.Line = im.int(l, "line of location")
+	}
+	if c, ok := obj["column"]; ok {
+		pos.Column = im.int(c, "column of location")
+	}
+	return pos
+}
+
+// token makes an orphan token for keywords and punctuations located at the last identifier
+func (im *scillaImporter) token(kind token.Kind, lit string) *token.Token {
+	t := token.NewOrphanToken(kind, lit)
+	t.Start, t.End, t.File = im.last, im.last, im.last.File
+	return t
+}
+
+func identKind(name string) token.Kind {
+	switch {
+	case strings.HasPrefix(name, "'"):
+		return token.TID
+	case strings.HasPrefix(name, "_"):
+		return token.SPID
+	case name != "" && unicode.IsUpper(rune(name[0])):
+		return token.CID
+	default:
+		return token.ID
+	}
+}
+
+// nameToken makes an orphan token for an identifier. An identifier is {"vname", "loc"}.
+func (im *scillaImporter) nameToken(v interface{}, what string) *token.Token {
+	obj := im.object(v, what)
+	im.last = im.loc(obj["loc"])
+	return im.plainNameToken(obj["vname"], what)
+}
+
+// plainNameToken makes an orphan token for a name which is a plain string without location such as
+// a message key. It is located at the last identifier.
+func (im *scillaImporter) plainNameToken(v interface{}, what string) *token.Token {
+	name := im.str(v, what+" name")
+	if name == "" {
+		im.fail("%s must not be empty", what)
+	}
+	t := token.NewOrphanToken(identKind(name), name)
+	end := im.last
+	end.Column += len(name)
+	t.Start, t.End, t.File = im.last, end, im.last.File
+	return t
+}
+
+func (im *scillaImporter) ident(v interface{}) *Ident {
+	t := im.nameToken(v, "identifier")
+	return &Ident{t, NewSymbol(t.Value())}
+}
+
+func (im *scillaImporter) optIdent(v interface{}) *Ident {
+	if v == nil {
+		return nil
+	}
+	return im.ident(v)
+}
+
+func (im *scillaImporter) varRef(v interface{}) *VarRef {
+	t := im.nameToken(v, "variable")
+	return &VarRef{t, NewSymbol(t.Value())}
+}
+
+func (im *scillaImporter) varRefs(v interface{}) []*VarRef {
+	arr := im.array(v, "variables")
+	refs := make([]*VarRef, 0, len(arr))
+	for _, e := range arr {
+		refs = append(refs, im.varRef(e))
+	}
+	return refs
+}
+
+func (im *scillaImporter) mapKeys(v interface{}) []*MapKey {
+	arr := im.array(v, "map keys")
+	keys := make([]*MapKey, 0, len(arr))
+	for _, e := range arr {
+		key := im.varRef(e)
+		keys = append(keys, &MapKey{im.token(token.LSQB, "["), key, im.token(token.RSQB, "]")})
+	}
+	return keys
+}
+
+func primTypeKind(name string) token.Kind {
+	switch {
+	case strings.HasPrefix(name, "Int") || strings.HasPrefix(name, "Uint"):
+		return token.INT_TYPE
+	case strings.HasPrefix(name, "ByStr"):
+		return token.BYSTR_TYPE
+	case name == "String":
+		return token.STRING_TYPE
+	case name == "BNum":
+		return token.BNUM_TYPE
+	case name == "Message":
+		return token.MESSAGE_TYPE
+	case name == "Event":
+		return token.EVENT_TYPE
+	}
+	return token.CID
+}
+
+// primTypeName returns the name of primitive type from a variant of PrimType in OCaml such as
+// ["Uint_typ", ["Bits32"]].
+func (im *scillaImporter) primTypeName(v interface{}) string {
+	name, args := im.variant(v, "primitive type")
+	bits := func() string {
+		im.args(name, args, 1)
+		b, _ := im.variant(args[0], "bit width")
+		return strings.TrimPrefix(b, "Bits")
+	}
+	switch name {
+	case "Int_typ":
+		return "Int" + bits()
+	case "Uint_typ":
+		return "Uint" + bits()
+	case "String_typ":
+		return "String"
+	case "Bnum_typ":
+		return "BNum"
+	case "Msg_typ":
+		return "Message"
+	case "Event_typ":
+		return "Event"
+	case "Exception_typ":
+		return "Exception"
+	case "Bystr_typ":
+		return "ByStr"
+	case "Bystrx_typ":
+		im.args(name, args, 1)
+		return "ByStr" + strconv.Itoa(im.int(args[0], "width of ByStrX"))
+	}
+	im.fail("unknown primitive type %q", name)
+	return ""
+}
+
+func (im *scillaImporter) types(v interface{}) []Type {
+	arr := im.array(v, "types")
+	ts := make([]Type, 0, len(arr))
+	for _, e := range arr {
+		ts = append(ts, im.typ(e))
+	}
+	return ts
+}
+
+func (im *scillaImporter) optType(v interface{}) Type {
+	if v == nil {
+		return nil
+	}
+	return im.typ(v)
+}
+
+func (im *scillaImporter) typ(v interface{}) Type {
+	name, args := im.variant(v, "type")
+	switch name {
+	case "PrimType":
+		im.args(name, args, 1)
+		prim := im.primTypeName(args[0])
+		return &PrimType{Token: im.token(primTypeKind(prim), prim)}
+	case "MapType":
+		im.args(name, args, 2)
+		return &MapType{MapToken: im.token(token.MAP, "Map"), Key: im.typ(args[0]), Value: im.typ(args[1])}
+	case "FunType":
+		im.args(name, args, 2)
+		return &FunType{Param: im.typ(args[0]), Ret: im.typ(args[1])}
+	case "ADT":
+		im.args(name, args, 2)
+		return &ADTType{Ident: im.ident(args[0]), Args: im.types(args[1])}
+	case "TypeVar":
+		im.args(name, args, 1)
+		return &TypeVar{Token: im.nameToken(args[0], "type variable")}
+	case "PolyFun":
+		im.args(name, args, 2)
+		forall := im.token(token.FORALL, "forall")
+		tvar := im.ident(args[0])
+		return &PolyType{ForallToken: forall, TVar: tvar, Body: im.typ(args[1])}
+	case "Address":
+		im.args(name, args, 1)
+		return im.addressType(args[0])
+	}
+	im.fail("unknown type %q", name)
+	return nil
+}
+
+// addressType imports kind of address type. Since the width is always 20, ByStr20 is used.
+func (im *scillaImporter) addressType(v interface{}) Type {
+	t := &AddressType{
+		ByStrToken: im.token(token.BYSTR_TYPE, "ByStr20"),
+		WithToken:  im.token(token.WITH, "with"),
+	}
+	if v != nil {
+		kind, args := im.variant(v, "address kind")
+		switch kind {
+		case "AnyAddr":
+		case "LibAddr":
+			t.KindToken = im.token(token.LIBRARY, "library")
+		case "ContrAddr":
+			im.args(kind, args, 1)
+			t.KindToken = im.token(token.CONTRACT, "contract")
+			for _, f := range im.array(args[0], "address fields") {
+				pair := im.array(f, "address field")
+				im.args("address field", pair, 2)
+				field := im.token(token.FIELD, "field")
+				t.Fields = append(t.Fields, &AddressField{field, im.ident(pair[0]), im.typ(pair[1])})
+			}
+		default:
+			im.fail("unknown address kind %q", kind)
+		}
+	}
+	t.EndToken = im.token(token.END, "end")
+	return t
+}
+
+func (im *scillaImporter) literal(v interface{}) Expr {
+	name, args := im.variant(v, "literal")
+	switch name {
+	case "StringLit":
+		im.args(name, args, 1)
+		return &StringLit{im.token(token.STRING_LIT, strconv.Quote(im.str(args[0], "string literal")))}
+	case "IntLit", "UintLit":
+		// ["IntLit", ["Int32L", "42"]]
+		im.args(name, args, 1)
+		w, a := im.variant(args[0], "integer literal")
+		im.args(w, a, 1)
+		ty, val := strings.TrimSuffix(w, "L"), im.str(a[0], "integer literal")
+		return &IntLit{im.token(token.INT_TYPE, ty), im.token(token.NUM_LIT, val)}
+	case "BNum":
+		im.args(name, args, 1)
+		return &BNumLit{im.token(token.BNUM_TYPE, "BNum"), im.token(token.NUM_LIT, im.str(args[0], "block number"))}
+	case "ByStrX", "ByStr":
+		im.args(name, args, 1)
+		return &HexLit{im.token(token.HEX_LIT, im.str(args[0], "hex literal"))}
+	case "Map":
+		im.args(name, args, 2)
+		kv := im.array(args[0], "types of map literal")
+		im.args("types of map literal", kv, 2)
+		if len(im.array(args[1], "entries of map literal")) > 0 {
+			im.fail("non-empty map literal cannot be represented in source")
+		}
+		emp := im.token(token.EMP, "Emp")
+		return &EmpLit{emp, im.typ(kv[0]), im.typ(kv[1])}
+	}
+	im.fail("unknown literal %q", name)
+	return nil
+}
+
+func (im *scillaImporter) pattern(v interface{}) Pattern {
+	name, args := im.variant(v, "pattern")
+	switch name {
+	case "Wildcard":
+		return &WildcardPattern{Token: im.token(token.UNDERSCORE, "_")}
+	case "Binder":
+		im.args(name, args, 1)
+		return &BinderPattern{Ident: im.ident(args[0])}
+	case "Constructor":
+		im.args(name, args, 2)
+		ctor := im.ident(args[0])
+		arr := im.array(args[1], "constructor patterns")
+		ps := make([]Pattern, 0, len(arr))
+		for _, a := range arr {
+			ps = append(ps, im.pattern(a))
+		}
+		return &ConstrPattern{Ctor: ctor, Args: ps}
+	}
+	im.fail("unknown pattern %q", name)
+	return nil
+}
+
+// builtinIdent imports the name of builtin function from a variant such as ["Builtin_add"] or
+// ["Builtin_to_bystrx", 20].
+func (im *scillaImporter) builtinIdent(v interface{}) *Ident {
+	b, args := im.variant(v, "builtin")
+	if !strings.HasPrefix(b, "Builtin_") {
+		im.fail("unknown builtin %q", b)
+	}
+	name := strings.TrimPrefix(b, "Builtin_")
+	switch name {
+	case "to_bystrx", "bystr_to_bystrx":
+		im.args(b, args, 1)
+		name = strings.TrimSuffix(name, "x") + strconv.Itoa(im.int(args[0], "width of "+b))
+	default:
+		im.args(b, args, 0)
+	}
+	return &Ident{im.token(token.ID, name), NewSymbol(name)}
+}
+
+func (im *scillaImporter) expr(v interface{}) Expr {
+	name, args := im.variant(v, "expression")
+	switch name {
+	case "Literal":
+		im.args(name, args, 1)
+		return im.literal(args[0])
+	case "Var":
+		im.args(name, args, 1)
+		return im.varRef(args[0])
+	case "Let":
+		im.args(name, args, 4)
+		let := im.token(token.LET, "let")
+		id := im.ident(args[0])
+		return &Let{let, id, im.optType(args[1]), im.expr(args[2]), im.expr(args[3])}
+	case "Message":
+		im.args(name, args, 1)
+		msg := &Message{LBraceToken: im.token(token.LBRACE, "{")}
+		for _, e := range im.array(args[0], "message entries") {
+			pair := im.array(e, "message entry")
+			im.args("message entry", pair, 2)
+			key := im.plainNameToken(pair[0], "message key")
+			kind, payload := im.variant(pair[1], "message payload")
+			im.args(kind, payload, 1)
+			var val Expr
+			switch kind {
+			case "MVar":
+				val = im.varRef(payload[0])
+			case "MLit":
+				val = im.literal(payload[0])
+			default:
+				im.fail("unknown message payload %q", kind)
+			}
+			msg.Entries = append(msg.Entries, &MessageEntry{key, val})
+		}
+		msg.RBraceToken = im.token(token.RBRACE, "}")
+		return msg
+	case "Fun":
+		im.args(name, args, 3)
+		fun := im.token(token.FUN, "fun")
+		param := &Param{Ident: im.ident(args[0]), Type: im.typ(args[1])}
+		return &Fun{fun, param, im.expr(args[2])}
+	case "TFun":
+		im.args(name, args, 2)
+		tfun := im.token(token.TFUN, "tfun")
+		return &TFun{tfun, im.ident(args[0]), im.expr(args[1])}
+	case "App":
+		im.args(name, args, 2)
+		return &App{im.varRef(args[0]), im.varRefs(args[1])}
+	case "TApp":
+		im.args(name, args, 2)
+		at := im.token(token.AT, "@")
+		return &TApp{at, im.varRef(args[0]), im.types(args[1])}
+	case "Builtin":
+		im.args(name, args, 2)
+		b := &Builtin{BuiltinToken: im.token(token.BUILTIN, "builtin"), Ident: im.builtinIdent(args[0])}
+		b.Args = im.varRefs(args[1])
+		if len(b.Args) == 0 {
+			b.RParenToken = im.token(token.RPAREN, ")")
+		}
+		return b
+	case "Constr":
+		im.args(name, args, 3)
+		c := &Constr{Ident: im.ident(args[0]), TypeArgs: im.types(args[1])}
+		if len(c.TypeArgs) > 0 {
+			c.LBraceToken, c.RBraceToken = im.token(token.LBRACE, "{"), im.token(token.RBRACE, "}")
+		}
+		c.Args = im.varRefs(args[2])
+		return c
+	case "MatchExpr":
+		im.args(name, args, 2)
+		m := &Match{MatchToken: im.token(token.MATCH, "match"), Target: im.varRef(args[0])}
+		for _, a := range im.array(args[1], "match arms") {
+			pair := im.array(a, "match arm")
+			im.args("match arm", pair, 2)
+			bar := im.token(token.BAR, "|")
+			m.Arms = append(m.Arms, &MatchArm{bar, im.pattern(pair[0]), im.expr(pair[1])})
+		}
+		m.EndToken = im.token(token.END, "end")
+		return m
+	}
+	im.fail("unknown expression %q", name)
+	return nil
+}
+
+// fetch returns true when the value is fetched from map, false when `exists` is checked
+func (im *scillaImporter) fetch(v interface{}) bool {
+	b, ok := v.(bool)
+	if !ok {
+		im.fail("flag of fetching map value must be a boolean but got %s", describeJSON(v))
+	}
+	return b
+}
+
+func (im *scillaImporter) stmts(v interface{}) []Stmt {
+	arr := im.array(v, "statements")
+	stmts := make([]Stmt, 0, len(arr))
+	for _, s := range arr {
+		stmts = append(stmts, im.stmt(s))
+	}
+	return stmts
+}
+
+func (im *scillaImporter) stmt(v interface{}) Stmt {
+	name, args := im.variant(v, "statement")
+	switch name {
+	case "Load":
+		im.args(name, args, 2)
+		return &Load{im.ident(args[0]), im.varRef(args[1])}
+	case "RemoteLoad":
+		im.args(name, args, 3)
+		id := im.ident(args[0])
+		and := im.token(token.AND, "&")
+		return &RemoteLoad{id, and, im.varRef(args[1]), im.ident(args[2])}
+	case "Store":
+		im.args(name, args, 2)
+		return &Store{im.varRef(args[0]), im.varRef(args[1])}
+	case "Bind":
+		im.args(name, args, 2)
+		return &Bind{im.ident(args[0]), im.expr(args[1])}
+	case "MapUpdate":
+		im.args(name, args, 3)
+		if args[2] == nil {
+			del := im.token(token.DELETE, "delete")
+			return &MapDelete{del, im.varRef(args[0]), im.mapKeys(args[1])}
+		}
+		return &MapUpdate{im.varRef(args[0]), im.mapKeys(args[1]), im.varRef(args[2])}
+	case "MapGet":
+		im.args(name, args, 4)
+		s := &MapGet{Ident: im.ident(args[0])}
+		if !im.fetch(args[3]) {
+			s.ExistsToken = im.token(token.EXISTS, "exists")
+		}
+		s.Map, s.Keys = im.varRef(args[1]), im.mapKeys(args[2])
+		return s
+	case "RemoteMapGet":
+		im.args(name, args, 5)
+		s := &RemoteMapGet{Ident: im.ident(args[0]), AndToken: im.token(token.AND, "&")}
+		if !im.fetch(args[4]) {
+			s.ExistsToken = im.token(token.EXISTS, "exists")
+		}
+		s.Addr, s.Map, s.Keys = im.varRef(args[1]), im.ident(args[2]), im.mapKeys(args[3])
+		return s
+	case "ReadFromBC":
+		im.args(name, args, 2)
+		id := im.ident(args[0])
+		and := im.token(token.AND, "&")
+		var query string
+		switch q, _ := im.variant(args[1], "blockchain query"); q {
+		case "CurBlockNum":
+			query = "BLOCKNUMBER"
+		case "ChainID":
+			query = "CHAINID"
+		default:
+			im.fail("unknown blockchain query %q", q)
+		}
+		return &ReadFromBC{id, and, im.token(token.CID, query)}
+	case "AcceptPayment":
+		return &Accept{im.token(token.ACCEPT, "accept")}
+	case "SendMsgs":
+		im.args(name, args, 1)
+		return &Send{im.token(token.SEND, "send"), im.varRef(args[0])}
+	case "CreateEvnt":
+		im.args(name, args, 1)
+		return &Event{im.token(token.EVENT, "event"), im.varRef(args[0])}
+	case "Throw":
+		im.args(name, args, 1)
+		s := &Throw{Token: im.token(token.THROW, "throw")}
+		if args[0] != nil {
+			s.Exception = im.varRef(args[0])
+		}
+		return s
+	case "MatchStmt":
+		im.args(name, args, 2)
+		m := &MatchStmt{MatchToken: im.token(token.MATCH, "match"), Target: im.varRef(args[0])}
+		for _, a := range im.array(args[1], "match arms") {
+			pair := im.array(a, "match arm")
+			im.args("match arm", pair, 2)
+			bar := im.token(token.BAR, "|")
+			m.Arms = append(m.Arms, &StmtArm{bar, im.pattern(pair[0]), im.stmts(pair[1])})
+		}
+		m.EndToken = im.token(token.END, "end")
+		return m
+	case "CallProc":
+		im.args(name, args, 2)
+		return &CallProc{im.varRef(args[0]), im.varRefs(args[1])}
+	case "Iterate":
+		im.args(name, args, 2)
+		forall := im.token(token.FORALL, "forall")
+		return &Iterate{forall, im.varRef(args[0]), im.varRef(args[1])}
+	}
+	im.fail("unknown statement %q", name)
+	return nil
+}
+
+func (im *scillaImporter) params(v interface{}) []*Param {
+	arr := im.array(v, "parameters")
+	ps := make([]*Param, 0, len(arr))
+	for _, p := range arr {
+		pair := im.array(p, "parameter")
+		im.args("parameter", pair, 2)
+		ps = append(ps, &Param{Ident: im.ident(pair[0]), Type: im.typ(pair[1])})
+	}
+	return ps
+}
+
+func (im *scillaImporter) component(v interface{}) *Component {
+	obj := im.object(v, "component")
+	kind, _ := im.variant(obj["comp_type"], "component type")
+	c := &Component{}
+	switch kind {
+	case "CompTrans":
+		c.Token = im.token(token.TRANSITION, "transition")
+	case "CompProc":
+		c.Token = im.token(token.PROCEDURE, "procedure")
+	default:
+		im.fail("unknown component type %q", kind)
+	}
+	c.Ident = im.ident(obj["comp_name"])
+	c.Token.Start, c.Token.End, c.Token.File = im.last, im.last, im.last.File
+	c.Params = im.params(obj["comp_params"])
+	c.RParenToken = im.token(token.RPAREN, ")")
+	c.Body = im.stmts(obj["comp_body"])
+	c.EndToken = im.token(token.END, "end")
+	return c
+}
+
+func (im *scillaImporter) library(v interface{}) *Library {
+	obj := im.object(v, "library")
+	lib := &Library{Ident: im.ident(obj["lname"])}
+	lib.LibraryToken = im.token(token.LIBRARY, "library")
+	for _, e := range im.array(obj["lentries"], "library entries") {
+		name, args := im.variant(e, "library entry")
+		switch name {
+		case "LibVar":
+			im.args(name, args, 3)
+			d := &LetDecl{Ident: im.ident(args[0])}
+			d.LetToken = im.token(token.LET, "let")
+			d.Type, d.Bound = im.optType(args[1]), im.expr(args[2])
+			lib.Entries = append(lib.Entries, d)
+		case "LibTyp":
+			im.args(name, args, 2)
+			d := &TypeDecl{Ident: im.ident(args[0])}
+			d.TypeToken = im.token(token.TYPE, "type")
+			for _, c := range im.array(args[1], "constructors") {
+				ctor := im.object(c, "constructor")
+				cd := &CtorDecl{Ident: im.ident(ctor["cname"])}
+				cd.BarToken = im.token(token.BAR, "|")
+				cd.Types = im.types(ctor["c_arg_types"])
+				d.Ctors = append(d.Ctors, cd)
+			}
+			lib.Entries = append(lib.Entries, d)
+		default:
+			im.fail("unknown library entry %q", name)
+		}
+	}
+	return lib
+}
+
+// isTrueConstraint returns true when the constraint is `True`, which scilla-checker puts for a
+// contract without constraint.
+func isTrueConstraint(e Expr) bool {
+	c, ok := e.(*Constr)
+	return ok && c.Ident.Symbol.DisplayName == "True" && len(c.TypeArgs) == 0 && len(c.Args) == 0
+}
+
+func (im *scillaImporter) contract(v interface{}) *Contract {
+	obj := im.object(v, "contract")
+	c := &Contract{Ident: im.ident(obj["cname"])}
+	c.ContractToken = im.token(token.CONTRACT, "contract")
+	c.Params = im.params(obj["cparams"])
+	c.RParenToken = im.token(token.RPAREN, ")")
+	if cons, ok := obj["cconstraint"]; ok && cons != nil {
+		if e := im.expr(cons); !isTrueConstraint(e) {
+			c.Constraint = e
+		}
+	}
+	for _, f := range im.array(obj["cfields"], "fields") {
+		triple := im.array(f, "field")
+		im.args("field", triple, 3)
+		field := &Field{Ident: im.ident(triple[0])}
+		field.FieldToken = im.token(token.FIELD, "field")
+		field.Type, field.Init = im.typ(triple[1]), im.expr(triple[2])
+		c.Fields = append(c.Fields, field)
+	}
+	for _, comp := range im.array(obj["ccomps"], "components") {
+		c.Components = append(c.Components, im.component(comp))
+	}
+	return c
+}
+
+func (im *scillaImporter) module(v interface{}) *AST {
+	obj := im.object(v, "module")
+	a := &AST{}
+	if smver, ok := obj["smver"]; ok {
+		n := im.int(smver, "scilla version")
+		a.Version = &Version{im.token(token.SCILLA_VERSION, "scilla_version"), im.token(token.NUM_LIT, strconv.Itoa(n)), n}
+	}
+	if elibs, ok := obj["elibs"]; ok {
+		var names []*ImportName
+		for _, e := range im.array(elibs, "imports") {
+			pair := im.array(e, "import")
+			im.args("import", pair, 2)
+			names = append(names, &ImportName{im.ident(pair[0]), im.optIdent(pair[1])})
+		}
+		if len(names) > 0 {
+			tok := im.token(token.IMPORT, "import")
+			tok.Start, tok.End, tok.File = names[0].Pos(), names[0].Pos(), names[0].Pos().File
+			a.Imports = []*Import{{tok, names}}
+		}
+	}
+	if libs := obj["libs"]; libs != nil {
+		a.Library = im.library(libs)
+	}
+	if contr := obj["contr"]; contr != nil {
+		a.Contract = im.contract(contr)
+		a.Source = a.Contract.Ident.Token.File
+	}
+	return a
+}
+
+// ReadScillaJSON reads JSON AST in the shape of scilla-checker's AST (as output by
+// FprintScillaJSON) from given io.Reader object and builds AST from it. The shape follows Syntax.ml
+// of Zilliqa/scilla but is not verified against actual scilla-checker output. Nodes in the returned AST
+// consist of orphan tokens and their offsets are not available.
+func ReadScillaJSON(in io.Reader) (a *AST, err error) {
+	dec := json.NewDecoder(in)
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, locerr.NewError(fmt.Sprintf("Invalid scilla-checker JSON AST: %s", err.Error()))
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			e, ok := r.(scillaImportError)
+			if !ok {
+				panic(r)
+			}
+			a, err = nil, e.err
+		}
+	}()
+	im := &scillaImporter{sources: map[string]*locerr.Source{}}
+	return im.module(v), nil
+}
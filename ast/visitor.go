@@ -7,156 +7,247 @@ type Visitor interface {
 	// Returned value is a next visitor to use for succeeding visit. When wanting to stop
 	// visiting, please return nil.
 	// A visitor visits in depth-first order.
-	VisitTopdown(e Expr) Visitor
+	VisitTopdown(n Node) Visitor
 	// VisitBottomup defines the process when a node is visited. This method is called after
 	// children were visited. When VisitTopdown returned nil, this method won't be caled for the node.
-	VisitBottomup(e Expr)
+	VisitBottomup(n Node)
 }
 
 // Visit visits the tree with the visitor.
-func Visit(vis Visitor, e Expr) {
-	v := vis.VisitTopdown(e)
+func Visit(vis Visitor, n Node) {
+	v := vis.VisitTopdown(n)
 	if v == nil {
 		return
 	}
 
-	switch n := e.(type) {
-	case *Not:
-		Visit(v, n.Child)
-	case *Neg:
-		Visit(v, n.Child)
-	case *Add:
-		Visit(v, n.Left)
-		Visit(v, n.Right)
-	case *Sub:
-		Visit(v, n.Left)
-		Visit(v, n.Right)
-	case *Mul:
-		Visit(v, n.Left)
-		Visit(v, n.Right)
-	case *Div:
-		Visit(v, n.Left)
-		Visit(v, n.Right)
-	case *Mod:
-		Visit(v, n.Left)
-		Visit(v, n.Right)
-	case *FNeg:
-		Visit(v, n.Child)
-	case *FAdd:
-		Visit(v, n.Left)
-		Visit(v, n.Right)
-	case *FSub:
-		Visit(v, n.Left)
-		Visit(v, n.Right)
-	case *FMul:
-		Visit(v, n.Left)
-		Visit(v, n.Right)
-	case *FDiv:
-		Visit(v, n.Left)
-		Visit(v, n.Right)
-	case *Eq:
-		Visit(v, n.Left)
-		Visit(v, n.Right)
-	case *NotEq:
-		Visit(v, n.Left)
-		Visit(v, n.Right)
-	case *Less:
-		Visit(v, n.Left)
-		Visit(v, n.Right)
-	case *LessEq:
-		Visit(v, n.Left)
-		Visit(v, n.Right)
-	case *Greater:
-		Visit(v, n.Left)
-		Visit(v, n.Right)
-	case *GreaterEq:
-		Visit(v, n.Left)
-		Visit(v, n.Right)
-	case *And:
-		Visit(v, n.Left)
-		Visit(v, n.Right)
-	case *Or:
-		Visit(v, n.Left)
-		Visit(v, n.Right)
-	case *If:
-		Visit(v, n.Cond)
-		Visit(v, n.Then)
-		Visit(v, n.Else)
-	case *Let:
+	switch n := n.(type) {
+	case *AST:
+		if n.Version != nil {
+			Visit(v, n.Version)
+		}
+		for _, i := range n.Imports {
+			Visit(v, i)
+		}
+		if n.Library != nil {
+			Visit(v, n.Library)
+		}
+		if n.Contract != nil {
+			Visit(v, n.Contract)
+		}
+		for _, c := range n.Components {
+			Visit(v, c)
+		}
+		for _, d := range n.BadDecls {
+			Visit(v, d)
+		}
+	case *Import:
+		for _, i := range n.Names {
+			Visit(v, i)
+		}
+	case *ImportName:
+		Visit(v, n.Lib)
+		if n.Alias != nil {
+			Visit(v, n.Alias)
+		}
+	case *Library:
+		Visit(v, n.Ident)
+		for _, e := range n.Entries {
+			Visit(v, e)
+		}
+	case *LetDecl:
+		Visit(v, n.Ident)
 		if n.Type != nil {
 			Visit(v, n.Type)
 		}
 		Visit(v, n.Bound)
-		Visit(v, n.Body)
-	case *LetRec:
-		for _, p := range n.Func.Params {
-			if p.Type != nil {
-				Visit(v, p.Type)
-			}
+	case *TypeDecl:
+		Visit(v, n.Ident)
+		for _, c := range n.Ctors {
+			Visit(v, c)
 		}
-		if n.Func.RetType != nil {
-			Visit(v, n.Func.RetType)
+	case *CtorDecl:
+		Visit(v, n.Ident)
+		for _, t := range n.Types {
+			Visit(v, t)
 		}
-		Visit(v, n.Func.Body)
-		Visit(v, n.Body)
-	case *Apply:
-		Visit(v, n.Callee)
-		for _, e := range n.Args {
-			Visit(v, e)
+	case *Contract:
+		Visit(v, n.Ident)
+		for _, p := range n.Params {
+			Visit(v, p)
 		}
-	case *Tuple:
-		for _, e := range n.Elems {
-			Visit(v, e)
+		if n.Constraint != nil {
+			Visit(v, n.Constraint)
+		}
+		for _, f := range n.Fields {
+			Visit(v, f)
 		}
-	case *LetTuple:
+		for _, c := range n.Components {
+			Visit(v, c)
+		}
+	case *Param:
+		Visit(v, n.Ident)
+		Visit(v, n.Type)
+	case *Field:
+		Visit(v, n.Ident)
+		Visit(v, n.Type)
+		Visit(v, n.Init)
+	case *Component:
+		Visit(v, n.Ident)
+		for _, p := range n.Params {
+			Visit(v, p)
+		}
+		for _, s := range n.Body {
+			Visit(v, s)
+		}
+	case *EmpLit:
+		Visit(v, n.Key)
+		Visit(v, n.Value)
+	case *Let:
+		Visit(v, n.Ident)
 		if n.Type != nil {
 			Visit(v, n.Type)
 		}
 		Visit(v, n.Bound)
 		Visit(v, n.Body)
-	case *ArrayMake:
-		Visit(v, n.Size)
-		Visit(v, n.Elem)
-	case *ArraySize:
-		Visit(v, n.Target)
-	case *ArrayGet:
-		Visit(v, n.Array)
-		Visit(v, n.Index)
-	case *ArrayPut:
-		Visit(v, n.Array)
-		Visit(v, n.Index)
-		Visit(v, n.Assignee)
+	case *Fun:
+		Visit(v, n.Param)
+		Visit(v, n.Body)
+	case *TFun:
+		Visit(v, n.TVar)
+		Visit(v, n.Body)
+	case *App:
+		Visit(v, n.Func)
+		for _, a := range n.Args {
+			Visit(v, a)
+		}
+	case *TApp:
+		Visit(v, n.Func)
+		for _, t := range n.Types {
+			Visit(v, t)
+		}
+	case *Builtin:
+		Visit(v, n.Ident)
+		for _, a := range n.Args {
+			Visit(v, a)
+		}
+	case *Constr:
+		Visit(v, n.Ident)
+		for _, t := range n.TypeArgs {
+			Visit(v, t)
+		}
+		for _, a := range n.Args {
+			Visit(v, a)
+		}
+	case *Message:
+		for _, e := range n.Entries {
+			Visit(v, e)
+		}
+	case *MessageEntry:
+		Visit(v, n.Value)
 	case *Match:
 		Visit(v, n.Target)
-		Visit(v, n.IfSome)
-		Visit(v, n.IfNone)
-	case *Some:
-		Visit(v, n.Child)
-	case *ArrayLit:
-		for _, e := range n.Elems {
-			Visit(v, e)
+		for _, a := range n.Arms {
+			Visit(v, a)
 		}
-	case *FuncType:
-		for _, e := range n.ParamTypes {
-			Visit(v, e)
+	case *MatchArm:
+		Visit(v, n.Pattern)
+		Visit(v, n.Body)
+	case *Load:
+		Visit(v, n.Ident)
+		Visit(v, n.Field)
+	case *RemoteLoad:
+		Visit(v, n.Ident)
+		Visit(v, n.Addr)
+		Visit(v, n.Field)
+	case *Store:
+		Visit(v, n.Field)
+		Visit(v, n.Value)
+	case *Bind:
+		Visit(v, n.Ident)
+		Visit(v, n.Value)
+	case *MapKey:
+		Visit(v, n.Key)
+	case *MapUpdate:
+		Visit(v, n.Map)
+		for _, k := range n.Keys {
+			Visit(v, k)
 		}
-		Visit(v, n.RetType)
-	case *TupleType:
-		for _, e := range n.ElemTypes {
-			Visit(v, e)
+		Visit(v, n.Value)
+	case *MapDelete:
+		Visit(v, n.Map)
+		for _, k := range n.Keys {
+			Visit(v, k)
 		}
-	case *CtorType:
-		for _, e := range n.ParamTypes {
-			Visit(v, e)
+	case *MapGet:
+		Visit(v, n.Ident)
+		Visit(v, n.Map)
+		for _, k := range n.Keys {
+			Visit(v, k)
 		}
-	case *Typed:
-		Visit(v, n.Child)
-		Visit(v, n.Type)
-	case *TypeDecl:
-		Visit(v, n.Type)
-	case *External:
+	case *RemoteMapGet:
+		Visit(v, n.Ident)
+		Visit(v, n.Addr)
+		Visit(v, n.Map)
+		for _, k := range n.Keys {
+			Visit(v, k)
+		}
+	case *ReadFromBC:
+		Visit(v, n.Ident)
+	case *Send:
+		Visit(v, n.Msgs)
+	case *Event:
+		Visit(v, n.Event)
+	case *Throw:
+		if n.Exception != nil {
+			Visit(v, n.Exception)
+		}
+	case *MatchStmt:
+		Visit(v, n.Target)
+		for _, a := range n.Arms {
+			Visit(v, a)
+		}
+	case *StmtArm:
+		Visit(v, n.Pattern)
+		for _, s := range n.Body {
+			Visit(v, s)
+		}
+	case *CallProc:
+		Visit(v, n.Proc)
+		for _, a := range n.Args {
+			Visit(v, a)
+		}
+	case *Iterate:
+		Visit(v, n.List)
+		Visit(v, n.Proc)
+	case *BinderPattern:
+		Visit(v, n.Ident)
+	case *ConstrPattern:
+		Visit(v, n.Ctor)
+		for _, p := range n.Args {
+			Visit(v, p)
+		}
+	case *MapType:
+		Visit(v, n.Key)
+		Visit(v, n.Value)
+	case *FunType:
+		Visit(v, n.Param)
+		Visit(v, n.Ret)
+	case *PolyType:
+		Visit(v, n.TVar)
+		Visit(v, n.Body)
+	case *ADTType:
+		Visit(v, n.Ident)
+		for _, t := range n.Args {
+			Visit(v, t)
+		}
+	case *AddressType:
+		for _, f := range n.Fields {
+			Visit(v, f)
+		}
+	case *AddressField:
+		Visit(v, n.Ident)
 		Visit(v, n.Type)
 	}
 
-	vis.VisitBottomup(e)
+	vis.VisitBottomup(n)
 }
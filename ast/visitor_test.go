@@ -7,19 +7,17 @@ import (
 
 var testTree = &Let{
 	LetToken: &token.Token{},
-	Symbol:   NewSymbol("test"),
-	Bound: &Int{
-		Token: &token.Token{},
-		Value: 42,
+	Ident:    &Ident{&token.Token{}, NewSymbol("test")},
+	Bound: &IntLit{
+		TypeToken:  token.NewOrphanToken(token.INT_TYPE, "Uint32"),
+		ValueToken: token.NewOrphanToken(token.NUM_LIT, "42"),
 	},
-	Body: &Add{
-		Left: &VarRef{
-			Token:  &token.Token{},
-			Symbol: NewSymbol("test"),
-		},
-		Right: &Float{
-			Token: &token.Token{},
-			Value: 3.14,
+	Body: &Builtin{
+		BuiltinToken: &token.Token{},
+		Ident:        &Ident{&token.Token{}, NewSymbol("add")},
+		Args: []*VarRef{
+			{&token.Token{}, NewSymbol("test")},
+			{&token.Token{}, NewSymbol("test")},
 		},
 	},
 }
@@ -29,12 +27,12 @@ type testNumAllNodes struct {
 	buTotal int
 }
 
-func (v *testNumAllNodes) VisitTopdown(e Expr) Visitor {
+func (v *testNumAllNodes) VisitTopdown(n Node) Visitor {
 	v.tdTotal++
 	return v
 }
 
-func (v *testNumAllNodes) VisitBottomup(e Expr) {
+func (v *testNumAllNodes) VisitBottomup(n Node) {
 	v.buTotal++
 }
 
@@ -43,7 +41,7 @@ type testNumRootChildren struct {
 	rootVisited bool
 }
 
-func (v *testNumRootChildren) VisitTopdown(e Expr) Visitor {
+func (v *testNumRootChildren) VisitTopdown(n Node) Visitor {
 	v.numChildren++
 	if v.rootVisited {
 		return nil
@@ -52,21 +50,24 @@ func (v *testNumRootChildren) VisitTopdown(e Expr) Visitor {
 	return v
 }
 
-func (v *testNumRootChildren) VisitBottomup(Expr) {
+func (v *testNumRootChildren) VisitBottomup(Node) {
 }
 
 func TestVisitorVisit(t *testing.T) {
 	v := &testNumAllNodes{0, 0}
 	Visit(v, testTree)
-	if v.tdTotal != 5 {
-		t.Fatalf("5 is expected as total nodes but actually %d", v.tdTotal)
+	if v.tdTotal != 7 {
+		t.Fatalf("7 is expected as total nodes but actually %d", v.tdTotal)
+	}
+	if v.buTotal != 7 {
+		t.Fatalf("7 is expected as total nodes visited bottom-up but actually %d", v.buTotal)
 	}
 }
 
 func TestVisitorCancelVisit(t *testing.T) {
 	v := &testNumRootChildren{0, false}
 	Visit(v, testTree)
-	if v.numChildren != 3 {
-		t.Fatalf("3 is expected as number of root children but actually %d", v.numChildren)
+	if v.numChildren != 4 {
+		t.Fatalf("4 is expected as number of root children but actually %d", v.numChildren)
 	}
 }
@@ -0,0 +1,185 @@
+// Package builtins provides the catalogue of Scilla builtin operations called by `builtin` keyword.
+// Each builtin describes its arity, polymorphic signatures and evaluation function so that both the
+// type checker and evaluators share the same definition.
+package builtins
+
+import (
+	"fmt"
+	"goscilla/internal/suggest"
+	"goscilla/types"
+	"goscilla/value"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Class is a set of types which a type variable in signature can be instantiated with, such as
+// integer types.
+type Class struct {
+	Name     string
+	Contains func(types.Type) bool
+}
+
+// Sig is a signature of builtin. Type variables in Params are instantiated by matching with types of
+// arguments. Each type variable may be constrained by a class.
+type Sig struct {
+	Params []types.Type
+	Result types.Type
+	Where  map[string]*Class
+	// ResultOf computes the result type from the instantiated type variables when it cannot be
+	// written as a type, like concatenation of ByStrN. Result is only used for documentation then.
+	ResultOf func(targs map[string]types.Type) types.Type
+}
+
+// EvalFunc evaluates a builtin with argument values whose types are already checked.
+type EvalFunc func(args []value.Value) (value.Value, error)
+
+// Builtin is a builtin operation such as `builtin add x y`.
+type Builtin struct {
+	Name  string
+	Arity int
+	Sigs  []*Sig
+	Eval  EvalFunc
+}
+
+var registry = map[string]*Builtin{}
+
+// widths are families of builtins whose names end with the width of byte string like to_bystr20.
+// Since the width can be any positive number, they are created when looked up.
+var widths = map[string]func(n int) *Builtin{}
+
+func newBuiltin(name string, eval EvalFunc, sigs ...*Sig) *Builtin {
+	return &Builtin{name, len(sigs[0].Params), sigs, eval}
+}
+
+func register(name string, eval EvalFunc, sigs ...*Sig) {
+	registry[name] = newBuiltin(name, eval, sigs...)
+}
+
+// Lookup returns the builtin of the name. When it does not exist, the error suggests similar names.
+func Lookup(name string) (*Builtin, error) {
+	if b, ok := registry[name]; ok {
+		return b, nil
+	}
+	for prefix, f := range widths {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		w := name[len(prefix):]
+		if n, err := strconv.Atoi(w); err == nil && n > 0 && strconv.Itoa(n) == w {
+			return f(n), nil
+		}
+	}
+	msg := fmt.Sprintf("Unknown builtin %s", name)
+	if s := suggest.Similar(name, Names()); s != "" {
+		msg += fmt.Sprintf(". Did you mean %s?", s)
+	}
+	return nil, fmt.Errorf("%s", msg)
+}
+
+// Names returns names of all builtins in alphabetical order. Builtins of widths are not included.
+func Names() []string {
+	ns := make([]string, 0, len(registry))
+	for n := range registry {
+		ns = append(ns, n)
+	}
+	sort.Strings(ns)
+	return ns
+}
+
+// Type resolves the overload of the builtin with types of arguments and returns the type of the
+// result.
+func (b *Builtin) Type(args []types.Type) (types.Type, error) {
+	if len(args) != b.Arity {
+		return nil, fmt.Errorf("Builtin %s takes %d arguments but %d given", b.Name, b.Arity, len(args))
+	}
+	// Addresses are passed to builtins as ByStr20
+	bs := make([]types.Type, 0, len(args))
+	for _, a := range args {
+		if _, ok := a.(*types.AddressType); ok {
+			a = types.ByStr20
+		}
+		bs = append(bs, a)
+	}
+	for _, s := range b.Sigs {
+		if t := s.instantiate(bs); t != nil {
+			return t, nil
+		}
+	}
+	ts := make([]string, 0, len(args))
+	for _, a := range args {
+		ts = append(ts, a.String())
+	}
+	ss := make([]string, 0, len(b.Sigs))
+	for _, s := range b.Sigs {
+		ss = append(ss, s.String())
+	}
+	return nil, fmt.Errorf("Builtin %s cannot be applied to arguments of types %s. Expected %s", b.Name, strings.Join(ts, ", "), strings.Join(ss, " or "))
+}
+
+// instantiate returns the result type when the arguments match the signature. Otherwise it
+// returns nil.
+func (s *Sig) instantiate(args []types.Type) types.Type {
+	targs := map[string]types.Type{}
+	for i, p := range s.Params {
+		if !s.match(p, args[i], targs) {
+			return nil
+		}
+	}
+	if s.ResultOf != nil {
+		return s.ResultOf(targs)
+	}
+	return types.Subst(s.Result, targs)
+}
+
+func (s *Sig) match(p, a types.Type, targs map[string]types.Type) bool {
+	switch p := p.(type) {
+	case *types.TypeVar:
+		if t, ok := targs[p.Name]; ok {
+			return types.Equal(t, a)
+		}
+		// Type variables of enclosing `tfun` are accepted as any class like Scilla does so that
+		// libraries can define polymorphic functions such as int_le over integer types
+		if _, ok := a.(*types.TypeVar); !ok {
+			if c, ok := s.Where[p.Name]; ok && !c.Contains(a) {
+				return false
+			}
+		}
+		targs[p.Name] = a
+		return true
+	case *types.MapType:
+		a, ok := a.(*types.MapType)
+		return ok && s.match(p.Key, a.Key, targs) && s.match(p.Value, a.Value, targs)
+	case *types.ADT:
+		a, ok := a.(*types.ADT)
+		if !ok || a.Name != p.Name || len(a.Args) != len(p.Args) {
+			return false
+		}
+		for i := range p.Args {
+			if !s.match(p.Args[i], a.Args[i], targs) {
+				return false
+			}
+		}
+		return true
+	}
+	return types.Equal(p, a)
+}
+
+// String returns the signature like "'A -> 'A -> Bool where 'A is integer type".
+func (s *Sig) String() string {
+	ts := append(append([]types.Type{}, s.Params...), s.Result)
+	sig := types.Fun(ts...).String()
+	if len(s.Where) == 0 {
+		return sig
+	}
+	vs := make([]string, 0, len(s.Where))
+	for v := range s.Where {
+		vs = append(vs, v)
+	}
+	sort.Strings(vs)
+	cs := make([]string, 0, len(vs))
+	for _, v := range vs {
+		cs = append(cs, fmt.Sprintf("%s is %s", v, s.Where[v].Name))
+	}
+	return fmt.Sprintf("%s where %s", sig, strings.Join(cs, ", "))
+}
@@ -0,0 +1,481 @@
+package builtins
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"goscilla/types"
+	"goscilla/value"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func uint32v(i int64) value.Value {
+	return value.NewInt(types.Uint32, big.NewInt(i))
+}
+
+func TestType(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		args []types.Type
+		want string
+	}{
+		{"add", []types.Type{types.Uint32, types.Uint32}, "Uint32"},
+		{"lt", []types.Type{types.Int256, types.Int256}, "Bool"},
+		{"eq", []types.Type{types.String, types.String}, "Bool"},
+		{"eq", []types.Type{types.ByStr20, types.ByStr20}, "Bool"},
+		{"eq", []types.Type{&types.AddressType{Kind: types.AnyAddr}, types.ByStr20}, "Bool"},
+		{"to_bystr", []types.Type{types.ContractAddress()}, "ByStr"},
+		{"pow", []types.Type{types.Int64, types.Uint32}, "Int64"},
+		{"to_uint64", []types.Type{types.String}, "Option (Uint64)"},
+		{"to_uint256", []types.Type{types.ByStr32}, "Uint256"},
+		{"to_nat", []types.Type{types.Uint32}, "Nat"},
+		{"concat", []types.Type{types.ByStr20, types.ByStr32}, "ByStr52"},
+		{"concat", []types.Type{types.String, types.String}, "String"},
+		{"to_bystr", []types.Type{types.ByStr20}, "ByStr"},
+		{"to_bystr20", []types.Type{types.ByStr}, "Option (ByStr20)"},
+		{"to_bystr3", []types.Type{types.ByStr}, "Option (ByStr3)"},
+		{"to_bystr16", []types.Type{types.Uint128}, "ByStr16"},
+		{"bystr_to_bystr7", []types.Type{types.ByStr}, "Option (ByStr7)"},
+		{"sha256hash", []types.Type{types.Uint128}, "ByStr32"},
+		{"badd", []types.Type{types.BNum, types.Uint64}, "BNum"},
+		{"put", []types.Type{&types.MapType{Key: types.ByStr20, Value: types.Uint128}, types.ByStr20, types.Uint128}, "Map (ByStr20) (Uint128)"},
+		{"get", []types.Type{&types.MapType{Key: types.String, Value: types.Bool}, types.String}, "Option (Bool)"},
+		{"to_list", []types.Type{&types.MapType{Key: types.String, Value: types.Bool}}, "List (Pair (String) (Bool))"},
+	} {
+		b, err := Lookup(tc.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ty, err := b.Type(tc.args)
+		if err != nil {
+			t.Errorf("%s: %s", tc.name, err)
+			continue
+		}
+		if ty.String() != tc.want {
+			t.Errorf("Wanted %s for %s but got %s", tc.want, tc.name, ty)
+		}
+	}
+}
+
+func TestTypeError(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		args []types.Type
+		want string
+	}{
+		{"add", []types.Type{types.Uint32}, "Builtin add takes 2 arguments but 1 given"},
+		{"add", []types.Type{types.Uint32, types.Int32}, "Builtin add cannot be applied to arguments of types Uint32, Int32. Expected 'A -> 'A -> 'A where 'A is integer type"},
+		{"to_uint32", []types.Type{types.ByStr32}, "Builtin to_uint32 cannot be applied to arguments of types ByStr32"},
+		{"badd", []types.Type{types.BNum, types.Int32}, "Builtin badd cannot be applied"},
+		{"eq", []types.Type{types.Bool, types.Bool}, "Builtin eq cannot be applied"},
+	} {
+		b, err := Lookup(tc.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = b.Type(tc.args)
+		if err == nil {
+			t.Errorf("Error did not occur for %s with %v", tc.name, tc.args)
+			continue
+		}
+		if !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("Error %q should contain %q", err, tc.want)
+		}
+	}
+}
+
+func TestLookupError(t *testing.T) {
+	_, err := Lookup("concatt")
+	if err == nil || err.Error() != "Unknown builtin concatt. Did you mean concat?" {
+		t.Error("Unexpected error:", err)
+	}
+	_, err = Lookup("foo")
+	if err == nil || err.Error() != "Unknown builtin foo" {
+		t.Error("Unexpected error:", err)
+	}
+	for _, name := range []string{"to_bystr0", "to_bystr020", "to_bystr-1", "bystr_to_bystr"} {
+		if _, err := Lookup(name); err == nil {
+			t.Error("Builtin of invalid width was found:", name)
+		}
+	}
+}
+
+func eval(t *testing.T, name string, args ...value.Value) (value.Value, error) {
+	b, err := Lookup(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := make([]types.Type, 0, len(args))
+	for _, a := range args {
+		ts = append(ts, a.Type())
+	}
+	ty, err := b.Type(ts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := b.Eval(args)
+	if err == nil && !types.Equal(v.Type(), ty) {
+		t.Fatalf("Type of result %s of %s is %s but signature says %s", v, name, v.Type(), ty)
+	}
+	return v, err
+}
+
+func TestEval(t *testing.T) {
+	m := value.NewMap(&types.MapType{Key: types.String, Value: types.Uint32})
+	m.Set(value.String("a"), uint32v(1))
+
+	for _, tc := range []struct {
+		name string
+		args []value.Value
+		want string
+	}{
+		{"add", []value.Value{uint32v(1), uint32v(2)}, "Uint32 3"},
+		{"sub", []value.Value{value.NewInt(types.Int32, big.NewInt(1)), value.NewInt(types.Int32, big.NewInt(3))}, "Int32 -2"},
+		{"div", []value.Value{value.NewInt(types.Int32, big.NewInt(-7)), value.NewInt(types.Int32, big.NewInt(2))}, "Int32 -3"},
+		{"rem", []value.Value{value.NewInt(types.Int32, big.NewInt(-7)), value.NewInt(types.Int32, big.NewInt(2))}, "Int32 -1"},
+		{"pow", []value.Value{uint32v(3), uint32v(4)}, "Uint32 81"},
+		{"pow", []value.Value{value.NewInt(types.Int32, big.NewInt(-1)), uint32v(4294967295)}, "Int32 -1"},
+		{"isqrt", []value.Value{uint32v(17)}, "Uint32 4"},
+		{"lt", []value.Value{uint32v(1), uint32v(2)}, "True"},
+		{"eq", []value.Value{value.String("a"), value.String("b")}, "False"},
+		{"to_uint32", []value.Value{value.String("42")}, "Some {(Uint32)} (Uint32 42)"},
+		{"to_uint32", []value.Value{value.String("-1")}, "None {(Uint32)}"},
+		{"to_int32", []value.Value{uint32v(4294967295)}, "None {(Int32)}"},
+		{"to_nat", []value.Value{uint32v(2)}, "Succ (Succ Zero)"},
+		{"concat", []value.Value{value.String("foo"), value.String("bar")}, `"foobar"`},
+		{"concat", []value.Value{value.ByStrN{1}, value.ByStrN{2, 3}}, "0x010203"},
+		{"substr", []value.Value{value.String("hello"), uint32v(1), uint32v(3)}, `"ell"`},
+		{"strlen", []value.Value{value.String("hello")}, "Uint32 5"},
+		{"strrev", []value.Value{value.String("abc")}, `"cba"`},
+		{"to_string", []value.Value{uint32v(12)}, `"12"`},
+		{"to_bystr", []value.Value{value.ByStrN{0xab, 0xcd}}, "0xabcd"},
+		{"to_bystr2", []value.Value{value.ByStr{0xab, 0xcd}}, "Some {(ByStr2)} 0xabcd"},
+		{"to_bystr4", []value.Value{value.ByStr{0xab, 0xcd}}, "None {(ByStr4)}"},
+		{"to_bystr3", []value.Value{value.ByStr{1, 2, 3}}, "Some {(ByStr3)} 0x010203"},
+		{"bystr_to_bystr2", []value.Value{value.ByStr{0xab, 0xcd}}, "Some {(ByStr2)} 0xabcd"},
+		{"bystr_to_bystr1", []value.Value{value.ByStr{0xab, 0xcd}}, "None {(ByStr1)}"},
+		{"to_uint32", []value.Value{value.ByStrN{1, 2}}, "Uint32 258"},
+		{"badd", []value.Value{&value.BNum{Value: big.NewInt(10)}, uint32v(5)}, "BNum 15"},
+		{"bsub", []value.Value{&value.BNum{Value: big.NewInt(10)}, &value.BNum{Value: big.NewInt(15)}}, "Int256 -5"},
+		{"put", []value.Value{m, value.String("b"), uint32v(2)}, `["a" => Uint32 1; "b" => Uint32 2]`},
+		{"get", []value.Value{m, value.String("a")}, "Some {(Uint32)} (Uint32 1)"},
+		{"contains", []value.Value{m, value.String("b")}, "False"},
+		{"remove", []value.Value{m, value.String("a")}, "Emp (String) (Uint32)"},
+		{"size", []value.Value{m}, "Uint32 1"},
+	} {
+		v, err := eval(t, tc.name, tc.args...)
+		if err != nil {
+			t.Errorf("%s %v: %s", tc.name, tc.args, err)
+			continue
+		}
+		if v.String() != tc.want {
+			t.Errorf("Wanted %s for %s %v but got %s", tc.want, tc.name, tc.args, v)
+		}
+	}
+
+	if m.Len() != 1 {
+		t.Error("put or remove modified the map argument:", m)
+	}
+}
+
+func TestEvalError(t *testing.T) {
+	_, max := value.Bounds(types.Uint32)
+	for _, tc := range []struct {
+		name string
+		args []value.Value
+		want string
+	}{
+		{"add", []value.Value{value.NewInt(types.Uint32, max), uint32v(1)}, "overflow"},
+		{"sub", []value.Value{uint32v(0), uint32v(1)}, "underflow"},
+		{"mul", []value.Value{value.NewInt(types.Uint32, max), uint32v(2)}, "overflow"},
+		{"pow", []value.Value{uint32v(2), uint32v(32)}, "overflow"},
+		{"div", []value.Value{uint32v(1), uint32v(0)}, "Division by zero"},
+		{"rem", []value.Value{uint32v(1), uint32v(0)}, "Division by zero"},
+		{"substr", []value.Value{value.String("abc"), uint32v(2), uint32v(2)}, "out of bounds"},
+	} {
+		_, err := eval(t, tc.name, tc.args...)
+		if err == nil {
+			t.Errorf("Error did not occur for %s %v", tc.name, tc.args)
+			continue
+		}
+		if !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("Error %q for %s should contain %q", err, tc.name, tc.want)
+		}
+	}
+}
+
+func TestBech32(t *testing.T) {
+	addr := value.ByStrN{0x4b, 0xaf, 0x5f, 0xad, 0xa8, 0xe5, 0xdb, 0x92, 0xc3, 0xd3, 0x24, 0x26, 0x18, 0xc5, 0xb4, 0x71, 0x33, 0xae, 0x00, 0x3c}
+	const bech32 = "zil1fwh4ltdguhde9s7nysnp33d5wye6uqpugufkz7"
+
+	v, err := eval(t, "bystr20_to_bech32", value.String("zil"), addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.String() != `Some {(String)} "`+bech32+`"` {
+		t.Error("Unexpected bech32 address:", v)
+	}
+
+	v, err = eval(t, "bech32_to_bystr20", value.String("zil"), value.String(bech32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.String() != "Some {(ByStr20)} "+addr.String() {
+		t.Error("Unexpected address:", v)
+	}
+
+	v, err = eval(t, "bech32_to_bystr20", value.String("zil"), value.String(bech32[:len(bech32)-1]+"8"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.String() != "None {(ByStr20)}" {
+		t.Error("Invalid checksum was accepted:", v)
+	}
+}
+
+func TestHash(t *testing.T) {
+	some := value.Some(types.Uint32, uint32v(1))
+	for _, tc := range []struct {
+		name string
+		arg  value.Value
+		want string
+	}{
+		{"sha256hash", value.String("abc"), "0xba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+		{"sha256hash", value.ByStr("abc"), "0xba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+		{"sha256hash", value.NewInt(types.Int32, big.NewInt(-1)), "0xad95131bc0b799c0b1af477fb14fcf26a6a9f76079e48bf090acb7e8367bfd0e"},
+		{"sha256hash", &value.BNum{Value: big.NewInt(10)}, "0x4a44dc15364204a80fe80e9039455cc1608281820fe2b24f1e5233ade6af1dd5"},
+		{"sha256hash", some, "0x11ac8dec075af42c0134981bd1089d2245a18ac9d9ee3522220b6b2703e36d3a"},
+		{"keccak256hash", value.String(""), "0xc5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470"},
+		{"keccak256hash", value.String("abc"), "0x4e03657aea45a94fc7d47ba826c8d667c0d1e6e33a64a036ec44f58fa12d6c45"},
+		{"ripemd160hash", value.String(""), "0x9c1185a5c5e9fc54612808977ee8f548b2258d31"},
+		{"ripemd160hash", value.String("message digest"), "0x5d0689ef49d2fae572b881b123a85ffa21595f36"},
+		{"ripemd160hash", value.String(strings.Repeat("1234567890", 8)), "0x9b752e45573d4b39f4dbd3323cab82bf63326bfb"},
+	} {
+		v, err := eval(t, tc.name, tc.arg)
+		if err != nil {
+			t.Errorf("%s %s: %s", tc.name, tc.arg, err)
+			continue
+		}
+		if v.String() != tc.want {
+			t.Errorf("Wanted %s for %s %s but got %s", tc.want, tc.name, tc.arg, v)
+		}
+	}
+}
+
+// The bytes hashed for values other than strings, byte strings, integers and block numbers are
+// their JSON representation in the format of scilla-runner state files.
+func TestHashSerialization(t *testing.T) {
+	for _, tc := range []struct {
+		arg  value.Value
+		want string
+	}{
+		{value.Some(types.Uint32, uint32v(1)), `{"constructor":"Some","argtypes":["Uint32"],"arguments":["1"]}`},
+		{value.None(types.String), `{"constructor":"None","argtypes":["String"],"arguments":[]}`},
+		{value.True, `{"constructor":"True","argtypes":[],"arguments":[]}`},
+	} {
+		b, err := serialize(tc.arg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != tc.want {
+			t.Errorf("Wanted %s for %s but got %s", tc.want, tc.arg, b)
+		}
+	}
+}
+
+func TestCurve(t *testing.T) {
+	g2 := mulPoint(big.NewInt(2), secpG)
+	g3 := addPoints(g2, secpG)
+	for _, tc := range []struct {
+		p    *point
+		x, y string
+	}{
+		{g2, "c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee5", "1ae168fea63dc339a3c58419466ceaeef7f632653266d0e1236431a950cfe52a"},
+		{g3, "f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9", "388f7b0f632de8140fe337e62a37f3566500a99934c2231b6cb9fd7584b8e672"},
+	} {
+		if tc.p.x.Cmp(hexInt(tc.x)) != 0 || tc.p.y.Cmp(hexInt(tc.y)) != 0 {
+			t.Errorf("Wanted (%s, %s) but got (%x, %x)", tc.x, tc.y, tc.p.x, tc.p.y)
+		}
+		p, err := parsePoint(tc.p.compressed())
+		if err != nil || p.y.Cmp(tc.p.y) != 0 {
+			t.Errorf("Compressed point %x was not parsed: %v", tc.p.compressed(), err)
+		}
+	}
+	if mulPoint(secpN, secpG) != nil {
+		t.Error("nG is not the point at infinity")
+	}
+}
+
+// schnorrSign signs the message with the private key as Schnorr.cpp of Zilliqa does, using the
+// fixed nonce for testing.
+func schnorrSign(priv, nonce *big.Int, msg []byte) value.ByStrN {
+	pub := mulPoint(priv, secpG).compressed()
+	r := schnorrChallenge(mulPoint(nonce, secpG), pub, msg)
+	s := new(big.Int).Sub(nonce, new(big.Int).Mul(r, priv))
+	s.Mod(s, secpN)
+	return value.ByStrN(append(intToBytes(r, 32), intToBytes(s, 32)...))
+}
+
+func TestSignature(t *testing.T) {
+	priv := hexInt("e19d05c5452598e24caad4a0d85a49146f7be089515c905ae6a19e8a578a6930")
+	pub := value.ByStrN(mulPoint(priv, secpG).compressed())
+	msg := value.ByStr("hello, world")
+	sig := schnorrSign(priv, big.NewInt(123456789), msg)
+
+	// Signature of "Satoshi Nakamoto" by private key 1 in RFC6979 test vectors of secp256k1
+	satoshi := value.ByStr("Satoshi Nakamoto")
+	ecdsa := value.ByStrN(append(
+		hexInt("934b1ea10a4b3c1757e2b0c017d0b6143ce3c9a7e6a4a49860d7a6ab210ee3d8").FillBytes(make([]byte, 32)),
+		hexInt("2442ce9d2b916064108014783e923ec36b49743e2ffa1c4496f01a512aafd9e5").FillBytes(make([]byte, 32))...))
+	g := value.ByStrN(secpG.compressed())
+
+	tampered := append(value.ByStrN{}, sig...)
+	tampered[40] ^= 1
+	high := append(value.ByStrN{}, ecdsa[:32]...)
+	high = append(high, intToBytes(new(big.Int).Sub(secpN, new(big.Int).SetBytes(ecdsa[32:])), 32)...)
+
+	for _, tc := range []struct {
+		name string
+		args []value.Value
+		want string
+	}{
+		{"schnorr_verify", []value.Value{pub, msg, sig}, "True"},
+		{"schnorr_verify", []value.Value{pub, value.ByStr("hello, world!"), sig}, "False"},
+		{"schnorr_verify", []value.Value{pub, msg, tampered}, "False"},
+		{"schnorr_verify", []value.Value{g, msg, sig}, "False"},
+		{"schnorr_verify", []value.Value{pub, msg, value.ByStrN(make([]byte, 64))}, "False"},
+		{"ecdsa_verify", []value.Value{g, satoshi, ecdsa}, "True"},
+		{"ecdsa_verify", []value.Value{g, msg, ecdsa}, "False"},
+		{"ecdsa_verify", []value.Value{pub, satoshi, ecdsa}, "False"},
+		{"ecdsa_verify", []value.Value{g, satoshi, high}, "False"},
+		{"ecdsa_verify", []value.Value{value.ByStrN(make([]byte, 33)), satoshi, ecdsa}, "False"},
+		{"schnorr_get_address", []value.Value{g}, "0x29e562f73488c8a2bb9dbc5700b361d54b9b0554"},
+	} {
+		v, err := eval(t, tc.name, tc.args...)
+		if err != nil {
+			t.Errorf("%s %v: %s", tc.name, tc.args, err)
+			continue
+		}
+		if v.String() != tc.want {
+			t.Errorf("Wanted %s for %s %v but got %s", tc.want, tc.name, tc.args, v)
+		}
+	}
+
+	found := false
+	for id := int64(0); id < 4; id++ {
+		v, err := eval(t, "ecdsa_recover_pk", satoshi, ecdsa, uint32v(id))
+		if err != nil {
+			continue
+		}
+		if v.String() == value.ByStrN(secpG.uncompressed()).String() {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("Public key was not recovered from ECDSA signature")
+	}
+	if _, err := eval(t, "ecdsa_recover_pk", satoshi, ecdsa, uint32v(4)); err == nil {
+		t.Error("Invalid recovery ID was accepted")
+	}
+}
+
+// testdata/schnorr.json is the first entries of the known answer tests of Schnorr signature in
+// Zilliqa, taken from schnorr/data of github.com/Zilliqa/gozilliqa-sdk v1.2.0.
+func TestSchnorrKnownAnswers(t *testing.T) {
+	b, err := ioutil.ReadFile(filepath.Join("testdata", "schnorr.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var vectors []struct {
+		Msg, Pub, Priv, K, R, S string
+	}
+	if err := json.Unmarshal(b, &vectors); err != nil {
+		t.Fatal(err)
+	}
+	bytes := func(s string) []byte {
+		b, err := hex.DecodeString(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+
+	for i, v := range vectors {
+		pub := value.ByStrN(bytes(v.Pub))
+		msg := value.ByStr(bytes(v.Msg))
+		sig := value.ByStrN(append(intToBytes(hexInt(v.R), 32), intToBytes(hexInt(v.S), 32)...))
+
+		if got := schnorrSign(hexInt(v.Priv), hexInt(v.K), msg); got.String() != sig.String() {
+			t.Errorf("Vector %d: wanted signature %s but got %s", i, sig, got)
+		}
+		r, err := eval(t, "schnorr_verify", pub, msg, sig)
+		if err != nil {
+			t.Fatalf("Vector %d: %s", i, err)
+		}
+		if r.String() != "True" {
+			t.Errorf("Vector %d: signature was not verified", i)
+		}
+		tampered := append(value.ByStr{}, msg...)
+		tampered[0] ^= 1
+		r, err = eval(t, "schnorr_verify", pub, tampered, sig)
+		if err != nil {
+			t.Fatalf("Vector %d: %s", i, err)
+		}
+		if r.String() != "False" {
+			t.Errorf("Vector %d: signature of tampered message was verified", i)
+		}
+	}
+}
+
+func TestCost(t *testing.T) {
+	u128 := value.NewInt(types.Uint128, big.NewInt(3))
+	for _, tc := range []struct {
+		name string
+		args []value.Value
+		want uint64
+	}{
+		{"add", []value.Value{uint32v(1), uint32v(2)}, 4},
+		{"add", []value.Value{u128, u128}, 8},
+		{"mul", []value.Value{u128, u128}, 40},
+		{"pow", []value.Value{uint32v(2), uint32v(3)}, 80},
+		{"to_nat", []value.Value{uint32v(3)}, 4},
+		{"eq", []value.Value{value.String("foo"), value.String("bar")}, 3},
+		{"concat", []value.Value{value.String("foo"), value.String("ab")}, 5},
+		{"sha256hash", []value.Value{value.ByStrN(make([]byte, 20))}, 30},
+		{"ripemd160hash", []value.Value{value.String("abc")}, 10},
+		{"schnorr_verify", []value.Value{value.ByStrN(make([]byte, 33)), value.ByStr(make([]byte, 128)), value.ByStrN(make([]byte, 64))}, 280},
+		{"to_bystr20", []value.Value{value.ByStr(make([]byte, 20))}, 20},
+		{"to_uint64", []value.Value{uint32v(1)}, 4},
+	} {
+		b, err := Lookup(tc.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c := b.Cost(tc.args); c != tc.want {
+			t.Errorf("Wanted cost %d for %s %v but got %d", tc.want, tc.name, tc.args, c)
+		}
+	}
+}
+
+func TestLiteralCost(t *testing.T) {
+	msg := &value.Msg{Typ: types.Message, Entries: []*value.MsgEntry{{Key: "_tag", Value: value.String("Foo")}}}
+	for _, tc := range []struct {
+		v    value.Value
+		want uint64
+	}{
+		{uint32v(1), 4},
+		{value.String("hello"), 5},
+		{&value.BNum{Value: big.NewInt(1)}, 64},
+		{value.ByStrN(make([]byte, 20)), 20},
+		{value.Some(types.Uint32, uint32v(1)), 4},
+		{value.Bool(true), 0},
+		{msg, 7},
+	} {
+		if c := LiteralCost(tc.v); c != tc.want {
+			t.Errorf("Wanted cost %d for %s but got %d", tc.want, tc.v, c)
+		}
+	}
+}
@@ -0,0 +1,425 @@
+package builtins
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"goscilla/types"
+	"goscilla/value"
+	"math/big"
+	"strings"
+)
+
+// Type variables used in signatures
+var (
+	tA = types.Var("'A")
+	tK = types.Var("'K")
+	tV = types.Var("'V")
+	tX = types.Var("'X")
+	tY = types.Var("'Y")
+)
+
+// Classes of type variables
+var (
+	intClass = &Class{"integer type", func(t types.Type) bool {
+		_, ok := t.(*types.IntType)
+		return ok
+	}}
+	uintClass = &Class{"unsigned integer type", func(t types.Type) bool {
+		i, ok := t.(*types.IntType)
+		return ok && !i.Signed
+	}}
+	primClass = &Class{"primitive type", func(t types.Type) bool {
+		switch t.(type) {
+		case *types.IntType, *types.StringType, *types.BNumType, *types.ByStrType, *types.ByStrNType:
+			return true
+		}
+		return false
+	}}
+	byStrNClass = &Class{"ByStrN type", func(t types.Type) bool {
+		_, ok := t.(*types.ByStrNType)
+		return ok
+	}}
+	dataClass = &Class{"type of data", func(t types.Type) bool {
+		switch t.(type) {
+		case *types.FunType, *types.PolyType:
+			return false
+		}
+		return true
+	}}
+)
+
+func sig(result types.Type, params ...types.Type) *Sig {
+	return &Sig{Params: params, Result: result}
+}
+
+// where constrains the type variable of the signature by the class
+func (s *Sig) where(v string, c *Class) *Sig {
+	if s.Where == nil {
+		s.Where = map[string]*Class{}
+	}
+	s.Where[v] = c
+	return s
+}
+
+// arith returns the evaluation function of binary integer operator
+func arith(op func(x, y *value.Int) (*value.Int, error)) EvalFunc {
+	return func(args []value.Value) (value.Value, error) {
+		z, err := op(args[0].(*value.Int), args[1].(*value.Int))
+		if err != nil {
+			return nil, err
+		}
+		return z, nil
+	}
+}
+
+func bytesOf(v value.Value) []byte {
+	switch v := v.(type) {
+	case value.String:
+		return []byte(v)
+	case value.ByStr:
+		return v
+	case value.ByStrN:
+		return v
+	}
+	panic(fmt.Sprintf("Value %s is not a byte string", v))
+}
+
+func isPrintable(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < 0x20 || s[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// intToBytes returns big endian representation of the unsigned integer in n bytes
+func intToBytes(i *big.Int, n int) []byte {
+	b := make([]byte, n)
+	return i.FillBytes(b)
+}
+
+// serialize returns the bytes of the value to be hashed as serialize_literal of Scilla does. Byte
+// strings and strings are hashed as they are, integers as big endian two's complement of their
+// width, block numbers as decimal strings and other values as compact JSON.
+func serialize(v value.Value) ([]byte, error) {
+	switch v := v.(type) {
+	case value.String, value.ByStr, value.ByStrN:
+		return bytesOf(v), nil
+	case *value.Int:
+		i := v.Value
+		if i.Sign() < 0 {
+			i = new(big.Int).Add(i, new(big.Int).Lsh(big.NewInt(1), uint(v.Typ.Bits)))
+		}
+		return intToBytes(i, v.Typ.Bits/8), nil
+	case *value.BNum:
+		return []byte(v.Value.String()), nil
+	}
+	j, err := value.ToJSON(v)
+	if err != nil {
+		return nil, err
+	}
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(j); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(b.Bytes(), []byte("\n")), nil
+}
+
+func hash(f func([]byte) []byte) EvalFunc {
+	return func(args []value.Value) (value.Value, error) {
+		b, err := serialize(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return value.ByStrN(f(b)), nil
+	}
+}
+
+func registerInts() {
+	register("add", arith((*value.Int).Add), sig(tA, tA, tA).where("'A", intClass))
+	register("sub", arith((*value.Int).Sub), sig(tA, tA, tA).where("'A", intClass))
+	register("mul", arith((*value.Int).Mul), sig(tA, tA, tA).where("'A", intClass))
+	register("div", arith((*value.Int).Div), sig(tA, tA, tA).where("'A", intClass))
+	register("rem", arith((*value.Int).Rem), sig(tA, tA, tA).where("'A", intClass))
+	register("pow", arith((*value.Int).Pow), sig(tA, tA, types.Uint32).where("'A", intClass))
+	register("isqrt", func(args []value.Value) (value.Value, error) {
+		z, err := args[0].(*value.Int).Isqrt()
+		if err != nil {
+			return nil, err
+		}
+		return z, nil
+	}, sig(tA, tA).where("'A", uintClass))
+	register("lt", func(args []value.Value) (value.Value, error) {
+		return value.Bool(args[0].(*value.Int).Value.Cmp(args[1].(*value.Int).Value) < 0), nil
+	}, sig(types.Bool, tA, tA).where("'A", intClass))
+
+	for _, signed := range []bool{true, false} {
+		for _, bits := range types.IntBits {
+			to := &types.IntType{Signed: signed, Bits: bits}
+			name := "to_" + strings.ToLower(to.String())
+			sigs := []*Sig{
+				sig(types.Option(to), tA).where("'A", intClass),
+				sig(types.Option(to), types.String),
+			}
+			if !signed {
+				max := bits / 8
+				sigs = append(sigs, sig(to, tX).where("'X", &Class{fmt.Sprintf("ByStrN type where N <= %d", max), func(t types.Type) bool {
+					b, ok := t.(*types.ByStrNType)
+					return ok && b.Size <= max
+				}}))
+			}
+			register(name, func(args []value.Value) (value.Value, error) {
+				var x *value.Int
+				switch a := args[0].(type) {
+				case *value.Int:
+					x = a
+				case value.String:
+					v, err := value.ParseInt(to, string(a))
+					if err != nil {
+						return value.None(to), nil
+					}
+					x = v
+				case value.ByStrN:
+					return value.NewInt(to, new(big.Int).SetBytes(a)), nil
+				}
+				v, err := x.Convert(to)
+				if err != nil {
+					return value.None(to), nil
+				}
+				return value.Some(to, v), nil
+			}, sigs...)
+		}
+	}
+
+	register("to_nat", func(args []value.Value) (value.Value, error) {
+		return value.Nat(args[0].(*value.Int).Value.Uint64()), nil
+	}, sig(types.Nat, types.Uint32))
+}
+
+func registerStrings() {
+	register("eq", func(args []value.Value) (value.Value, error) {
+		return value.Bool(value.Equal(args[0], args[1])), nil
+	}, sig(types.Bool, tA, tA).where("'A", primClass))
+	register("concat", func(args []value.Value) (value.Value, error) {
+		switch x := args[0].(type) {
+		case value.String:
+			return x + args[1].(value.String), nil
+		case value.ByStr:
+			return value.ByStr(append(append([]byte{}, x...), bytesOf(args[1])...)), nil
+		}
+		return value.ByStrN(append(append([]byte{}, bytesOf(args[0])...), bytesOf(args[1])...)), nil
+	},
+		sig(types.String, types.String, types.String),
+		sig(types.ByStr, types.ByStr, types.ByStr),
+		&Sig{
+			Params: []types.Type{tX, tY},
+			Result: types.Var("ByStr(X+Y)"),
+			Where:  map[string]*Class{"'X": byStrNClass, "'Y": byStrNClass},
+			ResultOf: func(targs map[string]types.Type) types.Type {
+				return types.ByStrN(targs["'X"].(*types.ByStrNType).Size + targs["'Y"].(*types.ByStrNType).Size)
+			},
+		},
+	)
+	register("substr", func(args []value.Value) (value.Value, error) {
+		idx, l := args[1].(*value.Int).Value.Uint64(), args[2].(*value.Int).Value.Uint64()
+		switch s := args[0].(type) {
+		case value.String:
+			if idx+l > uint64(len(s)) {
+				return nil, fmt.Errorf("Index out of bounds in substr: %d+%d for length %d", idx, l, len(s))
+			}
+			return s[idx : idx+l], nil
+		case value.ByStr:
+			if idx+l > uint64(len(s)) {
+				return nil, fmt.Errorf("Index out of bounds in substr: %d+%d for length %d", idx, l, len(s))
+			}
+			return append(value.ByStr{}, s[idx:idx+l]...), nil
+		}
+		return nil, nil
+	},
+		sig(types.String, types.String, types.Uint32, types.Uint32),
+		sig(types.ByStr, types.ByStr, types.Uint32, types.Uint32),
+	)
+	register("strlen", func(args []value.Value) (value.Value, error) {
+		var l int
+		switch s := args[0].(type) {
+		case value.String:
+			l = len(s)
+		case value.ByStr:
+			l = len(s)
+		}
+		return value.NewInt(types.Uint32, big.NewInt(int64(l))), nil
+	}, sig(types.Uint32, types.String), sig(types.Uint32, types.ByStr))
+	register("strrev", func(args []value.Value) (value.Value, error) {
+		s := append([]byte{}, bytesOf(args[0])...)
+		for i, j := 0, len(s)-1; i < j; i, j = i+1, j-1 {
+			s[i], s[j] = s[j], s[i]
+		}
+		if _, ok := args[0].(value.String); ok {
+			return value.String(s), nil
+		}
+		return value.ByStr(s), nil
+	}, sig(types.String, types.String), sig(types.ByStr, types.ByStr))
+	register("to_string", func(args []value.Value) (value.Value, error) {
+		switch a := args[0].(type) {
+		case *value.Int:
+			return value.String(a.Value.String()), nil
+		default:
+			return value.String(a.String()), nil // 0x... for byte strings
+		}
+	},
+		sig(types.String, tA).where("'A", intClass),
+		sig(types.String, types.ByStr),
+		sig(types.String, tX).where("'X", byStrNClass),
+	)
+	register("to_ascii", func(args []value.Value) (value.Value, error) {
+		s := string(bytesOf(args[0]))
+		if !isPrintable(s) {
+			return nil, fmt.Errorf("Byte string %s is not printable ASCII", args[0])
+		}
+		return value.String(s), nil
+	}, sig(types.String, types.ByStr), sig(types.String, tX).where("'X", byStrNClass))
+}
+
+func registerByStrs() {
+	register("to_bystr", func(args []value.Value) (value.Value, error) {
+		return value.ByStr(append([]byte{}, bytesOf(args[0])...)), nil
+	}, sig(types.ByStr, tX).where("'X", byStrNClass))
+
+	widths["to_bystr"] = func(n int) *Builtin {
+		to := types.ByStrN(n)
+		sigs := []*Sig{sig(types.Option(to), types.ByStr)}
+		for _, bits := range types.IntBits {
+			if bits == n*8 {
+				sigs = append(sigs, sig(to, &types.IntType{Signed: false, Bits: bits}))
+			}
+		}
+		return newBuiltin(fmt.Sprintf("to_bystr%d", n), func(args []value.Value) (value.Value, error) {
+			switch a := args[0].(type) {
+			case value.ByStr:
+				return byStrToByStrN(a, n), nil
+			case *value.Int:
+				return value.ByStrN(intToBytes(a.Value, n)), nil
+			}
+			return nil, nil
+		}, sigs...)
+	}
+	widths["bystr_to_bystr"] = func(n int) *Builtin {
+		return newBuiltin(fmt.Sprintf("bystr_to_bystr%d", n), func(args []value.Value) (value.Value, error) {
+			return byStrToByStrN(args[0].(value.ByStr), n), nil
+		}, sig(types.Option(types.ByStrN(n)), types.ByStr))
+	}
+
+	register("bech32_to_bystr20", func(args []value.Value) (value.Value, error) {
+		hrp, data, err := bech32Decode(string(args[1].(value.String)))
+		if err != nil || hrp != string(args[0].(value.String)) || len(data) != 20 {
+			return value.None(types.ByStr20), nil
+		}
+		return value.Some(types.ByStr20, value.ByStrN(data)), nil
+	}, sig(types.Option(types.ByStr20), types.String, types.String))
+	register("bystr20_to_bech32", func(args []value.Value) (value.Value, error) {
+		s, err := bech32Encode(string(args[0].(value.String)), args[1].(value.ByStrN))
+		if err != nil {
+			return value.None(types.String), nil
+		}
+		return value.Some(types.String, value.String(s)), nil
+	}, sig(types.Option(types.String), types.String, types.ByStr20))
+
+	register("sha256hash", hash(func(b []byte) []byte {
+		h := sha256.Sum256(b)
+		return h[:]
+	}), sig(types.ByStr32, tA).where("'A", dataClass))
+	register("keccak256hash", hash(keccak256), sig(types.ByStr32, tA).where("'A", dataClass))
+	register("ripemd160hash", hash(ripemd160), sig(types.ByStr20, tA).where("'A", dataClass))
+	register("schnorr_verify", func(args []value.Value) (value.Value, error) {
+		return value.Bool(schnorrVerify(bytesOf(args[0]), bytesOf(args[1]), bytesOf(args[2]))), nil
+	}, sig(types.Bool, types.ByStrN(33), types.ByStr, types.ByStrN(64)))
+	register("ecdsa_verify", func(args []value.Value) (value.Value, error) {
+		return value.Bool(ecdsaVerify(bytesOf(args[0]), bytesOf(args[1]), bytesOf(args[2]))), nil
+	}, sig(types.Bool, types.ByStrN(33), types.ByStr, types.ByStrN(64)))
+	register("ecdsa_recover_pk", func(args []value.Value) (value.Value, error) {
+		pk, err := ecdsaRecover(bytesOf(args[0]), bytesOf(args[1]), args[2].(*value.Int).Value.Uint64())
+		if err != nil {
+			return nil, err
+		}
+		return value.ByStrN(pk), nil
+	}, sig(types.ByStrN(65), types.ByStr, types.ByStrN(64), types.Uint32))
+	register("schnorr_get_address", func(args []value.Value) (value.Value, error) {
+		return value.ByStrN(schnorrAddress(bytesOf(args[0]))), nil
+	}, sig(types.ByStr20, types.ByStrN(33)))
+}
+
+// byStrToByStrN returns Some of the byte string with the width n or None when its length differs.
+func byStrToByStrN(b value.ByStr, n int) value.Value {
+	to := types.ByStrN(n)
+	if len(b) != n {
+		return value.None(to)
+	}
+	return value.Some(to, value.ByStrN(append([]byte{}, b...)))
+}
+
+func registerBNums() {
+	register("blt", func(args []value.Value) (value.Value, error) {
+		return value.Bool(args[0].(*value.BNum).Value.Cmp(args[1].(*value.BNum).Value) < 0), nil
+	}, sig(types.Bool, types.BNum, types.BNum))
+	register("badd", func(args []value.Value) (value.Value, error) {
+		return &value.BNum{Value: new(big.Int).Add(args[0].(*value.BNum).Value, args[1].(*value.Int).Value)}, nil
+	}, sig(types.BNum, types.BNum, tA).where("'A", uintClass))
+	register("bsub", func(args []value.Value) (value.Value, error) {
+		d := new(big.Int).Sub(args[0].(*value.BNum).Value, args[1].(*value.BNum).Value)
+		if v := value.NewInt(types.Int256, d); v != nil {
+			return v, nil
+		}
+		return nil, &value.IntError{Kind: value.IntOverflow, Op: "bsub", Typ: types.Int256}
+	}, sig(types.Int256, types.BNum, types.BNum))
+}
+
+func registerMaps() {
+	m := &types.MapType{Key: tK, Value: tV}
+	register("put", func(args []value.Value) (value.Value, error) {
+		r := args[0].(*value.Map).Copy()
+		r.Set(args[1], args[2])
+		return r, nil
+	}, sig(m, m, tK, tV))
+	register("get", func(args []value.Value) (value.Value, error) {
+		mv := args[0].(*value.Map)
+		if v, ok := mv.Get(args[1]); ok {
+			return value.Some(mv.Typ.Value, v), nil
+		}
+		return value.None(mv.Typ.Value), nil
+	}, sig(types.Option(tV), m, tK))
+	register("contains", func(args []value.Value) (value.Value, error) {
+		_, ok := args[0].(*value.Map).Get(args[1])
+		return value.Bool(ok), nil
+	}, sig(types.Bool, m, tK))
+	register("remove", func(args []value.Value) (value.Value, error) {
+		r := args[0].(*value.Map).Copy()
+		r.Delete(args[1])
+		return r, nil
+	}, sig(m, m, tK))
+	register("to_list", func(args []value.Value) (value.Value, error) {
+		mv := args[0].(*value.Map)
+		es := mv.Entries()
+		elems := make([]value.Value, 0, len(es))
+		for _, e := range es {
+			elems = append(elems, value.Pair(mv.Typ.Key, mv.Typ.Value, e.Key, e.Value))
+		}
+		return value.List(types.Pair(mv.Typ.Key, mv.Typ.Value), elems...), nil
+	}, sig(types.List(types.Pair(tK, tV)), m))
+	register("size", func(args []value.Value) (value.Value, error) {
+		return value.NewInt(types.Uint32, big.NewInt(int64(args[0].(*value.Map).Len()))), nil
+	}, sig(types.Uint32, m))
+}
+
+// Builtin operations from BuiltIns.ml of Zilliqa/scilla
+func init() {
+	registerInts()
+	registerStrings()
+	registerByStrs()
+	registerBNums()
+	registerMaps()
+}
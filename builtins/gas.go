@@ -0,0 +1,115 @@
+package builtins
+
+import (
+	"goscilla/value"
+	"strings"
+)
+
+// LiteralCost returns the size of the value for gas accounting as literal_cost in Gas.ml of
+// Zilliqa/scilla. Integers cost their widths in bytes and block numbers 64. Strings and byte
+// strings cost their lengths. Maps, ADTs and messages cost the sum of their contents. Constructor
+// tags and closures cost nothing.
+func LiteralCost(v value.Value) uint64 {
+	switch v := v.(type) {
+	case *value.Int:
+		return uint64(v.Typ.Bits / 8)
+	case value.String:
+		return uint64(len(v))
+	case *value.BNum:
+		return 64
+	case value.ByStr:
+		return uint64(len(v))
+	case value.ByStrN:
+		return uint64(len(v))
+	case *value.Map:
+		var c uint64
+		for _, e := range v.Entries() {
+			c += LiteralCost(e.Key) + LiteralCost(e.Value)
+		}
+		return c
+	case *value.ADT:
+		var c uint64
+		for _, a := range v.Args {
+			c += LiteralCost(a)
+		}
+		return c
+	case *value.Msg:
+		var c uint64
+		for _, e := range v.Entries {
+			c += uint64(len(e.Key)) + LiteralCost(e.Value)
+		}
+		return c
+	}
+	return 0
+}
+
+// intCost scales the base cost of integer operation by the width of the integer. Operations on 32
+// and 64 bit integers cost the base, 128 bit twice and 256 bit four times.
+func intCost(base uint64, v value.Value) uint64 {
+	i, ok := v.(*value.Int)
+	if !ok || i.Typ.Bits <= 64 {
+		return base
+	}
+	return base * uint64(i.Typ.Bits/64)
+}
+
+// hashCost is the cost of hashing the value with the base cost per 20 bytes.
+func hashCost(base uint64, v value.Value) uint64 {
+	return (LiteralCost(v)/20 + 1) * base
+}
+
+// signatureCost is the cost of verifying the signature of the message.
+func signatureCost(msg value.Value) uint64 {
+	return 250 + LiteralCost(msg)/64*15
+}
+
+// Cost returns the gas cost of evaluating the builtin with the arguments, following the costers of
+// builtins in Gas.ml of Zilliqa/scilla.
+func (b *Builtin) Cost(args []value.Value) uint64 {
+	switch b.Name {
+	case "add", "sub", "lt", "isqrt":
+		return intCost(4, args[0])
+	case "mul", "div", "rem":
+		return intCost(20, args[0])
+	case "pow":
+		return intCost(20, args[0]) * (args[1].(*value.Int).Value.Uint64() + 1)
+	case "to_nat":
+		// Nat is built with one constructor per successor
+		return args[0].(*value.Int).Value.Uint64() + 1
+	case "eq":
+		switch x := args[0].(type) {
+		case *value.Int:
+			return intCost(4, x)
+		case *value.BNum:
+			return 32
+		}
+		return LiteralCost(args[0])
+	case "concat":
+		return LiteralCost(args[0]) + LiteralCost(args[1])
+	case "substr", "strlen", "strrev", "to_string", "to_ascii", "to_bystr":
+		return LiteralCost(args[0])
+	case "blt", "badd", "bsub":
+		return 32
+	case "sha256hash", "keccak256hash":
+		return hashCost(15, args[0])
+	case "ripemd160hash":
+		return hashCost(10, args[0])
+	case "schnorr_verify", "ecdsa_verify":
+		return signatureCost(args[1])
+	case "ecdsa_recover_pk":
+		return signatureCost(args[0])
+	case "schnorr_get_address", "bech32_to_bystr20", "bystr20_to_bech32":
+		return 50
+	case "put", "get", "contains", "remove", "size":
+		return 1
+	case "to_list":
+		return uint64(args[0].(*value.Map).Len()) + 1
+	}
+	switch {
+	case strings.HasPrefix(b.Name, "to_int") || strings.HasPrefix(b.Name, "to_uint"):
+		return 4
+	case strings.HasPrefix(b.Name, "to_bystr") || strings.HasPrefix(b.Name, "bystr_to_bystr"):
+		return LiteralCost(args[0])
+	}
+	return 1
+}
@@ -0,0 +1,220 @@
+package builtins
+
+import (
+	"crypto/sha256"
+	"errors"
+	"math/big"
+)
+
+// Elliptic curve secp256k1 y^2 = x^3 + 7 and signature schemes over it. Zilliqa uses EC-Schnorr
+// for transactions and Scilla provides ECDSA as well. Points are computed in affine coordinates
+// since performance does not matter for evaluating contracts.
+
+func hexInt(s string) *big.Int {
+	i, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		panic(s)
+	}
+	return i
+}
+
+var (
+	secpP  = hexInt("fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2f")
+	secpN  = hexInt("fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141")
+	secpG  = &point{hexInt("79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"), hexInt("483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8")}
+	secpB  = big.NewInt(7)
+	halfN  = new(big.Int).Rsh(secpN, 1)
+	errKey = errors.New("Invalid public key")
+)
+
+// point is a point on the curve. nil is the point at infinity.
+type point struct {
+	x, y *big.Int
+}
+
+func modP(i *big.Int) *big.Int {
+	return i.Mod(i, secpP)
+}
+
+func addPoints(a, b *point) *point {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	var l *big.Int
+	if a.x.Cmp(b.x) == 0 {
+		if new(big.Int).Add(a.y, b.y).Cmp(secpP) == 0 || a.y.Sign() == 0 {
+			return nil
+		}
+		// Tangent: 3x^2 / 2y
+		l = new(big.Int).Mul(a.x, a.x)
+		l.Mul(l, big.NewInt(3))
+		l.Mul(l, new(big.Int).ModInverse(new(big.Int).Lsh(a.y, 1), secpP))
+	} else {
+		l = new(big.Int).Sub(b.y, a.y)
+		l.Mul(l, new(big.Int).ModInverse(modP(new(big.Int).Sub(b.x, a.x)), secpP))
+	}
+	modP(l)
+	x := new(big.Int).Mul(l, l)
+	x = modP(x.Sub(x, a.x).Sub(x, b.x))
+	y := new(big.Int).Sub(a.x, x)
+	y = modP(y.Mul(y, l).Sub(y, a.y))
+	return &point{x, y}
+}
+
+func mulPoint(k *big.Int, p *point) *point {
+	var r *point
+	for i := k.BitLen() - 1; i >= 0; i-- {
+		r = addPoints(r, r)
+		if k.Bit(i) == 1 {
+			r = addPoints(r, p)
+		}
+	}
+	return r
+}
+
+// liftX returns the point of the x coordinate whose y coordinate has the parity.
+func liftX(x *big.Int, odd bool) (*point, error) {
+	if x.Cmp(secpP) >= 0 {
+		return nil, errKey
+	}
+	y2 := new(big.Int).Exp(x, big.NewInt(3), secpP)
+	y2 = modP(y2.Add(y2, secpB))
+	y := new(big.Int).ModSqrt(y2, secpP)
+	if y == nil {
+		return nil, errKey
+	}
+	if (y.Bit(0) == 1) != odd {
+		y.Sub(secpP, y)
+	}
+	return &point{x, y}, nil
+}
+
+// parsePoint parses the point in compressed form (33 bytes) or uncompressed form (65 bytes).
+func parsePoint(b []byte) (*point, error) {
+	switch {
+	case len(b) == 33 && (b[0] == 2 || b[0] == 3):
+		return liftX(new(big.Int).SetBytes(b[1:]), b[0] == 3)
+	case len(b) == 65 && b[0] == 4:
+		p := &point{new(big.Int).SetBytes(b[1:33]), new(big.Int).SetBytes(b[33:])}
+		y2 := new(big.Int).Exp(p.x, big.NewInt(3), secpP)
+		y2 = modP(y2.Add(y2, secpB))
+		if p.x.Cmp(secpP) >= 0 || new(big.Int).Exp(p.y, big.NewInt(2), secpP).Cmp(y2) != 0 {
+			return nil, errKey
+		}
+		return p, nil
+	}
+	return nil, errKey
+}
+
+func (p *point) compressed() []byte {
+	b := make([]byte, 33)
+	b[0] = 2 + byte(p.y.Bit(0))
+	p.x.FillBytes(b[1:])
+	return b
+}
+
+func (p *point) uncompressed() []byte {
+	b := make([]byte, 65)
+	b[0] = 4
+	p.x.FillBytes(b[1:33])
+	p.y.FillBytes(b[33:])
+	return b
+}
+
+// scalars parses r and s of the signature in 64 bytes. Both must be in [1, n-1].
+func scalars(sig []byte) (r, s *big.Int, ok bool) {
+	r, s = new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+	ok = r.Sign() > 0 && r.Cmp(secpN) < 0 && s.Sign() > 0 && s.Cmp(secpN) < 0
+	return
+}
+
+// schnorrChallenge returns H(Q, kpub, m) mod n of EC-Schnorr of Zilliqa.
+func schnorrChallenge(q *point, pub, msg []byte) *big.Int {
+	h := sha256.New()
+	h.Write(q.compressed())
+	h.Write(pub)
+	h.Write(msg)
+	return new(big.Int).Mod(new(big.Int).SetBytes(h.Sum(nil)), secpN)
+}
+
+// schnorrVerify verifies the signature (r, s) of the message with the compressed public key as
+// Schnorr.cpp of Zilliqa does: Q = sG + r*kpub and r must be equal to H(Q, kpub, m) mod n.
+func schnorrVerify(pub, msg, sig []byte) bool {
+	k, err := parsePoint(pub)
+	if err != nil || len(pub) != 33 {
+		return false
+	}
+	r, s, ok := scalars(sig)
+	if !ok {
+		return false
+	}
+	q := addPoints(mulPoint(s, secpG), mulPoint(r, k))
+	if q == nil {
+		return false
+	}
+	return schnorrChallenge(q, pub, msg).Cmp(r) == 0
+}
+
+// schnorrAddress returns the address of the account of the public key, which is the last 20 bytes
+// of SHA256 hash of the compressed public key.
+func schnorrAddress(pub []byte) []byte {
+	h := sha256.Sum256(pub)
+	return h[12:]
+}
+
+// ecdsaVerify verifies the signature (r, s) of SHA256 hash of the message with the public key like
+// libsecp256k1. Only signatures whose s is in lower half of the order are valid so that they are
+// not malleable.
+func ecdsaVerify(pub, msg, sig []byte) bool {
+	k, err := parsePoint(pub)
+	if err != nil {
+		return false
+	}
+	r, s, ok := scalars(sig)
+	if !ok || s.Cmp(halfN) > 0 {
+		return false
+	}
+	h := sha256.Sum256(msg)
+	e := new(big.Int).SetBytes(h[:])
+	w := new(big.Int).ModInverse(s, secpN)
+	u1 := e.Mul(e, w).Mod(e, secpN)
+	u2 := w.Mul(r, w).Mod(w, secpN)
+	x := addPoints(mulPoint(u1, secpG), mulPoint(u2, k))
+	if x == nil {
+		return false
+	}
+	return new(big.Int).Mod(x.x, secpN).Cmp(r) == 0
+}
+
+// ecdsaRecover recovers the public key in uncompressed form from the signature of SHA256 hash of
+// the message and the recovery ID from 0 to 3.
+func ecdsaRecover(msg, sig []byte, recid uint64) ([]byte, error) {
+	r, s, ok := scalars(sig)
+	if !ok || recid > 3 {
+		return nil, errors.New("Invalid signature or recovery ID")
+	}
+	x := new(big.Int).Set(r)
+	if recid&2 != 0 {
+		x.Add(x, secpN)
+	}
+	p, err := liftX(x, recid&1 == 1)
+	if err != nil {
+		return nil, errors.New("Public key cannot be recovered from the signature")
+	}
+	h := sha256.Sum256(msg)
+	e := new(big.Int).SetBytes(h[:])
+	// Q = r^-1 (sR - eG)
+	rinv := new(big.Int).ModInverse(r, secpN)
+	u1 := new(big.Int).Neg(e)
+	u1.Mul(u1, rinv).Mod(u1, secpN)
+	u2 := new(big.Int).Mul(s, rinv)
+	u2.Mod(u2, secpN)
+	q := addPoints(mulPoint(u1, secpG), mulPoint(u2, p))
+	if q == nil {
+		return nil, errors.New("Public key cannot be recovered from the signature")
+	}
+	return q.uncompressed(), nil
+}
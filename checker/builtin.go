@@ -0,0 +1,20 @@
+package checker
+
+import (
+	"goscilla/ast"
+	"goscilla/builtins"
+	"goscilla/types"
+)
+
+// builtin resolves the overload of the builtin operation from the catalogue in builtins package
+func (c *checker) builtin(e *ast.Builtin, args []types.Type) types.Type {
+	b, err := builtins.Lookup(e.Ident.Symbol.Name)
+	if err != nil {
+		c.errorIn(e.Ident, "%s", err)
+	}
+	t, err := b.Type(args)
+	if err != nil {
+		c.errorIn(e, "%s", err)
+	}
+	return t
+}
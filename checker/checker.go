@@ -0,0 +1,391 @@
+// Package checker provides static type checking of Scilla modules.
+package checker
+
+import (
+	"fmt"
+	"github.com/rhysd/locerr"
+	"goscilla/ast"
+	"goscilla/token"
+	"goscilla/types"
+	"sort"
+	"strings"
+)
+
+// ErrorList is a list of type errors sorted by position.
+type ErrorList []*locerr.Error
+
+func (l ErrorList) Error() string {
+	msgs := make([]string, 0, len(l))
+	for _, err := range l {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func (l ErrorList) sort() {
+	sort.SliceStable(l, func(i, j int) bool {
+		return l[i].Start.Offset < l[j].Start.Offset
+	})
+}
+
+// Info is a result of type checking which later passes can refer.
+type Info struct {
+	// Types of all expressions in the module
+	Types map[ast.Expr]types.Type
+	// Types of all variables declared in the module, including library entries, parameters,
+	// pattern binders and variables bound in statements
+	Defs map[*ast.Ident]types.Type
+	// ADT definitions visible in the module, including builtin ADTs
+	ADTs map[string]*types.ADTDef
+	// Types of contract fields, including implicit `_balance`
+	Fields map[string]types.Type
+	// Libraries imported by the module
+	Imports map[*ast.ImportName]*Library
+	// Type arguments of all type applications like `@f Uint32`
+	TypeArgs map[*ast.TApp][]types.Type
+}
+
+// Implicit parameters of contract and components
+var (
+	contractImplicits = []struct {
+		name string
+		typ  types.Type
+	}{
+		{"_this_address", types.ContractAddress()},
+		{"_creation_block", types.BNum},
+		{"_scilla_version", types.Uint32},
+	}
+	componentImplicits = []struct {
+		name string
+		typ  types.Type
+	}{
+		{"_sender", &types.AddressType{Kind: types.AnyAddr}},
+		{"_origin", &types.AddressType{Kind: types.AnyAddr}},
+		{"_amount", types.Uint128},
+	}
+)
+
+// RecursionPrinciples are the types of recursion principles from Recursion.ml of Zilliqa/scilla
+// which are available everywhere
+var RecursionPrinciples = func() map[string]types.Type {
+	a, b, t := types.Var("'A"), types.Var("'B"), types.Var("'T")
+	return map[string]types.Type{
+		"nat_fold":   types.Forall(types.Fun(types.Fun(t, types.Nat, t), t, types.Nat, t), "'T"),
+		"nat_foldk":  types.Forall(types.Fun(types.Fun(t, types.Nat, types.Fun(t, t), t), t, types.Nat, t), "'T"),
+		"list_foldl": types.Forall(types.Fun(types.Fun(b, a, b), b, types.List(a), b), "'A", "'B"),
+		"list_foldr": types.Forall(types.Fun(types.Fun(a, b, b), b, types.List(a), b), "'A", "'B"),
+		"list_foldk": types.Forall(types.Fun(types.Fun(b, a, types.Fun(b, b), b), b, types.List(a), b), "'A", "'B"),
+	}
+}()
+
+// bailout is used as a panic value to abort checking the current declaration. It is recovered by
+// try. When err is nil, the cause was already reported (e.g. referring a variable whose
+// declaration had an error) and nothing is reported.
+type bailout struct {
+	err *locerr.Error
+}
+
+// scope is a persistent linked list of local variables. Shadowing is expressed by prepending.
+type scope struct {
+	name   string
+	typ    types.Type // nil when the declaration of the variable had an error
+	parent *scope
+}
+
+func (s *scope) bind(name string, t types.Type) *scope {
+	return &scope{name, t, s}
+}
+
+func (s *scope) lookup(name string) (types.Type, bool) {
+	for ; s != nil; s = s.parent {
+		if s.name == name {
+			return s.typ, true
+		}
+	}
+	return nil, false
+}
+
+type proc struct {
+	params []types.Type
+}
+
+type checker struct {
+	conf    *Config
+	info    *Info
+	errs    ErrorList
+	libErrs ErrorList                // Errors in imported libraries
+	globals map[string]types.Type    // Library entries and contract parameters
+	adts    map[string]*types.ADTDef // ADTs visible by their names
+	ctors   map[string]*types.ADTDef
+	origins map[string]string // Libraries where imported names are defined
+	procs   map[string]*proc  // Procedures declared so far. nil when the procedure has an error
+	tvars   []string          // Type variables in scope
+}
+
+func newChecker(conf *Config) *checker {
+	c := &checker{
+		conf: conf,
+		info: &Info{
+			Types:    map[ast.Expr]types.Type{},
+			Defs:     map[*ast.Ident]types.Type{},
+			ADTs:     map[string]*types.ADTDef{},
+			Fields:   map[string]types.Type{},
+			Imports:  map[*ast.ImportName]*Library{},
+			TypeArgs: map[*ast.TApp][]types.Type{},
+		},
+		globals: map[string]types.Type{},
+		adts:    map[string]*types.ADTDef{},
+		ctors:   map[string]*types.ADTDef{},
+		origins: map[string]string{},
+		procs:   map[string]*proc{},
+	}
+	for n, t := range RecursionPrinciples {
+		c.globals[n] = t
+	}
+	for _, d := range types.BuiltinADTs {
+		c.info.ADTs[d.Name] = d
+		c.adts[d.Name] = d
+		for _, ctor := range d.Ctors {
+			c.ctors[ctor.Name] = d
+		}
+	}
+	return c
+}
+
+func (c *checker) errorIn(n ast.Node, format string, args ...interface{}) {
+	panic(bailout{locerr.ErrorIn(n.Pos(), n.End(), fmt.Sprintf(format, args...))})
+}
+
+func (c *checker) mismatch(n ast.Node, what string, want, have types.Type) {
+	if h, ok := n.(*ast.HexLit); ok {
+		if w, ok := want.(*types.ByStrNType); ok {
+			c.errorIn(n, "Type mismatch in %s. Hex literal %s has %d bytes but %s must have %d bytes", what, h.Token.Value(), have.(*types.ByStrNType).Size, want, w.Size)
+		}
+	}
+	c.errorIn(n, "Type mismatch in %s. Expected %s but got %s", what, want, have)
+}
+
+// try runs f and records the error which aborted it. It returns false when f was aborted.
+func (c *checker) try(f func()) (ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			b, isBailout := r.(bailout)
+			if !isBailout {
+				panic(r)
+			}
+			if b.err != nil {
+				c.errs = append(c.errs, b.err)
+			}
+			ok = false
+		}
+	}()
+	f()
+	return true
+}
+
+func (c *checker) declare(i *ast.Ident, t types.Type) {
+	if t != nil {
+		c.info.Defs[i] = t
+	}
+}
+
+func (c *checker) declareGlobal(i *ast.Ident, t types.Type) {
+	c.globals[i.Symbol.Name] = t
+	c.declare(i, t)
+}
+
+// Config configures type checking.
+type Config struct {
+	// Importer resolves libraries imported by the module. When it is nil, importing libraries
+	// causes errors.
+	Importer Importer
+}
+
+// Check type checks the module and returns the types of its declarations and expressions. When
+// the module has type errors, they are returned as ErrorList with partially filled Info.
+// Declarations which have errors are skipped and errors caused by them are not reported.
+// Libraries cannot be imported. Use Config to import libraries.
+func Check(a *ast.AST) (*Info, error) {
+	return (&Config{}).Check(a)
+}
+
+// Check type checks the module with the configuration. Errors in imported libraries precede the
+// errors in the module.
+func (conf *Config) Check(a *ast.AST) (*Info, error) {
+	c := newChecker(conf)
+	c.module(a)
+	if len(c.errs) > 0 || len(c.libErrs) > 0 {
+		c.errs.sort()
+		return c.info, append(c.libErrs, c.errs...)
+	}
+	return c.info, nil
+}
+
+func (c *checker) module(a *ast.AST) {
+	for _, i := range a.Imports {
+		for _, n := range i.Names {
+			c.try(func() { c.importLib(n) })
+		}
+	}
+	if a.Library != nil {
+		c.library(a.Library)
+	}
+	if a.Contract != nil {
+		c.contract(a.Contract)
+	}
+	if len(a.Components) > 0 {
+		// Snippet which only contains components
+		c.components(a.Components, map[string]types.Type{"_balance": types.Uint128})
+	}
+}
+
+func (c *checker) library(l *ast.Library) {
+	for _, e := range l.Entries {
+		switch e := e.(type) {
+		case *ast.LetDecl:
+			var t types.Type
+			c.try(func() {
+				t = c.let(e.Type, e.Bound, nil)
+			})
+			c.declareGlobal(e.Ident, t)
+		case *ast.TypeDecl:
+			c.try(func() { c.typeDecl(e) })
+		}
+	}
+}
+
+func (c *checker) typeDecl(d *ast.TypeDecl) {
+	name := d.Ident.Symbol.Name
+	if lib, ok := c.origins["type "+name]; ok {
+		c.errorIn(d.Ident, "Type %s is already defined in library %s", d.Ident.Symbol.DisplayName, lib)
+	}
+	if _, ok := c.info.ADTs[name]; ok || types.Prim(name) != nil {
+		c.errorIn(d.Ident, "Type %s is already defined", d.Ident.Symbol.DisplayName)
+	}
+	def := &types.ADTDef{Name: name, Ctors: make([]*types.CtorDef, 0, len(d.Ctors))}
+	for _, ctor := range d.Ctors {
+		n := ctor.Ident.Symbol.Name
+		if _, ok := c.ctors[n]; ok || def.Ctor(n) != nil {
+			c.errorIn(ctor.Ident, "Constructor %s is already defined", ctor.Ident.Symbol.DisplayName)
+		}
+		args := make([]types.Type, 0, len(ctor.Types))
+		for _, t := range ctor.Types {
+			args = append(args, c.typ(t))
+		}
+		def.Ctors = append(def.Ctors, &types.CtorDef{Name: n, Args: args})
+	}
+	c.info.ADTs[name] = def
+	c.adts[name] = def
+	for _, ctor := range def.Ctors {
+		c.ctors[ctor.Name] = def
+	}
+}
+
+func (c *checker) contract(k *ast.Contract) {
+	for _, i := range contractImplicits {
+		c.globals[i.name] = i.typ
+	}
+
+	seen := map[string]bool{}
+	for _, p := range k.Params {
+		var t types.Type
+		c.try(func() {
+			name := p.Ident.Symbol.Name
+			if seen[name] {
+				c.errorIn(p.Ident, "Parameter %s is already defined", p.Ident.Symbol.DisplayName)
+			}
+			seen[name] = true
+			t = c.typ(p.Type)
+		})
+		c.declareGlobal(p.Ident, t)
+	}
+
+	if k.Constraint != nil {
+		c.try(func() {
+			if t := c.expr(k.Constraint, nil); !types.Assignable(types.Bool, t) {
+				c.mismatch(k.Constraint, "contract constraint", types.Bool, t)
+			}
+		})
+	}
+
+	fields := map[string]types.Type{"_balance": types.Uint128}
+	for _, f := range k.Fields {
+		name := f.Ident.Symbol.Name
+		var t types.Type
+		c.try(func() {
+			if _, ok := fields[name]; ok {
+				c.errorIn(f.Ident, "Field %s is already defined", f.Ident.Symbol.DisplayName)
+			}
+			t = c.let(f.Type, f.Init, nil)
+		})
+		if _, ok := fields[name]; !ok {
+			fields[name] = t
+		}
+		c.declare(f.Ident, t)
+	}
+
+	c.components(k.Components, fields)
+}
+
+func (c *checker) components(comps []*ast.Component, fields map[string]types.Type) {
+	for n, t := range fields {
+		if t != nil {
+			c.info.Fields[n] = t
+		}
+	}
+	transitions := map[string]bool{}
+	for _, comp := range comps {
+		name := comp.Ident.Symbol.Name
+		var p *proc
+		c.try(func() {
+			if _, ok := c.procs[name]; ok || transitions[name] {
+				c.errorIn(comp.Ident, "Component %s is already defined", comp.Ident.Symbol.DisplayName)
+			}
+			p = c.component(comp, fields)
+		})
+		if comp.Token.Kind == token.PROCEDURE {
+			// p is nil when the procedure has an error. Calls of it are not reported
+			if _, ok := c.procs[name]; !ok {
+				c.procs[name] = p
+			}
+		} else {
+			transitions[name] = true
+		}
+	}
+}
+
+func (c *checker) component(comp *ast.Component, fields map[string]types.Type) *proc {
+	var s *scope
+	for _, i := range componentImplicits {
+		s = s.bind(i.name, i.typ)
+	}
+	p := &proc{params: make([]types.Type, 0, len(comp.Params))}
+	seen := map[string]bool{}
+	for _, param := range comp.Params {
+		name := param.Ident.Symbol.Name
+		if seen[name] {
+			c.errorIn(param.Ident, "Parameter %s is already defined", param.Ident.Symbol.DisplayName)
+		}
+		seen[name] = true
+		t := c.typ(param.Type)
+		c.declare(param.Ident, t)
+		p.params = append(p.params, t)
+		s = s.bind(name, t)
+	}
+	sc := &stmtChecker{c, fields}
+	sc.stmts(comp.Body, s)
+	return p
+}
+
+// let checks `let x : T = e` and returns the type of x. ty may be nil.
+func (c *checker) let(ty ast.Type, bound ast.Expr, s *scope) types.Type {
+	t := c.expr(bound, s)
+	if ty == nil {
+		return t
+	}
+	want := c.typ(ty)
+	if !types.Assignable(want, t) {
+		c.mismatch(bound, "type annotation", want, t)
+	}
+	return want
+}
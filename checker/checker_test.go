@@ -0,0 +1,430 @@
+package checker
+
+import (
+	"github.com/rhysd/locerr"
+	"goscilla/ast"
+	"goscilla/syntax"
+	"goscilla/types"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func parse(t *testing.T, src *locerr.Source) *ast.AST {
+	a, err := syntax.Parse(src)
+	if err != nil {
+		t.Fatal("Parse error:", err)
+	}
+	return a
+}
+
+func TestCheckOK(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.scilla"))
+	if err != nil {
+		panic(err)
+	}
+	for _, f := range files {
+		t.Run(f, func(t *testing.T) {
+			s, err := locerr.NewSourceFromFile(f)
+			if err != nil {
+				panic(err)
+			}
+			if _, err := Check(parse(t, s)); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestCheckInfo(t *testing.T) {
+	s, err := locerr.NewSourceFromFile(filepath.Join("testdata", "wallet.scilla"))
+	if err != nil {
+		panic(err)
+	}
+	a := parse(t, s)
+	info, err := Check(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"one_msg": "Message -> List (Message)",
+		"sum":     "List (Uint32) -> Uint32",
+		"id_bool": "Bool -> Bool",
+		"first":   "forall 'A. forall 'B. Pair ('A) ('B) -> 'A",
+		"hash":    "String -> ByStr32",
+	}
+	for _, e := range a.Library.Entries {
+		d, ok := e.(*ast.LetDecl)
+		if !ok {
+			continue
+		}
+		name := d.Ident.Symbol.DisplayName
+		if w, ok := want[name]; ok {
+			if have := info.Defs[d.Ident].String(); have != w {
+				t.Errorf("Wanted type %q for %s but got %q", w, name, have)
+			}
+			if have := info.Types[d.Bound].String(); have != w {
+				t.Errorf("Wanted type %q for expression bound to %s but got %q", w, name, have)
+			}
+		}
+	}
+
+	if have := info.Fields["allowances"].String(); have != "Map (ByStr20) (Map (ByStr20) (Uint128))" {
+		t.Error("Unexpected field type", have)
+	}
+	if !types.Equal(info.Fields["_balance"], types.Uint128) {
+		t.Error("Implicit field _balance is missing", info.Fields)
+	}
+	if d, ok := info.ADTs["Error"]; !ok || len(d.Ctors) != 2 {
+		t.Error("User-defined ADT Error is missing", info.ADTs)
+	}
+}
+
+func TestCheckError(t *testing.T) {
+	for _, tc := range []struct {
+		what string
+		code string
+		want []string
+	}{
+		{
+			"let annotation",
+			`library L let x : Uint32 = Int32 1`,
+			[]string{"Type mismatch in type annotation. Expected Uint32 but got Int32"},
+		},
+		{
+			"uint overflow",
+			`library L let x = Uint128 340282366920938463463374607431768211456`,
+			[]string{"Integer literal 340282366920938463463374607431768211456 is out of range of Uint128. It must be from 0 to 340282366920938463463374607431768211455"},
+		},
+		{
+			"negative uint",
+			`library L let x = Uint32 -1`,
+			[]string{"Integer literal -1 is out of range of Uint32"},
+		},
+		{
+			"int underflow",
+			`library L let x = Int32 -2147483649 let y = Int32 -2147483648`,
+			[]string{"Integer literal -2147483649 is out of range of Int32. It must be from -2147483648 to 2147483647"},
+		},
+		{
+			"negative block number",
+			`library L let x = BNum -1`,
+			[]string{"Block number literal -1 must not be negative"},
+		},
+		{
+			"odd hex digits",
+			`library L let x = 0xabc`,
+			[]string{"Hex literal 0xabc must have even number of hex digits"},
+		},
+		{
+			"hex literal length",
+			`library L let x : ByStr20 = 0x1234`,
+			[]string{"Type mismatch in type annotation. Hex literal 0x1234 has 2 bytes but ByStr20 must have 20 bytes"},
+		},
+		{
+			"undefined variable",
+			`library L let x = y`,
+			[]string{"Undefined variable y"},
+		},
+		{
+			"function argument",
+			`library L let f = fun (x : Uint32) => x let s = "a" let y = f s`,
+			[]string{"Type mismatch in argument of f. Expected Uint32 but got String"},
+		},
+		{
+			"too many arguments",
+			`library L let f = fun (x : Uint32) => x let a = Uint32 1 let y = f a a`,
+			[]string{"f takes 1 arguments but 2 given"},
+		},
+		{
+			"polymorphic function without instantiation",
+			`library L let id = tfun 'A => fun (x : 'A) => x let a = Uint32 1 let y = id a`,
+			[]string{"id of type forall 'A. 'A -> 'A must be instantiated with @"},
+		},
+		{
+			"instantiation of monomorphic function",
+			`library L let f = fun (x : Uint32) => x let g = @f Uint32`,
+			[]string{"f of type Uint32 -> Uint32 cannot be instantiated with type Uint32"},
+		},
+		{
+			"unbound type variable",
+			`library L let f = fun (x : 'A) => x`,
+			[]string{"Type variable 'A is not bound"},
+		},
+		{
+			"type variable unbound after failed tfun",
+			`library L let f = tfun 'A => fun (x : 'A) => builtin nope x let g = fun (y : 'A) => y`,
+			[]string{"Unknown builtin nope", "Type variable 'A is not bound"},
+		},
+		{
+			"undefined type",
+			`library L let f = fun (x : Foo) => x`,
+			[]string{"Type Foo is not defined"},
+		},
+		{
+			"wrong number of type arguments",
+			`library L let f = fun (x : Option Uint32 Uint32) => x`,
+			[]string{"Type Option takes 1 type arguments but 2 given"},
+		},
+		{
+			"unknown builtin",
+			`library L let a = Uint32 1 let b = builtin foo a`,
+			[]string{"Unknown builtin foo"},
+		},
+		{
+			"misspelled builtin",
+			`library L let a = "a" let b = builtin concatt a a`,
+			[]string{"Unknown builtin concatt. Did you mean concat?"},
+		},
+		{
+			"builtin argument types",
+			`library L let a = Uint32 1 let b = Int32 1 let c = builtin add a b`,
+			[]string{"Builtin add cannot be applied to arguments of types Uint32, Int32"},
+		},
+		{
+			"builtin arity",
+			`library L let a = Uint32 1 let c = builtin add a`,
+			[]string{"Builtin add takes 2 arguments but 1 given"},
+		},
+		{
+			"constructor type arguments",
+			`library L let x = Nil`,
+			[]string{"Constructor Nil takes 1 type arguments but 0 given"},
+		},
+		{
+			"constructor arguments",
+			`library L let s = "a" let x = Some {Uint32} s`,
+			[]string{"Type mismatch in argument of constructor Some. Expected Uint32 but got String"},
+		},
+		{
+			"undefined constructor",
+			`library L let x = Foo`,
+			[]string{"Undefined constructor Foo"},
+		},
+		{
+			"match arms",
+			`library L let f = fun (b : Bool) => match b with | True => Uint32 1 | False => Int32 1 end`,
+			[]string{"Type mismatch in match arm. Expected Uint32 but got Int32"},
+		},
+		{
+			"pattern of other type",
+			`library L let f = fun (o : Option Uint32) => match o with | True => o end`,
+			[]string{"Constructor True of type Bool cannot match value of type Option (Uint32)"},
+		},
+		{
+			"pattern arguments",
+			`library L let f = fun (o : Option Uint32) => match o with | Some => o end`,
+			[]string{"Constructor Some takes 1 arguments but 0 given in pattern"},
+		},
+		{
+			"duplicated binders in pattern",
+			`library L let f = fun (p : Pair Uint32 Uint32) => match p with | Pair a a => a end`,
+			[]string{"Variable a is bound more than once in pattern"},
+		},
+		{
+			"non-exhaustive match",
+			`library L let f = fun (o : Option Uint32) => match o with | Some x => x end`,
+			[]string{"Match is not exhaustive. For example, a value matching pattern None is not matched by any arm"},
+		},
+		{
+			"non-exhaustive nested match",
+			`library L type T = | A of Bool Uint32 | B let f = fun (o : Option T) => match o with | Some (A True _) => True | Some B => True | None => False end`,
+			[]string{"For example, a value matching pattern Some (A False _) is not matched by any arm"},
+		},
+		{
+			"non-exhaustive match on primitive",
+			`library L let f = fun (p : Pair Uint32 Bool) => match p with | Pair _ True => True end`,
+			[]string{"For example, a value matching pattern Pair _ False is not matched by any arm"},
+		},
+		{
+			"unreachable arm",
+			`library L let f = fun (b : Bool) => match b with | x => x | True => b end`,
+			[]string{"Pattern True is unreachable since values matching it are matched by previous arms"},
+		},
+		{
+			"unreachable nested arm",
+			`library L let f = fun (l : List Bool) => match l with | Cons True _ => True | Nil => False | Cons _ _ => True | Cons False Nil => False end`,
+			[]string{"Pattern Cons False Nil is unreachable"},
+		},
+		{
+			"match statement",
+			`library L contract C() transition T(o : Option Bool) match o with | Some True => | None => | None => end end`,
+			[]string{"Match is not exhaustive. For example, a value matching pattern Some False is not matched by any arm", "Pattern None is unreachable"},
+		},
+		{
+			"duplicated type",
+			`library L type T = | A type T = | B`,
+			[]string{"Type T is already defined"},
+		},
+		{
+			"duplicated constructor",
+			`library L type T = | Some`,
+			[]string{"Constructor Some is already defined"},
+		},
+		{
+			"message key",
+			`library L let a = Uint32 1 let m = { _tag : "a"; _amount : a }`,
+			[]string{"Type mismatch in value of _amount. Expected Uint128 but got Uint32"},
+		},
+		{
+			"errors are not cascaded",
+			`library L let x : Uint32 = Int32 1 let y = x let z = y`,
+			[]string{"Type mismatch in type annotation"},
+		},
+		{
+			"multiple errors",
+			`library L let x = y let z = Nil`,
+			[]string{"Undefined variable y", "Constructor Nil takes 1 type arguments"},
+		},
+		{
+			"import",
+			`scilla_version 0 import ListUtils library L`,
+			[]string{"Library ListUtils cannot be imported"},
+		},
+		{
+			"constraint",
+			`library L contract C(a : Uint32) with a =>`,
+			[]string{"Type mismatch in contract constraint. Expected Bool but got Uint32"},
+		},
+		{
+			"field initializer",
+			`library L contract C() field f : Uint32 = Int32 0`,
+			[]string{"Type mismatch in type annotation. Expected Uint32 but got Int32"},
+		},
+		{
+			"duplicated field",
+			`library L contract C() field f : Uint32 = Uint32 0 field f : Uint32 = Uint32 0`,
+			[]string{"Field f is already defined"},
+		},
+		{
+			"undefined field",
+			`library L contract C() transition T() x <- f end`,
+			[]string{"Undefined field f"},
+		},
+		{
+			"store",
+			`library L contract C() field f : Uint32 = Uint32 0 transition T(s : String) f := s end`,
+			[]string{"Type mismatch in store to field f. Expected Uint32 but got String"},
+		},
+		{
+			"map key",
+			`library L contract C() field m : Map ByStr20 Uint32 = Emp ByStr20 Uint32 transition T(k : String) x <- m[k] end`,
+			[]string{"Type mismatch in map key. Expected ByStr20 but got String"},
+		},
+		{
+			"too many map keys",
+			`library L contract C() field m : Map ByStr20 Uint32 = Emp ByStr20 Uint32 transition T() delete m[_sender][_sender] end`,
+			[]string{"m cannot be accessed with 2 keys since the value of type Uint32 is not a map"},
+		},
+		{
+			"map update",
+			`library L contract C() field m : Map ByStr20 (Map ByStr20 Uint32) = Emp ByStr20 (Map ByStr20 Uint32) transition T(v : Uint32) m[_sender] := v end`,
+			[]string{"Type mismatch in update of map m. Expected Map (ByStr20) (Uint32) but got Uint32"},
+		},
+		{
+			"send",
+			`library L contract C() transition T() m = { _tag : "" }; send m end`,
+			[]string{"Type mismatch in send. Expected List (Message) but got Message"},
+		},
+		{
+			"event",
+			`library L contract C() transition T() m = { _tag : "" }; event m end`,
+			[]string{"Type mismatch in event. Expected Event but got Message"},
+		},
+		{
+			"throw",
+			`library L contract C() transition T() m = { _eventname : "" }; throw m end`,
+			[]string{"Type mismatch in throw. Expected Exception but got Event"},
+		},
+		{
+			"undefined procedure",
+			`library L contract C() transition T() P end`,
+			[]string{"Undefined procedure P"},
+		},
+		{
+			"procedure arguments",
+			`library L contract C() procedure P(a : Uint32) end transition T(s : String) P s end`,
+			[]string{"Type mismatch in argument of procedure P. Expected Uint32 but got String"},
+		},
+		{
+			"forall",
+			`library L contract C() procedure P(a : Uint32) end transition T(l : List String) forall l P end`,
+			[]string{"Type mismatch in forall over procedure P. Expected Uint32 but got String"},
+		},
+		{
+			"remote read",
+			`library L contract C() transition T(a : ByStr20) x <- & a.f end`,
+			[]string{"Remote state can only be read via address type but a has type ByStr20"},
+		},
+		{
+			"remote field",
+			`library L contract C() transition T(a : ByStr20 with contract field g : Uint32 end) x <- & a.f end`,
+			[]string{"Field f is not declared in address type ByStr20 with contract field g : Uint32 end"},
+		},
+		{
+			"remote field of library",
+			`library L contract C() transition T(a : ByStr20 with library end) x <- & a.f end`,
+			[]string{"Field f is not declared in address type ByStr20 with library end"},
+		},
+		{
+			"byte string as address",
+			`library L contract C(a : ByStr20) field f : ByStr20 with end = a`,
+			[]string{"Type mismatch in type annotation. Expected ByStr20 with end but got ByStr20"},
+		},
+		{
+			"missing field in address",
+			`library L contract C() procedure P(a : ByStr20 with contract field g : Uint32 end) end transition T(a : ByStr20 with contract field f : Uint32 end) P a end`,
+			[]string{"Type mismatch in argument of procedure P. Expected ByStr20 with contract field g : Uint32 end but got ByStr20 with contract field f : Uint32 end"},
+		},
+		{
+			"field type in address",
+			`library L contract C(b : ByStr20 with contract field g : Uint32, field h : String end) field f : ByStr20 with contract field g : Uint32 end = b transition T(a : ByStr20 with contract field g : Int32 end) f := a end`,
+			[]string{"Type mismatch in store to field f. Expected ByStr20 with contract field g : Uint32 end but got ByStr20 with contract field g : Int32 end"},
+		},
+		{
+			"library address as contract address",
+			`library L contract C() procedure P(a : ByStr20 with contract end) end transition T(a : ByStr20 with library end) P a end`,
+			[]string{"Expected ByStr20 with contract end but got ByStr20 with library end"},
+		},
+		{
+			"blockchain query",
+			`library L contract C() transition T() x <- & FOO end`,
+			[]string{"Unknown blockchain query FOO"},
+		},
+		{
+			"statements after error",
+			`library L contract C() transition T() x = y; z = x; w = u end`,
+			[]string{"Undefined variable y", "Undefined variable u"},
+		},
+		{
+			"variables in match arms",
+			`library L contract C() transition T(o : Option Uint32) match o with | Some v => | None => end; x = v end`,
+			[]string{"Undefined variable v"},
+		},
+		{
+			"duplicated component",
+			`library L contract C() transition T() end transition T() end`,
+			[]string{"Component T is already defined"},
+		},
+	} {
+		t.Run(tc.what, func(t *testing.T) {
+			_, err := Check(parse(t, locerr.NewDummySource(tc.code)))
+			if err == nil {
+				t.Fatal("Error did not occur")
+			}
+			errs, ok := err.(ErrorList)
+			if !ok {
+				t.Fatalf("Error is not ErrorList: %T", err)
+			}
+			if len(errs) != len(tc.want) {
+				t.Fatalf("Wanted %d errors but got %d: %s", len(tc.want), len(errs), err)
+			}
+			for i, w := range tc.want {
+				if msg := strings.Join(errs[i].Messages, " "); !strings.Contains(msg, w) {
+					t.Errorf("Error %d should contain %q but got %q", i, w, msg)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,258 @@
+package checker
+
+import (
+	"goscilla/ast"
+	"goscilla/types"
+	"goscilla/value"
+)
+
+// expr infers the type of the expression in the scope of local variables.
+func (c *checker) expr(e ast.Expr, s *scope) types.Type {
+	t := c.inferExpr(e, s)
+	c.info.Types[e] = t
+	return t
+}
+
+// lookup returns the type of the variable. When the declaration of the variable had an error,
+// checking is aborted without reporting a new error.
+func (c *checker) lookup(v *ast.VarRef, s *scope) types.Type {
+	t, ok := s.lookup(v.Symbol.Name)
+	if !ok {
+		t, ok = c.globals[v.Symbol.Name]
+	}
+	if !ok {
+		c.errorIn(v, "Undefined variable %s", v.Symbol.DisplayName)
+	}
+	if t == nil {
+		panic(bailout{})
+	}
+	return t
+}
+
+func (c *checker) ref(v *ast.VarRef, s *scope) types.Type {
+	t := c.lookup(v, s)
+	c.info.Types[v] = t
+	return t
+}
+
+func (c *checker) inferExpr(e ast.Expr, s *scope) types.Type {
+	switch e := e.(type) {
+	case *ast.StringLit:
+		return types.String
+	case *ast.IntLit:
+		name := e.TypeToken.Value()
+		t, ok := types.Prim(name).(*types.IntType)
+		if !ok {
+			c.errorIn(e, "Integer literal must have integer type but got %s", name)
+		}
+		if _, err := value.ParseInt(t, e.ValueToken.Value()); err != nil {
+			c.errorIn(e, "%s", err)
+		}
+		return t
+	case *ast.BNumLit:
+		if _, err := value.ParseBNum(e.ValueToken.Value()); err != nil {
+			c.errorIn(e, "%s", err)
+		}
+		return types.BNum
+	case *ast.HexLit:
+		b, err := value.ParseHex(e.Token.Value())
+		if err != nil {
+			c.errorIn(e, "%s", err)
+		}
+		return b.Type()
+	case *ast.EmpLit:
+		return &types.MapType{Key: c.typ(e.Key), Value: c.typ(e.Value)}
+	case *ast.VarRef:
+		return c.lookup(e, s)
+	case *ast.Let:
+		t := c.let(e.Type, e.Bound, s)
+		c.declare(e.Ident, t)
+		return c.expr(e.Body, s.bind(e.Ident.Symbol.Name, t))
+	case *ast.Fun:
+		param := c.typ(e.Param.Type)
+		c.declare(e.Param.Ident, param)
+		ret := c.expr(e.Body, s.bind(e.Param.Ident.Symbol.Name, param))
+		return &types.FunType{Param: param, Ret: ret}
+	case *ast.TFun:
+		tv := e.TVar.Symbol.Name
+		n := len(c.tvars)
+		c.tvars = append(c.tvars, tv)
+		defer func() { c.tvars = c.tvars[:n] }()
+		return &types.PolyType{TVar: tv, Body: c.expr(e.Body, s)}
+	case *ast.App:
+		return c.app(e, s)
+	case *ast.TApp:
+		t := c.ref(e.Func, s)
+		args := make([]types.Type, 0, len(e.Types))
+		for _, a := range e.Types {
+			arg := c.typ(a)
+			p, ok := t.(*types.PolyType)
+			if !ok {
+				c.errorIn(a, "%s of type %s cannot be instantiated with type %s", e.Func.Symbol.DisplayName, t, arg)
+			}
+			t = p.Instantiate(arg)
+			args = append(args, arg)
+		}
+		c.info.TypeArgs[e] = args
+		return t
+	case *ast.Builtin:
+		args := make([]types.Type, 0, len(e.Args))
+		for _, a := range e.Args {
+			args = append(args, c.ref(a, s))
+		}
+		return c.builtin(e, args)
+	case *ast.Constr:
+		return c.constr(e, s)
+	case *ast.Message:
+		return c.message(e, s)
+	case *ast.Match:
+		target := c.ref(e.Target, s)
+		var ret types.Type
+		for _, arm := range e.Arms {
+			t := c.expr(arm.Body, c.pattern(arm.Pattern, target, s))
+			if ret == nil {
+				ret = t
+			} else if !types.Assignable(ret, t) {
+				c.mismatch(arm.Body, "match arm", ret, t)
+			}
+		}
+		if ret == nil {
+			c.errorIn(e, "Match expression must have at least one arm")
+		}
+		c.arms(e, target, matchPatterns(e.Arms))
+		return ret
+	}
+	c.errorIn(e, "%s is not a valid expression", e.Name())
+	return nil
+}
+
+func (c *checker) app(e *ast.App, s *scope) types.Type {
+	t := c.ref(e.Func, s)
+	for i, a := range e.Args {
+		f, ok := t.(*types.FunType)
+		if !ok {
+			if _, ok := t.(*types.PolyType); ok {
+				c.errorIn(e.Func, "%s of type %s must be instantiated with @ before applying arguments", e.Func.Symbol.DisplayName, t)
+			}
+			c.errorIn(a, "%s takes %d arguments but %d given", e.Func.Symbol.DisplayName, i, len(e.Args))
+		}
+		if at := c.ref(a, s); !types.Assignable(f.Param, at) {
+			c.mismatch(a, "argument of "+e.Func.Symbol.DisplayName, f.Param, at)
+		}
+		t = f.Ret
+	}
+	return t
+}
+
+func (c *checker) constr(e *ast.Constr, s *scope) types.Type {
+	name := e.Ident.Symbol.Name
+	def, ok := c.ctors[name]
+	if !ok {
+		c.errorIn(e.Ident, "Undefined constructor %s", e.Ident.Symbol.DisplayName)
+	}
+	if len(e.TypeArgs) != len(def.TParams) {
+		c.errorIn(e, "Constructor %s takes %d type arguments but %d given", e.Ident.Symbol.DisplayName, len(def.TParams), len(e.TypeArgs))
+	}
+	targs := make([]types.Type, 0, len(e.TypeArgs))
+	for _, a := range e.TypeArgs {
+		targs = append(targs, c.typ(a))
+	}
+	params := def.CtorArgs(def.Ctor(unqualified(name)), targs)
+	if len(e.Args) != len(params) {
+		c.errorIn(e, "Constructor %s takes %d arguments but %d given", e.Ident.Symbol.DisplayName, len(params), len(e.Args))
+	}
+	for i, a := range e.Args {
+		if t := c.ref(a, s); !types.Assignable(params[i], t) {
+			c.mismatch(a, "argument of constructor "+e.Ident.Symbol.DisplayName, params[i], t)
+		}
+	}
+	return &types.ADT{Name: def.Name, Args: targs}
+}
+
+// Types of reserved keys of message literals
+var messageKeys = map[string]types.Type{
+	"_tag":       types.String,
+	"_recipient": types.ByStr20,
+	"_amount":    types.Uint128,
+	"_eventname": types.String,
+	"_exception": types.String,
+}
+
+// message infers the type of message literal. A literal with `_eventname` is an event and a
+// literal with `_exception` is an exception.
+func (c *checker) message(e *ast.Message, s *scope) types.Type {
+	var ret types.Type = types.Message
+	seen := map[string]bool{}
+	for _, ent := range e.Entries {
+		key := ent.Key.Value()
+		if seen[key] {
+			c.errorIn(ent.Value, "Key %s is duplicated in message", key)
+		}
+		seen[key] = true
+		t := c.expr(ent.Value, s)
+		if want, ok := messageKeys[key]; ok && !types.Assignable(want, t) {
+			c.mismatch(ent.Value, "value of "+key, want, t)
+		}
+		if _, ok := t.(*types.FunType); ok {
+			c.errorIn(ent.Value, "Function of type %s cannot be a value of message", t)
+		}
+		switch key {
+		case "_eventname":
+			ret = types.Event
+		case "_exception":
+			ret = types.Exception
+		}
+	}
+	return ret
+}
+
+// matchPatterns returns the patterns of the match arms.
+func matchPatterns(arms []*ast.MatchArm) []ast.Pattern {
+	ps := make([]ast.Pattern, 0, len(arms))
+	for _, a := range arms {
+		ps = append(ps, a.Pattern)
+	}
+	return ps
+}
+
+// pattern checks the pattern against the type of matched value and returns the scope extended
+// with binders in the pattern.
+func (c *checker) pattern(p ast.Pattern, t types.Type, s *scope) *scope {
+	seen := map[string]bool{}
+	return c.subPattern(p, t, s, seen)
+}
+
+func (c *checker) subPattern(p ast.Pattern, t types.Type, s *scope, seen map[string]bool) *scope {
+	switch p := p.(type) {
+	case *ast.WildcardPattern:
+		return s
+	case *ast.BinderPattern:
+		name := p.Ident.Symbol.Name
+		if seen[name] {
+			c.errorIn(p, "Variable %s is bound more than once in pattern", p.Ident.Symbol.DisplayName)
+		}
+		seen[name] = true
+		c.declare(p.Ident, t)
+		return s.bind(name, t)
+	case *ast.ConstrPattern:
+		name := p.Ctor.Symbol.Name
+		def, ok := c.ctors[name]
+		if !ok {
+			c.errorIn(p.Ctor, "Undefined constructor %s", p.Ctor.Symbol.DisplayName)
+		}
+		adt, ok := t.(*types.ADT)
+		if !ok || adt.Name != def.Name || len(adt.Args) != len(def.TParams) {
+			c.errorIn(p, "Constructor %s of type %s cannot match value of type %s", p.Ctor.Symbol.DisplayName, def.Name, t)
+		}
+		args := def.CtorArgs(def.Ctor(unqualified(name)), adt.Args)
+		if len(p.Args) != len(args) {
+			c.errorIn(p, "Constructor %s takes %d arguments but %d given in pattern", p.Ctor.Symbol.DisplayName, len(args), len(p.Args))
+		}
+		for i, a := range p.Args {
+			s = c.subPattern(a, args[i], s, seen)
+		}
+		return s
+	}
+	c.errorIn(p, "%s is not a valid pattern", p.Name())
+	return nil
+}
@@ -0,0 +1,138 @@
+package checker
+
+import (
+	"fmt"
+	"github.com/rhysd/locerr"
+	"goscilla/ast"
+	"goscilla/types"
+	"strings"
+)
+
+// Importer resolves libraries imported by `import` statements.
+type Importer interface {
+	// Import returns the type checked library of the name. When the library has errors, they
+	// should be returned as ErrorList.
+	Import(name string) (*Library, error)
+}
+
+// Library is a type checked library which can be imported by other modules.
+type Library struct {
+	Name string
+	AST  *ast.AST
+	Info *Info
+	// Types of entries defined by `let` in the library
+	Values map[string]types.Type
+	// ADTs defined by `type` in the library
+	ADTs map[string]*types.ADTDef
+}
+
+// CheckLibrary type checks the module which only consists of a library such as .scillib file and
+// returns it so that other modules can import it.
+func (conf *Config) CheckLibrary(a *ast.AST) (*Library, error) {
+	if a.Library == nil {
+		return nil, locerr.ErrorIn(a.Pos(), a.End(), "Library module must declare library")
+	}
+	if a.Contract != nil || len(a.Components) > 0 {
+		return nil, locerr.ErrorIn(a.Pos(), a.End(), fmt.Sprintf("Library module %s must not contain contract", a.Library.Ident.Symbol.DisplayName))
+	}
+	info, err := conf.Check(a)
+	if err != nil {
+		return nil, err
+	}
+	lib := &Library{
+		Name:   a.Library.Ident.Symbol.Name,
+		AST:    a,
+		Info:   info,
+		Values: map[string]types.Type{},
+		ADTs:   map[string]*types.ADTDef{},
+	}
+	for _, e := range a.Library.Entries {
+		switch e := e.(type) {
+		case *ast.LetDecl:
+			lib.Values[e.Ident.Symbol.Name] = info.Defs[e.Ident]
+		case *ast.TypeDecl:
+			lib.ADTs[e.Ident.Symbol.Name] = info.ADTs[e.Ident.Symbol.Name]
+		}
+	}
+	return lib, nil
+}
+
+// unqualified returns the name without alias of library. For example, `F.entry` is `entry`.
+func unqualified(name string) string {
+	return name[strings.LastIndexByte(name, '.')+1:]
+}
+
+// importLib makes entries and types of the imported library visible in the module. When the
+// library is imported with alias F, its names are only visible as qualified names like `F.entry`.
+func (c *checker) importLib(n *ast.ImportName) {
+	name := n.Lib.Symbol.DisplayName
+	if c.conf.Importer == nil {
+		c.errorIn(n.Lib, "Library %s cannot be imported since no library is available", name)
+	}
+	for prev := range c.info.Imports {
+		if prev.Lib.Symbol.Name == n.Lib.Symbol.Name {
+			c.errorIn(n.Lib, "Library %s is already imported", name)
+		}
+		if n.Alias != nil && prev.Alias != nil && prev.Alias.Symbol.Name == n.Alias.Symbol.Name {
+			c.errorIn(n.Alias, "Alias %s is already used for library %s", n.Alias.Symbol.DisplayName, prev.Lib.Symbol.DisplayName)
+		}
+	}
+	lib, err := c.conf.Importer.Import(n.Lib.Symbol.Name)
+	if err != nil {
+		if errs, ok := err.(ErrorList); ok {
+			c.addLibErrs(errs)
+			c.errorIn(n.Lib, "Library %s cannot be imported since it has errors", name)
+		}
+		c.errorIn(n.Lib, "Library %s cannot be imported. %s", name, err)
+	}
+
+	prefix := ""
+	if n.Alias != nil {
+		prefix = n.Alias.Symbol.Name + "."
+	}
+	// Check all names before adding them so that the library is imported entirely or not at all.
+	// ADTs are identified by their names regardless of alias
+	conflict := func(key, what string) {
+		if from, ok := c.origins[key]; ok {
+			c.errorIn(n.Lib, "Library %s cannot be imported since %s is also defined in library %s", name, what, from)
+		}
+	}
+	for _, d := range lib.ADTs {
+		conflict("type "+d.Name, "type "+d.Name)
+		for _, ctor := range d.Ctors {
+			conflict("ctor "+prefix+ctor.Name, "constructor "+prefix+ctor.Name)
+		}
+	}
+	for v := range lib.Values {
+		conflict("value "+prefix+v, prefix+v)
+	}
+
+	c.info.Imports[n] = lib
+	for _, d := range lib.ADTs {
+		c.origins["type "+d.Name] = lib.Name
+		c.info.ADTs[d.Name] = d
+		c.adts[prefix+d.Name] = d
+		for _, ctor := range d.Ctors {
+			c.origins["ctor "+prefix+ctor.Name] = lib.Name
+			c.ctors[prefix+ctor.Name] = d
+		}
+	}
+	for v, t := range lib.Values {
+		c.origins["value "+prefix+v] = lib.Name
+		c.globals[prefix+v] = t
+	}
+}
+
+// addLibErrs adds errors in imported library. The same error is added only once even if the
+// library is imported by other imported libraries.
+func (c *checker) addLibErrs(errs ErrorList) {
+Outer:
+	for _, e := range errs {
+		for _, have := range c.libErrs {
+			if have == e {
+				continue Outer
+			}
+		}
+		c.libErrs = append(c.libErrs, e)
+	}
+}
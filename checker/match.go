@@ -0,0 +1,169 @@
+package checker
+
+import (
+	"goscilla/ast"
+	"goscilla/types"
+	"strings"
+)
+
+// pat is a pattern simplified for checking exhaustiveness and redundancy of match arms. Binders are
+// wildcards whose names are kept only for messages.
+type pat struct {
+	ctor string // empty for wildcard
+	name string // name of binder
+	args []*pat
+}
+
+func wildcard() *pat {
+	return &pat{}
+}
+
+func simplify(p ast.Pattern) *pat {
+	switch p := p.(type) {
+	case *ast.BinderPattern:
+		return &pat{name: p.Ident.Symbol.DisplayName}
+	case *ast.ConstrPattern:
+		args := make([]*pat, 0, len(p.Args))
+		for _, a := range p.Args {
+			args = append(args, simplify(a))
+		}
+		return &pat{ctor: unqualified(p.Ctor.Symbol.Name), args: args}
+	}
+	return wildcard()
+}
+
+// String returns the pattern in Scilla syntax such as "Some (Pair _ x)".
+func (p *pat) String() string {
+	if p.ctor == "" {
+		if p.name != "" {
+			return p.name
+		}
+		return "_"
+	}
+	ss := []string{p.ctor}
+	for _, a := range p.args {
+		s := a.String()
+		if len(a.args) > 0 {
+			s = "(" + s + ")"
+		}
+		ss = append(ss, s)
+	}
+	return strings.Join(ss, " ")
+}
+
+// arms reports arms which are never reached and a value which no arm matches. t is the type of the
+// matched value. Patterns must be already type checked.
+func (c *checker) arms(match ast.Node, t types.Type, ps []ast.Pattern) {
+	rows := make([][]*pat, 0, len(ps))
+	ts := []types.Type{t}
+	for _, p := range ps {
+		row := []*pat{simplify(p)}
+		if _, ok := c.useful(rows, row, ts); !ok {
+			c.report(p, "Pattern %s is unreachable since values matching it are matched by previous arms", row[0])
+		}
+		rows = append(rows, row)
+	}
+	if w, ok := c.useful(rows, []*pat{wildcard()}, ts); ok {
+		c.report(match, "Match is not exhaustive. For example, a value matching pattern %s is not matched by any arm", w[0])
+	}
+}
+
+// report records the error without aborting the check
+func (c *checker) report(n ast.Node, format string, args ...interface{}) {
+	c.try(func() { c.errorIn(n, format, args...) })
+}
+
+// ctorArgs returns the types of arguments of the constructor of the ADT type.
+func (c *checker) ctorArgs(t types.Type, ctor string) []types.Type {
+	adt := t.(*types.ADT)
+	def := c.info.ADTs[adt.Name]
+	return def.CtorArgs(def.Ctor(ctor), adt.Args)
+}
+
+// useful returns whether some value matching the row of patterns q is not matched by any of rows.
+// ts are the types of the columns. When q is useful, it also returns the patterns of such a value.
+// This is the algorithm described in "Warnings for pattern matching" by Luc Maranget.
+func (c *checker) useful(rows [][]*pat, q []*pat, ts []types.Type) ([]*pat, bool) {
+	if len(q) == 0 {
+		return []*pat{}, len(rows) == 0
+	}
+
+	if q[0].ctor != "" {
+		return c.usefulCtor(rows, q, ts, q[0].ctor)
+	}
+
+	var def *types.ADTDef
+	if adt, ok := ts[0].(*types.ADT); ok {
+		def = c.info.ADTs[adt.Name]
+	}
+	used := map[string]bool{}
+	for _, r := range rows {
+		if r[0].ctor != "" {
+			used[r[0].ctor] = true
+		}
+	}
+
+	if def != nil && len(used) == len(def.Ctors) {
+		// All constructors appear in the column. q is useful when it is useful for some constructor.
+		for _, ctor := range def.Ctors {
+			if w, ok := c.usefulCtor(rows, q, ts, ctor.Name); ok {
+				return w, true
+			}
+		}
+		return nil, false
+	}
+
+	// Some constructors are missing in the column. Only rows starting with wildcard can match them.
+	var rest [][]*pat
+	for _, r := range rows {
+		if r[0].ctor == "" {
+			rest = append(rest, r[1:])
+		}
+	}
+	w, ok := c.useful(rest, q[1:], ts[1:])
+	if !ok {
+		return nil, false
+	}
+	head := wildcard()
+	if def != nil {
+		for _, ctor := range def.Ctors {
+			if !used[ctor.Name] {
+				head = &pat{ctor: ctor.Name}
+				for range ctor.Args {
+					head.args = append(head.args, wildcard())
+				}
+				break
+			}
+		}
+	}
+	return append([]*pat{head}, w...), true
+}
+
+// usefulCtor is useful for q whose first pattern is specialized with the constructor.
+func (c *checker) usefulCtor(rows [][]*pat, q []*pat, ts []types.Type, ctor string) ([]*pat, bool) {
+	args := c.ctorArgs(ts[0], ctor)
+	specialize := func(r []*pat) []*pat {
+		s := make([]*pat, 0, len(args)+len(r)-1)
+		if r[0].ctor == "" {
+			for range args {
+				s = append(s, wildcard())
+			}
+		} else {
+			s = append(s, r[0].args...)
+		}
+		return append(s, r[1:]...)
+	}
+
+	var spec [][]*pat
+	for _, r := range rows {
+		if r[0].ctor == "" || r[0].ctor == ctor {
+			spec = append(spec, specialize(r))
+		}
+	}
+	w, ok := c.useful(spec, specialize(q), append(append([]types.Type{}, args...), ts[1:]...))
+	if !ok {
+		return nil, false
+	}
+	head := &pat{ctor: ctor, args: w[:len(args)]}
+	return append([]*pat{head}, w[len(args):]...), true
+}
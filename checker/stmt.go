@@ -0,0 +1,203 @@
+package checker
+
+import (
+	"goscilla/ast"
+	"goscilla/types"
+)
+
+// stmtChecker checks statements in body of a component.
+type stmtChecker struct {
+	*checker
+	fields map[string]types.Type
+}
+
+// stmts checks the statements in order. Each statement is checked even if previous statements
+// have errors. Variables bound by statements which have errors are not reported when used later.
+func (c *stmtChecker) stmts(ss []ast.Stmt, s *scope) {
+	for _, st := range ss {
+		next := s
+		if !c.try(func() { next = c.stmt(st, s) }) {
+			if i := boundIdent(st); i != nil {
+				next = s.bind(i.Symbol.Name, nil)
+			}
+		}
+		s = next
+	}
+}
+
+// boundIdent returns the variable bound by the statement, or nil when it binds nothing.
+func boundIdent(s ast.Stmt) *ast.Ident {
+	switch s := s.(type) {
+	case *ast.Load:
+		return s.Ident
+	case *ast.RemoteLoad:
+		return s.Ident
+	case *ast.Bind:
+		return s.Ident
+	case *ast.MapGet:
+		return s.Ident
+	case *ast.RemoteMapGet:
+		return s.Ident
+	case *ast.ReadFromBC:
+		return s.Ident
+	}
+	return nil
+}
+
+func (c *stmtChecker) field(f *ast.VarRef) types.Type {
+	t, ok := c.fields[f.Symbol.Name]
+	if !ok {
+		c.errorIn(f, "Undefined field %s", f.Symbol.DisplayName)
+	}
+	if t == nil {
+		panic(bailout{})
+	}
+	return t
+}
+
+func (c *stmtChecker) bind(i *ast.Ident, t types.Type, s *scope) *scope {
+	c.declare(i, t)
+	return s.bind(i.Symbol.Name, t)
+}
+
+// mapValue returns the type of the value in the map accessed with the keys. When not all keys are
+// given, the type is a nested map.
+func (c *stmtChecker) mapValue(m string, t types.Type, keys []*ast.MapKey, s *scope) types.Type {
+	for i, k := range keys {
+		mt, ok := t.(*types.MapType)
+		if !ok {
+			c.errorIn(k, "%s cannot be accessed with %d keys since the value of type %s is not a map", m, i+1, t)
+		}
+		if kt := c.ref(k.Key, s); !types.Assignable(mt.Key, kt) {
+			c.mismatch(k, "map key", mt.Key, kt)
+		}
+		t = mt.Value
+	}
+	return t
+}
+
+// remoteField returns the type of the field of contract at the address.
+func (c *stmtChecker) remoteField(addr *ast.VarRef, f *ast.Ident, s *scope) types.Type {
+	t := c.ref(addr, s)
+	at, ok := t.(*types.AddressType)
+	if !ok {
+		c.errorIn(addr, "Remote state can only be read via address type but %s has type %s", addr.Symbol.DisplayName, t)
+	}
+	ft := at.RemoteField(f.Symbol.Name)
+	if ft == nil {
+		c.errorIn(f, "Field %s is not declared in address type %s", f.Symbol.DisplayName, t)
+	}
+	return ft
+}
+
+func (c *stmtChecker) procedure(p *ast.VarRef) *proc {
+	pr, ok := c.procs[p.Symbol.Name]
+	if !ok {
+		c.errorIn(p, "Undefined procedure %s", p.Symbol.DisplayName)
+	}
+	if pr == nil {
+		panic(bailout{})
+	}
+	return pr
+}
+
+// stmt checks the statement and returns the scope extended with the variable it binds.
+func (c *stmtChecker) stmt(st ast.Stmt, s *scope) *scope {
+	switch st := st.(type) {
+	case *ast.Load:
+		return c.bind(st.Ident, c.field(st.Field), s)
+	case *ast.RemoteLoad:
+		return c.bind(st.Ident, c.remoteField(st.Addr, st.Field, s), s)
+	case *ast.Store:
+		if st.Field.Symbol.Name == "_balance" {
+			c.errorIn(st.Field, "Implicit field _balance cannot be modified")
+		}
+		want, have := c.field(st.Field), c.ref(st.Value, s)
+		if !types.Assignable(want, have) {
+			c.mismatch(st.Value, "store to field "+st.Field.Symbol.DisplayName, want, have)
+		}
+	case *ast.Bind:
+		return c.bind(st.Ident, c.expr(st.Value, s), s)
+	case *ast.MapUpdate:
+		want := c.mapValue(st.Map.Symbol.DisplayName, c.field(st.Map), st.Keys, s)
+		if have := c.ref(st.Value, s); !types.Assignable(want, have) {
+			c.mismatch(st.Value, "update of map "+st.Map.Symbol.DisplayName, want, have)
+		}
+	case *ast.MapDelete:
+		c.mapValue(st.Map.Symbol.DisplayName, c.field(st.Map), st.Keys, s)
+	case *ast.MapGet:
+		t := c.mapValue(st.Map.Symbol.DisplayName, c.field(st.Map), st.Keys, s)
+		if st.ExistsToken != nil {
+			return c.bind(st.Ident, types.Bool, s)
+		}
+		return c.bind(st.Ident, types.Option(t), s)
+	case *ast.RemoteMapGet:
+		t := c.mapValue(st.Map.Symbol.DisplayName, c.remoteField(st.Addr, st.Map, s), st.Keys, s)
+		if st.ExistsToken != nil {
+			return c.bind(st.Ident, types.Bool, s)
+		}
+		return c.bind(st.Ident, types.Option(t), s)
+	case *ast.ReadFromBC:
+		switch q := st.Query.Value(); q {
+		case "BLOCKNUMBER":
+			return c.bind(st.Ident, types.BNum, s)
+		case "CHAINID":
+			return c.bind(st.Ident, types.Uint32, s)
+		default:
+			c.errorIn(st, "Unknown blockchain query %s", q)
+		}
+	case *ast.Accept:
+		// Nothing to check
+	case *ast.Send:
+		if t := c.ref(st.Msgs, s); !types.Assignable(types.List(types.Message), t) {
+			c.mismatch(st.Msgs, "send", types.List(types.Message), t)
+		}
+	case *ast.Event:
+		if t := c.ref(st.Event, s); !types.Assignable(types.Event, t) {
+			c.mismatch(st.Event, "event", types.Event, t)
+		}
+	case *ast.Throw:
+		if st.Exception != nil {
+			if t := c.ref(st.Exception, s); !types.Assignable(types.Exception, t) {
+				c.mismatch(st.Exception, "throw", types.Exception, t)
+			}
+		}
+	case *ast.MatchStmt:
+		target := c.ref(st.Target, s)
+		for _, arm := range st.Arms {
+			// Variables bound in arms are not visible after the match
+			c.stmts(arm.Body, c.pattern(arm.Pattern, target, s))
+		}
+		ps := make([]ast.Pattern, 0, len(st.Arms))
+		for _, a := range st.Arms {
+			ps = append(ps, a.Pattern)
+		}
+		c.arms(st, target, ps)
+	case *ast.CallProc:
+		p := c.procedure(st.Proc)
+		if len(st.Args) != len(p.params) {
+			c.errorIn(st, "Procedure %s takes %d arguments but %d given", st.Proc.Symbol.DisplayName, len(p.params), len(st.Args))
+		}
+		for i, a := range st.Args {
+			if t := c.ref(a, s); !types.Assignable(p.params[i], t) {
+				c.mismatch(a, "argument of procedure "+st.Proc.Symbol.DisplayName, p.params[i], t)
+			}
+		}
+	case *ast.Iterate:
+		t := c.ref(st.List, s)
+		l, ok := t.(*types.ADT)
+		if !ok || l.Name != "List" {
+			c.errorIn(st.List, "forall can only iterate over list but %s has type %s", st.List.Symbol.DisplayName, t)
+		}
+		p := c.procedure(st.Proc)
+		if len(p.params) != 1 {
+			c.errorIn(st.Proc, "Procedure %s iterated by forall must take 1 argument but takes %d", st.Proc.Symbol.DisplayName, len(p.params))
+		}
+		if !types.Assignable(p.params[0], l.Args[0]) {
+			c.mismatch(st.List, "forall over procedure "+st.Proc.Symbol.DisplayName, p.params[0], l.Args[0])
+		}
+	default:
+		c.errorIn(st, "%s is not a valid statement", st.Name())
+	}
+	return s
+}
@@ -0,0 +1,79 @@
+package checker
+
+import (
+	"goscilla/ast"
+	"goscilla/token"
+	"goscilla/types"
+)
+
+// typ converts the type annotation to a type. Type variables in it must be bound by enclosing
+// `tfun` or `forall`.
+func (c *checker) typ(t ast.Type) types.Type {
+	switch t := t.(type) {
+	case *ast.PrimType:
+		name := t.Token.Value()
+		if p := types.Prim(name); p != nil {
+			return p
+		}
+		c.errorIn(t, "Unknown primitive type %s", name)
+	case *ast.MapType:
+		return &types.MapType{Key: c.typ(t.Key), Value: c.typ(t.Value)}
+	case *ast.FunType:
+		return &types.FunType{Param: c.typ(t.Param), Ret: c.typ(t.Ret)}
+	case *ast.PolyType:
+		tv := t.TVar.Symbol.Name
+		n := len(c.tvars)
+		c.tvars = append(c.tvars, tv)
+		defer func() { c.tvars = c.tvars[:n] }()
+		return &types.PolyType{TVar: tv, Body: c.typ(t.Body)}
+	case *ast.TypeVar:
+		name := t.Token.Value()
+		for _, tv := range c.tvars {
+			if tv == name {
+				return types.Var(name)
+			}
+		}
+		c.errorIn(t, "Type variable %s is not bound", name)
+	case *ast.ADTType:
+		name := t.Ident.Symbol.Name
+		if p := types.Prim(name); p != nil && len(t.Args) == 0 {
+			return p
+		}
+		def, ok := c.adts[name]
+		if !ok {
+			c.errorIn(t.Ident, "Type %s is not defined", t.Ident.Symbol.DisplayName)
+		}
+		if len(t.Args) != len(def.TParams) {
+			c.errorIn(t, "Type %s takes %d type arguments but %d given", t.Ident.Symbol.DisplayName, len(def.TParams), len(t.Args))
+		}
+		args := make([]types.Type, 0, len(t.Args))
+		for _, a := range t.Args {
+			args = append(args, c.typ(a))
+		}
+		return &types.ADT{Name: def.Name, Args: args}
+	case *ast.AddressType:
+		if b := t.ByStrToken.Value(); b != "ByStr20" {
+			c.errorIn(t, "Address type must be ByStr20 but got %s", b)
+		}
+		if t.KindToken == nil {
+			return &types.AddressType{Kind: types.AnyAddr}
+		}
+		if t.KindToken.Kind == token.LIBRARY {
+			return &types.AddressType{Kind: types.LibAddr}
+		}
+		fs := make([]*types.AddressField, 0, len(t.Fields))
+		seen := map[string]bool{}
+		for _, f := range t.Fields {
+			name := f.Ident.Symbol.Name
+			if seen[name] {
+				c.errorIn(f.Ident, "Field %s is duplicated in address type", f.Ident.Symbol.DisplayName)
+			}
+			seen[name] = true
+			fs = append(fs, &types.AddressField{Name: name, Type: c.typ(f.Type)})
+		}
+		return types.ContractAddress(fs...)
+	default:
+		c.errorIn(t, "%s is not a valid type", t.Name())
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package cst
+
+import (
+	"github.com/rhysd/locerr"
+	"goscilla/ast"
+	"goscilla/syntax"
+	"goscilla/token"
+	"math"
+	"sort"
+)
+
+// span is a range of AST node in source. order is the order in which the node was visited.
+type span struct {
+	node       ast.Node
+	start, end int
+	order      int
+}
+
+// spanCollector is a visitor to collect ranges of all nodes in AST
+type spanCollector struct {
+	spans []span
+}
+
+func (c *spanCollector) VisitTopdown(n ast.Node) ast.Visitor {
+	c.spans = append(c.spans, span{n, n.Pos().Offset, n.End().Offset, len(c.spans)})
+	return c
+}
+
+func (c *spanCollector) VisitBottomup(ast.Node) {}
+
+func collectSpans(a *ast.AST) []span {
+	c := &spanCollector{}
+	if a != nil {
+		if a.Version != nil {
+			ast.Visit(c, a.Version)
+		}
+		for _, i := range a.Imports {
+			ast.Visit(c, i)
+		}
+		if a.Library != nil {
+			ast.Visit(c, a.Library)
+		}
+		if a.Contract != nil {
+			ast.Visit(c, a.Contract)
+		}
+		for _, comp := range a.Components {
+			ast.Visit(c, comp)
+		}
+		for _, d := range a.BadDecls {
+			ast.Visit(c, d)
+		}
+	}
+	// Outer nodes come first. Nodes which have the same range are ordered as visited (parent first).
+	sort.SliceStable(c.spans, func(i, j int) bool {
+		l, r := c.spans[i], c.spans[j]
+		if l.start != r.start {
+			return l.start < r.start
+		}
+		if l.end != r.end {
+			return l.end > r.end
+		}
+		return l.order < r.order
+	})
+	return c.spans
+}
+
+func isTrivia(k token.Kind) bool {
+	return k == token.WHITESPACE || k == token.NEWLINE || k == token.COMMENT
+}
+
+// attachTrivia converts lexed tokens into significant tokens with their leading and trailing trivia.
+func attachTrivia(tokens []token.Token) []*Token {
+	var (
+		toks    []*Token
+		pending []*token.Token
+		last    *Token
+		inLine  bool
+	)
+	for i := range tokens {
+		t := &tokens[i]
+		if isTrivia(t.Kind) {
+			if inLine {
+				last.Trailing = append(last.Trailing, t)
+				inLine = t.Kind != token.NEWLINE
+			} else {
+				pending = append(pending, t)
+			}
+			continue
+		}
+		if t.Start.Offset == t.End.Offset && t.Kind != token.EOF {
+			// Lexer may emit an empty illegal token on error. It has no byte to keep.
+			continue
+		}
+		last = &Token{Leading: pending, Token: t}
+		toks = append(toks, last)
+		pending, inLine = nil, true
+	}
+	if len(pending) > 0 && last != nil {
+		// Token stream ended without EOF
+		last.Trailing = append(last.Trailing, pending...)
+	}
+	return toks
+}
+
+// Build builds a concrete syntax tree from the AST and tokens lexed from the same source.
+// tokens must contain all tokens emitted by the lexer including whitespaces, newlines and comments.
+// The returned node is the root of the tree. Its AST field is nil.
+func Build(a *ast.AST, tokens []token.Token) *Node {
+	spans := collectSpans(a)
+	root := &Node{}
+	nodes, ends := []*Node{root}, []int{math.MaxInt32}
+
+	// Close nodes which end at or before the offset
+	closeTo := func(offset int) {
+		for len(nodes) > 1 && ends[len(ends)-1] <= offset {
+			nodes, ends = nodes[:len(nodes)-1], ends[:len(ends)-1]
+		}
+	}
+
+	// Open nodes which start at or before the offset
+	next := 0
+	openTo := func(offset int) {
+		for next < len(spans) && spans[next].start <= offset {
+			s := spans[next]
+			next++
+			closeTo(s.start)
+			n := &Node{AST: s.node}
+			parent := nodes[len(nodes)-1]
+			parent.Children = append(parent.Children, n)
+			nodes, ends = append(nodes, n), append(ends, s.end)
+		}
+	}
+
+	for _, t := range attachTrivia(tokens) {
+		offset := t.Token.Start.Offset
+		openTo(offset)
+		closeTo(offset)
+		parent := nodes[len(nodes)-1]
+		parent.Children = append(parent.Children, t)
+	}
+	openTo(math.MaxInt32)
+
+	return root
+}
+
+func lex(src *locerr.Source) []token.Token {
+	l := syntax.NewLexer(src)
+	go l.Lex()
+	var toks []token.Token
+	for t := range l.Tokens {
+		toks = append(toks, t)
+		if t.Kind == token.EOF {
+			break
+		}
+	}
+	return toks
+}
+
+// Parse parses the source into a concrete syntax tree. Like syntax.ParsePartial, the tree is
+// built even if the source contains errors. All errors in the source are returned.
+func Parse(src *locerr.Source) (*Node, syntax.ErrorList) {
+	a, errs := syntax.ParsePartial(src)
+	return Build(a, lex(src)), errs
+}
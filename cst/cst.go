@@ -0,0 +1,157 @@
+// Package cst provides a lossless concrete syntax tree of Scilla source.
+//
+// While ast drops whitespaces, newlines and comments, a concrete syntax tree keeps every token
+// lexed from the source. Trivia (whitespaces, newlines and comments) are attached to the nearest
+// significant token and significant tokens are put into the innermost AST node covering them.
+// Writing the tree back gives exactly the original bytes of the source.
+package cst
+
+import (
+	"bytes"
+	"github.com/rhysd/locerr"
+	"goscilla/ast"
+	"goscilla/token"
+	"io"
+)
+
+// Element is an element of concrete syntax tree. It is *Node or *Token.
+type Element interface {
+	io.WriterTo
+	// Pos returns the start position of the element excluding trivia.
+	Pos() locerr.Pos
+	// End returns the end position of the element excluding trivia.
+	End() locerr.Pos
+	elem()
+}
+
+// Token is a significant token with trivia around it.
+// Leading trivia are whitespaces, newlines and comments between the previous token's trailing
+// trivia and the token. Trailing trivia are whitespaces and comments following the token on the
+// same line, including the newline which ends the line.
+type Token struct {
+	Leading  []*token.Token
+	Token    *token.Token
+	Trailing []*token.Token
+}
+
+// Node is a node of concrete syntax tree which corresponds to an AST node.
+// Children contains tokens and child nodes in source order. Tokens which are not covered by any
+// AST node, such as parentheses around an expression, belong to the nearest enclosing node.
+type Node struct {
+	// AST is the corresponding AST node. It is nil for the root node.
+	AST      ast.Node
+	Children []Element
+}
+
+func (t *Token) Pos() locerr.Pos {
+	return t.Token.Start
+}
+func (t *Token) End() locerr.Pos {
+	return t.Token.End
+}
+
+// Pos returns the start position of the first token in the node. When the node contains no token,
+// the start position of its AST node is returned.
+func (n *Node) Pos() locerr.Pos {
+	if t := n.FirstToken(); t != nil {
+		return t.Pos()
+	}
+	return n.AST.Pos()
+}
+
+// End returns the end position of the last token in the node. When the node contains no token,
+// the end position of its AST node is returned.
+func (n *Node) End() locerr.Pos {
+	if t := n.LastToken(); t != nil {
+		return t.End()
+	}
+	return n.AST.End()
+}
+
+// FirstToken returns the first token in the node. It returns nil when the node contains no token.
+func (n *Node) FirstToken() *Token {
+	for _, c := range n.Children {
+		switch c := c.(type) {
+		case *Token:
+			return c
+		case *Node:
+			if t := c.FirstToken(); t != nil {
+				return t
+			}
+		}
+	}
+	return nil
+}
+
+// LastToken returns the last token in the node. It returns nil when the node contains no token.
+func (n *Node) LastToken() *Token {
+	for i := len(n.Children) - 1; i >= 0; i-- {
+		switch c := n.Children[i].(type) {
+		case *Token:
+			return c
+		case *Node:
+			if t := c.LastToken(); t != nil {
+				return t
+			}
+		}
+	}
+	return nil
+}
+
+// Tokens returns all significant tokens in the node in source order.
+func (n *Node) Tokens() []*Token {
+	var toks []*Token
+	for _, c := range n.Children {
+		switch c := c.(type) {
+		case *Token:
+			toks = append(toks, c)
+		case *Node:
+			toks = append(toks, c.Tokens()...)
+		}
+	}
+	return toks
+}
+
+func (*Token) elem() {}
+func (*Node) elem()  {}
+
+func writeRaw(w io.Writer, t *token.Token) (int64, error) {
+	n, err := w.Write(t.File.Code[t.Start.Offset:t.End.Offset])
+	return int64(n), err
+}
+
+// WriteTo writes the token with its trivia to the writer.
+func (t *Token) WriteTo(w io.Writer) (int64, error) {
+	var total int64
+	for _, toks := range [][]*token.Token{t.Leading, {t.Token}, t.Trailing} {
+		for _, tok := range toks {
+			n, err := writeRaw(w, tok)
+			total += n
+			if err != nil {
+				return total, err
+			}
+		}
+	}
+	return total, nil
+}
+
+// WriteTo writes all tokens in the node with their trivia to the writer.
+// Writing the root node produces exactly the source code which the tree was built from.
+func (n *Node) WriteTo(w io.Writer) (int64, error) {
+	var total int64
+	for _, c := range n.Children {
+		written, err := c.WriteTo(w)
+		total += written
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// String returns the source code of the node including trivia.
+func (n *Node) String() string {
+	var buf bytes.Buffer
+	_, _ = n.WriteTo(&buf)
+	return buf.String()
+}
@@ -0,0 +1,128 @@
+package cst
+
+import (
+	"github.com/rhysd/locerr"
+	"goscilla/ast"
+	"goscilla/token"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func testFiles(t *testing.T) []string {
+	files := []string{filepath.FromSlash("../test.scilla")}
+	for _, pat := range []string{"../syntax/testdata/*.scilla", "../syntax/testdata/lexer/invalid/*.scilla"} {
+		matched, err := filepath.Glob(filepath.FromSlash(pat))
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, matched...)
+	}
+	return files
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, file := range testFiles(t) {
+		t.Run(file, func(t *testing.T) {
+			code, err := ioutil.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			src, err := locerr.NewSourceFromFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tree, _ := Parse(src)
+			if out := tree.String(); out != string(code) {
+				t.Fatalf("Source was not restored.\nWant:\n%s\nGot:\n%s", code, out)
+			}
+		})
+	}
+}
+
+func TestRoundTripBrokenSource(t *testing.T) {
+	for _, code := range []string{
+		"",
+		"(* only comment *)\n",
+		"library Foo\nlet x = builtin add (\nlet y = x\n",
+		"transition T()\n  x <- ;\n  match y with\n  | Some 1 => accept\n  end\n",
+		"contract Foo()\r\nfield f : Uint32 = Uint32 0 (* trailing *)\r\n\r\n",
+		"library Foo let x = a < b ! c",
+	} {
+		tree, _ := Parse(locerr.NewDummySource(code))
+		if out := tree.String(); out != code {
+			t.Errorf("Source was not restored.\nWant: %q\nGot:  %q", code, out)
+		}
+	}
+}
+
+func TestTrivia(t *testing.T) {
+	code := `(* doc *)
+let x = Uint32 1 (* one *)
+
+(* two *)
+let y = x
+`
+	tree, errs := Parse(locerr.NewDummySource("library Foo\n" + code))
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	toks := tree.Tokens()
+
+	var let1, one, let2 *Token
+	for _, tok := range toks {
+		switch tok.Token.Kind {
+		case token.LET:
+			if let1 == nil {
+				let1 = tok
+			} else {
+				let2 = tok
+			}
+		case token.NUM_LIT:
+			one = tok
+		}
+	}
+
+	if len(let1.Leading) != 2 || let1.Leading[0].Kind != token.COMMENT || let1.Leading[1].Kind != token.NEWLINE {
+		t.Error("Comment above declaration should be leading trivia:", let1.Leading)
+	}
+	if len(one.Trailing) != 3 || one.Trailing[1].Kind != token.COMMENT || one.Trailing[2].Kind != token.NEWLINE {
+		t.Error("Comment on the same line should be trailing trivia:", one.Trailing)
+	}
+	if len(let2.Leading) != 3 || let2.Leading[1].Kind != token.COMMENT {
+		t.Error("Blank line and comment should be leading trivia:", let2.Leading)
+	}
+	eof := toks[len(toks)-1]
+	if eof.Token.Kind != token.EOF || len(eof.Leading) != 0 {
+		t.Error("EOF should be the last token without trivia:", eof.Token)
+	}
+}
+
+func TestNodes(t *testing.T) {
+	tree, errs := Parse(locerr.NewDummySource("library Foo\nlet x = builtin add a b\n"))
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	lib := tree.Children[0].(*Node)
+	if _, ok := lib.AST.(*ast.Library); !ok {
+		t.Fatal("First child should be library but actually", lib.AST.Name())
+	}
+	if tok := lib.Children[0].(*Token); tok.Token.Kind != token.LIBRARY {
+		t.Error("Library token should belong to library node:", tok.Token)
+	}
+	let := lib.Children[2].(*Node)
+	if _, ok := let.AST.(*ast.LetDecl); !ok {
+		t.Fatal("Let declaration was not found in library node:", let.AST.Name())
+	}
+	if s := let.String(); s != "let x = builtin add a b\n" {
+		t.Errorf("Unexpected source of let declaration: %q", s)
+	}
+	builtin := let.Children[3].(*Node)
+	if n := len(builtin.Children); n != 4 {
+		t.Fatalf("Builtin should have 4 children but actually %d", n)
+	}
+	if p, e := builtin.Pos(), builtin.End(); p.Column != 9 || e.Column != 24 {
+		t.Errorf("Unexpected range of builtin: %d-%d", p.Column, e.Column)
+	}
+}
@@ -0,0 +1,37 @@
+package cst
+
+import (
+	"fmt"
+	"github.com/rhysd/locerr"
+	"os"
+)
+
+func Example() {
+	src := locerr.NewDummySource(`library Example
+
+(* The answer *)
+let answer = Uint32 42 (* not 41 *)
+`)
+
+	// Parse source into concrete syntax tree. Errors are returned with a tree built from
+	// the partial AST.
+	tree, errs := Parse(src)
+	if len(errs) > 0 {
+		panic(errs)
+	}
+
+	// Nodes in the tree correspond to AST nodes
+	lib := tree.Children[0].(*Node)
+	fmt.Println(lib.AST.Name())
+
+	// Writing the tree restores the original source including comments and whitespaces
+	if _, err := tree.WriteTo(os.Stdout); err != nil {
+		panic(err)
+	}
+	// Output:
+	// Library (Example)
+	// library Example
+	//
+	// (* The answer *)
+	// let answer = Uint32 42 (* not 41 *)
+}
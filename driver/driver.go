@@ -1,15 +1,21 @@
-// Package driver is amediator to glue all packages for GoCaqml. provides a compiler function for GoCaml codes.
-// It provides compiler functinalities for GoCaml.
+// Package driver glues the packages of GoScilla together. It lexes, parses, checks and runs Scilla
+// source codes and prints their tokens, ASTs, contract info and errors for the command line.
 package driver
 
 import (
 	"fmt"
 	"github.com/rhysd/locerr"
 	"github.com/sirupsen/logrus"
+	"goscilla/ast"
+	"goscilla/checker"
+	"goscilla/loader"
 	"goscilla/prettifier"
+	"goscilla/resolver"
+	"goscilla/runner"
 	"goscilla/syntax"
 	"goscilla/token"
 	"os"
+	"path/filepath"
 )
 
 type OptLevel int
@@ -21,8 +27,11 @@ const (
 	O3
 )
 
-// Driver instance to compile GoCaml code into other representations.
+// Driver instance to process Scilla code into other representations.
 type Driver struct {
+	// LibPath is the list of directories to search libraries imported by modules. The directory of
+	// the source file is searched first and the standard library is searched last.
+	LibPath []string
 }
 
 // Lex PrintTokens returns the lexed tokens for a source code.
@@ -59,16 +68,206 @@ func (d *Driver) Prettify(src *locerr.Source) {
 }
 
 // Parse parses the source and returns the parsed AST.
-//func (d *Driver) Parse(src *locerr.Source) (*ast.AST, error) {
-//return syntax.Parse(src)
-//}
+func (d *Driver) Parse(src *locerr.Source) (*ast.AST, error) {
+	return syntax.Parse(src)
+}
+
+// Check parses the source, resolves names and type checks it. Syntax errors, undefined names or
+// type errors are returned.
+func (d *Driver) Check(src *locerr.Source) error {
+	_, _, err := d.check(src)
+	return err
+}
+
+func (d *Driver) check(src *locerr.Source) (*ast.AST, *checker.Info, error) {
+	a, err := d.Parse(src)
+	if err != nil {
+		return nil, nil, err
+	}
+	l := d.loader(src)
+	if _, err := (&resolver.Config{Importer: l}).Resolve(a); err != nil {
+		return nil, nil, err
+	}
+	info, err := (&checker.Config{Importer: l}).Check(a)
+	return a, info, err
+}
+
+// CheckInit checks the contract in the source and validates init parameters in the JSON file
+// against it. The contract constraint is evaluated with the parameters.
+func (d *Driver) CheckInit(src *locerr.Source, initFile string) error {
+	a, info, err := d.check(src)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(initFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	params, err := runner.ReadParams(f)
+	if err != nil {
+		return fmt.Errorf("%s: %s", initFile, err)
+	}
+	_, err = runner.CheckInit(a, info, params)
+	return err
+}
+
+// RunFiles are paths of the JSON files given to scilla-runner.
+type RunFiles struct {
+	Init       string
+	State      string // Empty on deployment
+	Message    string // Empty on deployment
+	Blockchain string
+	Output     string // Empty means stdout
+	GasLimit   uint64
+}
+
+// Run deploys the contract in the source or executes its transition invoked by the message with
+// the JSON files and writes output.json like scilla-runner. When the execution fails, the error is
+// also written to stdout in the JSON format of scilla-runner.
+func (d *Driver) Run(src *locerr.Source, files *RunFiles) error {
+	a, info, err := d.check(src)
+	if err != nil {
+		return err
+	}
+	in := &runner.Input{GasLimit: files.GasLimit}
+	if in.Init, err = readParamsFile(files.Init); err != nil {
+		return err
+	}
+	if in.Blockchain, err = readParamsFile(files.Blockchain); err != nil {
+		return err
+	}
+	if files.Message != "" {
+		if in.State, err = readParamsFile(files.State); err != nil {
+			return err
+		}
+		f, err := os.Open(files.Message)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if in.Message, err = runner.ReadMessage(f); err != nil {
+			return fmt.Errorf("%s: %s", files.Message, err)
+		}
+	}
+
+	out, err := runner.Run(a, info, in)
+	if err != nil {
+		if rerr, ok := err.(*runner.Error); ok {
+			if werr := rerr.WriteJSON(os.Stdout); werr != nil {
+				return werr
+			}
+			fmt.Println()
+		}
+		return err
+	}
+	if files.Output == "" {
+		if err := out.WriteJSON(os.Stdout); err != nil {
+			return err
+		}
+		fmt.Println()
+		return nil
+	}
+	f, err := os.Create(files.Output)
+	if err != nil {
+		return err
+	}
+	if err := out.WriteJSON(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func readParamsFile(path string) ([]*runner.Param, error) {
+	if path == "" {
+		return nil, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	ps, err := runner.ReadParams(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	return ps, nil
+}
+
+func (d *Driver) loader(src *locerr.Source) *loader.Loader {
+	path := d.LibPath
+	if src.Exists {
+		path = append([]string{filepath.Dir(src.Path)}, path...)
+	}
+	return loader.New(path...)
+}
+
+// PrintErrors outputs the error to stderr. Each error in syntax.ErrorList or checker.ErrorList is
+// output with its location in source.
+func (d *Driver) PrintErrors(err error) {
+	var errs []*locerr.Error
+	switch err := err.(type) {
+	case syntax.ErrorList:
+		errs = err
+	case checker.ErrorList:
+		errs = err
+	case *locerr.Error:
+		errs = []*locerr.Error{err}
+	case *runner.Error:
+		errs = []*locerr.Error{err.Err}
+	}
+	if errs == nil {
+		_, _ = fmt.Fprintln(os.Stderr, err.Error())
+		return
+	}
+	for _, e := range errs {
+		e.PrintToFile(os.Stderr)
+		_, _ = fmt.Fprintln(os.Stderr)
+	}
+}
 
 // PrintAST outputs AST structure to stdout.
-//func (d *Driver) PrintAST(src *locerr.Source) {
-//	a, err := d.Parse(src)
-//	if err != nil {
-//		_, _ = fmt.Fprintln(os.Stderr, err.Error())
-//		return
-//	}
-//	ast.Println(a)
-//}
+func (d *Driver) PrintAST(src *locerr.Source) error {
+	a, err := d.Parse(src)
+	if err != nil {
+		return err
+	}
+	ast.Println(a)
+	return nil
+}
+
+// PrintASTJSON outputs AST as JSON to stdout. See ast.FprintJSON for the schema.
+func (d *Driver) PrintASTJSON(src *locerr.Source) error {
+	a, err := d.Parse(src)
+	if err != nil {
+		return err
+	}
+	return ast.FprintJSON(os.Stdout, a)
+}
+
+// PrintASTScillaJSON outputs AST as JSON in the shape of scilla-checker's AST to stdout.
+func (d *Driver) PrintASTScillaJSON(src *locerr.Source) error {
+	a, err := d.Parse(src)
+	if err != nil {
+		return err
+	}
+	return ast.FprintScillaJSON(os.Stdout, a)
+}
+
+// PrintContractInfo outputs the interface of the contract as JSON in the shape of
+// `scilla-checker -contractinfo` output to stdout.
+func (d *Driver) PrintContractInfo(src *locerr.Source) error {
+	a, info, err := d.check(src)
+	if err != nil {
+		return err
+	}
+	return ast.FprintContractInfo(os.Stdout, a, typeOf(info))
+}
+
+// typeOf returns types of expressions found by the type checker for contract info.
+func typeOf(info *checker.Info) ast.TypeOf {
+	return func(e ast.Expr) string {
+		return info.Types[e].String()
+	}
+}
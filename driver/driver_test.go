@@ -0,0 +1,41 @@
+package driver
+
+import (
+	"github.com/rhysd/locerr"
+	"goscilla/ast"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestContractInfoEvents(t *testing.T) {
+	src, err := locerr.NewSourceFromFile(filepath.FromSlash("../syntax/testdata/basic.scilla"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, info, err := (&Driver{}).check(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ci, err := ast.NewContractInfo(a, typeOf(info))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Types of event parameters which are not literals are found by the type checker
+	want := []ast.ContractInfoEvent{
+		{Name: "init_reserve", Params: []ast.ContractInfoParam{
+			{Name: "reserve", Type: "ByStr20"},
+			{Name: "aToken_address", Type: "ByStr20"},
+			{Name: "interest_rate_strategy_address", Type: "ByStr20"},
+		}},
+		{Name: "test_get_operation_list", Params: []ast.ContractInfoParam{{Name: "r", Type: "List (Uint32)"}}},
+		{Name: "test_read_reserve_balance", Params: []ast.ContractInfoParam{{Name: "bal", Type: "Uint128"}}},
+		{Name: "test_read_strategy", Params: []ast.ContractInfoParam{{Name: "strategy_contract_base_variable_borrow_rate", Type: "Uint256"}}},
+	}
+	if !reflect.DeepEqual(ci.Events, want) {
+		t.Errorf("Wanted events %v but got %v", want, ci.Events)
+	}
+	if len(ci.Warnings) != 0 {
+		t.Errorf("Unexpected warnings: %v", ci.Warnings)
+	}
+}
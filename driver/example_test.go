@@ -7,8 +7,8 @@ import (
 )
 
 func Example() {
-	// Compile testdata/from-mincaml/ack.ml
-	file := filepath.FromSlash("../testdata/from-mincaml/ack.ml")
+	// Process syntax/testdata/basic.scilla
+	file := filepath.FromSlash("../syntax/testdata/basic.scilla")
 	src, err := locerr.NewSourceFromFile(file)
 	if err != nil {
 		// File not found
@@ -21,45 +21,51 @@ func Example() {
 	d.PrintTokens(src)
 
 	// Show AST nodes
-	d.PrintAST(src)
-
-	// Parse file into AST
-	parsed, err := d.Parse(src)
-	if err != nil {
-		panic(err)
+	if err := d.PrintAST(src); err != nil {
+		d.PrintErrors(err)
 	}
-	fmt.Println(parsed)
 
-	// Resolving symbols and type analysis
-	env, inferred, err := d.SemanticAnalysis(src)
-	if err != nil {
-		panic(err)
+	// Show AST as JSON
+	if err := d.PrintASTJSON(src); err != nil {
+		d.PrintErrors(err)
 	}
 
-	// Show environment of type analysis
-	env.Dump()
+	// Show AST and contract info as JSON compatible with scilla-checker
+	if err := d.PrintASTScillaJSON(src); err != nil {
+		d.PrintErrors(err)
+	}
+	if err := d.PrintContractInfo(src); err != nil {
+		d.PrintErrors(err)
+	}
 
-	// Show inferred types of all AST nodes
-	for e, t := range inferred {
-		fmt.Printf("Node '%s' at '%s' => Type '%s'", e.Name(), e.Pos(), t.String())
+	// Type check the file. Imported libraries are searched in LibPath and the standard library
+	d.LibPath = []string{"path/to/libs"}
+	if err := d.Check(src); err != nil {
+		d.PrintErrors(err)
 	}
 
-	// Show LLVM IR for the source
-	ir, err := d.EmitLLVMIR(src)
-	if err != nil {
-		panic(err)
+	// Validate parameters in init.json against the contract and its constraint
+	if err := d.CheckInit(src, "path/to/init.json"); err != nil {
+		d.PrintErrors(err)
 	}
-	fmt.Println(ir)
 
-	// Show native assembly code for the source
-	asm, err := d.EmitAsm(src)
-	if err != nil {
-		panic(err)
+	// Execute the transition invoked by the message like scilla-runner and write output.json
+	files := &RunFiles{
+		Init:       "path/to/init.json",
+		State:      "path/to/input_state.json",
+		Message:    "path/to/input_message.json",
+		Blockchain: "path/to/input_blockchain.json",
+		Output:     "path/to/output.json",
+		GasLimit:   8000,
+	}
+	if err := d.Run(src, files); err != nil {
+		d.PrintErrors(err)
 	}
-	fmt.Println(asm)
 
-	// Compile the source into an executable
-	if err := d.Compile(src); err != nil {
+	// Parse file into AST
+	parsed, err := d.Parse(src)
+	if err != nil {
 		panic(err)
 	}
+	fmt.Println(parsed)
 }
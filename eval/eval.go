@@ -0,0 +1,457 @@
+// Package eval evaluates pure Scilla expressions such as library entries and contract constraints.
+// Expressions must be type checked in advance. Types of expressions in checker.Info are used to
+// construct values.
+package eval
+
+import (
+	"fmt"
+	"github.com/rhysd/locerr"
+	"goscilla/ast"
+	"goscilla/builtins"
+	"goscilla/checker"
+	"goscilla/types"
+	"goscilla/value"
+	"strconv"
+	"strings"
+)
+
+// Closure is a function value created by `fun`.
+type Closure struct {
+	Typ   *types.FunType
+	Param *ast.Ident
+	Body  ast.Expr
+	env   *Env
+	ev    *Evaluator // Evaluator of the module where the function is defined
+}
+
+func (c *Closure) Type() types.Type { return c.Typ }
+func (c *Closure) String() string   { return "<closure>" }
+
+// TClosure is a type function value created by `tfun`. Its type variable is bound in the
+// environment of the body when it is instantiated with `@`.
+type TClosure struct {
+	Typ  *types.PolyType
+	Body ast.Expr
+	env  *Env
+	ev   *Evaluator
+}
+
+func (c *TClosure) Type() types.Type { return c.Typ }
+func (c *TClosure) String() string   { return "<type closure>" }
+
+// Native is a function value implemented in Go such as recursion principles. It computes the
+// result when it is applied to Arity arguments. Applying fewer arguments results in a partially
+// applied Native.
+type Native struct {
+	Name  string
+	Typ   types.Type
+	Arity int
+	args  []value.Value
+	fn    func(ev *Evaluator, n ast.Node, args []value.Value) value.Value
+}
+
+func (f *Native) Type() types.Type { return f.Typ }
+func (f *Native) String() string   { return "<builtin " + f.Name + ">" }
+
+// Env is a persistent linked list of local variables and type variables bound by `tfun`.
+// Shadowing is expressed by prepending.
+type Env struct {
+	name   string
+	val    value.Value
+	typ    types.Type // Set when the entry binds the type variable of the name
+	parent *Env
+}
+
+// Bind returns the environment extended with the variable. nil is an empty environment.
+func (e *Env) Bind(name string, v value.Value) *Env {
+	return &Env{name, v, nil, e}
+}
+
+// BindType returns the environment extended with the type variable like 'A instantiated with t.
+func (e *Env) BindType(tvar string, t types.Type) *Env {
+	return &Env{tvar, nil, t, e}
+}
+
+// Lookup returns the value of the variable.
+func (e *Env) Lookup(name string) (value.Value, bool) {
+	for ; e != nil; e = e.parent {
+		if e.name == name && e.typ == nil {
+			return e.val, true
+		}
+	}
+	return nil, false
+}
+
+// subst instantiates type variables in the type with types bound in the environment.
+func (e *Env) subst(t types.Type) types.Type {
+	s := map[string]types.Type{}
+	for ; e != nil; e = e.parent {
+		if _, ok := s[e.name]; !ok && e.typ != nil {
+			s[e.name] = e.typ
+		}
+	}
+	if len(s) == 0 {
+		return t
+	}
+	return types.Subst(t, s)
+}
+
+// Evaluator evaluates expressions of a type checked module.
+type Evaluator struct {
+	info    *checker.Info
+	globals map[string]value.Value
+	libs    map[*checker.Library]*Evaluator // Imported libraries evaluated so far. Shared by importers
+	gas     *gasMeter                       // Shared by importers
+}
+
+// gasMeter receives gas costs of evaluated expressions. charge is nil when gas is not metered.
+type gasMeter struct {
+	charge func(n ast.Node, cost uint64)
+}
+
+// New creates an evaluator of the module type checked with the info. Recursion principles such
+// as nat_fold and list_foldl are defined as global variables.
+func New(info *checker.Info) *Evaluator {
+	return newEvaluator(info, map[*checker.Library]*Evaluator{}, &gasMeter{})
+}
+
+func newEvaluator(info *checker.Info, libs map[*checker.Library]*Evaluator, gas *gasMeter) *Evaluator {
+	ev := &Evaluator{info, map[string]value.Value{}, libs, gas}
+	for _, p := range principles {
+		ev.globals[p.Name] = p
+	}
+	return ev
+}
+
+// Define defines the global variable such as a contract parameter.
+func (ev *Evaluator) Define(name string, v value.Value) {
+	ev.globals[name] = v
+}
+
+// SetCharge sets the function which is called with the gas cost of every expression evaluated by
+// the evaluator, including bodies of functions in imported libraries. Costs follow Gas.ml of
+// Zilliqa/scilla: a literal costs its size computed by builtins.LiteralCost, a match costs the
+// number of its arms, a builtin costs what Builtin.Cost returns and other expressions cost 1. The
+// function may panic to abort the evaluation when the gas runs out.
+func (ev *Evaluator) SetCharge(f func(n ast.Node, cost uint64)) {
+	ev.gas.charge = f
+}
+
+func (ev *Evaluator) charge(n ast.Node, cost uint64) {
+	if ev.gas.charge != nil {
+		ev.gas.charge(n, cost)
+	}
+}
+
+// Global returns the value of the global variable.
+func (ev *Evaluator) Global(name string) (value.Value, bool) {
+	v, ok := ev.globals[name]
+	return v, ok
+}
+
+// failure is used as a panic value to abort evaluation on runtime errors.
+type failure struct {
+	err *locerr.Error
+}
+
+// fail aborts the evaluation with the error at the node. n may be nil when the error has no
+// location in source.
+func (ev *Evaluator) fail(n ast.Node, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	if n == nil {
+		panic(failure{locerr.NewError(msg)})
+	}
+	panic(failure{locerr.ErrorIn(n.Pos(), n.End(), msg)})
+}
+
+// catch recovers the failure in f and returns it as an error.
+func catch(f func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			fl, ok := r.(failure)
+			if !ok {
+				panic(r)
+			}
+			err = fl.err
+		}
+	}()
+	f()
+	return nil
+}
+
+// Module evaluates the imported libraries and the library entries of the module and defines them
+// as global variables.
+func (ev *Evaluator) Module(a *ast.AST) error {
+	return catch(func() { ev.module(a) })
+}
+
+func (ev *Evaluator) module(a *ast.AST) {
+	for _, i := range a.Imports {
+		for _, n := range i.Names {
+			lib, ok := ev.info.Imports[n]
+			if !ok {
+				ev.fail(n.Lib, "Library %s is not imported", n.Lib.Symbol.DisplayName)
+			}
+			ev.importLib(n, lib)
+		}
+	}
+	if a.Library == nil {
+		return
+	}
+	for _, e := range a.Library.Entries {
+		if d, ok := e.(*ast.LetDecl); ok {
+			ev.globals[d.Ident.Symbol.Name] = ev.eval(d.Bound, nil)
+		}
+	}
+}
+
+func (ev *Evaluator) importLib(n *ast.ImportName, lib *checker.Library) {
+	sub, ok := ev.libs[lib]
+	if !ok {
+		sub = newEvaluator(lib.Info, ev.libs, ev.gas)
+		sub.module(lib.AST)
+		ev.libs[lib] = sub
+	}
+	prefix := ""
+	if n.Alias != nil {
+		prefix = n.Alias.Symbol.Name + "."
+	}
+	for _, e := range lib.AST.Library.Entries {
+		if d, ok := e.(*ast.LetDecl); ok {
+			ev.globals[prefix+d.Ident.Symbol.Name] = sub.globals[d.Ident.Symbol.Name]
+		}
+	}
+}
+
+// Eval evaluates the expression in the environment. Runtime errors are returned with their
+// positions in source.
+func (ev *Evaluator) Eval(e ast.Expr, env *Env) (v value.Value, err error) {
+	err = catch(func() { v = ev.eval(e, env) })
+	return
+}
+
+// Apply applies the function value to the arguments in order.
+func (ev *Evaluator) Apply(f value.Value, args ...value.Value) (v value.Value, err error) {
+	err = catch(func() {
+		v = f
+		for _, a := range args {
+			v = ev.apply(nil, v, a)
+		}
+	})
+	return
+}
+
+// Instantiate instantiates the polymorphic value with the types in order like `@f T1 T2`.
+func (ev *Evaluator) Instantiate(f value.Value, ts ...types.Type) (v value.Value, err error) {
+	err = catch(func() {
+		v = f
+		for _, t := range ts {
+			v = ev.instantiate(nil, v, t)
+		}
+	})
+	return
+}
+
+func (ev *Evaluator) apply(n ast.Node, f, arg value.Value) value.Value {
+	switch f := f.(type) {
+	case *Closure:
+		return f.ev.eval(f.Body, f.env.Bind(f.Param.Symbol.Name, arg))
+	case *Native:
+		args := append(append(make([]value.Value, 0, f.Arity), f.args...), arg)
+		if len(args) == f.Arity {
+			return f.fn(ev, n, args)
+		}
+		t := f.Typ
+		if ft, ok := t.(*types.FunType); ok {
+			t = ft.Ret
+		}
+		return &Native{f.Name, t, f.Arity, args, f.fn}
+	}
+	ev.fail(n, "Value of type %s cannot be applied to argument %s", f.Type(), arg)
+	return nil
+}
+
+func (ev *Evaluator) instantiate(n ast.Node, f value.Value, t types.Type) value.Value {
+	switch f := f.(type) {
+	case *TClosure:
+		return f.ev.eval(f.Body, f.env.BindType(f.Typ.TVar, t))
+	case *Native:
+		if p, ok := f.Typ.(*types.PolyType); ok {
+			return &Native{f.Name, p.Instantiate(t), f.Arity, f.args, f.fn}
+		}
+	}
+	ev.fail(n, "Value of type %s cannot be instantiated with type %s", f.Type(), t)
+	return nil
+}
+
+// typeOf returns the type of the expression where type variables are instantiated in the
+// environment.
+func (ev *Evaluator) typeOf(e ast.Expr, env *Env) types.Type {
+	t, ok := ev.info.Types[e]
+	if !ok {
+		ev.fail(e, "Type of %s is unknown. The expression must be type checked", e.Name())
+	}
+	return env.subst(t)
+}
+
+func (ev *Evaluator) ref(v *ast.VarRef, env *Env) value.Value {
+	if x, ok := env.Lookup(v.Symbol.Name); ok {
+		return x
+	}
+	if x, ok := ev.globals[v.Symbol.Name]; ok {
+		return x
+	}
+	ev.fail(v, "Value of variable %s is not available", v.Symbol.DisplayName)
+	return nil
+}
+
+func (ev *Evaluator) refs(vs []*ast.VarRef, env *Env) []value.Value {
+	xs := make([]value.Value, 0, len(vs))
+	for _, v := range vs {
+		xs = append(xs, ev.ref(v, env))
+	}
+	return xs
+}
+
+func (ev *Evaluator) eval(e ast.Expr, env *Env) value.Value {
+	switch m := e.(type) {
+	case *ast.StringLit, *ast.IntLit, *ast.BNumLit, *ast.HexLit, *ast.EmpLit:
+		v := ev.literal(e, env)
+		ev.charge(e, builtins.LiteralCost(v))
+		return v
+	case *ast.Match:
+		ev.charge(e, uint64(len(m.Arms)))
+	case *ast.Builtin:
+		// Charged with the values of arguments
+	default:
+		ev.charge(e, 1)
+	}
+
+	switch e := e.(type) {
+	case *ast.VarRef:
+		return ev.ref(e, env)
+	case *ast.Let:
+		return ev.eval(e.Body, env.Bind(e.Ident.Symbol.Name, ev.eval(e.Bound, env)))
+	case *ast.Fun:
+		return &Closure{ev.typeOf(e, env).(*types.FunType), e.Param.Ident, e.Body, env, ev}
+	case *ast.TFun:
+		return &TClosure{ev.typeOf(e, env).(*types.PolyType), e.Body, env, ev}
+	case *ast.App:
+		f := ev.ref(e.Func, env)
+		for _, a := range ev.refs(e.Args, env) {
+			f = ev.apply(e, f, a)
+		}
+		return f
+	case *ast.TApp:
+		targs, ok := ev.info.TypeArgs[e]
+		if !ok {
+			ev.fail(e, "Type arguments of %s are unknown. The expression must be type checked", e.Func.Symbol.DisplayName)
+		}
+		f := ev.ref(e.Func, env)
+		for _, t := range targs {
+			f = ev.instantiate(e, f, env.subst(t))
+		}
+		return f
+	case *ast.Builtin:
+		return ev.builtin(e, env)
+	case *ast.Constr:
+		t, ok := ev.typeOf(e, env).(*types.ADT)
+		if !ok {
+			ev.fail(e, "Constructor %s must construct ADT value", e.Ident.Symbol.DisplayName)
+		}
+		name := e.Ident.Symbol.Name
+		return &value.ADT{Name: t.Name, Ctor: name[strings.LastIndexByte(name, '.')+1:], TArgs: t.Args, Args: ev.refs(e.Args, env)}
+	case *ast.Message:
+		m := &value.Msg{Typ: ev.typeOf(e, env)}
+		for _, ent := range e.Entries {
+			m.Entries = append(m.Entries, &value.MsgEntry{Key: ent.Key.Value(), Value: ev.eval(ent.Value, env)})
+		}
+		return m
+	case *ast.Match:
+		target := ev.ref(e.Target, env)
+		for _, arm := range e.Arms {
+			if bound, ok := Match(arm.Pattern, target, env); ok {
+				return ev.eval(arm.Body, bound)
+			}
+		}
+		ev.fail(e, "No arm matches value %s", target)
+	}
+	ev.fail(e, "%s cannot be evaluated", e.Name())
+	return nil
+}
+
+// literal evaluates the literal expression.
+func (ev *Evaluator) literal(e ast.Expr, env *Env) value.Value {
+	switch e := e.(type) {
+	case *ast.StringLit:
+		s, err := strconv.Unquote(e.Token.Value())
+		if err != nil {
+			ev.fail(e, "Invalid string literal %s: %s", e.Token.Value(), err)
+		}
+		return value.String(s)
+	case *ast.IntLit:
+		t, ok := types.Prim(e.TypeToken.Value()).(*types.IntType)
+		if !ok {
+			ev.fail(e, "Integer literal must have integer type but got %s", e.TypeToken.Value())
+		}
+		v, err := value.ParseInt(t, e.ValueToken.Value())
+		if err != nil {
+			ev.fail(e, "%s", err)
+		}
+		return v
+	case *ast.BNumLit:
+		v, err := value.ParseBNum(e.ValueToken.Value())
+		if err != nil {
+			ev.fail(e, "%s", err)
+		}
+		return v
+	case *ast.HexLit:
+		v, err := value.ParseHex(e.Token.Value())
+		if err != nil {
+			ev.fail(e, "%s", err)
+		}
+		return v
+	case *ast.EmpLit:
+		return value.NewMap(ev.typeOf(e, env).(*types.MapType))
+	}
+	ev.fail(e, "%s is not a literal", e.Name())
+	return nil
+}
+
+func (ev *Evaluator) builtin(e *ast.Builtin, env *Env) value.Value {
+	b, err := builtins.Lookup(e.Ident.Symbol.Name)
+	if err != nil {
+		ev.fail(e.Ident, "%s", err)
+	}
+	args := ev.refs(e.Args, env)
+	ev.charge(e, b.Cost(args))
+	v, err := b.Eval(args)
+	if err != nil {
+		ev.fail(e, "Builtin %s failed: %s", b.Name, err)
+	}
+	return v
+}
+
+// Match matches the value with the pattern and returns the environment extended with binders in
+// the pattern.
+func Match(p ast.Pattern, v value.Value, env *Env) (*Env, bool) {
+	switch p := p.(type) {
+	case *ast.WildcardPattern:
+		return env, true
+	case *ast.BinderPattern:
+		return env.Bind(p.Ident.Symbol.Name, v), true
+	case *ast.ConstrPattern:
+		adt, ok := v.(*value.ADT)
+		name := p.Ctor.Symbol.Name
+		if !ok || adt.Ctor != name[strings.LastIndexByte(name, '.')+1:] || len(adt.Args) != len(p.Args) {
+			return nil, false
+		}
+		for i, a := range p.Args {
+			if env, ok = Match(a, adt.Args[i], env); !ok {
+				return nil, false
+			}
+		}
+		return env, true
+	}
+	return nil, false
+}
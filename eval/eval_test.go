@@ -0,0 +1,372 @@
+package eval
+
+import (
+	"github.com/rhysd/locerr"
+	"goscilla/ast"
+	"goscilla/checker"
+	"goscilla/loader"
+	"goscilla/resolver"
+	"goscilla/syntax"
+	"goscilla/types"
+	"goscilla/value"
+	"math/big"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func evalModule(t *testing.T, src *locerr.Source) *Evaluator {
+	a, err := syntax.Parse(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := loader.New()
+	if _, err := (&resolver.Config{Importer: l}).Resolve(a); err != nil {
+		t.Fatal(err)
+	}
+	info, err := (&checker.Config{Importer: l}).Check(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ev := New(info)
+	if err := ev.Module(a); err != nil {
+		t.Fatal(err)
+	}
+	return ev
+}
+
+func global(t *testing.T, ev *Evaluator, name string) value.Value {
+	v, ok := ev.Global(name)
+	if !ok {
+		t.Fatalf("%s is not defined", name)
+	}
+	return v
+}
+
+func uint32Value(i int64) *value.Int {
+	return value.NewInt(types.Uint32, big.NewInt(i))
+}
+
+func TestBasic(t *testing.T) {
+	src, err := locerr.NewSourceFromFile(filepath.Join("..", "syntax", "testdata", "basic.scilla"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ev := evalModule(t, src)
+
+	msg := &value.Msg{Typ: types.Message, Entries: []*value.MsgEntry{{Key: "_tag", Value: value.String("AddFunds")}}}
+	v, err := ev.Apply(global(t, ev, "one_msg"), msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `Cons {(Message)} ({_tag : "AddFunds"}) (Nil {(Message)})`; v.String() != want {
+		t.Errorf("Wanted %s but got %s", want, v)
+	}
+
+	v, err = ev.Apply(global(t, ev, "make_error"), &value.ADT{Name: "Error", Ctor: "SenderNotOwner"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{_exception : "Error"; code : Int32 -5}`; v.String() != want || !types.Equal(v.Type(), types.Exception) {
+		t.Errorf("Wanted %s but got %s", want, v)
+	}
+
+	ray := global(t, ev, "ray")
+	two, _ := value.ParseInt(types.Uint256, "2000000000000000000000000000")
+	v, err = ev.Apply(global(t, ev, "ray_mul"), ray, two)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !value.Equal(v, two) {
+		t.Errorf("ray * 2 ray must be 2 ray but got %s", v)
+	}
+
+	// get_operation_list uses nat_fold and list_filter of ListUtils
+	v, err = ev.Apply(global(t, ev, "get_operation_list"), uint32Value(1), uint32Value(100000000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	xs, ok := v.(*value.ADT).Elems()
+	if !ok {
+		t.Fatalf("Result is not a list: %s", v)
+	}
+	var want []string
+	for n := int64(100000000) >> 25; n < 100000000; n = 100000000 >> (25 - len(want)) {
+		want = append(want, "Uint32 "+big.NewInt(n).String())
+	}
+	have := make([]string, 0, len(xs))
+	for _, x := range xs {
+		have = append(have, x.String())
+	}
+	if strings.Join(have, ", ") != strings.Join(want, ", ") {
+		t.Errorf("Wanted %v but got %v", want, have)
+	}
+}
+
+// Reference implementation of the lending math in basic.scilla ported from WadRayMath of Aave.
+var (
+	refRay     = new(big.Int).Exp(big.NewInt(10), big.NewInt(27), nil)
+	refHalfRay = new(big.Int).Div(refRay, big.NewInt(2))
+)
+
+func refRayMul(a, b *big.Int) *big.Int {
+	c := new(big.Int).Mul(a, b)
+	return c.Add(c, refHalfRay).Div(c, refRay)
+}
+
+func refRayDiv(a, b *big.Int) *big.Int {
+	c := new(big.Int).Mul(a, refRay)
+	return c.Add(c, new(big.Int).Div(b, big.NewInt(2))).Div(c, b)
+}
+
+// refRayPow follows ray_pow of basic.scilla. Unlike rayPow of Aave, operation_list_fold_fn
+// multiplies z by x before squaring x, so this only checks that the evaluator runs the contract's
+// algorithm consistently.
+func refRayPow(x *big.Int, n uint64) *big.Int {
+	z := refRay
+	if n%2 != 0 {
+		z = x
+	}
+	for n /= 2; n != 0; n /= 2 {
+		next := refRayMul(x, x)
+		if n%2 != 0 {
+			z = refRayMul(z, x)
+		}
+		x = next
+	}
+	return z
+}
+
+func TestRayMath(t *testing.T) {
+	src, err := locerr.NewSourceFromFile(filepath.Join("..", "syntax", "testdata", "basic.scilla"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ev := evalModule(t, src)
+	u256 := func(i *big.Int) *value.Int {
+		v := value.NewInt(types.Uint256, i)
+		if v == nil {
+			t.Fatalf("%s is out of range of Uint256", i)
+		}
+		return v
+	}
+	secondsPerYear := big.NewInt(31556926)
+	rate, _ := new(big.Int).SetString("52345678901234567890123456", 10) // About 5.2% in ray
+	now := big.NewInt(1600000000)
+
+	for _, elapsed := range []int64{1, 3600, 86400, 31556926, 40000000} {
+		last := new(big.Int).Sub(now, big.NewInt(elapsed))
+
+		// rate * (elapsed * 10^9 / (seconds_per_year * 10^9)) + ray
+		wadRay := big.NewInt(1000000000)
+		delta := refRayDiv(new(big.Int).Mul(big.NewInt(elapsed), wadRay), new(big.Int).Mul(secondsPerYear, wadRay))
+		want := new(big.Int).Add(refRayMul(rate, delta), refRay)
+		v, err := ev.Apply(global(t, ev, "calculate_linear_interest"), u256(rate), u256(now), u256(last))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !value.Equal(v, u256(want)) {
+			t.Errorf("Linear interest for %d seconds: wanted %s but got %s", elapsed, want, v)
+		}
+
+		// (rate / seconds_per_year + ray) ^ elapsed
+		want = refRayPow(new(big.Int).Add(new(big.Int).Div(rate, secondsPerYear), refRay), uint64(elapsed))
+		v, err = ev.Apply(global(t, ev, "calculate_compounded_interest"), u256(rate), u256(now), u256(last))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !value.Equal(v, u256(want)) {
+			t.Errorf("Compounded interest for %d seconds: wanted %s but got %s", elapsed, want, v)
+		}
+	}
+
+	// Literal results of ray_mul and ray_div which round half up like rayMul and rayDiv of Aave
+	ray := func(s string) *value.Int {
+		i, ok := new(big.Int).SetString(s, 10)
+		if !ok {
+			t.Fatal(s)
+		}
+		return u256(i)
+	}
+	for _, tc := range []struct {
+		name string
+		args []value.Value
+		want string
+	}{
+		{"ray_mul", []value.Value{ray("1500000000000000000000000000"), ray("2000000000000000000000000000")}, "3000000000000000000000000000"},
+		{"ray_mul", []value.Value{ray("1"), ray("500000000000000000000000000")}, "1"},
+		{"ray_mul", []value.Value{ray("1"), ray("499999999999999999999999999")}, "0"},
+		{"ray_div", []value.Value{ray("1000000000000000000000000000"), ray("3000000000000000000000000000")}, "333333333333333333333333333"},
+		{"ray_div", []value.Value{ray("2000000000000000000000000000"), ray("3000000000000000000000000000")}, "666666666666666666666666667"},
+		{"ray_pow", []value.Value{ray("1500000000000000000000000000"), ray("0")}, "1000000000000000000000000000"},
+		{"ray_pow", []value.Value{ray("1500000000000000000000000000"), ray("1")}, "1500000000000000000000000000"},
+		// rayPow of Aave returns 1024 ray, but ray_pow of basic.scilla multiplies z by x before
+		// squaring x
+		{"ray_pow", []value.Value{ray("2000000000000000000000000000"), ray("10")}, "32000000000000000000000000000"},
+	} {
+		v, err := ev.Apply(global(t, ev, tc.name), tc.args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !value.Equal(v, ray(tc.want)) {
+			t.Errorf("Wanted %s for %s %v but got %s", tc.want, tc.name, tc.args, v)
+		}
+	}
+
+	// Uint256 does not wrap around on overflow
+	_, max := value.Bounds(types.Uint256)
+	_, err = ev.Apply(global(t, ev, "ray_mul"), u256(max), u256(refRay))
+	if err == nil || !strings.Contains(err.Error(), "Integer overflow in mul of Uint256") {
+		t.Errorf("Overflow was not detected: %v", err)
+	}
+	_, err = ev.Apply(global(t, ev, "ray_div"), u256(refRay), u256(new(big.Int)))
+	if err == nil || !strings.Contains(err.Error(), "Division by zero in div of Uint256") {
+		t.Errorf("Division by zero was not detected: %v", err)
+	}
+}
+
+func TestEvalLibrary(t *testing.T) {
+	code := `scilla_version 0
+library L
+let one = Uint32 1
+let two = Uint32 2
+let three = Uint32 3
+let xs = let nil = Nil {Uint32} in let l3 = Cons {Uint32} three nil in let l2 = Cons {Uint32} two l3 in Cons {Uint32} one l2
+let some = tfun 'A => fun (x : 'A) => Some {'A} x
+let some_one = let f = @some Uint32 in f one
+let empty = tfun 'K => tfun 'V => Emp 'K 'V
+let empty_map = @empty String Uint32
+let sum = let add = fun (acc : Uint32) => fun (x : Uint32) => builtin add acc x in let fold = @list_foldl Uint32 Uint32 in fold add one xs
+let reversed =
+  let nil = Nil {Uint32} in
+  let rev = fun (x : Uint32) => fun (acc : List Uint32) => Cons {Uint32} x acc in
+  let fold = @list_foldr Uint32 (List Uint32) in
+  fold rev nil xs
+let first_gt_one =
+  let none = None {Uint32} in
+  let find = fun (acc : Option Uint32) => fun (x : Uint32) => fun (k : Option Uint32 -> Option Uint32) =>
+    let gt = builtin lt one x in
+    match gt with
+    | True => Some {Uint32} x
+    | False => k acc
+    end in
+  let fold = @list_foldk Uint32 (Option Uint32) in
+  fold find none xs
+let nat_three = builtin to_nat three
+let count =
+  let zero = Uint32 0 in
+  let inc = fun (acc : Uint32) => fun (n : Nat) => builtin add acc one in
+  let fold = @nat_fold Uint32 in
+  fold inc zero nat_three
+let countk =
+  let zero = Uint32 0 in
+  let inc = fun (acc : Uint32) => fun (n : Nat) => fun (k : Uint32 -> Uint32) => let acc = builtin add acc two in k acc in
+  let fold = @nat_foldk Uint32 in
+  fold inc zero nat_three
+let shadow = let x = one in let f = fun (x : Uint32) => x in let x = two in f three
+let unpair = let a = "a" in let p = Pair {Uint32 String} one a in match p with | Pair _ s => s end
+`
+	ev := evalModule(t, locerr.NewDummySource(code))
+	for name, want := range map[string]string{
+		"some_one":     "Some {(Uint32)} (Uint32 1)",
+		"empty_map":    "Emp (String) (Uint32)",
+		"sum":          "Uint32 7",
+		"reversed":     "Cons {(Uint32)} (Uint32 1) (Cons {(Uint32)} (Uint32 2) (Cons {(Uint32)} (Uint32 3) (Nil {(Uint32)})))",
+		"first_gt_one": "Some {(Uint32)} (Uint32 2)",
+		"count":        "Uint32 3",
+		"countk":       "Uint32 6",
+		"shadow":       "Uint32 3",
+		"unpair":       `"a"`,
+	} {
+		if v := global(t, ev, name); v.String() != want {
+			t.Errorf("Wanted %s for %s but got %s", want, name, v)
+		}
+	}
+
+	some := global(t, ev, "some")
+	if _, ok := some.(*TClosure); !ok {
+		t.Fatalf("some must be type closure but got %T", some)
+	}
+	f, err := ev.Instantiate(some, types.String)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "String -> Option (String)"; f.Type().String() != want {
+		t.Errorf("Wanted %s but got %s", want, f.Type())
+	}
+	v, err := ev.Apply(f, value.String("x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `Some {(String)} "x"`; v.String() != want {
+		t.Errorf("Wanted %s but got %s", want, v)
+	}
+}
+
+func TestEvalError(t *testing.T) {
+	code := `scilla_version 0
+library L
+let zero = Uint32 0
+let div = fun (x : Uint32) => builtin div x zero
+`
+	ev := evalModule(t, locerr.NewDummySource(code))
+	_, err := ev.Apply(global(t, ev, "div"), uint32Value(1))
+	if err == nil {
+		t.Fatal("Division by zero did not cause an error")
+	}
+	e, ok := err.(*locerr.Error)
+	if !ok {
+		t.Fatalf("Error is not located: %T", err)
+	}
+	if msg := strings.Join(e.Messages, " "); !strings.Contains(msg, "Builtin div failed") || e.Start.Line != 4 {
+		t.Errorf("Unexpected error: %s", err)
+	}
+
+	if _, err := ev.Apply(uint32Value(1), uint32Value(1)); err == nil {
+		t.Error("Applying integer did not cause an error")
+	}
+
+	// Expressions which are not type checked cannot be evaluated
+	a, err := syntax.Parse(locerr.NewDummySource(`scilla_version 0 library L let x = Nil {Uint32}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e2 := New(&checker.Info{})
+	if err := e2.Module(a); err == nil || !strings.Contains(err.Error(), "must be type checked") {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestCharge(t *testing.T) {
+	a, err := syntax.Parse(locerr.NewDummySource(`scilla_version 0
+library L
+let x = Uint128 1
+let f = fun (a : Uint128) => builtin mul a a
+let y = f x
+let z = let t = True in match t with | True => "ab" | False => "" end
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := loader.New()
+	if _, err := (&resolver.Config{Importer: l}).Resolve(a); err != nil {
+		t.Fatal(err)
+	}
+	info, err := (&checker.Config{Importer: l}).Check(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ev := New(info)
+	var gas uint64
+	ev.SetCharge(func(n ast.Node, cost uint64) { gas += cost })
+	if err := ev.Module(a); err != nil {
+		t.Fatal(err)
+	}
+	// x: literal of 16 bytes
+	// f: function 1
+	// y: application 1 and mul of Uint128 40
+	// z: let 1, constructor 1, match of 2 arms and literal of 2 bytes
+	if want := uint64(16 + 1 + 41 + 6); gas != want {
+		t.Errorf("Wanted gas %d but got %d", want, gas)
+	}
+}
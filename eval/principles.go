@@ -0,0 +1,114 @@
+package eval
+
+import (
+	"goscilla/ast"
+	"goscilla/checker"
+	"goscilla/types"
+	"goscilla/value"
+)
+
+// Recursion principles from Recursion.ml of Zilliqa/scilla. Their types are defined by checker.
+var principles = []*Native{
+	principle("nat_fold", 3, natFold),
+	principle("nat_foldk", 3, natFoldk),
+	principle("list_foldl", 3, listFoldl),
+	principle("list_foldr", 3, listFoldr),
+	principle("list_foldk", 3, listFoldk),
+}
+
+func principle(name string, arity int, fn func(ev *Evaluator, n ast.Node, args []value.Value) value.Value) *Native {
+	return &Native{Name: name, Typ: checker.RecursionPrinciples[name], Arity: arity, fn: fn}
+}
+
+func (ev *Evaluator) adt(n ast.Node, v value.Value) *value.ADT {
+	adt, ok := v.(*value.ADT)
+	if !ok {
+		ev.fail(n, "Value %s must be an ADT value", v)
+	}
+	return adt
+}
+
+// continuation returns the function `fun (acc : T) => f acc` passed to the folding function of
+// nat_foldk and list_foldk.
+func continuation(name string, t types.Type, f func(acc value.Value) value.Value) *Native {
+	return &Native{Name: name, Typ: types.Fun(t, t), Arity: 1, fn: func(_ *Evaluator, _ ast.Node, args []value.Value) value.Value {
+		return f(args[0])
+	}}
+}
+
+// accType returns the type of accumulator of the fold from the folding function, which takes the
+// accumulator as its first parameter.
+func accType(fn value.Value) types.Type {
+	if f, ok := fn.Type().(*types.FunType); ok {
+		return f.Param
+	}
+	return fn.Type()
+}
+
+// nat_fold fn z (Succ n) = fn (nat_fold fn z n) n
+func natFold(ev *Evaluator, n ast.Node, args []value.Value) value.Value {
+	fn, acc := args[0], args[1]
+	var preds []value.Value
+	for v := ev.adt(n, args[2]); v.Ctor == "Succ"; v = ev.adt(n, v.Args[0]) {
+		preds = append(preds, v.Args[0])
+	}
+	for i := len(preds) - 1; i >= 0; i-- {
+		acc = ev.apply(n, ev.apply(n, fn, acc), preds[i])
+	}
+	return acc
+}
+
+// nat_foldk fn z (Succ n) = fn z n (fun (acc : T) => nat_foldk fn acc n)
+func natFoldk(ev *Evaluator, n ast.Node, args []value.Value) value.Value {
+	fn, acc := args[0], args[1]
+	nat := ev.adt(n, args[2])
+	if nat.Ctor != "Succ" {
+		return acc
+	}
+	pred := nat.Args[0]
+	k := continuation("nat_foldk", accType(fn), func(acc value.Value) value.Value {
+		return natFoldk(ev, n, []value.Value{fn, acc, pred})
+	})
+	return ev.apply(n, ev.apply(n, ev.apply(n, fn, acc), pred), k)
+}
+
+func (ev *Evaluator) elems(n ast.Node, v value.Value) []value.Value {
+	xs, ok := ev.adt(n, v).Elems()
+	if !ok {
+		ev.fail(n, "Value %s must be a list", v)
+	}
+	return xs
+}
+
+// list_foldl fn z (Cons x xs) = list_foldl fn (fn z x) xs
+func listFoldl(ev *Evaluator, n ast.Node, args []value.Value) value.Value {
+	fn, acc := args[0], args[1]
+	for _, x := range ev.elems(n, args[2]) {
+		acc = ev.apply(n, ev.apply(n, fn, acc), x)
+	}
+	return acc
+}
+
+// list_foldr fn z (Cons x xs) = fn x (list_foldr fn z xs)
+func listFoldr(ev *Evaluator, n ast.Node, args []value.Value) value.Value {
+	fn, acc := args[0], args[1]
+	xs := ev.elems(n, args[2])
+	for i := len(xs) - 1; i >= 0; i-- {
+		acc = ev.apply(n, ev.apply(n, fn, xs[i]), acc)
+	}
+	return acc
+}
+
+// list_foldk fn z (Cons x xs) = fn z x (fun (acc : B) => list_foldk fn acc xs)
+func listFoldk(ev *Evaluator, n ast.Node, args []value.Value) value.Value {
+	fn, acc := args[0], args[1]
+	l := ev.adt(n, args[2])
+	if l.Ctor != "Cons" {
+		return acc
+	}
+	x, rest := l.Args[0], l.Args[1]
+	k := continuation("list_foldk", accType(fn), func(acc value.Value) value.Value {
+		return listFoldk(ev, n, []value.Value{fn, acc, rest})
+	})
+	return ev.apply(n, ev.apply(n, ev.apply(n, fn, acc), x), k)
+}
@@ -6,5 +6,5 @@ require (
 	github.com/fatih/color v1.13.0 // indirect
 	github.com/mattn/go-colorable v0.1.11 // indirect
 	github.com/rhysd/locerr v0.0.0-20170710120751-9e34f7a52ee7
-	github.com/sirupsen/logrus v1.8.1 // indirect
+	github.com/sirupsen/logrus v1.8.1
 )
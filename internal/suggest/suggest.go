@@ -0,0 +1,49 @@
+// Package suggest finds similar names to suggest for misspelled identifiers in error messages.
+package suggest
+
+import "sort"
+
+// Similar returns the most similar name to the name among candidates, or an empty string when no
+// candidate is similar enough. Ties are broken in alphabetical order.
+func Similar(name string, candidates []string) string {
+	cs := append([]string{}, candidates...)
+	sort.Strings(cs)
+	best, dist := "", len(name)/2+1
+	for _, c := range cs {
+		if d := editDistance(name, c); d < dist {
+			best, dist = c, d
+		}
+	}
+	return best
+}
+
+// editDistance returns Levenshtein distance between the two strings
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
@@ -0,0 +1,124 @@
+// Package loader resolves libraries imported by Scilla modules. Libraries are searched in the
+// directories of search path as `<Name>.scillib` files and then in the standard library bundled in
+// stdlib package. Each library is parsed and type checked only once.
+package loader
+
+import (
+	"fmt"
+	"github.com/rhysd/locerr"
+	"goscilla/checker"
+	"goscilla/resolver"
+	"goscilla/stdlib"
+	"goscilla/syntax"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// EnvPath is the environment variable which lists directories of libraries separated by
+// os.PathListSeparator, like PATH.
+const EnvPath = "SCILLA_STDLIB_PATH"
+
+// PathFromEnv returns the search path in EnvPath environment variable.
+func PathFromEnv() []string {
+	return SplitPath(os.Getenv(EnvPath))
+}
+
+// SplitPath splits the list of directories separated by os.PathListSeparator. Empty elements are
+// ignored.
+func SplitPath(list string) []string {
+	var dirs []string
+	for _, d := range filepath.SplitList(list) {
+		if d != "" {
+			dirs = append(dirs, d)
+		}
+	}
+	return dirs
+}
+
+type entry struct {
+	lib *checker.Library
+	err error
+}
+
+// Loader loads libraries and implements checker.Importer.
+type Loader struct {
+	// Path is the list of directories to search libraries in order. They are searched before the
+	// standard library so that users can replace libraries in it.
+	Path    []string
+	libs    map[string]*entry
+	loading []string // Chain of libraries being loaded to detect import cycles
+}
+
+// New creates a loader which searches libraries in the directories.
+func New(path ...string) *Loader {
+	return &Loader{Path: path, libs: map[string]*entry{}}
+}
+
+// Import implements checker.Importer. Errors in the library are returned as checker.ErrorList.
+func (l *Loader) Import(name string) (*checker.Library, error) {
+	for i, n := range l.loading {
+		if n == name {
+			chain := append(append([]string{}, l.loading[i:]...), name)
+			return nil, fmt.Errorf("Import cycle is detected: %s", strings.Join(chain, " -> "))
+		}
+	}
+	if e, ok := l.libs[name]; ok {
+		return e.lib, e.err
+	}
+	l.loading = append(l.loading, name)
+	lib, err := l.load(name)
+	l.loading = l.loading[:len(l.loading)-1]
+	l.libs[name] = &entry{lib, err}
+	return lib, err
+}
+
+// Find returns the source of the library. It returns an error when the library is not found.
+func (l *Loader) Find(name string) (*locerr.Source, error) {
+	for _, dir := range l.Path {
+		src, err := locerr.NewSourceFromFile(filepath.Join(dir, name+stdlib.Ext))
+		if err == nil {
+			return src, nil
+		}
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	if src, ok := stdlib.Source(name); ok {
+		return src, nil
+	}
+	where := "standard library"
+	if len(l.Path) > 0 {
+		where = strings.Join(l.Path, ", ") + " and " + where
+	}
+	return nil, fmt.Errorf("Library %s is not found in %s", name, where)
+}
+
+func (l *Loader) load(name string) (*checker.Library, error) {
+	src, err := l.Find(name)
+	if err != nil {
+		return nil, err
+	}
+	a, err := syntax.Parse(src)
+	if err != nil {
+		if errs, ok := err.(syntax.ErrorList); ok {
+			return nil, checker.ErrorList(errs)
+		}
+		return nil, err
+	}
+	if _, err := (&resolver.Config{Importer: l}).Resolve(a); err != nil {
+		return nil, err
+	}
+	lib, err := (&checker.Config{Importer: l}).CheckLibrary(a)
+	if err != nil {
+		if e, ok := err.(*locerr.Error); ok {
+			return nil, checker.ErrorList{e}
+		}
+		return nil, err
+	}
+	if lib.Name != name {
+		i := a.Library.Ident
+		return nil, checker.ErrorList{locerr.ErrorIn(i.Pos(), i.End(), fmt.Sprintf("Library %s is declared in %s but it must be %s", lib.Name, src.Path, name))}
+	}
+	return lib, nil
+}
@@ -0,0 +1,253 @@
+package loader
+
+import (
+	"github.com/rhysd/locerr"
+	"goscilla/checker"
+	"goscilla/stdlib"
+	"goscilla/syntax"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStdlib(t *testing.T) {
+	l := New()
+	for _, name := range stdlib.Names() {
+		t.Run(name, func(t *testing.T) {
+			lib, err := l.Import(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if lib.Name != name {
+				t.Fatal("Unexpected library name", lib.Name)
+			}
+		})
+	}
+
+	for lib, want := range map[string]map[string]string{
+		"BoolUtils": {"andb": "Bool -> Bool -> Bool"},
+		"IntUtils":  {"uint128_le": "Uint128 -> Uint128 -> Bool", "int_neq": "forall 'A. 'A -> 'A -> Bool"},
+		"ListUtils": {
+			"list_map":    "forall 'A. forall 'B. ('A -> 'B) -> List ('A) -> List ('B)",
+			"list_filter": "forall 'A. ('A -> Bool) -> List ('A) -> List ('A)",
+			"list_zip":    "forall 'A. forall 'B. List ('A) -> List ('B) -> List (Pair ('A) ('B))",
+		},
+		"NatUtils":    {"nat_to_int": "Nat -> Uint32"},
+		"PairUtils":   {"fst": "forall 'A. forall 'B. Pair ('A) ('B) -> 'A"},
+		"Conversions": {"extract_uint64": "IntegerEncoding -> ByStr -> Uint32 -> Option (Pair (Uint64) (Uint32))"},
+		"Polynetwork": {"deserialize_TxParam": "ByStr -> Uint32 -> Option (Pair (TxParam) (Uint32))"},
+	} {
+		l, err := l.Import(lib)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name, w := range want {
+			if have := l.Values[name]; have == nil || have.String() != w {
+				t.Errorf("Wanted %q for %s.%s but got %v", w, lib, name, have)
+			}
+		}
+	}
+}
+
+func TestImportOnce(t *testing.T) {
+	l := New()
+	a, err := l.Import("ListUtils")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := l.Import("ListUtils")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a != b {
+		t.Error("Library was loaded twice")
+	}
+}
+
+func writeLibs(t *testing.T, libs map[string]string) string {
+	dir := t.TempDir()
+	for name, code := range libs {
+		if err := os.WriteFile(filepath.Join(dir, name+stdlib.Ext), []byte(code), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func check(t *testing.T, l *Loader, code string) error {
+	a, err := syntax.Parse(locerr.NewDummySource(code))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = (&checker.Config{Importer: l}).Check(a)
+	return err
+}
+
+func TestSearchPath(t *testing.T) {
+	dir := writeLibs(t, map[string]string{
+		"MyLib":     `scilla_version 0 import BoolUtils library MyLib type T = | A | B let not_a = fun (t : T) => match t with | A => False | B => True end let t = andb`,
+		"BoolUtils": `scilla_version 0 library BoolUtils let andb = Uint32 0 let negb = fun (b : Bool) => b`,
+	})
+	other := writeLibs(t, map[string]string{
+		"MyLib": `scilla_version 0 library MyLib let not_a = Uint32 0`,
+	})
+	l := New(dir, other)
+	err := check(t, l, `
+scilla_version 0
+import MyLib ListUtils
+library L
+let x = A
+let y = not_a x
+let n : Uint32 = t
+let f = @list_length T
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestImportError(t *testing.T) {
+	dir := writeLibs(t, map[string]string{
+		"Broken":   `scilla_version 0 library Broken let x : Uint32 = Int32 0`,
+		"Syntax":   `scilla_version 0 library Syntax let x =`,
+		"Wrong":    `scilla_version 0 library Other let x = Uint32 0`,
+		"Contract": `scilla_version 0 library Contract contract C()`,
+		"A":        `scilla_version 0 import B library A`,
+		"B":        `scilla_version 0 import A library B`,
+		"Self":     `scilla_version 0 import Self library Self`,
+	})
+
+	for _, tc := range []struct {
+		what string
+		lib  string
+		want []string
+	}{
+		{"not found", "Foo", []string{"Library Foo cannot be imported. Library Foo is not found in " + dir + " and standard library"}},
+		{"type error", "Broken", []string{"Type mismatch in type annotation", "Library Broken cannot be imported since it has errors"}},
+		{"syntax error", "Syntax", []string{"Unexpected token", "Library Syntax cannot be imported since it has errors"}},
+		{"name mismatch", "Wrong", []string{"Library Other is declared in " + filepath.Join(dir, "Wrong.scillib") + " but it must be Wrong", "Library Wrong cannot be imported since it has errors"}},
+		{"contract in library", "Contract", []string{"Library module Contract must not contain contract", "Library Contract cannot be imported since it has errors"}},
+		{"import cycle", "A", []string{"Library A cannot be imported. Import cycle is detected: A -> B -> A", "Library B cannot be imported since it has errors", "Library A cannot be imported since it has errors"}},
+		{"self import", "Self", []string{"Library Self cannot be imported. Import cycle is detected: Self -> Self", "Library Self cannot be imported since it has errors"}},
+	} {
+		t.Run(tc.what, func(t *testing.T) {
+			err := check(t, New(dir), `scilla_version 0 import `+tc.lib+` library L`)
+			if err == nil {
+				t.Fatal("Error did not occur")
+			}
+			errs, ok := err.(checker.ErrorList)
+			if !ok {
+				t.Fatalf("Error is not ErrorList: %T", err)
+			}
+			if len(errs) != len(tc.want) {
+				t.Fatalf("Wanted %d errors but got %d: %s", len(tc.want), len(errs), err)
+			}
+			for i, w := range tc.want {
+				if msg := strings.Join(errs[i].Messages, " "); !strings.Contains(msg, w) {
+					t.Errorf("Error %d should contain %q but got %q", i, w, msg)
+				}
+			}
+		})
+	}
+}
+
+func TestSplitPath(t *testing.T) {
+	sep := string(os.PathListSeparator)
+	dirs := SplitPath("a" + sep + sep + "b")
+	if len(dirs) != 2 || dirs[0] != "a" || dirs[1] != "b" {
+		t.Error("Unexpected directories", dirs)
+	}
+	if SplitPath("") != nil {
+		t.Error("Empty path should have no directory")
+	}
+}
+
+func TestQualifiedImport(t *testing.T) {
+	dir := writeLibs(t, map[string]string{
+		"Token": `scilla_version 0 library Token type Op = | Mint of Uint128 | Burn let zero = Uint128 0 let mint = Mint zero`,
+		"Other": `scilla_version 0 library Other let zero = Int32 0 let mint = Uint32 1`,
+	})
+	err := check(t, New(dir), `
+scilla_version 0
+import Token as T Other ListUtils as L
+library L
+let is_mint =
+  fun (op : T.Op) =>
+    match op with
+    | T.Mint _ => True
+    | T.Burn => False
+    end
+let ops =
+  let b = T.Burn in
+  let nil = Nil {T.Op} in
+  Cons {T.Op} b nil
+let n : Uint32 = let len = @L.list_length T.Op in len ops
+let x : Uint128 = T.zero
+let y : Int32 = zero
+let z : Uint32 = mint
+let w : T.Op = T.mint
+
+contract C(op : T.Op)
+transition Run(o : T.Op)
+  match o with
+  | T.Mint amount =>
+    v = T.zero;
+    e = { _eventname : "Mint"; amount : amount; zero : v }; event e
+  | T.Burn =>
+  end
+end
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestImportNames(t *testing.T) {
+	dir := writeLibs(t, map[string]string{
+		"A": `scilla_version 0 library A type T = | C let x = Uint32 0`,
+		"B": `scilla_version 0 library B type T = | D let y = Uint32 0`,
+		"X": `scilla_version 0 library X type U = | C let x = Uint32 0`,
+		"V": `scilla_version 0 library V let x = Uint32 0`,
+	})
+	for _, tc := range []struct {
+		what string
+		code string
+		want []string
+	}{
+		{"unqualified value", `import X B library L let v = B.y`, []string{"Undefined variable B.y"}},
+		{"qualified value", `import A as F library L let v = x`, []string{"Undefined variable x"}},
+		{"qualified type", `import A as F library L let f = fun (t : T) => t`, []string{"Type T is not defined"}},
+		{"qualified constructor", `import A as F library L let c = C`, []string{"Undefined constructor C"}},
+		{"undefined qualified name", `import A as F library L let v = F.z`, []string{"Undefined variable F.z"}},
+		{"constructor conflict", `import A X library L`, []string{"Library X cannot be imported since constructor C is also defined in library A"}},
+		{"value conflict", `import A V library L`, []string{"Library V cannot be imported since x is also defined in library A"}},
+		{"type conflict", `import A as F B as G library L`, []string{"Library B cannot be imported since type T is also defined in library A"}},
+		{"no conflict with alias", `import A X as Y library L let v : Uint32 = Y.x`, nil},
+		{"duplicated import", `import A A as F library L`, []string{"Library A is already imported"}},
+		{"duplicated alias", `import A as F X as F library L`, []string{"Alias F is already used for library A"}},
+		{"local type", `import A library L type T = | E`, []string{"Type T is already defined in library A"}},
+	} {
+		t.Run(tc.what, func(t *testing.T) {
+			err := check(t, New(dir), tc.code)
+			if tc.want == nil {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("Error did not occur")
+			}
+			errs := err.(checker.ErrorList)
+			if len(errs) != len(tc.want) {
+				t.Fatalf("Wanted %d errors but got %d: %s", len(tc.want), len(errs), err)
+			}
+			for i, w := range tc.want {
+				if msg := strings.Join(errs[i].Messages, " "); !strings.Contains(msg, w) {
+					t.Errorf("Error %d should contain %q but got %q", i, w, msg)
+				}
+			}
+		})
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/rhysd/locerr"
 	"github.com/sirupsen/logrus"
 	"goscilla/driver"
+	"goscilla/loader"
 	"os"
 )
 
@@ -13,14 +14,27 @@ var (
 	help       = flag.Bool("help", false, "Show this help")
 	showTokens = flag.Bool("tokens", false, "Show tokens for input")
 	showAST    = flag.Bool("ast", false, "Show AST for input")
+	astFormat  = flag.String("ast-format", "tree", "Format of AST shown by -ast. 'tree', 'json' or 'scilla' (JSON in the shape of scilla-checker's AST, not verified against its output)")
+	showInfo   = flag.Bool("contractinfo", false, "Show contract info as JSON compatible with scilla-checker")
 	check      = flag.Bool("check", false, "Check code (syntax, types, ...) and report errors if exist")
+	libDir     = flag.String("libdir", "", "Directories to search imported libraries separated by '"+string(os.PathListSeparator)+"'. They are searched before directories in $"+loader.EnvPath+" and the standard library")
 )
 
-const usageHeader = `Usage: gocaml [flags] [file]
+const usageHeader = `Usage: goscilla [flags] [file]
+       goscilla [flags] check-init contract.scilla init.json
+       goscilla [flags] run -init init.json [-istate input_state.json -imessage input_message.json]
+                            [-iblockchain input_blockchain.json] [-o output.json] -gaslimit N -i contract.scilla
 
-  Compiler for GoCaml.
-  When file is given as argument, compiler will compile it. Otherwise, compiler
-  attempt to read from STDIN as source code to compile.
+  Toolchain for Scilla.
+  When file is given as argument, goscilla will process it. Otherwise, goscilla
+  attempts to read from STDIN as source code to process.
+
+  check-init checks the contract and validates parameters in init.json against
+  it, including the contract constraint.
+
+  run deploys the contract or executes its transition invoked by the message
+  and writes output.json. Flags after run are the same as scilla-runner's.
+  Without -imessage, the contract is deployed.
 
 Flags:`
 
@@ -47,7 +61,18 @@ func main() {
 	var src *locerr.Source
 	var err error
 
-	if flag.NArg() == 0 {
+	initFile := ""
+	var run *driver.RunFiles
+	if flag.Arg(0) == "run" {
+		run, src, err = parseRunFlags(flag.Args()[1:])
+	} else if flag.Arg(0) == "check-init" {
+		if flag.NArg() != 3 {
+			usage()
+			os.Exit(4)
+		}
+		initFile = flag.Arg(2)
+		src, err = locerr.NewSourceFromFile(flag.Arg(1))
+	} else if flag.NArg() == 0 {
 		src, err = locerr.NewSourceFromStdin()
 	} else {
 		src, err = locerr.NewSourceFromFile(flag.Arg(0))
@@ -58,15 +83,58 @@ func main() {
 		os.Exit(4)
 	}
 
-	d := driver.Driver{}
+	d := driver.Driver{LibPath: append(loader.SplitPath(*libDir), loader.PathFromEnv()...)}
 
 	switch {
+	case run != nil:
+		err = d.Run(src, run)
+	case initFile != "":
+		err = d.CheckInit(src, initFile)
 	case *showTokens:
 		d.PrintTokens(src)
 	case *showAST:
+		switch *astFormat {
+		case "tree":
+			err = d.PrintAST(src)
+		case "json":
+			err = d.PrintASTJSON(src)
+		case "scilla":
+			err = d.PrintASTScillaJSON(src)
+		default:
+			_, _ = fmt.Fprintf(os.Stderr, "Unknown AST format '%s'. It should be 'tree', 'json' or 'scilla'\n", *astFormat)
+			os.Exit(4)
+		}
+	case *showInfo:
+		err = d.PrintContractInfo(src)
 	case *check:
+		err = d.Check(src)
 	default:
-		//d.PrintAST(src)
 		d.Prettify(src)
 	}
+
+	if err != nil {
+		d.PrintErrors(err)
+		os.Exit(1)
+	}
+}
+
+// parseRunFlags parses flags of run subcommand which are compatible with scilla-runner.
+func parseRunFlags(args []string) (*driver.RunFiles, *locerr.Source, error) {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	fs.Usage = usage
+	files := &driver.RunFiles{}
+	fs.StringVar(&files.Init, "init", "", "Path to init.json")
+	fs.StringVar(&files.State, "istate", "", "Path to input_state.json")
+	fs.StringVar(&files.Message, "imessage", "", "Path to input_message.json")
+	fs.StringVar(&files.Blockchain, "iblockchain", "", "Path to input_blockchain.json")
+	fs.StringVar(&files.Output, "o", "", "Path to output.json. Output to stdout when omitted")
+	fs.Uint64Var(&files.GasLimit, "gaslimit", 0, "Gas limit of the execution")
+	input := fs.String("i", "", "Path to the contract")
+	_ = fs.Parse(args)
+	if *input == "" || files.Init == "" || files.GasLimit == 0 || (files.Message != "" && files.State == "") {
+		usage()
+		os.Exit(4)
+	}
+	src, err := locerr.NewSourceFromFile(*input)
+	return files, src, err
 }
@@ -0,0 +1,526 @@
+// Package resolver binds names referred in Scilla modules to their declarations. It assigns unique
+// names to variables so that later passes do not need to care about shadowing.
+package resolver
+
+import (
+	"fmt"
+	"github.com/rhysd/locerr"
+	"goscilla/ast"
+	"goscilla/checker"
+	"goscilla/internal/suggest"
+	"goscilla/token"
+	"sort"
+)
+
+// Kind is a kind of declaration.
+type Kind int
+
+const (
+	Local      Kind = iota // Variables bound by let, fun, patterns and statements
+	Param                  // Parameters of contract, transitions and procedures
+	LibEntry               // Entries defined by let in libraries, including imported ones
+	Field                  // Contract fields
+	Procedure              // Procedures
+	Transition             // Transitions
+	Implicit               // Implicit parameters and fields such as _sender and _balance
+	Principle              // Recursion principles such as nat_fold
+)
+
+var kindNames = [...]string{
+	Local:      "variable",
+	Param:      "parameter",
+	LibEntry:   "library entry",
+	Field:      "field",
+	Procedure:  "procedure",
+	Transition: "transition",
+	Implicit:   "implicit",
+	Principle:  "recursion principle",
+}
+
+func (k Kind) String() string {
+	return kindNames[k]
+}
+
+// Decl is a declaration which names refer.
+type Decl struct {
+	Kind Kind
+	// Name is unique in the module. It is also set to Symbol.Name of the declaring identifier and
+	// the referring variables. Local variables shadowing other names are renamed to `x$1`, `x$2`, ...
+	Name string
+	// DisplayName is the name written in source. Qualified names such as `F.x` are kept as is
+	DisplayName string
+	// Ident is the declaring identifier. It is nil for implicit names and recursion principles
+	Ident *ast.Ident
+	// Lib is the name of library where the entry is imported from. Empty for names in the module
+	Lib string
+}
+
+// Table is a result of name resolution which later passes and editors can refer.
+type Table struct {
+	// Declarations introduced by identifiers in the module
+	Defs map[*ast.Ident]*Decl
+	// Declarations which variables, fields and procedures refer
+	Uses map[*ast.VarRef]*Decl
+}
+
+// Refs returns all references to the declaration sorted by position.
+func (t *Table) Refs(d *Decl) []*ast.VarRef {
+	var refs []*ast.VarRef
+	for v, to := range t.Uses {
+		if to == d {
+			refs = append(refs, v)
+		}
+	}
+	sort.Slice(refs, func(i, j int) bool {
+		return refs[i].Pos().Offset < refs[j].Pos().Offset
+	})
+	return refs
+}
+
+// At returns the declaration which is declared or referred at the byte offset in source. It
+// returns nil when no name is at the offset.
+func (t *Table) At(offset int) *Decl {
+	in := func(n ast.Node) bool {
+		return n.Pos().Offset <= offset && offset < n.End().Offset
+	}
+	for i, d := range t.Defs {
+		if in(i) {
+			return d
+		}
+	}
+	for v, d := range t.Uses {
+		if in(v) {
+			return d
+		}
+	}
+	return nil
+}
+
+type Config struct {
+	// Importer resolves libraries imported by the module. When it is nil or a library cannot be
+	// imported, names which cannot be resolved are not reported since they may be defined in the
+	// library. Type checker reports the import errors.
+	Importer checker.Importer
+}
+
+// Resolve resolves names in the module. Names in imported libraries are not available. Use Config
+// to import libraries.
+func Resolve(a *ast.AST) (*Table, error) {
+	return (&Config{}).Resolve(a)
+}
+
+// Resolve resolves names in the module and renames variables to their unique names. Undefined
+// names are returned as checker.ErrorList with partially filled Table.
+func (conf *Config) Resolve(a *ast.AST) (*Table, error) {
+	r := &resolver{
+		conf:    conf,
+		table:   &Table{Defs: map[*ast.Ident]*Decl{}, Uses: map[*ast.VarRef]*Decl{}},
+		globals: map[string]*Decl{},
+		fields:  map[string]*Decl{},
+		procs:   map[string]*Decl{},
+		counts:  map[string]int{},
+	}
+	r.module(a)
+	if len(r.errs) > 0 {
+		sort.SliceStable(r.errs, func(i, j int) bool {
+			return r.errs[i].Start.Offset < r.errs[j].Start.Offset
+		})
+		return r.table, r.errs
+	}
+	return r.table, nil
+}
+
+// Implicit names. Their types are defined by checker
+var (
+	contractImplicits  = []string{"_this_address", "_creation_block", "_scilla_version"}
+	componentImplicits = []string{"_sender", "_origin", "_amount"}
+)
+
+// scope is a persistent linked list of local variables like the scope of checker.
+type scope struct {
+	decl   *Decl
+	parent *scope
+}
+
+func (s *scope) bind(d *Decl) *scope {
+	return &scope{d, s}
+}
+
+func (s *scope) lookup(name string) *Decl {
+	for ; s != nil; s = s.parent {
+		if s.decl.DisplayName == name {
+			return s.decl
+		}
+	}
+	return nil
+}
+
+type resolver struct {
+	conf    *Config
+	table   *Table
+	errs    checker.ErrorList
+	globals map[string]*Decl // Library entries, contract parameters and recursion principles
+	fields  map[string]*Decl
+	procs   map[string]*Decl // Procedures and transitions declared so far
+	counts  map[string]int   // Number of declarations of each name to make unique names
+	opaque  bool             // Some library could not be imported
+}
+
+func (r *resolver) errorIn(n ast.Node, format string, args ...interface{}) {
+	r.errs = append(r.errs, locerr.ErrorIn(n.Pos(), n.End(), fmt.Sprintf(format, args...)))
+}
+
+// undefined reports the reference which cannot be resolved with the most similar name among
+// candidates.
+func (r *resolver) undefined(v *ast.VarRef, what string, candidates []string) {
+	name := v.Symbol.DisplayName
+	msg := fmt.Sprintf("Undefined %s %s", what, name)
+	if s := suggest.Similar(name, candidates); s != "" {
+		msg += fmt.Sprintf(". Did you mean %s?", s)
+	}
+	r.errs = append(r.errs, locerr.ErrorIn(v.Pos(), v.End(), msg))
+}
+
+// unique returns the unique name for a new declaration of the name.
+func (r *resolver) unique(name string) string {
+	n := r.counts[name]
+	r.counts[name]++
+	if n == 0 {
+		return name
+	}
+	return fmt.Sprintf("%s$%d", name, n)
+}
+
+// declare introduces the declaration of the identifier. Only local variables are renamed. Other
+// names are kept since they are visible from outside of the module.
+func (r *resolver) declare(i *ast.Ident, k Kind, local bool) *Decl {
+	name := i.Symbol.DisplayName
+	d := &Decl{Kind: k, Name: name, DisplayName: name, Ident: i}
+	if local {
+		d.Name = r.unique(name)
+	}
+	i.Symbol.Name = d.Name
+	r.table.Defs[i] = d
+	return d
+}
+
+func (r *resolver) use(v *ast.VarRef, d *Decl) {
+	v.Symbol.Name = d.Name
+	r.table.Uses[v] = d
+}
+
+// ref resolves the variable in local scope and then in globals.
+func (r *resolver) ref(v *ast.VarRef, s *scope) {
+	name := v.Symbol.DisplayName
+	d := s.lookup(name)
+	if d == nil {
+		d = r.globals[name]
+	}
+	if d != nil {
+		r.use(v, d)
+		return
+	}
+	if r.opaque {
+		return
+	}
+	var names []string
+	for ; s != nil; s = s.parent {
+		names = append(names, s.decl.DisplayName)
+	}
+	for n := range r.globals {
+		names = append(names, n)
+	}
+	r.undefined(v, "variable", names)
+}
+
+func (r *resolver) refs(vs []*ast.VarRef, s *scope) {
+	for _, v := range vs {
+		r.ref(v, s)
+	}
+}
+
+func (r *resolver) field(f *ast.VarRef) {
+	if d, ok := r.fields[f.Symbol.DisplayName]; ok {
+		r.use(f, d)
+		return
+	}
+	names := make([]string, 0, len(r.fields))
+	for n := range r.fields {
+		names = append(names, n)
+	}
+	r.undefined(f, "field", names)
+}
+
+func (r *resolver) procedure(p *ast.VarRef) {
+	d, ok := r.procs[p.Symbol.DisplayName]
+	if ok && d.Kind == Procedure {
+		r.use(p, d)
+		return
+	}
+	if ok {
+		r.errorIn(p, "Transition %s cannot be called. Only procedures can be called", p.Symbol.DisplayName)
+		return
+	}
+	var names []string
+	for n, d := range r.procs {
+		if d.Kind == Procedure {
+			names = append(names, n)
+		}
+	}
+	r.undefined(p, "procedure", names)
+}
+
+func (r *resolver) global(name string, d *Decl) {
+	r.globals[name] = d
+	if r.counts[name] == 0 {
+		r.counts[name] = 1
+	}
+}
+
+func (r *resolver) module(a *ast.AST) {
+	for n := range checker.RecursionPrinciples {
+		r.global(n, &Decl{Kind: Principle, Name: n, DisplayName: n})
+	}
+	for _, i := range a.Imports {
+		for _, n := range i.Names {
+			r.importLib(n)
+		}
+	}
+
+	// Local variables must not take names of globals declared later
+	if a.Library != nil {
+		for _, e := range a.Library.Entries {
+			if d, ok := e.(*ast.LetDecl); ok {
+				r.counts[d.Ident.Symbol.DisplayName] = 1
+			}
+		}
+	}
+	if a.Contract != nil {
+		for _, p := range a.Contract.Params {
+			r.counts[p.Ident.Symbol.DisplayName] = 1
+		}
+	}
+
+	if a.Library != nil {
+		for _, e := range a.Library.Entries {
+			if d, ok := e.(*ast.LetDecl); ok {
+				r.expr(d.Bound, nil)
+				r.global(d.Ident.Symbol.DisplayName, r.declare(d.Ident, LibEntry, false))
+			}
+		}
+	}
+	if a.Contract != nil {
+		r.contract(a.Contract)
+	}
+	if len(a.Components) > 0 {
+		// Snippet which only contains components
+		r.fields["_balance"] = &Decl{Kind: Implicit, Name: "_balance", DisplayName: "_balance"}
+		r.components(a.Components)
+	}
+}
+
+// importLib makes entries of the imported library visible. Entries imported with alias F are
+// only visible as qualified names like `F.entry`.
+func (r *resolver) importLib(n *ast.ImportName) {
+	if r.conf.Importer == nil {
+		r.opaque = true
+		return
+	}
+	lib, err := r.conf.Importer.Import(n.Lib.Symbol.Name)
+	if err != nil || lib.AST == nil || lib.AST.Library == nil {
+		r.opaque = true
+		return
+	}
+	prefix := ""
+	if n.Alias != nil {
+		prefix = n.Alias.Symbol.Name + "."
+	}
+	for _, e := range lib.AST.Library.Entries {
+		if d, ok := e.(*ast.LetDecl); ok {
+			name := prefix + d.Ident.Symbol.Name
+			r.global(name, &Decl{Kind: LibEntry, Name: name, DisplayName: name, Ident: d.Ident, Lib: lib.Name})
+		}
+	}
+}
+
+func (r *resolver) contract(k *ast.Contract) {
+	for _, n := range contractImplicits {
+		r.global(n, &Decl{Kind: Implicit, Name: n, DisplayName: n})
+	}
+	for _, p := range k.Params {
+		r.global(p.Ident.Symbol.DisplayName, r.declare(p.Ident, Param, false))
+	}
+	if k.Constraint != nil {
+		r.expr(k.Constraint, nil)
+	}
+	r.fields["_balance"] = &Decl{Kind: Implicit, Name: "_balance", DisplayName: "_balance"}
+	for _, f := range k.Fields {
+		r.expr(f.Init, nil)
+		d := r.declare(f.Ident, Field, false)
+		if _, ok := r.fields[d.Name]; !ok {
+			r.fields[d.Name] = d
+		}
+	}
+	r.components(k.Components)
+}
+
+// components resolves components in order. Procedures can only be called by components declared
+// after them.
+func (r *resolver) components(comps []*ast.Component) {
+	for _, comp := range comps {
+		r.component(comp)
+		k := Transition
+		if comp.Token.Kind == token.PROCEDURE {
+			k = Procedure
+		}
+		d := r.declare(comp.Ident, k, false)
+		if _, ok := r.procs[d.Name]; !ok {
+			r.procs[d.Name] = d
+		}
+	}
+}
+
+func (r *resolver) component(comp *ast.Component) {
+	var s *scope
+	for _, n := range componentImplicits {
+		s = s.bind(&Decl{Kind: Implicit, Name: n, DisplayName: n})
+	}
+	seen := map[string]bool{}
+	for _, p := range comp.Params {
+		name := p.Ident.Symbol.DisplayName
+		if seen[name] {
+			r.errorIn(p.Ident, "Parameter %s is already defined", name)
+		}
+		seen[name] = true
+		s = s.bind(r.declare(p.Ident, Param, true))
+	}
+	r.stmts(comp.Body, s)
+}
+
+func (r *resolver) expr(e ast.Expr, s *scope) {
+	switch e := e.(type) {
+	case *ast.VarRef:
+		r.ref(e, s)
+	case *ast.Let:
+		r.expr(e.Bound, s)
+		r.expr(e.Body, s.bind(r.declare(e.Ident, Local, true)))
+	case *ast.Fun:
+		r.expr(e.Body, s.bind(r.declare(e.Param.Ident, Local, true)))
+	case *ast.TFun:
+		r.expr(e.Body, s)
+	case *ast.App:
+		r.ref(e.Func, s)
+		r.refs(e.Args, s)
+	case *ast.TApp:
+		r.ref(e.Func, s)
+	case *ast.Builtin:
+		r.refs(e.Args, s)
+	case *ast.Constr:
+		r.refs(e.Args, s)
+	case *ast.Message:
+		for _, ent := range e.Entries {
+			r.expr(ent.Value, s)
+		}
+	case *ast.Match:
+		r.ref(e.Target, s)
+		for _, arm := range e.Arms {
+			r.expr(arm.Body, r.pattern(arm.Pattern, s))
+		}
+	}
+}
+
+// pattern returns the scope extended with binders in the pattern.
+func (r *resolver) pattern(p ast.Pattern, s *scope) *scope {
+	seen := map[string]bool{}
+	var bind func(p ast.Pattern)
+	bind = func(p ast.Pattern) {
+		switch p := p.(type) {
+		case *ast.BinderPattern:
+			name := p.Ident.Symbol.DisplayName
+			if seen[name] {
+				r.errorIn(p, "Variable %s is bound more than once in pattern", name)
+			}
+			seen[name] = true
+			s = s.bind(r.declare(p.Ident, Local, true))
+		case *ast.ConstrPattern:
+			for _, a := range p.Args {
+				bind(a)
+			}
+		}
+	}
+	bind(p)
+	return s
+}
+
+// stmts resolves the statements in order. Variables bound by statements are visible in the
+// following statements.
+func (r *resolver) stmts(ss []ast.Stmt, s *scope) {
+	for _, st := range ss {
+		s = r.stmt(st, s)
+	}
+}
+
+func (r *resolver) keys(keys []*ast.MapKey, s *scope) {
+	for _, k := range keys {
+		r.ref(k.Key, s)
+	}
+}
+
+// stmt resolves the statement and returns the scope extended with the variable it binds.
+func (r *resolver) stmt(st ast.Stmt, s *scope) *scope {
+	bind := func(i *ast.Ident) *scope {
+		return s.bind(r.declare(i, Local, true))
+	}
+	switch st := st.(type) {
+	case *ast.Load:
+		r.field(st.Field)
+		return bind(st.Ident)
+	case *ast.RemoteLoad:
+		r.ref(st.Addr, s)
+		return bind(st.Ident)
+	case *ast.Store:
+		r.field(st.Field)
+		r.ref(st.Value, s)
+	case *ast.Bind:
+		r.expr(st.Value, s)
+		return bind(st.Ident)
+	case *ast.MapUpdate:
+		r.field(st.Map)
+		r.keys(st.Keys, s)
+		r.ref(st.Value, s)
+	case *ast.MapDelete:
+		r.field(st.Map)
+		r.keys(st.Keys, s)
+	case *ast.MapGet:
+		r.field(st.Map)
+		r.keys(st.Keys, s)
+		return bind(st.Ident)
+	case *ast.RemoteMapGet:
+		r.ref(st.Addr, s)
+		r.keys(st.Keys, s)
+		return bind(st.Ident)
+	case *ast.ReadFromBC:
+		return bind(st.Ident)
+	case *ast.Send:
+		r.ref(st.Msgs, s)
+	case *ast.Event:
+		r.ref(st.Event, s)
+	case *ast.Throw:
+		if st.Exception != nil {
+			r.ref(st.Exception, s)
+		}
+	case *ast.MatchStmt:
+		r.ref(st.Target, s)
+		for _, arm := range st.Arms {
+			// Variables bound in arms are not visible after the match
+			r.stmts(arm.Body, r.pattern(arm.Pattern, s))
+		}
+	case *ast.CallProc:
+		r.procedure(st.Proc)
+		r.refs(st.Args, s)
+	case *ast.Iterate:
+		r.ref(st.List, s)
+		r.procedure(st.Proc)
+	}
+	return s
+}
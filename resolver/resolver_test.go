@@ -0,0 +1,298 @@
+package resolver
+
+import (
+	"github.com/rhysd/locerr"
+	"goscilla/ast"
+	"goscilla/checker"
+	"goscilla/syntax"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func parse(t *testing.T, src *locerr.Source) *ast.AST {
+	a, err := syntax.Parse(src)
+	if err != nil {
+		t.Fatal("Parse error:", err)
+	}
+	return a
+}
+
+// importer imports libraries from sources in memory
+type importer map[string]string
+
+func (im importer) Import(name string) (*checker.Library, error) {
+	code, ok := im[name]
+	if !ok {
+		return nil, locerr.NewError("Library " + name + " is not found")
+	}
+	a, err := syntax.Parse(locerr.NewDummySource(code))
+	if err != nil {
+		return nil, err
+	}
+	return (&checker.Config{Importer: im}).CheckLibrary(a)
+}
+
+func TestResolveAndCheck(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("..", "checker", "testdata", "*.scilla"))
+	if err != nil {
+		panic(err)
+	}
+	for _, f := range files {
+		t.Run(f, func(t *testing.T) {
+			s, err := locerr.NewSourceFromFile(f)
+			if err != nil {
+				panic(err)
+			}
+			a := parse(t, s)
+			if _, err := Resolve(a); err != nil {
+				t.Fatal(err)
+			}
+			// Renamed variables must be still type checked
+			if _, err := checker.Check(a); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestUniqueNames(t *testing.T) {
+	code := `
+library L
+let x = Uint32 0
+let f = fun (y : Uint32) => let x = y in let y = x in y
+contract C(p : Uint32)
+field f : Uint32 = x
+procedure P(x : Uint32)
+  y <- f;
+  match x with
+  | y =>
+    f := y
+  end
+end
+transition T()
+  x = p;
+  P x
+end`
+	a := parse(t, locerr.NewDummySource(code))
+	tbl, err := Resolve(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seen := map[string]bool{}
+	for i, d := range tbl.Defs {
+		if d.Ident != i || d.Name != i.Symbol.Name {
+			t.Errorf("Declaration of %s is not consistent with identifier: %+v", i.Symbol.DisplayName, d)
+		}
+		if d.Kind == Field || d.Kind == Procedure || d.Kind == Transition {
+			continue
+		}
+		if seen[d.Name] {
+			t.Errorf("Name %s of %s is not unique", d.Name, i.Symbol.DisplayName)
+		}
+		seen[d.Name] = true
+	}
+
+	for _, want := range []struct {
+		at   string
+		name string
+		kind Kind
+	}{
+		{"x = Uint32", "x", LibEntry},
+		{"y : Uint32) =>", "y", Local},
+		{"x = y in", "x$1", Local},
+		{"y = x in", "y$1", Local},
+		{"p : Uint32)", "p", Param},
+		{"f : Uint32 = x", "f", Field},
+		{"x : Uint32)\n", "x$2", Param},
+		{"y <- f", "y$2", Local},
+		{"y =>", "y$3", Local},
+		{"x = p", "x$3", Local},
+	} {
+		d := tbl.At(strings.Index(code, want.at))
+		if d == nil {
+			t.Errorf("No declaration at %q", want.at)
+			continue
+		}
+		if d.Name != want.name || d.Kind != want.kind {
+			t.Errorf("Wanted %s %s at %q but got %s %s", want.kind, want.name, want.at, d.Kind, d.Name)
+		}
+	}
+
+	for v, d := range tbl.Uses {
+		if v.Symbol.Name != d.Name {
+			t.Errorf("Reference to %s has name %s but declaration has %s", v.Symbol.DisplayName, v.Symbol.Name, d.Name)
+		}
+	}
+
+	// `f := y` refers the field and the binder in the pattern
+	d := tbl.At(strings.Index(code, "f := y"))
+	if d == nil || d.Kind != Field {
+		t.Fatalf("Field is not found at `f := y`: %+v", d)
+	}
+	if refs := tbl.Refs(d); len(refs) != 2 || refs[0].Pos().Line != 8 || refs[1].Pos().Line != 11 {
+		t.Errorf("Wanted references to field f at line 8 and 11 but got %v", refs)
+	}
+	if d := tbl.At(strings.Index(code, "y\n  end")); d == nil || d.Name != "y$3" {
+		t.Errorf("Wanted y$3 but got %+v", d)
+	}
+	if d := tbl.At(strings.Index(code, "P x")); d == nil || d.Kind != Procedure {
+		t.Errorf("Wanted procedure P but got %+v", d)
+	}
+	if d := tbl.At(0); d != nil {
+		t.Errorf("Wanted nothing at the beginning but got %+v", d)
+	}
+
+	if _, err := checker.Check(a); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestImplicitNames(t *testing.T) {
+	code := `library L contract C() with let b = _creation_block in True =>
+transition T() x = _sender; y = _amount; z = _this_address; n = nat_fold; b <- _balance end`
+	tbl, err := Resolve(parse(t, locerr.NewDummySource(code)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]Kind{
+		"_creation_block": Implicit,
+		"_sender":         Implicit,
+		"_amount":         Implicit,
+		"_this_address":   Implicit,
+		"nat_fold":        Principle,
+		"_balance":        Implicit,
+	}
+	for v, d := range tbl.Uses {
+		if k, ok := want[v.Symbol.DisplayName]; !ok || d.Kind != k || d.Ident != nil {
+			t.Errorf("Unexpected declaration of %s: %+v", v.Symbol.DisplayName, d)
+		}
+	}
+	if len(tbl.Uses) != len(want) {
+		t.Errorf("Wanted %d references but got %d", len(want), len(tbl.Uses))
+	}
+}
+
+func TestImportedNames(t *testing.T) {
+	im := importer{
+		"Lib": `scilla_version 0 library Lib let one = Uint32 1`,
+	}
+	code := `scilla_version 0 import Lib as L library M let two = builtin add L.one L.one`
+	tbl, err := (&Config{Importer: im}).Resolve(parse(t, locerr.NewDummySource(code)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tbl.Uses) != 2 {
+		t.Fatalf("Wanted 2 references but got %d", len(tbl.Uses))
+	}
+	for v, d := range tbl.Uses {
+		if d.Kind != LibEntry || d.Lib != "Lib" || d.Name != "L.one" || d.Ident == nil || d.Ident.Symbol.Name != "one" {
+			t.Errorf("Unexpected declaration of %s: %+v", v.Symbol.DisplayName, d)
+		}
+	}
+
+	// Names which may be defined in libraries failed to import are not reported
+	code = `scilla_version 0 import Unknown library M let x = y`
+	if _, err := (&Config{Importer: im}).Resolve(parse(t, locerr.NewDummySource(code))); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResolveError(t *testing.T) {
+	for _, tc := range []struct {
+		what string
+		code string
+		want []string
+	}{
+		{
+			"undefined variable",
+			`library L let total = Uint32 0 let x = totl`,
+			[]string{"Undefined variable totl. Did you mean total?"},
+		},
+		{
+			"no suggestion",
+			`library L let x = abcdef`,
+			[]string{"Undefined variable abcdef"},
+		},
+		{
+			"later library entry",
+			`library L let x = y let y = Uint32 0`,
+			[]string{"Undefined variable y"},
+		},
+		{
+			"local out of scope",
+			`library L let f = fun (count : Uint32) => count let x = coun`,
+			[]string{"Undefined variable coun"},
+		},
+		{
+			"variables in match arms",
+			`library L contract C() transition T(o : Option Uint32) match o with | Some v => | None => end; x = v end`,
+			[]string{"Undefined variable v"},
+		},
+		{
+			"component parameter",
+			`library L contract C() transition T(amount : Uint128) end transition U() x = amount end`,
+			[]string{"Undefined variable amount. Did you mean _amount?"},
+		},
+		{
+			"undefined field",
+			`library L contract C() field balances : Map ByStr20 Uint128 = Emp ByStr20 Uint128 transition T(k : ByStr20) delete balancs[k] end`,
+			[]string{"Undefined field balancs. Did you mean balances?"},
+		},
+		{
+			"local is not field",
+			`library L contract C() transition T(x : Uint32) y <- x end`,
+			[]string{"Undefined field x"},
+		},
+		{
+			"undefined procedure",
+			`library L contract C() procedure Pay() end transition T() Pai end`,
+			[]string{"Undefined procedure Pai. Did you mean Pay?"},
+		},
+		{
+			"procedure declared later",
+			`library L contract C() transition T() P end procedure P() end`,
+			[]string{"Undefined procedure P"},
+		},
+		{
+			"transition call",
+			`library L contract C() transition T() end transition U(xs : List Uint32) forall xs T end`,
+			[]string{"Transition T cannot be called. Only procedures can be called"},
+		},
+		{
+			"duplicated binders",
+			`library L let f = fun (p : Pair Uint32 Uint32) => match p with | Pair x x => x end`,
+			[]string{"Variable x is bound more than once in pattern"},
+		},
+		{
+			"duplicated parameters",
+			`library L contract C() transition T(a : Uint32, a : Uint32) end`,
+			[]string{"Parameter a is already defined"},
+		},
+		{
+			"multiple errors",
+			`library L let x = a let y = b`,
+			[]string{"Undefined variable a", "Undefined variable b"},
+		},
+	} {
+		t.Run(tc.what, func(t *testing.T) {
+			_, err := Resolve(parse(t, locerr.NewDummySource(tc.code)))
+			if err == nil {
+				t.Fatal("Error did not occur")
+			}
+			errs, ok := err.(checker.ErrorList)
+			if !ok {
+				t.Fatalf("Error is not ErrorList: %T", err)
+			}
+			if len(errs) != len(tc.want) {
+				t.Fatalf("Wanted %d errors but got %d: %s", len(tc.want), len(errs), err)
+			}
+			for i, w := range tc.want {
+				if msg := strings.Join(errs[i].Messages, " "); !strings.Contains(msg, w) {
+					t.Errorf("Error %d should contain %q but got %q", i, w, msg)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,363 @@
+package runner
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"github.com/rhysd/locerr"
+	"goscilla/ast"
+	"goscilla/checker"
+	"goscilla/types"
+	"goscilla/value"
+	"math/big"
+	"strconv"
+)
+
+// DefaultMaxDepth is the default limit of depth of chain calls. The transition invoked by the
+// transaction of a user is at depth 0 and the recipients of messages it sends are at depth 1.
+const DefaultMaxDepth = 10
+
+// Chain is a local blockchain in process which holds accounts and deployed contracts. After a
+// transition finishes, the messages it sent are delivered to their recipients one by one, and
+// messages sent by each recipient are delivered before the next one, as Zilliqa processes chain
+// calls in depth-first order. A transaction is atomic: when any transition in it fails, all
+// changes of balances and fields made by the transaction are rolled back.
+type Chain struct {
+	BlockNumber *big.Int // Value of BLOCKNUMBER
+	MaxDepth    int      // Limit of depth of chain calls
+	accounts    map[string]*account
+}
+
+// account is a user account or a contract on the chain.
+type account struct {
+	nonce    uint64
+	fields   map[string]value.Value // Including _balance. User accounts only have _balance
+	contract *contract              // nil for user accounts
+}
+
+// contract is the code of a deployed contract.
+type contract struct {
+	ast *ast.AST
+	m   *machine // Machine where the library and the contract parameters are evaluated
+}
+
+// Receipt is the result of a transaction processed by Chain.
+type Receipt struct {
+	GasRemaining uint64
+	Calls        []*Call  // Deliveries of messages in the order they were processed
+	Events       []*Event // Events emitted by all transitions in the order they were emitted
+}
+
+// Call is a delivery of a message to a contract or a user account in a transaction.
+type Call struct {
+	Depth     int
+	Sender    string
+	Recipient string
+	Tag       string
+	Amount    string
+	Accepted  bool // Whether the recipient accepted the amount. Users always accept it
+}
+
+// Event is an event emitted by the contract at the address.
+type Event struct {
+	Address string
+	Name    string
+	Params  []*Param
+}
+
+// NewChain returns the chain without any account.
+func NewChain() *Chain {
+	return &Chain{
+		BlockNumber: big.NewInt(1),
+		MaxDepth:    DefaultMaxDepth,
+		accounts:    map[string]*account{},
+	}
+}
+
+func address(s string) (value.ByStrN, error) {
+	v, err := value.FromJSON(types.ByStr20, s, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Address %s is invalid: %s", s, err)
+	}
+	return v.(value.ByStrN), nil
+}
+
+// account returns the account at the address. A user account is created when no account exists.
+func (c *Chain) account(a value.ByStrN) *account {
+	acc, ok := c.accounts[a.String()]
+	if !ok {
+		acc = &account{fields: map[string]value.Value{"_balance": value.NewInt(types.Uint128, new(big.Int))}}
+		c.accounts[a.String()] = acc
+	}
+	return acc
+}
+
+func (acc *account) balance() *value.Int {
+	return acc.fields["_balance"].(*value.Int)
+}
+
+// Fund adds the amount of ZIL to the balance of the account at the address.
+func (c *Chain) Fund(addr, amount string) error {
+	a, err := address(addr)
+	if err != nil {
+		return err
+	}
+	v, err := value.FromJSON(types.Uint128, amount, nil)
+	if err != nil {
+		return fmt.Errorf("Amount %s is invalid: %s", amount, err)
+	}
+	acc := c.account(a)
+	b, err := acc.balance().Add(v.(*value.Int))
+	if err != nil {
+		return fmt.Errorf("Balance of %s cannot be funded: %s", a, err)
+	}
+	acc.fields["_balance"] = b
+	return nil
+}
+
+// State returns _balance and the fields of the account at the address in the same form as states
+// in output.json.
+func (c *Chain) State(addr string) ([]*Param, error) {
+	a, err := address(addr)
+	if err != nil {
+		return nil, err
+	}
+	acc, ok := c.accounts[a.String()]
+	if !ok {
+		return nil, fmt.Errorf("No account exists at address %s", a)
+	}
+	if acc.contract == nil {
+		return []*Param{{"_balance", types.Uint128.String(), acc.balance().Value.String()}}, nil
+	}
+	ps, serr := states(acc.contract.ast.Contract, acc.contract.m.info, acc.fields)
+	if serr != nil {
+		return nil, serr
+	}
+	return ps, nil
+}
+
+// user returns the account at the address which sends a transaction.
+func (c *Chain) user(addr string) (value.ByStrN, *account, error) {
+	a, err := address(addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	acc := c.account(a)
+	if acc.contract != nil {
+		return nil, nil, fmt.Errorf("Transaction must be sent from user account but %s is contract", a)
+	}
+	return a, acc, nil
+}
+
+func (c *Chain) queries() map[string]value.Value {
+	return map[string]value.Value{"BLOCKNUMBER": &value.BNum{Value: new(big.Int).Set(c.BlockNumber)}}
+}
+
+// remote returns the fields of the account at the address for reading remote state.
+func (c *Chain) remote(a value.ByStrN) (map[string]value.Value, bool) {
+	acc, ok := c.accounts[a.String()]
+	if !ok {
+		return nil, false
+	}
+	return acc.fields, true
+}
+
+// Deploy deploys the contract from the user account and returns the address of the contract. The
+// address is derived from the address and the nonce of the sender as Zilliqa does. init must not
+// contain implicit parameters such as _this_address since the chain gives them. The module must be
+// resolved and type checked with the info.
+func (c *Chain) Deploy(from string, a *ast.AST, info *checker.Info, init []*Param, gasLimit uint64) (string, error) {
+	sender, user, err := c.user(from)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	h.Write(sender)
+	binary.Write(h, binary.BigEndian, user.nonce)
+	addr := value.ByStrN(h.Sum(nil)[12:])
+
+	version := 0
+	if a.Version != nil {
+		version = a.Version.Value
+	}
+	implicits := []*Param{
+		{"_scilla_version", types.Uint32.String(), strconv.Itoa(version)},
+		{"_this_address", types.ByStr20.String(), addr.String()},
+		{"_creation_block", types.BNum.String(), c.BlockNumber.String()},
+	}
+	params, err := CheckInit(a, info, append(implicits, init...))
+	if err != nil {
+		return "", err
+	}
+	m, err := newMachine(a, info, params, gasLimit)
+	if err != nil {
+		return "", err
+	}
+
+	m.chain = c.queries()
+	user.nonce++
+	if err := m.catch(func() { m.deploy(a.Contract) }); err != nil {
+		return "", &Error{err, m.gas}
+	}
+	acc := c.account(addr)
+	m.fields["_balance"] = acc.balance()
+	acc.fields = m.fields
+	acc.contract = &contract{a, m}
+	return addr.String(), nil
+}
+
+// Call processes the transaction which sends the message from the user account of msg.Sender to
+// the contract or the user account at the address. _origin of the message is ignored since it is
+// the sender. When the transaction fails, the state of the chain is rolled back. Runtime errors
+// are returned as *Error and invalid inputs as other errors.
+func (c *Chain) Call(to string, msg *Message, gasLimit uint64) (*Receipt, error) {
+	sender, user, err := c.user(msg.Sender)
+	if err != nil {
+		return nil, err
+	}
+	recipient, err := address(to)
+	if err != nil {
+		return nil, err
+	}
+	v, err := value.FromJSON(types.Uint128, msg.Amount, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Value of _amount in message is invalid: %s", err)
+	}
+	amount := v.(*value.Int)
+	b, err := user.balance().Sub(amount)
+	if err != nil {
+		return nil, fmt.Errorf("Balance %s of %s is not enough to send amount %s", user.balance().Value, sender, amount.Value)
+	}
+
+	x := &txn{chain: c, origin: sender, queries: c.queries(), gas: gasLimit, receipt: &Receipt{}}
+	saved := c.snapshot()
+	acc := c.account(recipient)
+	if acc.contract == nil {
+		user.fields["_balance"] = b
+		if err := x.transfer(sender, recipient, acc, msg.Tag, amount, 0); err != nil {
+			c.accounts = saved
+			return nil, fmt.Errorf("Balance of %s cannot receive amount %s: %s", recipient, amount.Value, err)
+		}
+		user.nonce++
+		x.receipt.GasRemaining = x.gas
+		return x.receipt, nil
+	}
+
+	m := x.machine(acc, amount)
+	m2 := *msg
+	m2.Origin = msg.Sender
+	t, env, err := m.message(acc.contract.ast.Contract, &m2)
+	if err != nil {
+		c.accounts = saved
+		return nil, err
+	}
+	user.fields["_balance"] = b
+	if err := x.execute(m, acc.contract.ast.Contract.Ident, sender, recipient, msg.Tag, 0, func() { m.stmts(t.Body, env) }); err != nil {
+		c.accounts = saved
+		c.account(sender).nonce++
+		return nil, &Error{err, x.gas}
+	}
+	user.nonce++
+	x.receipt.GasRemaining = x.gas
+	return x.receipt, nil
+}
+
+// snapshot copies the accounts to roll back a failed transaction.
+func (c *Chain) snapshot() map[string]*account {
+	s := make(map[string]*account, len(c.accounts))
+	for k, acc := range c.accounts {
+		fs := make(map[string]value.Value, len(acc.fields))
+		for n, v := range acc.fields {
+			fs[n] = clone(v)
+		}
+		s[k] = &account{acc.nonce, fs, acc.contract}
+	}
+	return s
+}
+
+// txn is a transaction being processed on the chain. Gas is shared by all transitions in it.
+type txn struct {
+	chain   *Chain
+	origin  value.ByStrN
+	queries map[string]value.Value
+	gas     uint64
+	receipt *Receipt
+}
+
+// machine returns the machine which executes a transition of the contract at the account.
+func (x *txn) machine(acc *account, amount *value.Int) *machine {
+	m := *acc.contract.m
+	m.fields = acc.fields
+	m.chain = x.queries
+	m.amount = amount
+	m.gas = x.gas
+	m.remote = x.chain.remote
+	m.ev.SetCharge(m.charge)
+	return &m
+}
+
+// transfer moves the amount to the user account. The amount was already deducted from the sender.
+func (x *txn) transfer(sender, to value.ByStrN, acc *account, tag string, amount *value.Int, depth int) error {
+	b, err := acc.balance().Add(amount)
+	if err != nil {
+		return err
+	}
+	acc.fields["_balance"] = b
+	x.receipt.Calls = append(x.receipt.Calls, &Call{depth, sender.String(), to.String(), tag, amount.Value.String(), true})
+	return nil
+}
+
+// execute runs the transition in the machine and then delivers the messages sent by it. The
+// library of the contract is charged before the transition as it is evaluated on every invocation
+// by scilla-runner. When the contract did not accept the amount, it is returned to the sender. n is
+// the node where the message was sent, at which errors of returning the amount are reported.
+func (x *txn) execute(m *machine, n ast.Node, sender, to value.ByStrN, tag string, depth int, run func()) *locerr.Error {
+	err := m.catch(func() {
+		m.charge(n, m.libGas)
+		run()
+		m.settle()
+	})
+	x.gas = m.gas
+	if err != nil {
+		return err
+	}
+	if !m.accepted {
+		s := x.chain.account(sender)
+		// This can overflow when the sender received funds from other messages delivered after the
+		// amount was deducted
+		b, err := s.balance().Add(m.amount)
+		if err != nil {
+			return locerr.ErrorIn(n.Pos(), n.End(), fmt.Sprintf("Amount %s cannot be returned to %s: %s", m.amount.Value, sender, err))
+		}
+		s.fields["_balance"] = b
+	}
+	x.receipt.Calls = append(x.receipt.Calls, &Call{depth, sender.String(), to.String(), tag, m.amount.Value.String(), m.accepted})
+	for _, e := range m.events {
+		x.receipt.Events = append(x.receipt.Events, &Event{to.String(), e.Name, e.Params})
+	}
+	for _, s := range m.sent {
+		if err := x.deliver(to, s, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deliver delivers the message sent by the contract at the address to its recipient.
+func (x *txn) deliver(sender value.ByStrN, s *sentMessage, depth int) *locerr.Error {
+	if depth > x.chain.MaxDepth {
+		return locerr.ErrorIn(s.send.Pos(), s.send.End(), fmt.Sprintf("Depth of chain calls exceeds the limit %d", x.chain.MaxDepth))
+	}
+	acc := x.chain.account(s.recipient)
+	if acc.contract == nil {
+		if err := x.transfer(sender, s.recipient, acc, s.tag, s.amount, depth); err != nil {
+			return locerr.ErrorIn(s.send.Pos(), s.send.End(), fmt.Sprintf("Balance of %s cannot receive amount %s: %s", s.recipient, s.amount.Value, err))
+		}
+		return nil
+	}
+	m := x.machine(acc, s.amount)
+	return x.execute(m, s.send, sender, s.recipient, s.tag, depth, func() {
+		t, env := m.receive(acc.contract.ast.Contract, s.send, s.msg, s.tag, sender, x.origin)
+		m.stmts(t.Body, env)
+	})
+}
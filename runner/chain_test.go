@@ -0,0 +1,400 @@
+package runner
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const pool = `scilla_version 0
+library Pool
+let two = fun (a : Message) => fun (b : Message) =>
+  let nil = Nil {Message} in
+  let l = Cons {Message} b nil in
+  Cons {Message} a l
+contract Pool(strategy : ByStr20, logger : ByStr20)
+field total : Uint128 = Uint128 0
+
+transition Deposit(share : Uint128, fee : Uint128)
+  accept;
+  t <- total;
+  n = builtin add t _amount;
+  total := n;
+  m1 = {_tag : "Invest"; _recipient : strategy; _amount : share; pool : _this_address};
+  what = "deposit";
+  m2 = {_tag : "Log"; _recipient : logger; _amount : fee; what : what};
+  ms = two m1 m2;
+  send ms
+end
+`
+
+const strategy = `scilla_version 0
+library Strategy
+let one = fun (m : Message) => let nil = Nil {Message} in Cons {Message} m nil
+let zero = Uint128 0
+contract Strategy(logger : ByStr20, limit : Uint128)
+field invested : Uint128 = zero
+
+transition Invest(pool : ByStr20 with contract field total : Uint128 end)
+  t <- & pool.total;
+  exceeded = builtin lt limit t;
+  match exceeded with
+  | True =>
+    e = {_exception : "TooMuch"};
+    throw e
+  | False =>
+    accept;
+    i <- invested;
+    n = builtin add i _amount;
+    invested := n;
+    e = {_eventname : "Invested"; sender : _sender; origin : _origin; total : t};
+    event e;
+    what = "invest";
+    m = {_tag : "Log"; _recipient : logger; _amount : zero; what : what};
+    ms = one m;
+    send ms
+  end
+end
+`
+
+const logger = `scilla_version 0
+library Logger
+contract Logger()
+field logs : List String = Nil {String}
+
+transition Log(what : String)
+  l <- logs;
+  n = Cons {String} what l;
+  logs := n;
+  e = {_eventname : "Logged"; what : what; sender : _sender};
+  event e
+end
+
+transition Loop(n : Uint32)
+  l <- logs;
+  what = "loop";
+  x = Cons {String} what l;
+  logs := x;
+  one = Uint32 1;
+  m = builtin add n one;
+  zero = Uint128 0;
+  msg = {_tag : "Loop"; _recipient : _this_address; _amount : zero; n : m};
+  nil = Nil {Message};
+  ms = Cons {Message} msg nil;
+  send ms
+end
+`
+
+const user = "0x1111111111111111111111111111111111111111"
+
+func deploy(t *testing.T, c *Chain, src, init string) string {
+	a, info := check(t, src)
+	ps, err := ReadParams(strings.NewReader(init))
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr, err := c.Deploy(user, a, info, ps, 10000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return addr
+}
+
+// deployPool deploys the pool, the strategy and the logger and returns their addresses.
+func deployPool(t *testing.T, c *Chain) (string, string, string) {
+	if err := c.Fund(user, "1000"); err != nil {
+		t.Fatal(err)
+	}
+	l := deploy(t, c, logger, `[]`)
+	s := deploy(t, c, strategy, fmt.Sprintf(`[
+  {"vname": "logger", "type": "ByStr20", "value": "%s"},
+  {"vname": "limit", "type": "Uint128", "value": "500"}
+]`, l))
+	p := deploy(t, c, pool, fmt.Sprintf(`[
+  {"vname": "strategy", "type": "ByStr20", "value": "%s"},
+  {"vname": "logger", "type": "ByStr20", "value": "%s"}
+]`, s, l))
+	return p, s, l
+}
+
+func deposit(amount string, share, fee int) *Message {
+	return &Message{
+		Tag:    "Deposit",
+		Amount: amount,
+		Sender: user,
+		Params: []*Param{{"share", "Uint128", fmt.Sprint(share)}, {"fee", "Uint128", fmt.Sprint(fee)}},
+	}
+}
+
+func state(t *testing.T, c *Chain, addr string) string {
+	ps, err := c.State(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ss := make([]string, 0, len(ps))
+	for _, p := range ps {
+		ss = append(ss, fmt.Sprintf("%s=%v", p.VName, p.Value))
+	}
+	return strings.Join(ss, " ")
+}
+
+func TestChainDeployAddress(t *testing.T) {
+	c := NewChain()
+	p, s, l := deployPool(t, c)
+	for i, addr := range []string{l, s, p} {
+		h := sha256.Sum256(append(mustHex(t, user), 0, 0, 0, 0, 0, 0, 0, byte(i)))
+		if want := "0x" + hex.EncodeToString(h[12:]); addr != want {
+			t.Errorf("Wanted address %s for nonce %d but got %s", want, i, addr)
+		}
+	}
+	if have := state(t, c, l); have != "_balance=0 logs=[]" {
+		t.Error("Unexpected state of logger:", have)
+	}
+}
+
+func mustHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s[2:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestChainCall(t *testing.T) {
+	c := NewChain()
+	p, s, l := deployPool(t, c)
+
+	r, err := c.Call(p, deposit("100", 60, 5), 10000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Messages are delivered in depth-first order
+	calls := make([]string, 0, len(r.Calls))
+	for _, call := range r.Calls {
+		calls = append(calls, fmt.Sprintf("%d %s %s->%s %s %v", call.Depth, call.Tag, call.Sender, call.Recipient, call.Amount, call.Accepted))
+	}
+	want := []string{
+		fmt.Sprintf("0 Deposit %s->%s 100 true", user, p),
+		fmt.Sprintf("1 Invest %s->%s 60 true", p, s),
+		fmt.Sprintf("2 Log %s->%s 0 false", s, l),
+		fmt.Sprintf("1 Log %s->%s 5 false", p, l),
+	}
+	if strings.Join(calls, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Wanted calls:\n%s\nbut got:\n%s", strings.Join(want, "\n"), strings.Join(calls, "\n"))
+	}
+
+	// _sender is the contract which sent the message and _origin is the user
+	events := make([]string, 0, len(r.Events))
+	for _, e := range r.Events {
+		ps := make([]string, 0, len(e.Params))
+		for _, p := range e.Params {
+			ps = append(ps, fmt.Sprintf("%s=%v", p.VName, p.Value))
+		}
+		events = append(events, fmt.Sprintf("%s %s %s", e.Address, e.Name, strings.Join(ps, " ")))
+	}
+	want = []string{
+		fmt.Sprintf("%s Invested sender=%s origin=%s total=100", s, p, user),
+		fmt.Sprintf("%s Logged what=invest sender=%s", l, s),
+		fmt.Sprintf("%s Logged what=deposit sender=%s", l, p),
+	}
+	if strings.Join(events, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Wanted events:\n%s\nbut got:\n%s", strings.Join(want, "\n"), strings.Join(events, "\n"))
+	}
+
+	if r.GasRemaining == 0 || r.GasRemaining >= 10000 {
+		t.Error("Gas was not shared by transitions:", r.GasRemaining)
+	}
+
+	// The fee which the logger did not accept is returned to the pool
+	for _, tc := range []struct {
+		addr, want string
+	}{
+		{user, "_balance=900"},
+		{p, "_balance=40 total=100"},
+		{s, "_balance=60 invested=60"},
+		{l, "_balance=0 logs=[deposit invest]"},
+	} {
+		if have := state(t, c, tc.addr); have != tc.want {
+			t.Errorf("Wanted state %q of %s but got %q", tc.want, tc.addr, have)
+		}
+	}
+
+	r, err = c.Call(l, &Message{Tag: "AddFunds", Amount: "10", Sender: user}, 100)
+	if err == nil {
+		t.Fatal("Transaction to the transition which is not defined succeeded:", r.Calls)
+	}
+	// Transfer to user account
+	other := "0x2222222222222222222222222222222222222222"
+	if _, err := c.Call(other, &Message{Tag: "AddFunds", Amount: "10", Sender: user}, 100); err != nil {
+		t.Fatal(err)
+	}
+	if have := state(t, c, other); have != "_balance=10" {
+		t.Error("Amount was not transferred to user account:", have)
+	}
+}
+
+func TestChainRollback(t *testing.T) {
+	c := NewChain()
+	p, s, l := deployPool(t, c)
+	if _, err := c.Call(p, deposit("300", 0, 0), 10000); err != nil {
+		t.Fatal(err)
+	}
+
+	// The strategy throws since total of the pool exceeds its limit after the pool accepted
+	_, err := c.Call(p, deposit("300", 100, 0), 10000)
+	if err == nil {
+		t.Fatal("Error did not occur")
+	}
+	e, ok := err.(*Error)
+	if !ok {
+		t.Fatal("Runtime error was not returned:", err)
+	}
+	if msg := e.Error(); !strings.Contains(msg, "TooMuch") {
+		t.Error("Unexpected error:", msg)
+	}
+	if e.GasRemaining >= 10000 {
+		t.Error("Gas was not consumed:", e.GasRemaining)
+	}
+	for _, tc := range []struct {
+		addr, want string
+	}{
+		{user, "_balance=700"},
+		{p, "_balance=300 total=300"},
+		{s, "_balance=0 invested=0"},
+		{l, "_balance=0 logs=[deposit invest]"},
+	} {
+		if have := state(t, c, tc.addr); have != tc.want {
+			t.Errorf("Wanted state %q of %s after rollback but got %q", tc.want, tc.addr, have)
+		}
+	}
+}
+
+func TestChainDepthLimit(t *testing.T) {
+	c := NewChain()
+	_, _, l := deployPool(t, c)
+	c.MaxDepth = 3
+	_, err := c.Call(l, &Message{Tag: "Loop", Amount: "0", Sender: user, Params: []*Param{{"n", "Uint32", "0"}}}, 10000)
+	if err == nil {
+		t.Fatal("Error did not occur")
+	}
+	if msg := err.Error(); !strings.Contains(msg, "Depth of chain calls exceeds the limit 3") {
+		t.Error("Unexpected error:", msg)
+	}
+	if have := state(t, c, l); have != "_balance=0 logs=[]" {
+		t.Error("Logs were not rolled back:", have)
+	}
+}
+
+func TestChainInputError(t *testing.T) {
+	c := NewChain()
+	p, _, l := deployPool(t, c)
+	for _, tc := range []struct {
+		to   string
+		msg  *Message
+		want string
+	}{
+		{p, deposit("2000", 0, 0), "Balance 1000 of " + user + " is not enough to send amount 2000"},
+		{p, &Message{Tag: "Deposit", Amount: "0", Sender: l}, "must be sent from user account"},
+		{p, &Message{Tag: "Deposit", Amount: "0", Sender: user}, "Parameter share is missing"},
+		{"0x12", deposit("0", 0, 0), "Address 0x12 is invalid"},
+	} {
+		_, err := c.Call(tc.to, tc.msg, 10000)
+		if err == nil {
+			t.Errorf("Error did not occur for %s", tc.msg.Tag)
+			continue
+		}
+		if _, ok := err.(*Error); ok {
+			t.Errorf("Invalid input %v was not rejected before execution: %s", tc.msg, err)
+		}
+		if !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("Error %q should contain %q", err, tc.want)
+		}
+	}
+}
+
+const bouncer = `scilla_version 0
+library Bouncer
+let one = fun (m : Message) => let nil = Nil {Message} in Cons {Message} m nil
+let two = fun (a : Message) => fun (b : Message) =>
+  let nil = Nil {Message} in
+  let l = Cons {Message} b nil in
+  Cons {Message} a l
+let zero = Uint128 0
+contract Bouncer()
+
+transition Start(c : ByStr20, b : ByStr20, x : Uint128)
+  m1 = {_tag : "Pay"; _recipient : c; _amount : zero; to : _this_address; x : x};
+  m2 = {_tag : "Refuse"; _recipient : b; _amount : x};
+  ms = two m1 m2;
+  send ms
+end
+
+transition Pay(to : ByStr20, x : Uint128)
+  m = {_tag : "Receive"; _recipient : to; _amount : x};
+  ms = one m;
+  send ms
+end
+
+transition Receive()
+  accept
+end
+
+transition Refuse()
+end
+`
+
+func TestChainRefundOverflow(t *testing.T) {
+	c := NewChain()
+	a := deploy(t, c, bouncer, `[]`)
+	b := deploy(t, c, bouncer, `[]`)
+	cc := deploy(t, c, bouncer, `[]`)
+	max := "340282366920938463463374607431768211455"
+	if err := c.Fund(a, max); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Fund(cc, "10"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The amount which b refuses cannot be returned since a got it back from c in the meantime
+	_, err := c.Call(a, &Message{Tag: "Start", Amount: "0", Sender: user, Params: []*Param{
+		{"c", "ByStr20", cc}, {"b", "ByStr20", b}, {"x", "Uint128", "10"},
+	}}, 10000)
+	if err == nil {
+		t.Fatal("Error did not occur")
+	}
+	e, ok := err.(*Error)
+	if !ok {
+		t.Fatal("Runtime error was not returned:", err)
+	}
+	if msg := e.Error(); !strings.Contains(msg, "Amount 10 cannot be returned to "+a) || !strings.Contains(msg, "send ms") {
+		t.Error("Unexpected error:", msg)
+	}
+	if have := state(t, c, a); have != "_balance="+max {
+		t.Error("Balance was not rolled back:", have)
+	}
+	if have := state(t, c, cc); have != "_balance=10" {
+		t.Error("Balance was not rolled back:", have)
+	}
+}
+
+func TestChainSendBeforeAccept(t *testing.T) {
+	c := NewChain()
+	if err := c.Fund(user, "1000"); err != nil {
+		t.Fatal(err)
+	}
+	f := deploy(t, c, forwarder, `[]`)
+	to := "0x2222222222222222222222222222222222222222"
+	if _, err := c.Call(f, &Message{Tag: "Forward", Amount: "100", Sender: user, Params: []*Param{{"to", "ByStr20", to}}}, 10000); err != nil {
+		t.Fatal(err)
+	}
+	if have := state(t, c, f); have != "_balance=0" {
+		t.Error("Accepted amount was not forwarded:", have)
+	}
+	if have := state(t, c, to); have != "_balance=100" {
+		t.Error("Recipient did not receive the amount:", have)
+	}
+}
@@ -0,0 +1,389 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/rhysd/locerr"
+	"goscilla/ast"
+	"goscilla/checker"
+	"goscilla/eval"
+	"goscilla/token"
+	"goscilla/types"
+	"goscilla/value"
+	"io"
+	"math/big"
+)
+
+// Message is the message which invokes a transition, read from input_message.json:
+// {"_tag": "Transfer", "_amount": "0", "_sender": "0x...", "_origin": "0x...", "params": [...]}.
+type Message struct {
+	Tag    string   `json:"_tag"`
+	Amount string   `json:"_amount"`
+	Sender string   `json:"_sender"`
+	Origin string   `json:"_origin"`
+	Params []*Param `json:"params"`
+}
+
+// ReadMessage reads the message such as input_message.json.
+func ReadMessage(r io.Reader) (*Message, error) {
+	var m Message
+	if err := json.NewDecoder(r).Decode(&m); err != nil {
+		return nil, fmt.Errorf("Message must be a JSON object with _tag, _amount, _sender, _origin and params: %s", err)
+	}
+	if m.Tag == "" || m.Amount == "" || m.Sender == "" {
+		return nil, fmt.Errorf("Message must have _tag, _amount and _sender")
+	}
+	// _origin was added later. Old messages are sent by the origin directly
+	if m.Origin == "" {
+		m.Origin = m.Sender
+	}
+	for i, p := range m.Params {
+		if p == nil || p.VName == "" || p.Type == "" || p.Value == nil {
+			return nil, fmt.Errorf("Parameter at index %d of message must have vname, type and value", i)
+		}
+	}
+	return &m, nil
+}
+
+// Input is the set of inputs of scilla-runner.
+type Input struct {
+	Init       []*Param // init.json
+	State      []*Param // input_state.json. It is not used on deployment
+	Message    *Message // input_message.json. nil means deployment of the contract
+	Blockchain []*Param // input_blockchain.json such as BLOCKNUMBER
+	GasLimit   uint64
+}
+
+// Error is a runtime error which aborts the execution, such as `throw` or running out of gas.
+type Error struct {
+	Err          *locerr.Error
+	GasRemaining uint64
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// failure is used as a panic value to abort execution on runtime errors.
+type failure struct {
+	err *locerr.Error
+}
+
+// machine executes statements of a contract. Fields are updated in place.
+type machine struct {
+	info     *checker.Info
+	ev       *eval.Evaluator
+	fields   map[string]value.Value // Including _balance
+	procs    map[string]*ast.Component
+	chain    map[string]value.Value // Values of blockchain queries such as BLOCKNUMBER
+	implicit *eval.Env              // _sender, _origin and _amount
+	amount   *value.Int
+	accepted bool
+	msgs     []*OutMessage
+	sent     []*sentMessage
+	events   []*OutEvent
+	gas      uint64
+	libGas   uint64 // Gas consumed by evaluating the library
+
+	// remote returns the fields including _balance of the account at the address. nil means
+	// remote state cannot be read
+	remote func(addr value.ByStrN) (map[string]value.Value, bool)
+}
+
+// sentMessage is a message sent by `send` to be delivered to its recipient by Chain.
+type sentMessage struct {
+	send      *ast.Send
+	msg       *value.Msg
+	tag       string
+	amount    *value.Int
+	recipient value.ByStrN
+}
+
+func (m *machine) fail(n ast.Node, format string, args ...interface{}) {
+	panic(failure{locerr.ErrorIn(n.Pos(), n.End(), fmt.Sprintf(format, args...))})
+}
+
+// Run deploys the contract or executes the transition invoked by the message in the input. The
+// module must be resolved and type checked with the info. Invalid inputs are returned as
+// checker.ErrorList located at declarations in source. Runtime errors are returned as *Error.
+func Run(a *ast.AST, info *checker.Info, in *Input) (*Output, error) {
+	k := a.Contract
+	var params map[string]value.Value
+	var err error
+	if in.Message == nil {
+		params, err = CheckInit(a, info, in.Init)
+	} else {
+		params, err = initValues(a, info, in.Init)
+	}
+	if err != nil {
+		return nil, err
+	}
+	chain, err := blockchain(in.Blockchain, info)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := newMachine(a, info, params, in.GasLimit)
+	if err != nil {
+		return nil, err
+	}
+	m.chain = chain
+
+	var run func()
+	if in.Message == nil {
+		run = func() { m.deploy(k) }
+	} else {
+		if err := m.state(k, in.State); err != nil {
+			return nil, err
+		}
+		t, env, err := m.message(k, in.Message)
+		if err != nil {
+			return nil, err
+		}
+		run = func() {
+			m.stmts(t.Body, env)
+			m.settle()
+		}
+	}
+
+	if err := m.catch(run); err != nil {
+		return nil, &Error{err, m.gas}
+	}
+
+	out := &Output{GasRemaining: m.gas, Accepted: m.accepted, Messages: m.msgs, Events: m.events}
+	if a.Version != nil {
+		out.ScillaMajorVersion = a.Version.Value
+	}
+	states, serr := states(k, info, m.fields)
+	if serr != nil {
+		return nil, &Error{serr, m.gas}
+	}
+	out.States = states
+	return out, nil
+}
+
+// states returns _balance and the fields of the contract in declaration order as parameters.
+func states(k *ast.Contract, info *checker.Info, fields map[string]value.Value) ([]*Param, *locerr.Error) {
+	ps := []*Param{{"_balance", types.Uint128.String(), fields["_balance"].(*value.Int).Value.String()}}
+	for _, f := range k.Fields {
+		j, err := value.ToJSON(fields[f.Ident.Symbol.Name])
+		if err != nil {
+			return nil, locerr.ErrorIn(f.Ident.Pos(), f.Ident.End(), fmt.Sprintf("Field %s cannot be output: %s", f.Ident.Symbol.DisplayName, err))
+		}
+		ps = append(ps, &Param{f.Ident.Symbol.DisplayName, info.Defs[f.Ident].String(), j})
+	}
+	return ps, nil
+}
+
+// newMachine evaluates the library with the gas and binds the contract parameters and the
+// implicit parameters in init.json. Running out of gas is returned as *Error.
+func newMachine(a *ast.AST, info *checker.Info, params map[string]value.Value, gas uint64) (*machine, error) {
+	m := &machine{
+		info:   info,
+		ev:     eval.New(info),
+		fields: map[string]value.Value{},
+		procs:  map[string]*ast.Component{},
+		amount: value.NewInt(types.Uint128, new(big.Int)),
+		gas:    gas,
+	}
+	m.ev.SetCharge(m.charge)
+	var err error
+	if ferr := m.catch(func() { err = m.ev.Module(a) }); ferr != nil {
+		return nil, &Error{ferr, m.gas}
+	}
+	if err != nil {
+		return nil, checker.ErrorList{err.(*locerr.Error)}
+	}
+	m.libGas = gas - m.gas
+	for _, i := range initImplicits {
+		m.ev.Define(i.name, params[i.name])
+	}
+	for _, p := range a.Contract.Params {
+		m.ev.Define(p.Ident.Symbol.Name, params[p.Ident.Symbol.DisplayName])
+	}
+	for _, c := range a.Contract.Components {
+		if c.Token.Kind == token.PROCEDURE {
+			m.procs[c.Ident.Symbol.Name] = c
+		}
+	}
+	return m, nil
+}
+
+// catch recovers the failure in f and returns its error.
+func (m *machine) catch(f func()) (err *locerr.Error) {
+	defer func() {
+		if r := recover(); r != nil {
+			fl, ok := r.(failure)
+			if !ok {
+				panic(r)
+			}
+			err = fl.err
+		}
+	}()
+	f()
+	return nil
+}
+
+// blockchain reads values of blockchain queries in input_blockchain.json.
+func blockchain(ps []*Param, info *checker.Info) (map[string]value.Value, error) {
+	vals := map[string]value.Value{}
+	for _, p := range ps {
+		t, err := types.Parse(p.Type, info.ADTs)
+		if err != nil {
+			return nil, fmt.Errorf("Type of %s in input_blockchain.json is invalid: %s", p.VName, err)
+		}
+		v, err := value.FromJSON(t, p.Value, info.ADTs)
+		if err != nil {
+			return nil, fmt.Errorf("Value of %s in input_blockchain.json is invalid: %s", p.VName, err)
+		}
+		vals[p.VName] = v
+	}
+	return vals, nil
+}
+
+// deploy initializes the fields of the contract.
+func (m *machine) deploy(k *ast.Contract) {
+	m.fields["_balance"] = value.NewInt(types.Uint128, new(big.Int))
+	for _, f := range k.Fields {
+		m.fields[f.Ident.Symbol.Name] = m.eval(f.Init, nil)
+	}
+}
+
+// state reads values of fields in input_state.json.
+func (m *machine) state(k *ast.Contract, ps []*Param) error {
+	var errs checker.ErrorList
+	errorIn := func(n ast.Node, format string, args ...interface{}) {
+		errs = append(errs, locerr.ErrorIn(n.Pos(), n.End(), fmt.Sprintf(format, args...)))
+	}
+	given := map[string]*Param{}
+	for _, p := range ps {
+		if _, ok := given[p.VName]; ok {
+			errorIn(k.Ident, "Field %s is given more than once in input_state.json", p.VName)
+		}
+		given[p.VName] = p
+	}
+	field := func(n ast.Node, name, display string, want types.Type) {
+		p, ok := given[display]
+		if !ok {
+			errorIn(n, "Field %s is missing in input_state.json", display)
+			return
+		}
+		delete(given, display)
+		v, err := paramValue("Field", "input_state.json", p, want, m.info.ADTs)
+		if err != nil {
+			errorIn(n, "%s", err)
+			return
+		}
+		m.fields[name] = v
+	}
+	field(k.Ident, "_balance", "_balance", types.Uint128)
+	for _, f := range k.Fields {
+		field(f.Ident, f.Ident.Symbol.Name, f.Ident.Symbol.DisplayName, m.info.Defs[f.Ident])
+	}
+	for _, p := range ps {
+		if _, ok := given[p.VName]; ok {
+			errorIn(k.Ident, "Field %s in input_state.json is not declared by contract %s", p.VName, k.Ident.Symbol.DisplayName)
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// message finds the transition invoked by the message and returns it with the environment where
+// its parameters and implicit parameters are bound.
+func (m *machine) message(k *ast.Contract, msg *Message) (*ast.Component, *eval.Env, error) {
+	t := transition(k, msg.Tag)
+	if t == nil {
+		return nil, nil, checker.ErrorList{locerr.ErrorIn(k.Ident.Pos(), k.Ident.End(), fmt.Sprintf("Transition %s invoked by input_message.json is not defined in contract %s", msg.Tag, k.Ident.Symbol.DisplayName))}
+	}
+
+	var errs checker.ErrorList
+	errorIn := func(n ast.Node, format string, args ...interface{}) {
+		errs = append(errs, locerr.ErrorIn(n.Pos(), n.End(), fmt.Sprintf(format, args...)))
+	}
+	implicit := func(name string, t types.Type, s string) value.Value {
+		v, err := value.FromJSON(t, s, nil)
+		if err != nil {
+			errorIn(k.Ident, "Value of %s in input_message.json is invalid: %s", name, err)
+		}
+		return v
+	}
+	amount := implicit("_amount", types.Uint128, msg.Amount)
+	sender := implicit("_sender", types.ByStr20, msg.Sender)
+	origin := implicit("_origin", types.ByStr20, msg.Origin)
+	if len(errs) > 0 {
+		return nil, nil, errs
+	}
+	m.amount = amount.(*value.Int)
+	m.implicit = m.implicit.Bind("_sender", sender).Bind("_origin", origin).Bind("_amount", amount)
+
+	given := map[string]*Param{}
+	for _, p := range msg.Params {
+		if _, ok := given[p.VName]; ok {
+			errorIn(t.Ident, "Parameter %s is given more than once in input_message.json", p.VName)
+		}
+		given[p.VName] = p
+	}
+	env := m.implicit
+	for _, p := range t.Params {
+		name := p.Ident.Symbol.DisplayName
+		g, ok := given[name]
+		if !ok {
+			errorIn(p.Ident, "Parameter %s is missing in input_message.json", name)
+			continue
+		}
+		delete(given, name)
+		v, err := paramValue("Parameter", "input_message.json", g, m.info.Defs[p.Ident], m.info.ADTs)
+		if err != nil {
+			errorIn(p.Ident, "%s", err)
+			continue
+		}
+		env = env.Bind(p.Ident.Symbol.Name, v)
+	}
+	for _, p := range msg.Params {
+		if _, ok := given[p.VName]; ok {
+			errorIn(t.Ident, "Parameter %s in input_message.json is not declared by transition %s", p.VName, msg.Tag)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, nil, errs
+	}
+	return t, env, nil
+}
+
+// transition returns the transition of the name in the contract. It returns nil when not found.
+func transition(k *ast.Contract, name string) *ast.Component {
+	for _, c := range k.Components {
+		if c.Token.Kind == token.TRANSITION && c.Ident.Symbol.DisplayName == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// receive finds the transition invoked by the message sent by another contract and returns it with
+// the environment where its parameters and implicit parameters are bound. Errors are reported at
+// the node n.
+func (m *machine) receive(k *ast.Contract, n ast.Node, msg *value.Msg, tag string, sender, origin value.ByStrN) (*ast.Component, *eval.Env) {
+	t := transition(k, tag)
+	if t == nil {
+		m.fail(n, "Transition %s invoked by message is not defined in contract %s", tag, k.Ident.Symbol.DisplayName)
+	}
+	m.implicit = m.implicit.Bind("_sender", sender).Bind("_origin", origin).Bind("_amount", m.amount)
+	env := m.implicit
+	for _, p := range t.Params {
+		name := p.Ident.Symbol.DisplayName
+		v := msg.Field(name)
+		if v == nil {
+			m.fail(n, "Parameter %s of transition %s is missing in message %s", name, tag, msg)
+		}
+		want := m.info.Defs[p.Ident]
+		if have := v.Type(); !types.Assignable(want, have) && !isAddress(want, have) {
+			m.fail(n, "Parameter %s of transition %s is declared as %s but message gives value of type %s", name, tag, want, have)
+		}
+		env = env.Bind(p.Ident.Symbol.Name, v)
+	}
+	return t, env
+}
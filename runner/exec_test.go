@@ -0,0 +1,519 @@
+package runner
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/rhysd/locerr"
+	"goscilla/ast"
+	"goscilla/checker"
+	"goscilla/loader"
+	"goscilla/resolver"
+	"goscilla/syntax"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const bank = `scilla_version 0
+import ListUtils
+library Bank
+let one_msg = fun (m : Message) => let nil = Nil {Message} in Cons {Message} m nil
+let zero = Uint128 0
+contract Bank(owner : ByStr20)
+field total : Uint128 = zero
+field allowances : Map ByStr20 (Map ByStr20 Uint128) = Emp ByStr20 (Map ByStr20 Uint128)
+field last : Option BNum = None {BNum}
+
+procedure Add(v : Uint128)
+  t <- total;
+  n = builtin add t v;
+  total := n
+end
+
+procedure Revoke(to : ByStr20)
+  delete allowances[_sender][to]
+end
+
+transition Deposit(to : ByStr20, amt : Uint128)
+  accept;
+  allowances[_sender][to] := amt;
+  Add amt;
+  bn <- & BLOCKNUMBER;
+  l = Some {BNum} bn;
+  last := l;
+  e = {_eventname : "Deposited"; from : _sender; amount : amt};
+  event e;
+  m = {_tag : "Notify"; _recipient : to; _amount : amt; amount : amt};
+  ms = one_msg m;
+  send ms
+end
+
+transition RevokeAll(tos : List ByStr20)
+  before <- allowances;
+  forall tos Revoke;
+  found <- exists allowances[_sender][owner];
+  match found with
+  | True =>
+    e = {_exception : "NotRevoked"};
+    throw e
+  | False =>
+    all <- allowances;
+    e = {_eventname : "Revoked"; before : before; after : all};
+    event e
+  end
+end
+`
+
+const (
+	owner = "0x1234567890123456789012345678901234567890"
+	other = "0xabfeccdc9012345678901234567890f777567890"
+)
+
+const bankInit = `[
+  {"vname": "_scilla_version", "type": "Uint32", "value": "0"},
+  {"vname": "_this_address", "type": "ByStr20", "value": "` + other + `"},
+  {"vname": "_creation_block", "type": "BNum", "value": "1"},
+  {"vname": "owner", "type": "ByStr20", "value": "` + owner + `"}
+]`
+
+const bankState = `[
+  {"vname": "_balance", "type": "Uint128", "value": "100"},
+  {"vname": "total", "type": "Uint128", "value": "3"},
+  {"vname": "allowances", "type": "Map (ByStr20) (Map (ByStr20) (Uint128))", "value": [
+    {"key": "` + owner + `", "val": [{"key": "` + owner + `", "val": "1"}, {"key": "` + other + `", "val": "2"}]}
+  ]},
+  {"vname": "last", "type": "Option (BNum)", "value": {"constructor": "None", "argtypes": ["BNum"], "arguments": []}}
+]`
+
+func checkBank(t *testing.T) (*ast.AST, *checker.Info) {
+	return check(t, bank)
+}
+
+func check(t *testing.T, src string) (*ast.AST, *checker.Info) {
+	a, err := syntax.Parse(locerr.NewDummySource(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := loader.New()
+	if _, err := (&resolver.Config{Importer: l}).Resolve(a); err != nil {
+		t.Fatal(err)
+	}
+	info, err := (&checker.Config{Importer: l}).Check(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a, info
+}
+
+func bankInput(t *testing.T, msg string) *Input {
+	init, err := ReadParams(strings.NewReader(bankInit))
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain, err := ReadParams(strings.NewReader(`[{"vname": "BLOCKNUMBER", "type": "BNum", "value": "42"}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := &Input{Init: init, Blockchain: chain, GasLimit: 8000}
+	if msg == "" {
+		return in
+	}
+	if in.State, err = ReadParams(strings.NewReader(bankState)); err != nil {
+		t.Fatal(err)
+	}
+	if in.Message, err = ReadMessage(strings.NewReader(msg)); err != nil {
+		t.Fatal(err)
+	}
+	return in
+}
+
+// runBank runs the bank contract and returns output.json. Expected outputs in tests are regression
+// outputs of goscilla, not captured from scilla-runner.
+func runBank(t *testing.T, in *Input) string {
+	a, info := checkBank(t)
+	out, err := Run(a, info, in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	if err := out.WriteJSON(&b); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+func TestRunDeploy(t *testing.T) {
+	have := runBank(t, bankInput(t, ""))
+	want := `{
+  "scilla_major_version": "0",
+  "gas_remaining": "7958",
+  "_accepted": "false",
+  "messages": [],
+  "states": [
+    { "vname": "_balance", "type": "Uint128", "value": "0" },
+    { "vname": "total", "type": "Uint128", "value": "0" },
+    {
+      "vname": "allowances",
+      "type": "Map (ByStr20) (Map (ByStr20) (Uint128))",
+      "value": []
+    },
+    {
+      "vname": "last",
+      "type": "Option (BNum)",
+      "value": {
+        "constructor": "None",
+        "argtypes": [ "BNum" ],
+        "arguments": []
+      }
+    }
+  ],
+  "events": []
+}`
+	if have != want {
+		t.Errorf("Wanted:\n%s\nbut got:\n%s", want, have)
+	}
+}
+
+func TestRunTransition(t *testing.T) {
+	have := runBank(t, bankInput(t, `{
+  "_tag": "Deposit", "_amount": "50", "_sender": "`+other+`", "_origin": "`+owner+`",
+  "params": [{"vname": "to", "type": "ByStr20", "value": "`+owner+`"}, {"vname": "amt", "type": "Uint128", "value": "7"}]
+}`))
+	want := `{
+  "scilla_major_version": "0",
+  "gas_remaining": "7620",
+  "_accepted": "true",
+  "messages": [
+    {
+      "_tag": "Notify",
+      "_amount": "7",
+      "_recipient": "0x1234567890123456789012345678901234567890",
+      "params": [ { "vname": "amount", "type": "Uint128", "value": "7" } ]
+    }
+  ],
+  "states": [
+    { "vname": "_balance", "type": "Uint128", "value": "143" },
+    { "vname": "total", "type": "Uint128", "value": "10" },
+    {
+      "vname": "allowances",
+      "type": "Map (ByStr20) (Map (ByStr20) (Uint128))",
+      "value": [
+        {
+          "key": "0x1234567890123456789012345678901234567890",
+          "val": [
+            { "key": "0x1234567890123456789012345678901234567890", "val": "1" },
+            { "key": "0xabfeccdc9012345678901234567890f777567890", "val": "2" }
+          ]
+        },
+        {
+          "key": "0xabfeccdc9012345678901234567890f777567890",
+          "val": [
+            { "key": "0x1234567890123456789012345678901234567890", "val": "7" }
+          ]
+        }
+      ]
+    },
+    {
+      "vname": "last",
+      "type": "Option (BNum)",
+      "value": {
+        "constructor": "Some",
+        "argtypes": [ "BNum" ],
+        "arguments": [ "42" ]
+      }
+    }
+  ],
+  "events": [
+    {
+      "_eventname": "Deposited",
+      "params": [
+        {
+          "vname": "from",
+          "type": "ByStr20",
+          "value": "0xabfeccdc9012345678901234567890f777567890"
+        },
+        { "vname": "amount", "type": "Uint128", "value": "7" }
+      ]
+    }
+  ]
+}`
+	if have != want {
+		t.Errorf("Wanted:\n%s\nbut got:\n%s", want, have)
+	}
+}
+
+func TestRunProcedureLoop(t *testing.T) {
+	have := runBank(t, bankInput(t, `{
+  "_tag": "RevokeAll", "_amount": "0", "_sender": "`+owner+`", "_origin": "`+owner+`",
+  "params": [{"vname": "tos", "type": "List ByStr20", "value": ["`+owner+`", "`+other+`"]}]
+}`))
+	// The map loaded before the deletions must not be affected by them
+	for _, want := range []string{
+		`"_accepted": "false"`,
+		`{ "vname": "_balance", "type": "Uint128", "value": "100" }`,
+		`"vname": "allowances",
+      "type": "Map (ByStr20) (Map (ByStr20) (Uint128))",
+      "value": [
+        { "key": "0x1234567890123456789012345678901234567890", "val": [] }
+      ]`,
+		`"_eventname": "Revoked"`,
+	} {
+		if !strings.Contains(have, want) {
+			t.Errorf("Output should contain %s but got:\n%s", want, have)
+		}
+	}
+	before := have[strings.Index(have, `"vname": "before"`):strings.Index(have, `"vname": "after"`)]
+	for _, want := range []string{`"val": "1"`, `"val": "2"`} {
+		if !strings.Contains(before, want) {
+			t.Errorf("Map loaded before deletions should contain %s but got:\n%s", want, before)
+		}
+	}
+}
+
+// forwarder sends the amount it receives to the recipient before accepting it.
+const forwarder = `scilla_version 0
+contract Forwarder()
+
+transition Forward(to : ByStr20)
+  m = {_tag : ""; _recipient : to; _amount : _amount};
+  nil = Nil {Message};
+  ms = Cons {Message} m nil;
+  send ms;
+  accept
+end
+`
+
+func TestRunSendBeforeAccept(t *testing.T) {
+	a, info := check(t, forwarder)
+	in := bankInput(t, "")
+	var err error
+	if in.Init, err = ReadParams(strings.NewReader(`[
+  {"vname": "_scilla_version", "type": "Uint32", "value": "0"},
+  {"vname": "_this_address", "type": "ByStr20", "value": "` + other + `"},
+  {"vname": "_creation_block", "type": "BNum", "value": "1"}
+]`)); err != nil {
+		t.Fatal(err)
+	}
+	if in.State, err = ReadParams(strings.NewReader(`[{"vname": "_balance", "type": "Uint128", "value": "0"}]`)); err != nil {
+		t.Fatal(err)
+	}
+	if in.Message, err = ReadMessage(strings.NewReader(`{"_tag": "Forward", "_amount": "100", "_sender": "` + owner + `", "params": [{"vname": "to", "type": "ByStr20", "value": "` + other + `"}]}`)); err != nil {
+		t.Fatal(err)
+	}
+	out, err := Run(a, info, in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !out.Accepted || len(out.Messages) != 1 || out.Messages[0].Amount != "100" {
+		t.Errorf("Unexpected output: %+v", out)
+	}
+	if b := out.States[0]; b.Value != "0" {
+		t.Errorf("Balance should be 0 after forwarding the accepted amount but got %v", b.Value)
+	}
+}
+
+func TestRunError(t *testing.T) {
+	deposit := func(amt string) string {
+		return `{"_tag": "Deposit", "_amount": "0", "_sender": "` + owner + `", "params": [{"vname": "to", "type": "ByStr20", "value": "` + owner + `"}, {"vname": "amt", "type": "Uint128", "value": "` + amt + `"}]}`
+	}
+	for _, tc := range []struct {
+		what  string
+		msg   string
+		setup func(in *Input)
+		want  string
+	}{
+		{
+			"throw",
+			`{"_tag": "RevokeAll", "_amount": "0", "_sender": "` + owner + `", "params": [{"vname": "tos", "type": "List ByStr20", "value": []}]}`,
+			nil,
+			`Exception thrown: {_exception : "NotRevoked"}`,
+		},
+		{
+			"insufficient balance",
+			deposit("101"),
+			nil,
+			"Balance 100 is not enough to send messages with amount 101 in total",
+		},
+		{
+			"builtin failure",
+			deposit("340282366920938463463374607431768211455"),
+			nil,
+			"Builtin add failed",
+		},
+		{
+			"out of gas",
+			deposit("1"),
+			func(in *Input) { in.GasLimit = 10 },
+			"Ran out of gas",
+		},
+		{
+			"no blockchain",
+			deposit("1"),
+			func(in *Input) { in.Blockchain = nil },
+			"Blockchain query BLOCKNUMBER is not given in input_blockchain.json",
+		},
+	} {
+		t.Run(tc.what, func(t *testing.T) {
+			in := bankInput(t, tc.msg)
+			if tc.setup != nil {
+				tc.setup(in)
+			}
+			a, info := checkBank(t)
+			_, err := Run(a, info, in)
+			rerr, ok := err.(*Error)
+			if !ok {
+				t.Fatalf("Runtime error did not occur: %v", err)
+			}
+			if !strings.Contains(rerr.Error(), tc.want) {
+				t.Errorf("Error should contain %q but got %q", tc.want, rerr.Error())
+			}
+			if rerr.GasRemaining >= in.GasLimit {
+				t.Errorf("Gas was not consumed: %d", rerr.GasRemaining)
+			}
+			var b bytes.Buffer
+			if err := rerr.WriteJSON(&b); err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(b.String(), `"errors": [`) || !strings.Contains(b.String(), `"start_location": {`) {
+				t.Errorf("Unexpected error JSON: %s", b.String())
+			}
+		})
+	}
+}
+
+func TestRunInputError(t *testing.T) {
+	for _, tc := range []struct {
+		what  string
+		msg   string
+		state [2]string
+		want  string
+	}{
+		{
+			"unknown transition",
+			`{"_tag": "Withdraw", "_amount": "0", "_sender": "` + owner + `", "params": []}`,
+			[2]string{},
+			"Transition Withdraw invoked by input_message.json is not defined in contract Bank",
+		},
+		{
+			"missing parameter",
+			`{"_tag": "Deposit", "_amount": "0", "_sender": "` + owner + `", "params": [{"vname": "to", "type": "ByStr20", "value": "` + owner + `"}]}`,
+			[2]string{},
+			"Parameter amt is missing in input_message.json",
+		},
+		{
+			"parameter type",
+			`{"_tag": "Deposit", "_amount": "0", "_sender": "` + owner + `", "params": [{"vname": "to", "type": "ByStr20", "value": "` + owner + `"}, {"vname": "amt", "type": "Uint32", "value": "1"}]}`,
+			[2]string{},
+			"Parameter amt has type Uint32 in input_message.json but it is declared as Uint128",
+		},
+		{
+			"missing field",
+			`{"_tag": "Deposit", "_amount": "0", "_sender": "` + owner + `", "params": []}`,
+			[2]string{`"total"`, `"totl"`},
+			"Field total is missing in input_state.json",
+		},
+		{
+			"invalid amount",
+			`{"_tag": "Deposit", "_amount": "-1", "_sender": "` + owner + `", "params": []}`,
+			[2]string{},
+			"Value of _amount in input_message.json is invalid",
+		},
+	} {
+		t.Run(tc.what, func(t *testing.T) {
+			in := bankInput(t, tc.msg)
+			if tc.state[0] != "" {
+				ps, err := ReadParams(strings.NewReader(strings.Replace(bankState, tc.state[0], tc.state[1], 1)))
+				if err != nil {
+					t.Fatal(err)
+				}
+				in.State = ps
+			}
+			a, info := checkBank(t)
+			_, err := Run(a, info, in)
+			errs, ok := err.(checker.ErrorList)
+			if !ok {
+				t.Fatalf("Error is not ErrorList: %v", err)
+			}
+			if msg := errs.Error(); !strings.Contains(msg, tc.want) {
+				t.Errorf("Error should contain %q but got %q", tc.want, msg)
+			}
+		})
+	}
+}
+
+// readParamsFile reads the JSON file of parameters. It returns nil when the file does not exist.
+func readParamsFile(t *testing.T, path string) []*Param {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	ps, err := ReadParams(f)
+	if err != nil {
+		t.Fatal(path, err)
+	}
+	return ps
+}
+
+// TestRunGolden runs contracts in testdata in the layout of tests/runner of Zilliqa/scilla. Each
+// directory has contract.scilla and init.json. Invocation N reads blockchain_N.json, state_N.json
+// and message_N.json, and its output is compared with output_N.json. Invocation without message
+// deploys the contract. The gas limit is 8000. Outputs of helloworld are regression outputs of
+// goscilla, not captured from scilla-runner, so they do not show compatibility with it.
+func TestRunGolden(t *testing.T) {
+	dirs, err := filepath.Glob(filepath.Join("testdata", "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range dirs {
+		src, err := ioutil.ReadFile(filepath.Join(dir, "contract.scilla"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; ; i++ {
+			file := func(name string) string {
+				return filepath.Join(dir, fmt.Sprintf("%s_%d.json", name, i))
+			}
+			if _, err := os.Stat(file("blockchain")); os.IsNotExist(err) {
+				break
+			}
+			t.Run(file("output"), func(t *testing.T) {
+				in := &Input{
+					Init:       readParamsFile(t, filepath.Join(dir, "init.json")),
+					State:      readParamsFile(t, file("state")),
+					Blockchain: readParamsFile(t, file("blockchain")),
+					GasLimit:   8000,
+				}
+				if f, err := os.Open(file("message")); err == nil {
+					defer f.Close()
+					if in.Message, err = ReadMessage(f); err != nil {
+						t.Fatal(err)
+					}
+				}
+				a, info := check(t, string(src))
+				var b bytes.Buffer
+				out, err := Run(a, info, in)
+				if rerr, ok := err.(*Error); ok {
+					err = rerr.WriteJSON(&b)
+				} else if err == nil {
+					err = out.WriteJSON(&b)
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				want, err := ioutil.ReadFile(file("output"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if have := b.String(); have != strings.TrimRight(string(want), "\n") {
+					t.Errorf("Wanted:\n%s\nbut got:\n%s", want, have)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,170 @@
+// Package runner runs Scilla contracts with JSON files compatible with scilla-runner of
+// Zilliqa/scilla, such as init.json. Chain simulates a local blockchain where several contracts
+// call each other with messages. Gas is charged after the structure of Gas.ml of Zilliqa/scilla, but
+// the costs are not verified against scilla-runner so the remaining gas may differ from its.
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/rhysd/locerr"
+	"goscilla/ast"
+	"goscilla/checker"
+	"goscilla/eval"
+	"goscilla/types"
+	"goscilla/value"
+	"io"
+	"strings"
+)
+
+// Param is an entry of JSON files like init.json: {"vname": "owner", "type": "ByStr20", "value": "0x..."}.
+type Param struct {
+	VName string      `json:"vname"`
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+// ReadParams reads the JSON array of parameters such as init.json.
+func ReadParams(r io.Reader) ([]*Param, error) {
+	var ps []*Param
+	if err := json.NewDecoder(r).Decode(&ps); err != nil {
+		return nil, fmt.Errorf("Parameters must be a JSON array of objects with vname, type and value: %s", err)
+	}
+	for i, p := range ps {
+		if p == nil || p.VName == "" || p.Type == "" || p.Value == nil {
+			return nil, fmt.Errorf("Parameter at index %d must have vname, type and value", i)
+		}
+	}
+	return ps, nil
+}
+
+// Implicit parameters in init.json which the blockchain gives on deployment
+var initImplicits = []struct {
+	name string
+	typ  types.Type
+}{
+	{"_scilla_version", types.Uint32},
+	{"_this_address", types.ByStr20},
+	{"_creation_block", types.BNum},
+}
+
+// initValues converts the parameters in init.json into values of contract parameters and implicit
+// parameters without evaluating the contract constraint.
+func initValues(a *ast.AST, info *checker.Info, params []*Param) (map[string]value.Value, error) {
+	k := a.Contract
+	if k == nil {
+		return nil, locerr.ErrorIn(a.Pos(), a.End(), "Module must declare contract to be deployed with init parameters")
+	}
+
+	var errs checker.ErrorList
+	errorIn := func(n ast.Node, format string, args ...interface{}) {
+		errs = append(errs, locerr.ErrorIn(n.Pos(), n.End(), fmt.Sprintf(format, args...)))
+	}
+
+	given := map[string]*Param{}
+	for _, p := range params {
+		if _, ok := given[p.VName]; ok {
+			errorIn(k.Ident, "Parameter %s is given more than once in init.json", p.VName)
+		}
+		given[p.VName] = p
+	}
+
+	vals := map[string]value.Value{}
+	param := func(n ast.Node, name string, want types.Type) {
+		p, ok := given[name]
+		if !ok {
+			errorIn(n, "Parameter %s is missing in init.json", name)
+			return
+		}
+		delete(given, name)
+		v, err := paramValue("Parameter", "init.json", p, want, info.ADTs)
+		if err != nil {
+			errorIn(n, "%s", err)
+			return
+		}
+		vals[name] = v
+	}
+
+	for _, i := range initImplicits {
+		param(k.Ident, i.name, i.typ)
+	}
+	if v, ok := vals["_scilla_version"]; ok && a.Version != nil {
+		if v.(*value.Int).Value.Int64() != int64(a.Version.Value) {
+			errorIn(a.Version, "Parameter _scilla_version is %s in init.json but the contract is written in Scilla version %d", v.(*value.Int).Value, a.Version.Value)
+		}
+	}
+	for _, p := range k.Params {
+		t, ok := info.Defs[p.Ident]
+		if !ok || t == nil {
+			errorIn(p.Ident, "Type of parameter %s is unknown. The contract must be type checked", p.Ident.Symbol.DisplayName)
+			continue
+		}
+		param(p.Ident, p.Ident.Symbol.DisplayName, t)
+	}
+	for _, p := range params {
+		if _, ok := given[p.VName]; ok {
+			errorIn(k.Ident, "Parameter %s in init.json is not declared by contract %s", p.VName, k.Ident.Symbol.DisplayName)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return vals, nil
+}
+
+// CheckInit validates the parameters in init.json against the contract in the module type checked
+// with the info. Every contract parameter and implicit parameter must be given with its type and
+// value. Then the contract constraint is evaluated with the values. It returns values of all
+// parameters. Problems are returned as checker.ErrorList located at the declarations in source.
+func CheckInit(a *ast.AST, info *checker.Info, params []*Param) (map[string]value.Value, error) {
+	vals, err := initValues(a, info, params)
+	if err != nil {
+		return nil, err
+	}
+	k := a.Contract
+	if k.Constraint == nil {
+		return vals, nil
+	}
+	ev := eval.New(info)
+	if err := ev.Module(a); err != nil {
+		return nil, checker.ErrorList{err.(*locerr.Error)}
+	}
+	for _, i := range initImplicits {
+		ev.Define(i.name, vals[i.name])
+	}
+	for _, p := range k.Params {
+		ev.Define(p.Ident.Symbol.Name, vals[p.Ident.Symbol.DisplayName])
+	}
+	v, err := ev.Eval(k.Constraint, nil)
+	if err != nil {
+		return nil, checker.ErrorList{err.(*locerr.Error)}
+	}
+	if !value.Equal(v, value.True) {
+		return nil, checker.ErrorList{locerr.ErrorIn(k.Constraint.Pos(), k.Constraint.End(), "Contract constraint is not satisfied by parameters in init.json")}
+	}
+	return vals, nil
+}
+
+// paramValue converts the value of the entry in the JSON file into the value of the declared type.
+// The type of the entry must match the declared type. kind is "Parameter" or "Field".
+func paramValue(kind, file string, p *Param, want types.Type, adts map[string]*types.ADTDef) (value.Value, error) {
+	t, err := types.Parse(p.Type, adts)
+	if err != nil {
+		return nil, fmt.Errorf("Type of %s %s in %s is invalid: %s", strings.ToLower(kind), p.VName, file, err)
+	}
+	if !types.Assignable(want, t) && !isAddress(want, t) {
+		return nil, fmt.Errorf("%s %s has type %s in %s but it is declared as %s", kind, p.VName, t, file, want)
+	}
+	v, err := value.FromJSON(want, p.Value, adts)
+	if err != nil {
+		return nil, fmt.Errorf("Value of %s %s in %s is invalid: %s", strings.ToLower(kind), p.VName, file, err)
+	}
+	return v, nil
+}
+
+// isAddress returns whether the parameter of address type is given as ByStr20. Fields of the
+// contract at the address cannot be checked without blockchain.
+func isAddress(want, have types.Type) bool {
+	_, ok := want.(*types.AddressType)
+	return ok && types.Equal(have, types.ByStr20)
+}
@@ -0,0 +1,156 @@
+package runner
+
+import (
+	"github.com/rhysd/locerr"
+	"goscilla/ast"
+	"goscilla/checker"
+	"goscilla/loader"
+	"goscilla/syntax"
+	"strings"
+	"testing"
+)
+
+const vault = `scilla_version 0
+import BoolUtils
+library Vault
+type Mode = | Open | Closed of Uint32
+let max_limit = Uint128 1000
+contract Vault(owner : ByStr20, limit : Uint128, mode : Mode, admins : List ByStr20, caps : Map ByStr20 Uint128,
+  token : ByStr20 with contract field balances : Map ByStr20 Uint128 end)
+with
+  let ok = builtin lt limit max_limit in
+  let has_admin = match admins with | Cons _ _ => True | Nil => False end in
+  andb ok has_admin
+=>
+field total : Uint128 = Uint128 0
+`
+
+const addr = "0x1234567890123456789012345678901234567890"
+
+const vaultInit = `[
+  {"vname": "_scilla_version", "type": "Uint32", "value": "0"},
+  {"vname": "_this_address", "type": "ByStr20", "value": "` + addr + `"},
+  {"vname": "_creation_block", "type": "BNum", "value": "100"},
+  {"vname": "owner", "type": "ByStr20", "value": "` + addr + `"},
+  {"vname": "limit", "type": "Uint128", "value": "10"},
+  {"vname": "mode", "type": "Mode", "value": {"constructor": "Closed", "argtypes": [], "arguments": ["3"]}},
+  {"vname": "admins", "type": "List ByStr20", "value": ["` + addr + `"]},
+  {"vname": "caps", "type": "Map ByStr20 Uint128", "value": [{"key": "` + addr + `", "val": "5"}]},
+  {"vname": "token", "type": "ByStr20", "value": "` + addr + `"}
+]`
+
+func checkVault(t *testing.T) (*ast.AST, *checker.Info) {
+	a, err := syntax.Parse(locerr.NewDummySource(vault))
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := (&checker.Config{Importer: loader.New()}).Check(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a, info
+}
+
+func TestCheckInit(t *testing.T) {
+	a, info := checkVault(t)
+	ps, err := ReadParams(strings.NewReader(vaultInit))
+	if err != nil {
+		t.Fatal(err)
+	}
+	vals, err := CheckInit(a, info, ps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{
+		"_creation_block": "BNum 100",
+		"limit":           "Uint128 10",
+		"mode":            "Closed (Uint32 3)",
+		"caps":            "[" + addr + " => Uint128 5]",
+		"token":           addr,
+	} {
+		if v, ok := vals[name]; !ok || v.String() != want {
+			t.Errorf("Wanted %s for %s but got %v", want, name, v)
+		}
+	}
+}
+
+func TestCheckInitError(t *testing.T) {
+	for _, tc := range []struct {
+		what    string
+		replace []string // Pairs of old and new strings in init.json
+		want    []string
+	}{
+		{
+			"constraint",
+			[]string{`"value": "10"`, `"value": "1000"`},
+			[]string{"Contract constraint is not satisfied by parameters in init.json"},
+		},
+		{
+			"missing",
+			[]string{`{"vname": "owner", "type": "ByStr20", "value": "` + addr + `"},`, ""},
+			[]string{"Parameter owner is missing in init.json"},
+		},
+		{
+			"missing implicit",
+			[]string{`{"vname": "_creation_block", "type": "BNum", "value": "100"},`, ""},
+			[]string{"Parameter _creation_block is missing in init.json"},
+		},
+		{
+			"type mismatch",
+			[]string{`"Uint128", "value": "10"`, `"Uint32", "value": "10"`},
+			[]string{"Parameter limit has type Uint32 in init.json but it is declared as Uint128"},
+		},
+		{
+			"invalid type",
+			[]string{`"type": "Mode"`, `"type": "Mod"`},
+			[]string{`Type of parameter mode in init.json is invalid: Invalid type "Mod": type Mod is not defined`},
+		},
+		{
+			"invalid value",
+			[]string{`"arguments": ["3"]`, `"arguments": []`},
+			[]string{"Value of parameter mode in init.json is invalid: Constructor Closed takes 1 arguments but 0 given"},
+		},
+		{
+			"unknown and duplicated",
+			[]string{`{"vname": "limit"`, `{"vname": "foo", "type": "Uint32", "value": "1"}, {"vname": "owner", "type": "ByStr20", "value": "` + addr + `"}, {"vname": "limit"`},
+			[]string{"Parameter owner is given more than once in init.json", "Parameter foo in init.json is not declared by contract Vault"},
+		},
+		{
+			"scilla version",
+			[]string{`"Uint32", "value": "0"`, `"Uint32", "value": "1"`},
+			[]string{"Parameter _scilla_version is 1 in init.json but the contract is written in Scilla version 0"},
+		},
+	} {
+		t.Run(tc.what, func(t *testing.T) {
+			a, info := checkVault(t)
+			ps, err := ReadParams(strings.NewReader(strings.Replace(vaultInit, tc.replace[0], tc.replace[1], 1)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = CheckInit(a, info, ps)
+			if err == nil {
+				t.Fatal("Error did not occur")
+			}
+			errs, ok := err.(checker.ErrorList)
+			if !ok {
+				t.Fatalf("Error is not ErrorList: %T", err)
+			}
+			if len(errs) != len(tc.want) {
+				t.Fatalf("Wanted %d errors but got %d: %s", len(tc.want), len(errs), err)
+			}
+			for i, w := range tc.want {
+				if msg := strings.Join(errs[i].Messages, " "); !strings.Contains(msg, w) {
+					t.Errorf("Error %d should contain %q but got %q", i, w, msg)
+				}
+			}
+		})
+	}
+}
+
+func TestReadParamsError(t *testing.T) {
+	for _, src := range []string{`{}`, `[{"vname": "x", "type": "Uint32"}]`, `[null]`} {
+		if _, err := ReadParams(strings.NewReader(src)); err == nil {
+			t.Errorf("%s was read without error", src)
+		}
+	}
+}
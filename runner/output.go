@@ -0,0 +1,202 @@
+package runner
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/rhysd/locerr"
+	"goscilla/value"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Output is the result of execution written to output.json.
+type Output struct {
+	ScillaMajorVersion int
+	GasRemaining       uint64
+	Accepted           bool
+	Messages           []*OutMessage
+	States             []*Param // Values of _balance and fields in declaration order
+	Events             []*OutEvent
+}
+
+// OutMessage is a message sent by `send`. Entries other than _tag, _amount and _recipient are
+// output as parameters.
+type OutMessage struct {
+	Tag       string
+	Amount    string
+	Recipient string
+	Params    []*Param
+}
+
+// OutEvent is an event emitted by `event`.
+type OutEvent struct {
+	Name   string
+	Params []*Param
+}
+
+func paramsJSON(ps []*Param) []interface{} {
+	js := make([]interface{}, 0, len(ps))
+	for _, p := range ps {
+		js = append(js, value.Object{{Key: "vname", Value: p.VName}, {Key: "type", Value: p.Type}, {Key: "value", Value: p.Value}})
+	}
+	return js
+}
+
+// JSON returns the output as JSON object in the same shape as output.json of scilla-runner.
+func (o *Output) JSON() value.Object {
+	msgs := make([]interface{}, 0, len(o.Messages))
+	for _, m := range o.Messages {
+		msgs = append(msgs, value.Object{
+			{Key: "_tag", Value: m.Tag},
+			{Key: "_amount", Value: m.Amount},
+			{Key: "_recipient", Value: m.Recipient},
+			{Key: "params", Value: paramsJSON(m.Params)},
+		})
+	}
+	events := make([]interface{}, 0, len(o.Events))
+	for _, e := range o.Events {
+		events = append(events, value.Object{{Key: "_eventname", Value: e.Name}, {Key: "params", Value: paramsJSON(e.Params)}})
+	}
+	return value.Object{
+		{Key: "scilla_major_version", Value: strconv.Itoa(o.ScillaMajorVersion)},
+		{Key: "gas_remaining", Value: strconv.FormatUint(o.GasRemaining, 10)},
+		{Key: "_accepted", Value: strconv.FormatBool(o.Accepted)},
+		{Key: "messages", Value: msgs},
+		{Key: "states", Value: paramsJSON(o.States)},
+		{Key: "events", Value: events},
+	}
+}
+
+// WriteJSON writes the output as output.json of scilla-runner.
+func (o *Output) WriteJSON(w io.Writer) error {
+	return writeJSON(w, o.JSON())
+}
+
+func locationJSON(p locerr.Pos) value.Object {
+	file := ""
+	if p.File != nil {
+		file = p.File.Path
+	}
+	return value.Object{{Key: "file", Value: file}, {Key: "line", Value: p.Line}, {Key: "column", Value: p.Column}}
+}
+
+// JSON returns the error as JSON object in the same shape as error output of scilla-runner.
+func (e *Error) JSON() value.Object {
+	return value.Object{
+		{Key: "gas_remaining", Value: strconv.FormatUint(e.GasRemaining, 10)},
+		{Key: "errors", Value: []interface{}{value.Object{
+			{Key: "error_message", Value: strings.Join(e.Err.Messages, " ")},
+			{Key: "start_location", Value: locationJSON(e.Err.Start)},
+			{Key: "end_location", Value: locationJSON(e.Err.End)},
+		}}},
+		{Key: "warnings", Value: []interface{}{}},
+	}
+}
+
+// WriteJSON writes the error as scilla-runner outputs on failure.
+func (e *Error) WriteJSON(w io.Writer) error {
+	return writeJSON(w, e.JSON())
+}
+
+// jsonWidth is the width of lines in JSON output.
+const jsonWidth = 80
+
+// writeJSON writes the JSON value in a layout modelled on Yojson's pretty printer which
+// scilla-runner uses. It is not verified to be byte-for-byte identical with scilla-runner's output.
+// An object or an array is written in one line like `{ "k": v }` when it fits in jsonWidth.
+// Otherwise each member or element is written in its own line indented by 2 spaces.
+func writeJSON(w io.Writer, j interface{}) error {
+	var b bytes.Buffer
+	if err := prettyJSON(&b, j, 0, 0); err != nil {
+		return err
+	}
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// prettyJSON writes the value which starts at the column of the line indented by indent.
+func prettyJSON(b *bytes.Buffer, j interface{}, indent, col int) error {
+	s, err := compactJSON(j)
+	if err != nil {
+		return err
+	}
+	if col+utf8.RuneCountInString(s) <= jsonWidth {
+		b.WriteString(s)
+		return nil
+	}
+	pad := strings.Repeat(" ", indent+2)
+	switch j := j.(type) {
+	case value.Object:
+		b.WriteString("{\n")
+		for i, m := range j {
+			k, _ := compactJSON(m.Key)
+			b.WriteString(pad + k + ": ")
+			if err := prettyJSON(b, m.Value, indent+2, indent+2+utf8.RuneCountInString(k)+2); err != nil {
+				return err
+			}
+			if i < len(j)-1 {
+				b.WriteByte(',')
+			}
+			b.WriteByte('\n')
+		}
+		b.WriteString(strings.Repeat(" ", indent) + "}")
+	case []interface{}:
+		b.WriteString("[\n")
+		for i, e := range j {
+			b.WriteString(pad)
+			if err := prettyJSON(b, e, indent+2, indent+2); err != nil {
+				return err
+			}
+			if i < len(j)-1 {
+				b.WriteByte(',')
+			}
+			b.WriteByte('\n')
+		}
+		b.WriteString(strings.Repeat(" ", indent) + "]")
+	default:
+		b.WriteString(s)
+	}
+	return nil
+}
+
+// compactJSON returns the value in one line.
+func compactJSON(j interface{}) (string, error) {
+	switch j := j.(type) {
+	case value.Object:
+		if len(j) == 0 {
+			return "{}", nil
+		}
+		ms := make([]string, 0, len(j))
+		for _, m := range j {
+			k, _ := compactJSON(m.Key)
+			v, err := compactJSON(m.Value)
+			if err != nil {
+				return "", err
+			}
+			ms = append(ms, k+": "+v)
+		}
+		return "{ " + strings.Join(ms, ", ") + " }", nil
+	case []interface{}:
+		if len(j) == 0 {
+			return "[]", nil
+		}
+		es := make([]string, 0, len(j))
+		for _, e := range j {
+			s, err := compactJSON(e)
+			if err != nil {
+				return "", err
+			}
+			es = append(es, s)
+		}
+		return "[ " + strings.Join(es, ", ") + " ]", nil
+	}
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(j); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(b.String(), "\n"), nil
+}
@@ -0,0 +1,337 @@
+package runner
+
+import (
+	"github.com/rhysd/locerr"
+	"goscilla/ast"
+	"goscilla/builtins"
+	"goscilla/eval"
+	"goscilla/token"
+	"goscilla/types"
+	"goscilla/value"
+	"math/big"
+)
+
+// Gas is charged after the structure of Gas.ml of Zilliqa/scilla. It is not verified against
+// scilla-runner and gas_remaining may differ from the one scilla-runner reports. Expressions are charged by the evaluator (see
+// eval.Evaluator.SetCharge). Statements which move values between fields, local variables,
+// messages and events cost the sizes of the values computed by builtins.LiteralCost. Keys of map
+// accesses cost their sizes. A match costs the number of its arms, forall the length of the list
+// and other statements cost 1. Binding a variable costs only its expression.
+
+// charge consumes the gas. Execution fails when the gas runs out.
+func (m *machine) charge(n ast.Node, cost uint64) {
+	if m.gas < cost {
+		m.gas = 0
+		m.fail(n, "Ran out of gas")
+	}
+	m.gas -= cost
+}
+
+// clone copies maps in the value so that in-place updates of fields do not affect the value held
+// by local variables, and vice versa.
+func clone(v value.Value) value.Value {
+	mp, ok := v.(*value.Map)
+	if !ok {
+		return v
+	}
+	c := value.NewMap(mp.Typ)
+	for _, e := range mp.Entries() {
+		c.Set(e.Key, clone(e.Value))
+	}
+	return c
+}
+
+func (m *machine) eval(e ast.Expr, env *eval.Env) value.Value {
+	v, err := m.ev.Eval(e, env)
+	if err != nil {
+		panic(failure{err.(*locerr.Error)})
+	}
+	return v
+}
+
+// ref returns the value of the variable referred by the statement. Unlike evaluating a variable
+// expression, it costs no gas.
+func (m *machine) ref(r *ast.VarRef, env *eval.Env) value.Value {
+	if v, ok := env.Lookup(r.Symbol.Name); ok {
+		return v
+	}
+	if v, ok := m.ev.Global(r.Symbol.Name); ok {
+		return v
+	}
+	m.fail(r, "Value of variable %s is not available", r.Symbol.DisplayName)
+	return nil
+}
+
+func (m *machine) field(f *ast.VarRef) value.Value {
+	v, ok := m.fields[f.Symbol.Name]
+	if !ok {
+		m.fail(f, "Value of field %s is not available", f.Symbol.DisplayName)
+	}
+	return v
+}
+
+// keys evaluates the keys of map access and charges their costs.
+func (m *machine) keys(n ast.Node, ks []*ast.MapKey, env *eval.Env) []value.Value {
+	vs := make([]value.Value, 0, len(ks))
+	for _, k := range ks {
+		v := m.ref(k.Key, env)
+		m.charge(n, builtins.LiteralCost(v))
+		vs = append(vs, v)
+	}
+	return vs
+}
+
+// nested returns the map in the map of the field which contains the value of the last key. When
+// create is true, missing nested maps are created in place. Otherwise nil is returned for missing
+// maps.
+func nested(mp *value.Map, keys []value.Value, create bool) *value.Map {
+	for _, k := range keys[:len(keys)-1] {
+		v, ok := mp.Get(k)
+		if !ok {
+			if !create {
+				return nil
+			}
+			v = value.NewMap(mp.Typ.Value.(*types.MapType))
+			mp.Set(k, v)
+		}
+		mp = v.(*value.Map)
+	}
+	return mp
+}
+
+func (m *machine) stmts(ss []ast.Stmt, env *eval.Env) {
+	for _, s := range ss {
+		env = m.stmt(s, env)
+	}
+}
+
+// stmt executes the statement and returns the environment extended with the variable it binds.
+func (m *machine) stmt(s ast.Stmt, env *eval.Env) *eval.Env {
+	switch s := s.(type) {
+	case *ast.Load:
+		v := clone(m.field(s.Field))
+		m.charge(s, builtins.LiteralCost(v))
+		return env.Bind(s.Ident.Symbol.Name, v)
+	case *ast.Store:
+		v := clone(m.ref(s.Value, env))
+		m.charge(s, builtins.LiteralCost(v))
+		m.fields[s.Field.Symbol.Name] = v
+	case *ast.Bind:
+		return env.Bind(s.Ident.Symbol.Name, m.eval(s.Value, env))
+	case *ast.MapUpdate:
+		ks := m.keys(s, s.Keys, env)
+		v := clone(m.ref(s.Value, env))
+		m.charge(s, builtins.LiteralCost(v))
+		nested(m.field(s.Map).(*value.Map), ks, true).Set(ks[len(ks)-1], v)
+	case *ast.MapDelete:
+		ks := m.keys(s, s.Keys, env)
+		m.charge(s, 1)
+		if mp := nested(m.field(s.Map).(*value.Map), ks, false); mp != nil {
+			mp.Delete(ks[len(ks)-1])
+		}
+	case *ast.MapGet:
+		return m.mapGet(s, s.Ident, s.ExistsToken, m.field(s.Map), s.Keys, env)
+	case *ast.RemoteLoad:
+		v := clone(m.remoteField(s.Addr, s.Field, env))
+		m.charge(s, builtins.LiteralCost(v))
+		return env.Bind(s.Ident.Symbol.Name, v)
+	case *ast.RemoteMapGet:
+		return m.mapGet(s, s.Ident, s.ExistsToken, m.remoteField(s.Addr, s.Map, env), s.Keys, env)
+	case *ast.ReadFromBC:
+		m.charge(s, 1)
+		q := s.Query.Value()
+		v, ok := m.chain[q]
+		if !ok {
+			m.fail(s, "Blockchain query %s is not given in input_blockchain.json", q)
+		}
+		return env.Bind(s.Ident.Symbol.Name, v)
+	case *ast.Accept:
+		m.charge(s, 1)
+		if !m.accepted {
+			b, err := m.fields["_balance"].(*value.Int).Add(m.amount)
+			if err != nil {
+				m.fail(s, "Balance cannot accept amount %s: %s", m.amount.Value, err)
+			}
+			m.fields["_balance"] = b
+			m.accepted = true
+		}
+	case *ast.Send:
+		m.send(s, m.ref(s.Msgs, env))
+	case *ast.Event:
+		m.event(s, m.ref(s.Event, env).(*value.Msg))
+	case *ast.Throw:
+		m.charge(s, 1)
+		if s.Exception == nil {
+			m.fail(s, "Exception thrown")
+		}
+		m.fail(s, "Exception thrown: %s", m.ref(s.Exception, env))
+	case *ast.MatchStmt:
+		m.charge(s, uint64(len(s.Arms)))
+		target := m.ref(s.Target, env)
+		for _, arm := range s.Arms {
+			if bound, ok := eval.Match(arm.Pattern, target, env); ok {
+				m.stmts(arm.Body, bound)
+				return env
+			}
+		}
+		m.fail(s, "No arm matches value %s", target)
+	case *ast.CallProc:
+		m.charge(s, 1)
+		args := make([]value.Value, 0, len(s.Args))
+		for _, a := range s.Args {
+			args = append(args, m.ref(a, env))
+		}
+		m.call(s.Proc, args)
+	case *ast.Iterate:
+		xs, ok := m.ref(s.List, env).(*value.ADT).Elems()
+		if !ok {
+			m.fail(s.List, "forall can only iterate over list")
+		}
+		m.charge(s, uint64(len(xs)))
+		for _, x := range xs {
+			m.call(s.Proc, []value.Value{x})
+		}
+	default:
+		m.fail(s, "%s cannot be executed", s.Name())
+	}
+	return env
+}
+
+// mapGet binds the value of the keys in the map to the variable. It is bound as Option unless the
+// statement checks existence of the value.
+func (m *machine) mapGet(s ast.Stmt, x *ast.Ident, exists *token.Token, mv value.Value, keys []*ast.MapKey, env *eval.Env) *eval.Env {
+	v := mv
+	if len(keys) > 0 {
+		v = nil
+		ks := m.keys(s, keys, env)
+		if mp := nested(mv.(*value.Map), ks, false); mp != nil {
+			v, _ = mp.Get(ks[len(ks)-1])
+		}
+	}
+	if exists != nil {
+		m.charge(s, 1)
+		return env.Bind(x.Symbol.Name, value.Bool(v != nil))
+	}
+	t := m.info.Defs[x].(*types.ADT).Args[0]
+	if v == nil {
+		return env.Bind(x.Symbol.Name, value.None(t))
+	}
+	v = clone(v)
+	m.charge(s, builtins.LiteralCost(v))
+	return env.Bind(x.Symbol.Name, value.Some(t, v))
+}
+
+// remoteField returns the value of the field of the account at the address.
+func (m *machine) remoteField(addr *ast.VarRef, f *ast.Ident, env *eval.Env) value.Value {
+	if m.remote == nil {
+		m.fail(addr, "Remote state of other contracts cannot be read by runner")
+	}
+	a := m.ref(addr, env).(value.ByStrN)
+	fields, ok := m.remote(a)
+	if !ok {
+		m.fail(addr, "No account exists at address %s", a)
+	}
+	v, ok := fields[f.Symbol.Name]
+	if !ok {
+		m.fail(f, "Account at address %s has no field %s", a, f.Symbol.DisplayName)
+	}
+	return v
+}
+
+// call executes the procedure with the arguments. Only implicit parameters of the message and the
+// arguments are visible in the body.
+func (m *machine) call(p *ast.VarRef, args []value.Value) {
+	proc, ok := m.procs[p.Symbol.Name]
+	if !ok {
+		m.fail(p, "Procedure %s is not defined", p.Symbol.DisplayName)
+	}
+	if len(args) != len(proc.Params) {
+		m.fail(p, "Procedure %s takes %d arguments but %d given", p.Symbol.DisplayName, len(proc.Params), len(args))
+	}
+	env := m.implicit
+	for i, param := range proc.Params {
+		env = env.Bind(param.Ident.Symbol.Name, args[i])
+	}
+	m.stmts(proc.Body, env)
+}
+
+// send queues the messages in the list. Their amounts are deducted from the balance by settle
+// after the transition finishes.
+func (m *machine) send(s *ast.Send, v value.Value) {
+	ms, ok := v.(*value.ADT).Elems()
+	if !ok {
+		m.fail(s.Msgs, "Only list of messages can be sent")
+	}
+	for _, x := range ms {
+		msg := x.(*value.Msg)
+		m.charge(s, builtins.LiteralCost(msg))
+		tag, ok := msg.Field("_tag").(value.String)
+		if !ok {
+			m.fail(s.Msgs, "Message %s must have _tag of type String", msg)
+		}
+		amount, ok := msg.Field("_amount").(*value.Int)
+		if !ok || !types.Equal(amount.Typ, types.Uint128) {
+			m.fail(s.Msgs, "Message %s must have _amount of type Uint128", msg)
+		}
+		to, ok := msg.Field("_recipient").(value.ByStrN)
+		if !ok || len(to) != 20 {
+			m.fail(s.Msgs, "Message %s must have _recipient of type ByStr20", msg)
+		}
+		m.sent = append(m.sent, &sentMessage{s, msg, string(tag), amount, to})
+		out := &OutMessage{Tag: string(tag), Amount: amount.Value.String(), Recipient: to.String()}
+		out.Params = m.params(s.Msgs, msg, "_tag", "_amount", "_recipient")
+		m.msgs = append(m.msgs, out)
+	}
+}
+
+// settle deducts the total amount of the messages sent by the transition from the balance. As
+// post_process_msgs in Eval.ml of Zilliqa/scilla, it is checked once after the transition
+// finishes so that the amount accepted by the transition can be sent regardless of the order of
+// `send` and `accept`.
+func (m *machine) settle() {
+	if len(m.sent) == 0 {
+		return
+	}
+	total := value.NewInt(types.Uint128, new(big.Int))
+	for _, s := range m.sent {
+		var err error
+		if total, err = total.Add(s.amount); err != nil {
+			m.fail(s.send, "Total amount of messages cannot be computed: %s", err)
+		}
+	}
+	balance := m.fields["_balance"].(*value.Int)
+	b, err := balance.Sub(total)
+	if err != nil {
+		m.fail(m.sent[0].send, "Balance %s is not enough to send messages with amount %s in total", balance.Value, total.Value)
+	}
+	m.fields["_balance"] = b
+}
+
+// event emits the event.
+func (m *machine) event(s *ast.Event, e *value.Msg) {
+	m.charge(s, builtins.LiteralCost(e))
+	name, ok := e.Field("_eventname").(value.String)
+	if !ok {
+		m.fail(s.Event, "Event %s must have _eventname of type String", e)
+	}
+	m.events = append(m.events, &OutEvent{string(name), m.params(s.Event, e, "_eventname")})
+}
+
+// params converts entries of the message into parameters in output.json except for the keys.
+func (m *machine) params(n ast.Node, msg *value.Msg, except ...string) []*Param {
+	ps := []*Param{}
+Entries:
+	for _, e := range msg.Entries {
+		for _, k := range except {
+			if e.Key == k {
+				continue Entries
+			}
+		}
+		j, err := value.ToJSON(e.Value)
+		if err != nil {
+			m.fail(n, "Entry %s of message cannot be output: %s", e.Key, err)
+		}
+		ps = append(ps, &Param{e.Key, e.Value.Type().String(), j})
+	}
+	return ps
+}
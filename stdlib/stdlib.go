@@ -0,0 +1,42 @@
+// Package stdlib embeds the standard libraries of Scilla such as ListUtils so that contracts can
+// import them without installing Scilla.
+package stdlib
+
+import (
+	"embed"
+	"github.com/rhysd/locerr"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Ext is the file extension of Scilla library files.
+const Ext = ".scillib"
+
+//go:embed *.scillib
+var files embed.FS
+
+// Names returns names of the standard libraries in alphabetical order.
+func Names() []string {
+	es, err := files.ReadDir(".")
+	if err != nil {
+		panic(err)
+	}
+	ns := make([]string, 0, len(es))
+	for _, e := range es {
+		ns = append(ns, strings.TrimSuffix(e.Name(), Ext))
+	}
+	sort.Strings(ns)
+	return ns
+}
+
+// Source returns the source of the standard library. It returns false when the library does not
+// exist.
+func Source(name string) (*locerr.Source, bool) {
+	file := name + Ext
+	b, err := files.ReadFile(file)
+	if err != nil {
+		return nil, false
+	}
+	return &locerr.Source{Path: path.Join("stdlib", file), Code: b}, true
+}
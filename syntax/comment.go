@@ -0,0 +1,74 @@
+package syntax
+
+import (
+	"goscilla/ast"
+	"goscilla/token"
+)
+
+// comment is a comment token with the index of the significant token following it.
+type comment struct {
+	tok  *token.Token
+	next int
+}
+
+// commentMap associates comment groups with significant tokens around them.
+// A group which starts on the same line as the preceding token is a trailing comment of the token.
+// Otherwise it is a leading comment of the token following it.
+type commentMap struct {
+	leading  map[int]*ast.CommentGroup
+	trailing map[int]*ast.CommentGroup
+}
+
+func newCommentMap(comments []comment, toks []*token.Token) *commentMap {
+	m := &commentMap{map[int]*ast.CommentGroup{}, map[int]*ast.CommentGroup{}}
+
+	var (
+		group    []*ast.Comment
+		next     int
+		trailing bool
+	)
+	flush := func() {
+		if len(group) == 0 {
+			return
+		}
+		g := &ast.CommentGroup{List: group}
+		if trailing {
+			m.trailing[next-1] = g
+		} else {
+			// When several groups precede the same token, the nearest one wins
+			m.leading[next] = g
+		}
+		group = nil
+	}
+
+	for _, c := range comments {
+		if len(group) > 0 {
+			last := group[len(group)-1].Token
+			if c.next != next || c.tok.Start.Line > last.End.Line+1 || trailing && c.tok.Start.Line > last.End.Line {
+				flush()
+			}
+		}
+		if len(group) == 0 {
+			next = c.next
+			trailing = next > 0 && toks[next-1].End.Line == c.tok.Start.Line
+		}
+		group = append(group, &ast.Comment{Token: c.tok})
+	}
+	flush()
+
+	return m
+}
+
+// doc returns the comment group just above the declaration starting at the token index.
+func (p *parser) doc(start int) *ast.CommentGroup {
+	g := p.comments.leading[start]
+	if g == nil || g.End().Line < p.tokens[start].Start.Line-1 {
+		return nil
+	}
+	return g
+}
+
+// lineComment returns the comment group following the token index on the same line.
+func (p *parser) lineComment(end int) *ast.CommentGroup {
+	return p.comments.trailing[end]
+}
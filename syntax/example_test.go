@@ -8,7 +8,7 @@ import (
 )
 
 func ExampleLexer_Lex() {
-	file := filepath.FromSlash("../testdata/basic.scilla")
+	file := filepath.FromSlash("testdata/basic.scilla")
 	src, err := locerr.NewSourceFromFile(file)
 	if err != nil {
 		// File not found
@@ -39,7 +39,7 @@ func ExampleLexer_Lex() {
 }
 
 func ExampleParse() {
-	file := filepath.FromSlash("../testdata/basic.scilla")
+	file := filepath.FromSlash("testdata/basic.scilla")
 	src, err := locerr.NewSourceFromFile(file)
 	if err != nil {
 		// File not found
@@ -1,166 +1,378 @@
 /*
-  This parser definition is based on min-caml/parser.mly
-  Copyright (c) 2005-2008, Eijiro Sumii, Moe Masuko, and Kenichi Asai
+  Grammar of Scilla, based on ScillaParser.mly of Zilliqa/scilla
+  (https://github.com/Zilliqa/scilla/blob/master/src/base/ScillaParser.mly).
+
+  `make syntax/grammar_yacc_test.go` generates a recognizer yyParse from this
+  file with goyacc. It is only compiled into tests and builds no AST.
+  syntax.Parse is a hand-written recursive descent parser (see parser.go) which
+  follows the productions below and recovers from syntax errors. Tests run both
+  of them on the same sources to check that they accept the same language.
 */
 
 %{
 package syntax
 
-import (
-	"fmt"
-	"strconv"
-	"goscilla/ast"
-	"goscilla/token"
-)
+import "goscilla/token"
 %}
 
-%union{
-	node ast.Expr
-	nodes []ast.Expr
+%union {
 	token *token.Token
-	funcdef *ast.FuncDef
-	decls []*ast.Symbol
-	decl *ast.Symbol
-	params []ast.Param
-	program *ast.AST
 }
 
-%token<token> ILLEGAL
+%token ILLEGAL
 
 // whitespaces
-%token<token> NEWLINE
-%token<token> COMMENT
-%token<token> WHITESPACE
+%token NEWLINE
+%token COMMENT
+%token WHITESPACE
 
 // Literals
-%token<token> STRING_LIT
-%token<token> NUM_LIT
-%token<token> HEX_LIT
+%token STRING_LIT
+%token NUM_LIT
+%token HEX_LIT
 
 // Prime Types
-%token<token> INT_TYPE
-%token<token> STRING_TYPE
-%token<token> BYSTR_TYPE
-%token<token> BNUM_TYPE
-%token<token> MESSAGE_TYPE
-%token<token> EVENT_TYPE
+%token INT_TYPE
+%token STRING_TYPE
+%token BYSTR_TYPE
+%token BNUM_TYPE
+%token MESSAGE_TYPE
+%token EVENT_TYPE
 
 // Keywords
-%token<token> FORALL
-%token<token> BUILTIN
-%token<token> LIBRARY
-%token<token> IMPORT
-%token<token> LET
-%token<token> IN
-%token<token> MATCH
-%token<token> WITH
-%token<token> END
-%token<token> FUN
-%token<token> TFUN
-%token<token> CONTRACT
-%token<token> TRANSITION
-%token<token> SEND
-%token<token> EVENT
-%token<token> FIELD
-%token<token> ACCEPT
-%token<token> EXISTS
-%token<token> DELETE
-%token<token> EMP
-%token<token> MAP
-%token<token> SCILLA_VERSION
-%token<token> TYPE
-%token<token> OF
-%token<token> TRY
-%token<token> CATCH
-%token<token> AS
-%token<token> PROCEDURE
-%token<token> THROW
+%token FORALL
+%token BUILTIN
+%token LIBRARY
+%token IMPORT
+%token LET
+%token IN
+%token MATCH
+%token WITH
+%token END
+%token FUN
+%token TFUN
+%token CONTRACT
+%token TRANSITION
+%token SEND
+%token EVENT
+%token FIELD
+%token ACCEPT
+%token EXISTS
+%token DELETE
+%token EMP
+%token MAP
+%token SCILLA_VERSION
+%token TYPE
+%token OF
+%token TRY
+%token CATCH
+%token AS
+%token PROCEDURE
+%token THROW
 
 // Separators
-%token<token> SEMICOLON
-%token<token> COLON
-%token<token> PERIOD
-%token<token> BAR
-%token<token> LSQB
-%token<token> RSQB
-%token<token> LPAREN
-%token<token> RPAREN
-%token<token> LBRACE
-%token<token> RBRACE
-%token<token> COMMA
-%token<token> ARROW
-%token<token> TARROW
-%token<token> EQ
-%token<token> AND
-%token<token> FETCH
-%token<token> ASSIGN
-%token<token> AT
-%token<token> UNDERSCORE
+%token SEMICOLON
+%token COLON
+%token PERIOD
+%token BAR
+%token LSQB
+%token RSQB
+%token LPAREN
+%token RPAREN
+%token LBRACE
+%token RBRACE
+%token COMMA
+%token ARROW
+%token TARROW
+%token EQ
+%token AND
+%token FETCH
+%token ASSIGN
+%token AT
+%token UNDERSCORE
 
 // Identifiers
-%token<token> ID   // simple name          [a-z][A-Za-z0-9_]*
-%token<token> CID  // qualified name       [A-Z][A-Za-z0-9_]*
-%token<token> TID  // type parameter name '[A-Z][A-Za-z0-9_]*
-%token<token> SPID // special const name  _[A-Za-z0-9_]*
+%token ID   // simple name          [a-z][A-Za-z0-9_]*
+%token CID  // qualified name       [A-Z][A-Za-z0-9_]*
+%token TID  // type parameter name '[A-Z][A-Za-z0-9_]*
+%token SPID // special const name  _[A-Za-z0-9_]*
 
 // from https://github.com/Zilliqa/scilla/blob/master/src/base/Datatypes.ml
 // Builtin ADT
-%token<token> BOOL
-%token<token> TRUE
-%token<token> FALSE
+%token BOOL
+%token TRUE
+%token FALSE
 
-%token<token> NAT
-%token<token> ZERO
-%token<token> SUCC
+%token NAT
+%token ZERO
+%token SUCC
 
-%token<token> OPTION
-%token<token> SOME
-%token<token> NONE
+%token OPTION
+%token SOME
+%token NONE
 
-%token<token> LIST
-%token<token> CONS
-%token<token> NIL
+%token LIST
+%token CONS
+%token NIL
 
-%token<token> PAIR
+%token PAIR
 
 // Other tokens
-%token<token> EOF
+%token EOF
 
-// Associativity
 %right TARROW
 
-%nonassoc IN
-%right SEMICOLON
-%nonassoc WITH
-%right FETCH
-%nonassoc BAR
-%left COMMA
-%left DOT
-%nonassoc IDENT
-
-%type<node> exp
-%type<node> simple_exp
-%type<node> seq_exp
-%type<nodes> elems
-%type<nodes> args
-%type<params> params
-%type<decls> pat
-%type<funcdef> fundef
-%type<token> match_arm_start
-%type<decl> match_ident
-%type<nodes> semi_elems
-%type<node> type_annotation
-%type<node> simple_type_annotation
-%type<node> type
-%type<node> simple_type
-%type<node> simple_type_or_tuple
-%type<nodes> arrow_types
-%type<nodes> simple_type_star_list
-%type<nodes> type_comma_list
-%type<program> toplevels
-%type<> opt_semi
-%type<> program
-
-%start program
+%start module
+
+%%
+
+module:
+	opt_version imports opt_library module_body EOF
+
+module_body:
+	components
+	| CONTRACT CID params opt_constraint fields components
+
+opt_version:
+	/* empty */
+	| SCILLA_VERSION NUM_LIT
+
+imports:
+	/* empty */
+	| imports IMPORT import_names
+
+import_names:
+	import_name
+	| import_names import_name
+
+import_name:
+	CID
+	| CID AS CID
+
+opt_library:
+	/* empty */
+	| LIBRARY CID lib_entries
+
+lib_entries:
+	/* empty */
+	| lib_entries lib_entry
+
+lib_entry:
+	LET ID opt_type_annot EQ exp
+	| TYPE CID
+	| TYPE CID EQ ctor_decls
+
+opt_type_annot:
+	/* empty */
+	| COLON type
+
+ctor_decls:
+	ctor_decl
+	| ctor_decls ctor_decl
+
+ctor_decl:
+	BAR ctor_name
+	| BAR ctor_name OF targs
+
+opt_constraint:
+	/* empty */
+	| WITH exp ARROW
+
+params:
+	LPAREN RPAREN
+	| LPAREN param_list RPAREN
+
+param_list:
+	param
+	| param_list COMMA param
+
+param:
+	ID COLON type
+
+fields:
+	/* empty */
+	| fields FIELD ID COLON type EQ exp
+
+components:
+	/* empty */
+	| components component
+
+component:
+	TRANSITION component_id params stmts END
+	| PROCEDURE component_id params stmts END
+
+component_id:
+	ID
+	| CID
+
+stmts:
+	/* empty */
+	| stmt_list
+	| stmt_list SEMICOLON
+
+stmt_list:
+	stmt
+	| stmt_list SEMICOLON stmt
+
+stmt:
+	ID FETCH sident
+	| ID FETCH sident map_keys
+	| ID FETCH EXISTS ID map_keys
+	| ID FETCH AND CID
+	| ID FETCH AND sident PERIOD sident
+	| ID FETCH AND sident PERIOD sident map_keys
+	| ID FETCH AND EXISTS sident PERIOD sident map_keys
+	| ID ASSIGN sident
+	| ID EQ exp
+	| ID map_keys ASSIGN sident
+	| DELETE ID map_keys
+	| ACCEPT
+	| SEND sident
+	| EVENT sident
+	| THROW
+	| THROW sident
+	| MATCH sident WITH stmt_arms END
+	| FORALL sident component_id
+	| component_id sidents
+
+map_keys:
+	LSQB sident RSQB
+	| map_keys LSQB sident RSQB
+
+stmt_arms:
+	/* empty */
+	| stmt_arms BAR pattern ARROW stmts
+
+exp:
+	LET ID opt_type_annot EQ exp IN exp
+	| FUN LPAREN ID COLON type RPAREN ARROW exp
+	| TFUN TID ARROW exp
+	| AT sident targs
+	| BUILTIN ID sidents1
+	| BUILTIN ID LPAREN RPAREN
+	| LBRACE msg_entries RBRACE
+	| MATCH sident WITH exp_arms END
+	| ctor_name opt_ctor_targs sidents
+	| sident sidents
+	| literal
+
+literal:
+	STRING_LIT
+	| HEX_LIT
+	| INT_TYPE NUM_LIT
+	| BNUM_TYPE NUM_LIT
+	| EMP targ targ
+
+msg_entries:
+	/* empty */
+	| msg_entry_list
+	| msg_entry_list SEMICOLON
+
+msg_entry_list:
+	msg_entry
+	| msg_entry_list SEMICOLON msg_entry
+
+msg_entry:
+	msg_key COLON literal
+	| msg_key COLON sident
+
+msg_key:
+	ID
+	| SPID
+	| CID
+
+opt_ctor_targs:
+	/* empty */
+	| LBRACE RBRACE
+	| LBRACE targs RBRACE
+
+exp_arms:
+	/* empty */
+	| exp_arms BAR pattern ARROW exp
+
+pattern:
+	arg_pattern
+	| ctor_name arg_patterns1
+
+arg_patterns1:
+	arg_pattern
+	| arg_patterns1 arg_pattern
+
+arg_pattern:
+	UNDERSCORE
+	| ID
+	| ctor_name
+	| LPAREN pattern RPAREN
+
+type:
+	FORALL TID PERIOD type
+	| type_app TARROW type
+	| type_app
+
+type_app:
+	type_name targs
+	| targ
+
+targs:
+	targ
+	| targs targ
+
+targ:
+	LPAREN type RPAREN
+	| type_name
+	| prim_type
+	| address_type
+	| TID
+	| MAP targ targ
+
+address_type:
+	BYSTR_TYPE WITH END
+	| BYSTR_TYPE WITH LIBRARY END
+	| BYSTR_TYPE WITH CONTRACT END
+	| BYSTR_TYPE WITH CONTRACT address_fields END
+
+address_fields:
+	FIELD ID COLON type
+	| address_fields COMMA FIELD ID COLON type
+
+prim_type:
+	INT_TYPE
+	| STRING_TYPE
+	| BYSTR_TYPE
+	| BNUM_TYPE
+	| MESSAGE_TYPE
+	| EVENT_TYPE
+
+type_name:
+	CID
+	| BOOL
+	| NAT
+	| OPTION
+	| LIST
+	| PAIR
+
+ctor_name:
+	CID
+	| TRUE
+	| FALSE
+	| ZERO
+	| SUCC
+	| SOME
+	| NONE
+	| CONS
+	| NIL
+	| PAIR
+
+sidents:
+	/* empty */
+	| sidents1
+
+sidents1:
+	sident
+	| sidents1 sident
+
+sident:
+	ID
+	| SPID
 
 %%
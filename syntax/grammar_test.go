@@ -0,0 +1,109 @@
+package syntax
+
+import (
+	"fmt"
+	"github.com/rhysd/locerr"
+	"goscilla/stdlib"
+	"goscilla/token"
+	"path/filepath"
+	"testing"
+)
+
+// yaccLexer feeds tokens to yyParse generated from grammar.go.y. Tokens are declared in the
+// grammar in the same order as token.Kind.
+type yaccLexer struct {
+	tokens chan token.Token
+	eof    bool
+	err    error
+}
+
+func (l *yaccLexer) Lex(lval *yySymType) int {
+	if l.eof {
+		return 0
+	}
+	for t := range l.tokens {
+		switch t.Kind {
+		case token.COMMENT, token.WHITESPACE, token.NEWLINE:
+			continue
+		case token.EOF:
+			l.eof = true
+		}
+		t := t
+		lval.token = &t
+		return ILLEGAL + int(t.Kind)
+	}
+	return 0
+}
+
+func (l *yaccLexer) Error(msg string) {
+	if l.err == nil {
+		l.err = fmt.Errorf("%s", msg)
+	}
+}
+
+// recognize runs the parser generated from grammar.go.y on the source.
+func recognize(src *locerr.Source) error {
+	lex := NewLexer(src)
+	go lex.Lex()
+	l := &yaccLexer{tokens: lex.Tokens}
+	yyParse(l)
+	// Drain the channel until EOF so that the lexer can finish
+	for !l.eof && (<-lex.Tokens).Kind != token.EOF {
+	}
+	return l.err
+}
+
+func TestGrammarTokens(t *testing.T) {
+	if EOF-ILLEGAL != int(token.EOF) {
+		t.Fatalf("Tokens in grammar.go.y are not declared in the order of token.Kind")
+	}
+}
+
+func TestGrammarAcceptsValidSources(t *testing.T) {
+	var srcs []*locerr.Source
+	files := append(validFiles(), filepath.FromSlash("../checker/testdata/wallet.scilla"), filepath.FromSlash("../checker/testdata/lending.scilla"))
+	for _, f := range files {
+		src, err := locerr.NewSourceFromFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		srcs = append(srcs, src)
+	}
+	for _, n := range stdlib.Names() {
+		src, _ := stdlib.Source(n)
+		srcs = append(srcs, src)
+	}
+
+	for _, src := range srcs {
+		t.Run(src.Path, func(t *testing.T) {
+			if _, err := Parse(src); err != nil {
+				t.Fatal(err)
+			}
+			if err := recognize(src); err != nil {
+				t.Fatal("Grammar rejected the source accepted by Parse:", err)
+			}
+		})
+	}
+}
+
+func TestGrammarRejectsInvalidSources(t *testing.T) {
+	for _, code := range []string{
+		"library Foo\nlet x = ",
+		"contract Foo()\nfield x : = Uint32 0",
+		"transition T()\n  x <- ;\nend",
+		"transition T()\n  match x with\n  | Some => \nend",
+		"library Foo let x = a < b",
+		"contract Foo() transition T() end library Foo",
+		"library Foo let f = fun (x : Map Uint32) => x",
+	} {
+		t.Run(code, func(t *testing.T) {
+			src := locerr.NewDummySource(code)
+			if _, err := Parse(src); err == nil {
+				t.Fatal("Parse accepted the invalid source")
+			}
+			if err := recognize(src); err == nil {
+				t.Fatal("Grammar accepted the source rejected by Parse")
+			}
+		})
+	}
+}
@@ -28,6 +28,7 @@ type Lexer struct {
 	Tokens           chan token.Token
 	top              rune
 	eof              bool
+	eofEmitted       bool
 	// Function called when error occurs.
 	// By default it outputs an error to stderr.
 	Error func(msg string, pos locerr.Pos)
@@ -59,6 +60,11 @@ func (l *Lexer) Lex() {
 	for l.state != nil {
 		l.state = l.state(l)
 	}
+	// Lexing may stop on an illegal token. Always terminate the stream with EOF
+	// so that a reader can drain the channel until EOF.
+	if !l.eofEmitted {
+		l.emit(token.EOF)
+	}
 }
 
 func (l *Lexer) emitIdent(ident string) {
@@ -89,8 +95,8 @@ func (l *Lexer) emitIdent(ident string) {
 
 func (l *Lexer) emitPrimeType(ident string) bool {
 	switch ident {
-	case "Int32", "Int64", "Int128", "Int256":
-	case "Uint32", "Uint64", "Uint128", "Uint256":
+	case "Int32", "Int64", "Int128", "Int256",
+		"Uint32", "Uint64", "Uint128", "Uint256":
 		l.emit(token.INT_TYPE)
 		return true
 	case "Event":
@@ -122,6 +128,9 @@ func (l *Lexer) emit(kind token.Kind) {
 	}
 	l.Tokens <- tok
 	l.start = l.current
+	if kind == token.EOF {
+		l.eofEmitted = true
+	}
 	if traceLexerTokenPos {
 		line := l.getCurrentPosString(tok.Start.Offset - tok.End.Offset)
 		_, _ = fmt.Fprintf(os.Stderr, "%s (%d:%d)\n", line, tok.Start.Line, tok.Start.Column)
@@ -227,7 +236,13 @@ func (l *Lexer) errmsg(msg string) {
 
 func (l *Lexer) eatIdent() bool {
 	if !isLetter(l.top) {
-		l.expected("letter for head character of identifer", l.top)
+		r := l.top
+		if !l.eof {
+			// Include the unexpected character in the illegal token so that
+			// lexing can continue after it.
+			l.eat()
+		}
+		l.expected("letter for head character of identifer", r)
 		return false
 	}
 	l.eat()
@@ -322,18 +337,23 @@ func isDigit(r rune) bool {
 }
 
 func isHex(r rune) bool {
-	return 'a' <= r && r <= 'z' ||
-		'A' <= r && r <= 'Z' ||
+	return 'a' <= r && r <= 'f' ||
+		'A' <= r && r <= 'F' ||
 		'0' <= r && r <= '9'
 }
 
 func lexIdent(l *Lexer) stateFn {
 	if l.top == '_' {
 		l.eat()
-		if !isLetter(l.top) {
-			l.emit(token.SPID)
+		if !isLetter(l.top) && !isDigit(l.top) {
+			l.emit(token.UNDERSCORE)
 			return lex
 		}
+		for isLetter(l.top) || isDigit(l.top) {
+			l.eat()
+		}
+		l.emit(token.SPID)
+		return lex
 	}
 	// ( : - < = already filtered out
 	for idx, s := range token.SeparatorTable {
@@ -353,7 +373,7 @@ func lexIdent(l *Lexer) stateFn {
 		if isTid {
 			l.emitIllegal("Expected <Type Parameter Name> after ' but got 'Nothing'")
 		}
-		return nil
+		return lex
 	}
 	i := string(l.src.Code[l.start.Offset:l.current.Offset])
 	if isTid {
@@ -367,10 +387,52 @@ func lexIdent(l *Lexer) stateFn {
 	if l.emitPrimeType(i) {
 		return lex
 	}
+	if l.top == '.' && isQualifier(i) {
+		return lexQualified
+	}
 	l.emitIdent(i)
 	return lex
 }
 
+// isQualifier returns whether the identifier can be an alias of library which qualifies names
+// like `F.entry`.
+func isQualifier(i string) bool {
+	if i[0] < 'A' || 'Z' < i[0] {
+		return false
+	}
+	for _, kw := range token.KeywordTable {
+		if i == kw {
+			return false
+		}
+	}
+	for _, adt := range token.BuiltinADTTable {
+		if i == adt {
+			return false
+		}
+	}
+	return true
+}
+
+// lexQualified lexes a name qualified by alias of library such as `F.entry` or `F.Ctor` as one
+// identifier. The kind of token is decided by the name after the period.
+func lexQualified(l *Lexer) stateFn {
+	next := l.current.Offset + 1
+	if next >= len(l.src.Code) || !isLetter(rune(l.src.Code[next])) || l.src.Code[next] == '_' {
+		// Not a qualified name. Period is lexed as separator
+		l.emitIdent(string(l.src.Code[l.start.Offset:l.current.Offset]))
+		return lex
+	}
+	l.eat() // Eat '.'
+	nameStart := l.current.Offset
+	l.eatIdent()
+	if c := l.src.Code[nameStart]; 'A' <= c && c <= 'Z' {
+		l.emit(token.CID)
+	} else {
+		l.emit(token.ID)
+	}
+	return lex
+}
+
 func lexStringLiteral(l *Lexer) stateFn {
 	l.eat() // Eat first '"'
 	for !l.eof {
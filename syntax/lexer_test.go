@@ -13,7 +13,6 @@ import (
 func TestLexingOK(t *testing.T) {
 	for _, testdir := range []string{
 		"testdata",
-		"../testdata/from-mincaml/",
 	} {
 		files, err := ioutil.ReadDir(filepath.FromSlash(testdir))
 		if err != nil {
@@ -22,7 +21,7 @@ func TestLexingOK(t *testing.T) {
 
 		for _, f := range files {
 			n := filepath.Join(testdir, f.Name())
-			if !strings.HasSuffix(n, ".ml") {
+			if !strings.HasSuffix(n, ".scilla") {
 				continue
 			}
 
@@ -49,10 +48,9 @@ func TestLexingOK(t *testing.T) {
 	}
 }
 
-// List literal can be lexed but parser should complain that it is not implemented yet.
-// This behavior is implemented because array literal ressembles to list literal.
-func TestLexingListLiteral(t *testing.T) {
-	s := locerr.NewDummySource("[1; 2; 3]")
+// Map keys are lexed as separators and identifiers.
+func TestLexingMapAccess(t *testing.T) {
+	s := locerr.NewDummySource("x <- m[k1][_sender]")
 	l := NewLexer(s)
 	go l.Lex()
 lexing:
@@ -69,6 +67,29 @@ lexing:
 	}
 }
 
+// Names qualified by alias of library are lexed as one identifier.
+func TestLexingQualifiedName(t *testing.T) {
+	s := locerr.NewDummySource("F.entry F.Ctor forall 'A. Foo. a.b")
+	l := NewLexer(s)
+	go l.Lex()
+	var have []string
+	for tok := range l.Tokens {
+		if tok.Kind == token.EOF {
+			break
+		}
+		if tok.Kind != token.WHITESPACE {
+			have = append(have, tok.String()[:strings.Index(tok.String(), "(")])
+		}
+	}
+	want := []string{
+		`<ID:"F.entry">`, `<CID:"F.Ctor">`, `<forall:"forall">`, `<TID:"'A">`, `<.:".">`,
+		`<CID:"Foo">`, `<.:".">`, `<ID:"a">`, `<.:".">`, `<ID:"b">`,
+	}
+	if strings.Join(have, " ") != strings.Join(want, " ") {
+		t.Errorf("Wanted %v but got %v", want, have)
+	}
+}
+
 func TestLexingIllegal(t *testing.T) {
 	testdir := filepath.FromSlash("testdata/lexer/invalid")
 	files, err := ioutil.ReadDir(testdir)
@@ -78,7 +99,7 @@ func TestLexingIllegal(t *testing.T) {
 
 	for _, f := range files {
 		n := filepath.Join(testdir, f.Name())
-		if !strings.HasSuffix(n, ".ml") {
+		if !strings.HasSuffix(n, ".scilla") {
 			continue
 		}
 
@@ -1,101 +1,1198 @@
-// Package syntax provides lexing and parsing from GoCaml source code into abstract syntax tree.
+// Package syntax provides lexing and parsing from Scilla source code into abstract syntax tree.
 package syntax
+
+import (
+	"fmt"
+	"github.com/rhysd/locerr"
+	"goscilla/ast"
+	"goscilla/token"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// parser is a hand-written recursive descent parser for Scilla.
+// The productions it accepts are described in grammar.go.y and follow
+// ScillaParser.mly of Zilliqa/scilla. Each parse method documents the
+// production it handles.
 //
-//import (
-//	"github.com/rhysd/locerr"
-//	"goscilla/ast"
-//	"goscilla/token"
-//)
-//
-//type pseudoLexer struct {
-//	lastToken *token.Token
-//	tokens    chan token.Token
-//	err       *locerr.Error
-//	result    *ast.AST
-//}
-//
-//func (l *pseudoLexer) Lex(lval *yySymType) int {
-//	for {
-//		select {
-//		case t := <-l.tokens:
-//			lval.token = &t
-//
-//			switch t.Kind {
-//			case token.EOF, token.ILLEGAL:
-//				// Zero means input ends
-//				// (see golang.org/x/tools/cmd/goyacc/testdata/expr/expr.y)
-//				return 0
-//			case token.COMMENT:
-//				continue
-//			}
-//
-//			l.lastToken = &t
-//
-//			// XXX:
-//			// Converting token value into yacc's token.
-//			// This conversion requires that token order must the same as
-//			// yacc's token order. EOF is a first token. So we can use it
-//			// to make an offset between token value and yacc's token value.
-//			return int(t.Kind) + ILLEGAL
-//		}
-//	}
-//}
-//
-//// Interface yyLexer requires this method.
-//func (l *pseudoLexer) Error(msg string) {
-//	if l.err == nil {
-//		if l.lastToken != nil {
-//			l.err = locerr.ErrorAt(l.lastToken.Start, msg)
-//		} else {
-//			l.err = locerr.NewError(msg)
-//		}
-//	} else {
-//		if l.lastToken != nil {
-//			l.err = l.err.NoteAt(l.lastToken.Start, msg)
-//		} else {
-//			l.err = l.err.Note(msg)
-//		}
-//	}
-//}
-//
-//func Parse(src *locerr.Source) (*ast.AST, error) {
-//	var lexErr *locerr.Error
-//	l := NewLexer(src)
-//	l.Error = func(msg string, pos locerr.Pos) {
-//		if lexErr == nil {
-//			lexErr = locerr.ErrorAt(pos, msg)
-//		} else {
-//			lexErr = lexErr.NoteAt(pos, msg)
-//		}
-//	}
-//	go l.Lex()
-//	parsed, err := ParseTokens(l.Tokens)
-//	if lexErr != nil {
-//		return nil, lexErr.Note("Lexing source into tokens failed")
-//	}
-//	if err != nil {
-//		return nil, err
-//	}
-//	return parsed, nil
-//}
-//
-//// ParseTokens parses given tokens and returns parsed AST.
-//// Tokens are passed via channel.
-//func ParseTokens(tokens chan token.Token) (*ast.AST, error) {
-//	yyErrorVerbose = true
-//
-//	l := &pseudoLexer{tokens: tokens}
-//	ret := yyParse(l)
-//
-//	if l.err != nil {
-//		l.Error("Error while parsing")
-//		return nil, l.err
-//	}
-//
-//	root := l.result
-//	if ret != 0 || root == nil {
-//		panic("FATAL: Parse failed for unknown reason")
-//	}
-//
-//	return root, nil
-//}
+// On a syntax error the parser records it and resynchronizes at the next
+// `transition`, `procedure`, `field`, library entry, statement, `end` or `|`
+// of match arm. Skipped source ranges are represented by ast.Bad* nodes.
+type parser struct {
+	src      *locerr.Source
+	tokens   []*token.Token
+	pos      int
+	tok      *token.Token
+	errs     ErrorList
+	badDecls []*ast.BadDecl
+	comments *commentMap
+}
+
+// bailout is used as a panic value to abort parsing the current construct.
+// It is recovered by try.
+type bailout struct {
+	err *locerr.Error
+}
+
+// ErrorList is a list of errors found while lexing and parsing a source,
+// sorted by position.
+type ErrorList []*locerr.Error
+
+func (l ErrorList) Error() string {
+	msgs := make([]string, 0, len(l))
+	for _, err := range l {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func (l ErrorList) sort() {
+	sort.SliceStable(l, func(i, j int) bool {
+		return l[i].Start.Offset < l[j].Start.Offset
+	})
+}
+
+func newParser(src *locerr.Source, tokens []*token.Token, comments []comment) *parser {
+	p := &parser{src: src, tokens: tokens, comments: newCommentMap(comments, tokens)}
+	p.tok = tokens[0]
+	return p
+}
+
+func (p *parser) next() {
+	if p.pos < len(p.tokens)-1 {
+		p.pos++
+	}
+	p.tok = p.tokens[p.pos]
+	if traceParser {
+		log.Tracef("parser: %s", p.tok.String())
+	}
+}
+
+func (p *parser) peek(n int) *token.Token {
+	i := p.pos + n
+	if i >= len(p.tokens) {
+		return p.tokens[len(p.tokens)-1]
+	}
+	return p.tokens[i]
+}
+
+func (p *parser) at(kinds ...token.Kind) bool {
+	for _, k := range kinds {
+		if p.tok.Kind == k {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) got(kind token.Kind) *token.Token {
+	if p.tok.Kind != kind {
+		return nil
+	}
+	t := p.tok
+	p.next()
+	return t
+}
+
+func (p *parser) expect(kinds ...token.Kind) *token.Token {
+	if !p.at(kinds...) {
+		names := make([]string, 0, len(kinds))
+		for _, k := range kinds {
+			names = append(names, fmt.Sprintf("'%s'", token.TokenTable[k]))
+		}
+		p.unexpected(strings.Join(names, " or "))
+	}
+	t := p.tok
+	p.next()
+	return t
+}
+
+func (p *parser) unexpected(expected string) {
+	panic(bailout{p.unexpectedError(expected)})
+}
+
+func (p *parser) unexpectedError(expected string) *locerr.Error {
+	msg := fmt.Sprintf("Unexpected token %s while parsing. Expected %s", describe(p.tok), expected)
+	return locerr.ErrorIn(p.tok.Start, p.tok.End, msg)
+}
+
+func (p *parser) errorAt(t *token.Token, msg string) {
+	panic(bailout{locerr.ErrorIn(t.Start, t.End, msg)})
+}
+
+// report records an error. An error at the same position as the previous
+// one is dropped since it is usually caused by the previous one.
+func (p *parser) report(err *locerr.Error) {
+	if n := len(p.errs); n > 0 && p.errs[n-1].Start.Offset == err.Start.Offset {
+		return
+	}
+	p.errs = append(p.errs, err)
+}
+
+// try calls f and returns false when f stopped at a syntax error. The error is
+// recorded and the current token is left at the position of the error.
+func (p *parser) try(f func()) (ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			b, isBailout := r.(bailout)
+			if !isBailout {
+				panic(r)
+			}
+			p.report(b.err)
+			ok = false
+		}
+	}()
+	f()
+	return true
+}
+
+// skipTo skips tokens until stop returns true for the current token. depth is
+// the nesting level of `match ... end`, address types, parens and braces which
+// are not closed yet. Tokens already consumed since the token at index start
+// are also taken into account.
+func (p *parser) skipTo(start int, stop func(t *token.Token, depth int) bool) {
+	depth := 0
+	for i := start; i < p.pos; i++ {
+		depth = p.nest(i, depth)
+	}
+	for !p.at(token.EOF) && !stop(p.tok, depth) {
+		depth = p.nest(p.pos, depth)
+		p.next()
+	}
+}
+
+// nest returns the nesting depth after the token at index i.
+func (p *parser) nest(i, depth int) int {
+	switch p.tokens[i].Kind {
+	case token.MATCH, token.LPAREN, token.LBRACE:
+		return depth + 1
+	case token.WITH:
+		if i > 0 && p.tokens[i-1].Kind == token.BYSTR_TYPE {
+			return depth + 1
+		}
+	case token.END, token.RPAREN, token.RBRACE:
+		if depth > 0 {
+			return depth - 1
+		}
+	}
+	return depth
+}
+
+// skipped returns the range of tokens consumed since the token at index start.
+// It is empty when no token was consumed.
+func (p *parser) skipped(start int) (locerr.Pos, locerr.Pos) {
+	if p.pos == start {
+		return p.tok.Start, p.tok.Start
+	}
+	return p.tokens[start].Start, p.tokens[p.pos-1].End
+}
+
+// closing consumes a closing token such as `end`. When it is missing, the
+// error is recorded and the last consumed token is returned instead so that
+// the enclosing node still has a valid range.
+func (p *parser) closing(kind token.Kind) *token.Token {
+	if t := p.got(kind); t != nil {
+		return t
+	}
+	p.report(p.unexpectedError(fmt.Sprintf("'%s'", token.TokenTable[kind])))
+	return p.tokens[p.pos-1]
+}
+
+// isDeclKeyword returns true when the kind always starts a new module level
+// declaration.
+func isDeclKeyword(k token.Kind) bool {
+	switch k {
+	case token.SCILLA_VERSION, token.IMPORT, token.LIBRARY, token.CONTRACT, token.TRANSITION, token.PROCEDURE:
+		return true
+	}
+	return false
+}
+
+// atDecl is a stop function of skipTo for module level declarations.
+func atDecl(t *token.Token, depth int) bool {
+	return isDeclKeyword(t.Kind) || depth == 0 && t.Kind == token.FIELD
+}
+
+// atStmtEnd is a stop function of skipTo for statements and bodies of match
+// arms.
+func atStmtEnd(t *token.Token, depth int) bool {
+	switch t.Kind {
+	case token.SEMICOLON, token.END, token.BAR:
+		return depth == 0
+	}
+	return isDeclKeyword(t.Kind)
+}
+
+// atArrow is a stop function of skipTo for patterns of match arms.
+func atArrow(t *token.Token, depth int) bool {
+	return depth == 0 && t.Kind == token.ARROW || atStmtEnd(t, depth)
+}
+
+// decl parses a module level declaration with f. When f fails, tokens until
+// the next declaration are skipped and recorded as ast.BadDecl.
+func (p *parser) decl(f func()) {
+	start := p.pos
+	if p.try(f) {
+		return
+	}
+	if p.pos == start && !p.at(token.EOF) {
+		// Ensure progress. The token cannot start any declaration here
+		p.next()
+	}
+	p.skipTo(start, atDecl)
+	from, to := p.skipped(start)
+	p.badDecls = append(p.badDecls, &ast.BadDecl{From: from, To: to})
+}
+
+func describe(t *token.Token) string {
+	if t.Kind == token.EOF {
+		return "EOF"
+	}
+	return fmt.Sprintf("%s (%s)", t.DisplayValue(), token.TokenTable[t.Kind])
+}
+
+func isSident(k token.Kind) bool {
+	return k == token.ID || k == token.SPID
+}
+
+// isCtorName returns true when the kind can be a name of constructor.
+func isCtorName(k token.Kind) bool {
+	switch k {
+	case token.CID, token.TRUE, token.FALSE, token.ZERO, token.SUCC,
+		token.SOME, token.NONE, token.CONS, token.NIL, token.PAIR:
+		return true
+	}
+	return false
+}
+
+// isTypeName returns true when the kind can be a name of ADT.
+func isTypeName(k token.Kind) bool {
+	switch k {
+	case token.CID, token.BOOL, token.NAT, token.OPTION, token.LIST, token.PAIR:
+		return true
+	}
+	return false
+}
+
+func isPrimType(k token.Kind) bool {
+	switch k {
+	case token.INT_TYPE, token.STRING_TYPE, token.BYSTR_TYPE, token.BNUM_TYPE, token.MESSAGE_TYPE, token.EVENT_TYPE:
+		return true
+	}
+	return false
+}
+
+func isTArgStart(k token.Kind) bool {
+	return k == token.LPAREN || k == token.TID || k == token.MAP || isTypeName(k) || isPrimType(k)
+}
+
+func ident(t *token.Token) *ast.Ident {
+	return &ast.Ident{Token: t, Symbol: ast.NewSymbol(t.Value())}
+}
+
+func varRef(t *token.Token) *ast.VarRef {
+	return &ast.VarRef{Token: t, Symbol: ast.NewSymbol(t.Value())}
+}
+
+func (p *parser) ident(kinds ...token.Kind) *ast.Ident {
+	return ident(p.expect(kinds...))
+}
+
+func (p *parser) sident() *ast.VarRef {
+	return varRef(p.expect(token.ID, token.SPID))
+}
+
+// module:
+//
+//	version? import* library? contract? component*
+func (p *parser) parseModule() *ast.AST {
+	a := &ast.AST{Source: p.src}
+	if p.at(token.SCILLA_VERSION) {
+		p.decl(func() { a.Version = p.parseVersion() })
+	}
+	for p.at(token.IMPORT) {
+		p.decl(func() { a.Imports = append(a.Imports, p.parseImport()) })
+	}
+	if p.at(token.LIBRARY) {
+		p.decl(func() { a.Library = p.parseLibrary() })
+	}
+	if p.at(token.CONTRACT) {
+		p.decl(func() { a.Contract = p.parseContract() })
+	}
+	for !p.at(token.EOF) {
+		if p.at(token.TRANSITION, token.PROCEDURE) && a.Contract == nil {
+			p.decl(func() { a.Components = append(a.Components, p.parseComponent()) })
+			continue
+		}
+		p.decl(func() { p.unexpected("'library', 'contract', 'transition' or 'procedure'") })
+	}
+	a.BadDecls = p.badDecls
+	a.EOFToken = p.tok
+	return a
+}
+
+// version:
+//
+//	SCILLA_VERSION NUM_LIT
+func (p *parser) parseVersion() *ast.Version {
+	v := p.expect(token.SCILLA_VERSION)
+	n := p.expect(token.NUM_LIT)
+	i, err := strconv.Atoi(n.Value())
+	if err != nil {
+		p.errorAt(n, fmt.Sprintf("Invalid Scilla version %s", n.Value()))
+	}
+	return &ast.Version{VersionToken: v, NumToken: n, Value: i}
+}
+
+// import:
+//
+//	IMPORT (CID | CID AS CID)+
+func (p *parser) parseImport() *ast.Import {
+	i := &ast.Import{ImportToken: p.expect(token.IMPORT)}
+	for {
+		n := &ast.ImportName{Lib: p.ident(token.CID)}
+		if p.got(token.AS) != nil {
+			n.Alias = p.ident(token.CID)
+		}
+		i.Names = append(i.Names, n)
+		if !p.at(token.CID) {
+			return i
+		}
+	}
+}
+
+// library:
+//
+//	LIBRARY CID (let_decl | type_decl)*
+func (p *parser) parseLibrary() *ast.Library {
+	l := &ast.Library{
+		Doc:          p.doc(p.pos),
+		LibraryToken: p.expect(token.LIBRARY),
+		Ident:        p.ident(token.CID),
+	}
+	for p.at(token.LET, token.TYPE) {
+		start, col := p.pos, p.tok.Start.Column
+		var e ast.LibEntry
+		if p.try(func() { e = p.parseLibEntry() }) {
+			l.Entries = append(l.Entries, e)
+			continue
+		}
+		// Next entry is a `let` or `type` not indented deeper than this one
+		p.skipTo(start, func(t *token.Token, depth int) bool {
+			return atDecl(t, depth) || (t.Kind == token.LET || t.Kind == token.TYPE) && t.Start.Column <= col
+		})
+		from, to := p.skipped(start)
+		l.Entries = append(l.Entries, &ast.BadDecl{From: from, To: to})
+	}
+	return l
+}
+
+func (p *parser) parseLibEntry() ast.LibEntry {
+	if p.at(token.TYPE) {
+		return p.parseTypeDecl()
+	}
+	return p.parseLetDecl()
+}
+
+// let_decl:
+//
+//	LET ID (COLON type)? EQ exp
+func (p *parser) parseLetDecl() *ast.LetDecl {
+	d := &ast.LetDecl{
+		Doc:      p.doc(p.pos),
+		LetToken: p.expect(token.LET),
+		Ident:    p.ident(token.ID),
+	}
+	if p.got(token.COLON) != nil {
+		d.Type = p.parseType()
+	}
+	p.expect(token.EQ)
+	d.Bound = p.parseExpr()
+	d.Comment = p.lineComment(p.pos - 1)
+	return d
+}
+
+// type_decl:
+//
+//	TYPE CID (EQ ctor_decl+)?
+//
+// ctor_decl:
+//
+//	BAR ctor_name (OF targ+)?
+func (p *parser) parseTypeDecl() *ast.TypeDecl {
+	d := &ast.TypeDecl{
+		Doc:       p.doc(p.pos),
+		TypeToken: p.expect(token.TYPE),
+		Ident:     p.ident(token.CID),
+	}
+	if p.got(token.EQ) == nil {
+		return d
+	}
+	for {
+		c := &ast.CtorDecl{Doc: p.doc(p.pos), BarToken: p.expect(token.BAR)}
+		if !isCtorName(p.tok.Kind) {
+			p.unexpected("constructor name")
+		}
+		c.Ident = ident(p.tok)
+		p.next()
+		if p.got(token.OF) != nil {
+			c.Types = append(c.Types, p.parseTArg())
+			for isTArgStart(p.tok.Kind) {
+				c.Types = append(c.Types, p.parseTArg())
+			}
+		}
+		c.Comment = p.lineComment(p.pos - 1)
+		d.Ctors = append(d.Ctors, c)
+		if !p.at(token.BAR) {
+			return d
+		}
+	}
+}
+
+// contract:
+//
+//	CONTRACT CID LPAREN params RPAREN (WITH exp ARROW)? field* component*
+func (p *parser) parseContract() *ast.Contract {
+	c := &ast.Contract{
+		Doc:           p.doc(p.pos),
+		ContractToken: p.expect(token.CONTRACT),
+		Ident:         p.ident(token.CID),
+	}
+	c.Params, c.RParenToken = p.parseParams()
+	if p.got(token.WITH) != nil {
+		start := p.pos
+		if !p.try(func() { c.Constraint = p.parseExpr(); p.expect(token.ARROW) }) {
+			p.skipTo(start, func(t *token.Token, depth int) bool {
+				return depth == 0 && t.Kind == token.ARROW || atDecl(t, depth)
+			})
+			from, to := p.skipped(start)
+			c.Constraint = &ast.BadExpr{From: from, To: to}
+			p.got(token.ARROW)
+		}
+	}
+	for p.at(token.FIELD) {
+		p.decl(func() { c.Fields = append(c.Fields, p.parseField()) })
+	}
+	for p.at(token.TRANSITION, token.PROCEDURE, token.FIELD) {
+		if p.at(token.FIELD) {
+			// Report a misplaced field but keep it in the contract
+			p.report(locerr.ErrorIn(p.tok.Start, p.tok.End, "Fields must be declared before transitions and procedures"))
+			p.decl(func() { c.Fields = append(c.Fields, p.parseField()) })
+			continue
+		}
+		p.decl(func() { c.Components = append(c.Components, p.parseComponent()) })
+	}
+	return c
+}
+
+// params:
+//
+//	LPAREN (param (COMMA param)*)? RPAREN
+//
+// param:
+//
+//	ID COLON type
+func (p *parser) parseParams() ([]*ast.Param, *token.Token) {
+	p.expect(token.LPAREN)
+	var params []*ast.Param
+	if r := p.got(token.RPAREN); r != nil {
+		return params, r
+	}
+	for {
+		start := p.pos
+		var param *ast.Param
+		if p.try(func() { param = p.parseParam() }) {
+			param.Doc = p.doc(start)
+			params = append(params, param)
+		} else {
+			p.skipTo(start, func(t *token.Token, depth int) bool {
+				return depth == 0 && (t.Kind == token.COMMA || t.Kind == token.RPAREN || t.Kind == token.END) || atDecl(t, depth)
+			})
+			if !p.at(token.COMMA, token.RPAREN) {
+				// The broken parameter was already reported. `)` is missing
+				return params, nil
+			}
+		}
+		comma := p.got(token.COMMA)
+		if param != nil {
+			// Comment may follow the comma: `x : T, (* comment *)`
+			param.Comment = p.lineComment(p.pos - 1)
+		}
+		if comma == nil {
+			return params, p.expect(token.RPAREN)
+		}
+	}
+}
+
+func (p *parser) parseParam() *ast.Param {
+	i := p.ident(token.ID)
+	p.expect(token.COLON)
+	return &ast.Param{Ident: i, Type: p.parseType()}
+}
+
+// field:
+//
+//	FIELD ID COLON type EQ exp
+func (p *parser) parseField() *ast.Field {
+	f := &ast.Field{
+		Doc:        p.doc(p.pos),
+		FieldToken: p.expect(token.FIELD),
+		Ident:      p.ident(token.ID),
+	}
+	p.expect(token.COLON)
+	f.Type = p.parseType()
+	p.expect(token.EQ)
+	f.Init = p.parseExpr()
+	f.Comment = p.lineComment(p.pos - 1)
+	return f
+}
+
+// component:
+//
+//	(TRANSITION | PROCEDURE) (ID | CID) params stmts END
+func (p *parser) parseComponent() *ast.Component {
+	c := &ast.Component{
+		Doc:   p.doc(p.pos),
+		Token: p.expect(token.TRANSITION, token.PROCEDURE),
+		Ident: p.ident(token.ID, token.CID),
+	}
+	c.Params, c.RParenToken = p.parseParams()
+	c.Body = p.parseStmts()
+	c.EndToken = p.closing(token.END)
+	return c
+}
+
+// stmts:
+//
+//	(stmt (SEMICOLON stmt)* SEMICOLON?)?
+//
+// A statement list ends at `end` of component or match, or `|` of next arm.
+// A broken statement is skipped until the next `;`, `end` or `|` and recorded
+// as ast.BadStmt.
+func (p *parser) parseStmts() []ast.Stmt {
+	var stmts []ast.Stmt
+	for !p.at(token.END, token.BAR, token.EOF) && !isDeclKeyword(p.tok.Kind) {
+		start := p.pos
+		var s ast.Stmt
+		if p.try(func() { s = p.parseStmt() }) {
+			stmts = append(stmts, s)
+		} else if p.skipTo(start, atStmtEnd); p.pos > start {
+			from, to := p.skipped(start)
+			stmts = append(stmts, &ast.BadStmt{From: from, To: to})
+		}
+		if p.got(token.SEMICOLON) == nil {
+			break
+		}
+	}
+	return stmts
+}
+
+// stmt:
+//
+//	ID FETCH sident                        (load)
+//	ID FETCH sident map_key+               (map get)
+//	ID FETCH EXISTS ID map_key+            (map exists)
+//	ID FETCH AND CID                       (read from blockchain)
+//	ID FETCH AND EXISTS? sident PERIOD sident map_key*   (remote read)
+//	ID ASSIGN sident                       (store)
+//	ID EQ exp                              (bind)
+//	ID map_key+ ASSIGN sident              (map update)
+//	DELETE ID map_key+
+//	ACCEPT
+//	SEND sident
+//	EVENT sident
+//	THROW sident?
+//	MATCH sident WITH stmt_arm* END
+//	FORALL sident (ID | CID)
+//	(ID | CID) sident*                     (procedure call)
+func (p *parser) parseStmt() ast.Stmt {
+	switch p.tok.Kind {
+	case token.ID:
+		switch p.peek(1).Kind {
+		case token.FETCH:
+			return p.parseFetch()
+		case token.ASSIGN:
+			f := varRef(p.tok)
+			p.next()
+			p.next()
+			return &ast.Store{Field: f, Value: p.sident()}
+		case token.EQ:
+			i := ident(p.tok)
+			p.next()
+			p.next()
+			return &ast.Bind{Ident: i, Value: p.parseExpr()}
+		case token.LSQB:
+			m := varRef(p.tok)
+			p.next()
+			keys := p.parseMapKeys()
+			p.expect(token.ASSIGN)
+			return &ast.MapUpdate{Map: m, Keys: keys, Value: p.sident()}
+		}
+		return p.parseCallProc()
+	case token.CID:
+		return p.parseCallProc()
+	case token.DELETE:
+		d := &ast.MapDelete{DeleteToken: p.tok}
+		p.next()
+		d.Map = varRef(p.expect(token.ID))
+		d.Keys = p.parseMapKeys()
+		return d
+	case token.ACCEPT:
+		s := &ast.Accept{Token: p.tok}
+		p.next()
+		return s
+	case token.SEND:
+		s := &ast.Send{Token: p.tok}
+		p.next()
+		s.Msgs = p.sident()
+		return s
+	case token.EVENT:
+		s := &ast.Event{Token: p.tok}
+		p.next()
+		s.Event = p.sident()
+		return s
+	case token.THROW:
+		s := &ast.Throw{Token: p.tok}
+		p.next()
+		if isSident(p.tok.Kind) {
+			s.Exception = p.sident()
+		}
+		return s
+	case token.MATCH:
+		return p.parseMatchStmt()
+	case token.FORALL:
+		s := &ast.Iterate{ForallToken: p.tok}
+		p.next()
+		s.List = p.sident()
+		s.Proc = varRef(p.expect(token.ID, token.CID))
+		return s
+	}
+	p.unexpected("statement")
+	return nil
+}
+
+func (p *parser) parseFetch() ast.Stmt {
+	lhs := ident(p.tok)
+	p.next()
+	p.expect(token.FETCH)
+
+	if and := p.got(token.AND); and != nil {
+		if q := p.got(token.CID); q != nil {
+			return &ast.ReadFromBC{Ident: lhs, AndToken: and, Query: q}
+		}
+		exists := p.got(token.EXISTS)
+		addr := p.sident()
+		p.expect(token.PERIOD)
+		field := p.ident(token.ID, token.SPID)
+		if exists == nil && !p.at(token.LSQB) {
+			return &ast.RemoteLoad{Ident: lhs, AndToken: and, Addr: addr, Field: field}
+		}
+		return &ast.RemoteMapGet{
+			Ident:       lhs,
+			AndToken:    and,
+			ExistsToken: exists,
+			Addr:        addr,
+			Map:         field,
+			Keys:        p.parseMapKeys(),
+		}
+	}
+
+	if exists := p.got(token.EXISTS); exists != nil {
+		m := varRef(p.expect(token.ID))
+		return &ast.MapGet{Ident: lhs, ExistsToken: exists, Map: m, Keys: p.parseMapKeys()}
+	}
+
+	r := p.sident()
+	if p.at(token.LSQB) {
+		return &ast.MapGet{Ident: lhs, Map: r, Keys: p.parseMapKeys()}
+	}
+	return &ast.Load{Ident: lhs, Field: r}
+}
+
+// map_key:
+//
+//	LSQB sident RSQB
+func (p *parser) parseMapKeys() []*ast.MapKey {
+	var keys []*ast.MapKey
+	for {
+		k := &ast.MapKey{LSQBToken: p.expect(token.LSQB)}
+		k.Key = p.sident()
+		k.RSQBToken = p.expect(token.RSQB)
+		keys = append(keys, k)
+		if !p.at(token.LSQB) {
+			return keys
+		}
+	}
+}
+
+func (p *parser) parseCallProc() *ast.CallProc {
+	s := &ast.CallProc{Proc: varRef(p.tok)}
+	p.next()
+	for isSident(p.tok.Kind) {
+		s.Args = append(s.Args, p.sident())
+	}
+	return s
+}
+
+// stmt_arm:
+//
+//	BAR pattern ARROW stmts
+func (p *parser) parseMatchStmt() *ast.MatchStmt {
+	s := &ast.MatchStmt{MatchToken: p.expect(token.MATCH)}
+	s.Target = p.sident()
+	p.expect(token.WITH)
+	for p.at(token.BAR) {
+		arm := &ast.StmtArm{BarToken: p.tok}
+		p.next()
+		arm.Pattern = p.parseArmPattern()
+		arm.Body = p.parseStmts()
+		s.Arms = append(s.Arms, arm)
+	}
+	s.EndToken = p.closing(token.END)
+	return s
+}
+
+// parseArmPattern parses `pattern ARROW` of a match arm. A broken pattern is
+// skipped until `=>` and recorded as ast.BadPattern.
+func (p *parser) parseArmPattern() ast.Pattern {
+	start := p.pos
+	var pat ast.Pattern
+	if p.try(func() { pat = p.parsePattern(); p.expect(token.ARROW) }) {
+		return pat
+	}
+	p.skipTo(start, atArrow)
+	from, to := p.skipped(start)
+	p.got(token.ARROW)
+	return &ast.BadPattern{From: from, To: to}
+}
+
+// exp:
+//
+//	LET ID (COLON type)? EQ exp IN exp
+//	FUN LPAREN ID COLON type RPAREN ARROW exp
+//	TFUN TID ARROW exp
+//	AT sident targ+
+//	BUILTIN ID (sident+ | LPAREN RPAREN)
+//	LBRACE (msg_entry (SEMICOLON msg_entry)*)? RBRACE
+//	MATCH sident WITH exp_arm* END
+//	ctor_name (LBRACE targ* RBRACE)? sident*
+//	sident sident*
+//	literal
+func (p *parser) parseExpr() ast.Expr {
+	switch p.tok.Kind {
+	case token.LET:
+		e := &ast.Let{LetToken: p.tok}
+		p.next()
+		e.Ident = p.ident(token.ID)
+		if p.got(token.COLON) != nil {
+			e.Type = p.parseType()
+		}
+		p.expect(token.EQ)
+		e.Bound = p.parseExpr()
+		p.expect(token.IN)
+		e.Body = p.parseExpr()
+		return e
+	case token.FUN:
+		e := &ast.Fun{FunToken: p.tok}
+		p.next()
+		p.expect(token.LPAREN)
+		e.Param = p.parseParam()
+		p.expect(token.RPAREN)
+		p.expect(token.ARROW)
+		e.Body = p.parseExpr()
+		return e
+	case token.TFUN:
+		e := &ast.TFun{TFunToken: p.tok}
+		p.next()
+		e.TVar = p.ident(token.TID)
+		p.expect(token.ARROW)
+		e.Body = p.parseExpr()
+		return e
+	case token.AT:
+		e := &ast.TApp{AtToken: p.tok}
+		p.next()
+		e.Func = p.sident()
+		e.Types = append(e.Types, p.parseTArg())
+		for isTArgStart(p.tok.Kind) {
+			e.Types = append(e.Types, p.parseTArg())
+		}
+		return e
+	case token.BUILTIN:
+		e := &ast.Builtin{BuiltinToken: p.tok}
+		p.next()
+		e.Ident = p.ident(token.ID)
+		if p.got(token.LPAREN) != nil {
+			e.RParenToken = p.expect(token.RPAREN)
+			return e
+		}
+		e.Args = append(e.Args, p.sident())
+		for isSident(p.tok.Kind) {
+			e.Args = append(e.Args, p.sident())
+		}
+		return e
+	case token.LBRACE:
+		return p.parseMessage()
+	case token.MATCH:
+		return p.parseMatchExpr()
+	case token.ID, token.SPID:
+		f := p.sident()
+		if !isSident(p.tok.Kind) {
+			return f
+		}
+		e := &ast.App{Func: f}
+		for isSident(p.tok.Kind) {
+			e.Args = append(e.Args, p.sident())
+		}
+		return e
+	}
+	if isCtorName(p.tok.Kind) {
+		return p.parseConstr()
+	}
+	return p.parseLiteral()
+}
+
+func (p *parser) parseConstr() *ast.Constr {
+	e := &ast.Constr{Ident: ident(p.tok)}
+	p.next()
+	if lb := p.got(token.LBRACE); lb != nil {
+		e.LBraceToken = lb
+		for isTArgStart(p.tok.Kind) {
+			e.TypeArgs = append(e.TypeArgs, p.parseTArg())
+		}
+		e.RBraceToken = p.expect(token.RBRACE)
+	}
+	for isSident(p.tok.Kind) {
+		e.Args = append(e.Args, p.sident())
+	}
+	return e
+}
+
+// literal:
+//
+//	STRING_LIT
+//	HEX_LIT
+//	INT_TYPE NUM_LIT
+//	BNUM_TYPE NUM_LIT
+//	EMP targ targ
+func (p *parser) parseLiteral() ast.Expr {
+	switch p.tok.Kind {
+	case token.STRING_LIT:
+		e := &ast.StringLit{Token: p.tok}
+		p.next()
+		return e
+	case token.HEX_LIT:
+		e := &ast.HexLit{Token: p.tok}
+		p.next()
+		return e
+	case token.INT_TYPE:
+		t := p.tok
+		p.next()
+		return &ast.IntLit{TypeToken: t, ValueToken: p.expect(token.NUM_LIT)}
+	case token.BNUM_TYPE:
+		t := p.tok
+		p.next()
+		return &ast.BNumLit{TypeToken: t, ValueToken: p.expect(token.NUM_LIT)}
+	case token.EMP:
+		e := &ast.EmpLit{EmpToken: p.tok}
+		p.next()
+		e.Key = p.parseTArg()
+		e.Value = p.parseTArg()
+		return e
+	}
+	p.unexpected("expression")
+	return nil
+}
+
+// msg_entry:
+//
+//	(ID | SPID | CID) COLON (literal | sident)
+func (p *parser) parseMessage() *ast.Message {
+	m := &ast.Message{LBraceToken: p.expect(token.LBRACE)}
+	if r := p.got(token.RBRACE); r != nil {
+		m.RBraceToken = r
+		return m
+	}
+	for {
+		e := &ast.MessageEntry{Key: p.expect(token.ID, token.SPID, token.CID)}
+		p.expect(token.COLON)
+		if isSident(p.tok.Kind) {
+			e.Value = p.sident()
+		} else {
+			e.Value = p.parseLiteral()
+		}
+		m.Entries = append(m.Entries, e)
+		if p.got(token.SEMICOLON) == nil || p.at(token.RBRACE) {
+			break
+		}
+	}
+	m.RBraceToken = p.expect(token.RBRACE)
+	return m
+}
+
+// exp_arm:
+//
+//	BAR pattern ARROW exp
+func (p *parser) parseMatchExpr() *ast.Match {
+	e := &ast.Match{MatchToken: p.expect(token.MATCH)}
+	e.Target = p.sident()
+	p.expect(token.WITH)
+	for p.at(token.BAR) {
+		arm := &ast.MatchArm{BarToken: p.tok}
+		p.next()
+		arm.Pattern = p.parseArmPattern()
+		start := p.pos
+		if !p.try(func() { arm.Body = p.parseExpr() }) {
+			p.skipTo(start, atStmtEnd)
+			from, to := p.skipped(start)
+			arm.Body = &ast.BadExpr{From: from, To: to}
+		}
+		e.Arms = append(e.Arms, arm)
+	}
+	e.EndToken = p.closing(token.END)
+	return e
+}
+
+// pattern:
+//
+//	UNDERSCORE
+//	ID
+//	ctor_name arg_pattern*
+//
+// arg_pattern:
+//
+//	UNDERSCORE
+//	ID
+//	ctor_name
+//	LPAREN pattern RPAREN
+func (p *parser) parsePattern() ast.Pattern {
+	if isCtorName(p.tok.Kind) {
+		c := &ast.ConstrPattern{Ctor: ident(p.tok)}
+		p.next()
+		for p.at(token.UNDERSCORE, token.ID, token.LPAREN) || isCtorName(p.tok.Kind) {
+			c.Args = append(c.Args, p.parseArgPattern())
+		}
+		return c
+	}
+	return p.parseArgPattern()
+}
+
+func (p *parser) parseArgPattern() ast.Pattern {
+	switch {
+	case p.at(token.UNDERSCORE):
+		w := &ast.WildcardPattern{Token: p.tok}
+		p.next()
+		return w
+	case p.at(token.ID):
+		b := &ast.BinderPattern{Ident: ident(p.tok)}
+		p.next()
+		return b
+	case p.at(token.LPAREN):
+		lparen := p.tok
+		p.next()
+		pat := p.parsePattern()
+		pat.(parenthesized).SetParens(lparen, p.expect(token.RPAREN))
+		return pat
+	case isCtorName(p.tok.Kind):
+		c := &ast.ConstrPattern{Ctor: ident(p.tok)}
+		p.next()
+		return c
+	}
+	p.unexpected("pattern")
+	return nil
+}
+
+// parenthesized is met by type and pattern nodes which embed ast.Parens.
+type parenthesized interface {
+	SetParens(lparen, rparen *token.Token)
+}
+
+// type:
+//
+//	FORALL TID PERIOD type
+//	type_app TARROW type
+//	type_app
+//
+// type_app:
+//
+//	type_name targ*
+//	MAP targ targ
+//	targ
+func (p *parser) parseType() ast.Type {
+	if forall := p.got(token.FORALL); forall != nil {
+		t := &ast.PolyType{ForallToken: forall, TVar: p.ident(token.TID)}
+		p.expect(token.PERIOD)
+		t.Body = p.parseType()
+		return t
+	}
+
+	var t ast.Type
+	if isTypeName(p.tok.Kind) {
+		adt := &ast.ADTType{Ident: ident(p.tok)}
+		p.next()
+		for isTArgStart(p.tok.Kind) {
+			adt.Args = append(adt.Args, p.parseTArg())
+		}
+		t = adt
+	} else {
+		t = p.parseTArg()
+	}
+
+	if p.got(token.TARROW) != nil {
+		return &ast.FunType{Param: t, Ret: p.parseType()}
+	}
+	return t
+}
+
+// targ:
+//
+//	LPAREN type RPAREN
+//	type_name
+//	prim_type
+//	address_type
+//	TID
+//	MAP targ targ
+func (p *parser) parseTArg() ast.Type {
+	switch {
+	case p.at(token.LPAREN):
+		lparen := p.tok
+		p.next()
+		t := p.parseType()
+		t.(parenthesized).SetParens(lparen, p.expect(token.RPAREN))
+		return t
+	case p.at(token.TID):
+		t := &ast.TypeVar{Token: p.tok}
+		p.next()
+		return t
+	case p.at(token.MAP):
+		t := &ast.MapType{MapToken: p.tok}
+		p.next()
+		t.Key = p.parseTArg()
+		t.Value = p.parseTArg()
+		return t
+	case isTypeName(p.tok.Kind):
+		t := &ast.ADTType{Ident: ident(p.tok)}
+		p.next()
+		return t
+	case p.at(token.BYSTR_TYPE) && p.peek(1).Kind == token.WITH:
+		return p.parseAddressType()
+	case isPrimType(p.tok.Kind):
+		t := &ast.PrimType{Token: p.tok}
+		p.next()
+		return t
+	}
+	p.unexpected("type")
+	return nil
+}
+
+// address_type:
+//
+//	BYSTR_TYPE WITH END
+//	BYSTR_TYPE WITH LIBRARY END
+//	BYSTR_TYPE WITH CONTRACT (address_field (COMMA address_field)*)? END
+//
+// address_field:
+//
+//	FIELD ID COLON type
+func (p *parser) parseAddressType() *ast.AddressType {
+	t := &ast.AddressType{ByStrToken: p.tok}
+	p.next()
+	t.WithToken = p.expect(token.WITH)
+	if lib := p.got(token.LIBRARY); lib != nil {
+		t.KindToken = lib
+	} else if c := p.got(token.CONTRACT); c != nil {
+		t.KindToken = c
+		for p.at(token.FIELD) {
+			f := &ast.AddressField{FieldToken: p.tok}
+			p.next()
+			f.Ident = p.ident(token.ID)
+			p.expect(token.COLON)
+			f.Type = p.parseType()
+			t.Fields = append(t.Fields, f)
+			if p.got(token.COMMA) == nil {
+				break
+			}
+		}
+	}
+	t.EndToken = p.expect(token.END)
+	return t
+}
+
+// Parse parses the source and returns the parsed AST. When the source
+// contains errors, all of them are returned as ErrorList.
+func Parse(src *locerr.Source) (*ast.AST, error) {
+	a, errs := ParsePartial(src)
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return a, nil
+}
+
+// ParsePartial parses the source and returns a best-effort AST with every
+// lexing and syntax error. Broken parts of the source are represented by
+// ast.BadDecl, ast.BadStmt, ast.BadExpr and ast.BadPattern nodes.
+func ParsePartial(src *locerr.Source) (*ast.AST, ErrorList) {
+	var lexErrs ErrorList
+	l := NewLexer(src)
+	l.Error = func(msg string, pos locerr.Pos) {
+		lexErrs = append(lexErrs, locerr.ErrorAt(pos, msg))
+	}
+	go l.Lex()
+	a, errs := parseTokens(l.Tokens, false)
+	errs = append(lexErrs, errs...)
+	errs.sort()
+	return a, errs
+}
+
+// ParseTokens parses given tokens and returns parsed AST.
+// Tokens are passed via channel. Whitespaces, newlines and comments are skipped.
+// The channel is always drained until EOF.
+func ParseTokens(tokens chan token.Token) (*ast.AST, error) {
+	a, errs := ParseTokensPartial(tokens)
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return a, nil
+}
+
+// ParseTokensPartial is the same as ParseTokens, but returns a best-effort AST
+// with all syntax errors like ParsePartial. Illegal tokens are reported as errors.
+func ParseTokensPartial(tokens chan token.Token) (*ast.AST, ErrorList) {
+	return parseTokens(tokens, true)
+}
+
+func parseTokens(tokens chan token.Token, reportIllegal bool) (*ast.AST, ErrorList) {
+	var (
+		toks     []*token.Token
+		comments []comment
+		errs     ErrorList
+	)
+	for t := range tokens {
+		t := t
+		switch t.Kind {
+		case token.COMMENT:
+			comments = append(comments, comment{&t, len(toks)})
+			continue
+		case token.WHITESPACE, token.NEWLINE:
+			continue
+		case token.ILLEGAL:
+			if reportIllegal && t.Start.Offset < t.End.Offset {
+				errs = append(errs, locerr.ErrorIn(t.Start, t.End, fmt.Sprintf("Illegal token %s", t.DisplayValue())))
+			}
+			continue
+		}
+		toks = append(toks, &t)
+		if t.Kind == token.EOF {
+			break
+		}
+	}
+	if len(toks) == 0 {
+		return nil, ErrorList{locerr.NewError("Token stream was closed before EOF")}
+	}
+
+	p := newParser(toks[0].File, toks, comments)
+	a := p.parseModule()
+	errs = append(errs, p.errs...)
+	errs.sort()
+	return a, errs
+}
@@ -0,0 +1,530 @@
+package syntax
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/rhysd/locerr"
+	"goscilla/ast"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// validFiles returns paths of Scilla sources which can be parsed without error
+func validFiles() []string {
+	files := []string{filepath.FromSlash("../test.scilla")}
+	infos, err := ioutil.ReadDir("testdata")
+	if err != nil {
+		panic(err)
+	}
+	for _, f := range infos {
+		if strings.HasSuffix(f.Name(), ".scilla") {
+			files = append(files, filepath.Join("testdata", f.Name()))
+		}
+	}
+	return files
+}
+
+func TestParsingOK(t *testing.T) {
+	for _, n := range validFiles() {
+		t.Run(fmt.Sprintf("Check parsing successfully: %s", n), func(t *testing.T) {
+			s, err := locerr.NewSourceFromFile(n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			a, err := Parse(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if a.Contract == nil && len(a.Components) == 0 {
+				t.Fatal("No contract or component was parsed")
+			}
+
+			if a.Contract == nil {
+				return // Snippets cannot be output as scilla-checker JSON AST
+			}
+
+			// JSON AST compatible with scilla-checker can be imported back
+			var want, have bytes.Buffer
+			if err := ast.FprintScillaJSON(&want, a); err != nil {
+				t.Fatal(err)
+			}
+			imported, err := ast.ReadScillaJSON(bytes.NewReader(want.Bytes()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := ast.FprintScillaJSON(&have, imported); err != nil {
+				t.Fatal(err)
+			}
+			if have.String() != want.String() {
+				t.Fatal("AST imported from scilla-checker JSON is different from the parsed one")
+			}
+		})
+	}
+}
+
+func TestParsingOKScillaJSON(t *testing.T) {
+	// The JSON AST exported from the source must match the fixture written after Syntax.ml of scilla
+	dir := filepath.FromSlash("../ast/testdata")
+	src, err := locerr.NewSourceFromFile(filepath.Join(dir, "hello_world.scilla"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	src.Path = "hello_world.scilla" // scilla-checker puts the file path given on command line
+	a, err := Parse(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := ast.FprintScillaJSON(&buf, a); err != nil {
+		t.Fatal(err)
+	}
+	var have, want interface{}
+	if err := json.Unmarshal(buf.Bytes(), &have); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(filepath.Join(dir, "hello_world.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(b, &want); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Fatalf("Exported JSON AST is different from the fixture:\n%s", buf.String())
+	}
+}
+
+func TestParseModule(t *testing.T) {
+	s := locerr.NewDummySource(`scilla_version 0
+import BoolUtils IntUtils as I
+
+library Foo
+
+let one_msg =
+  fun (msg : Message) =>
+  let nil_msg = Nil {Message} in
+  Cons {Message} msg nil_msg
+
+type Error =
+  | NotOwner
+  | Code of Int32
+
+contract Foo(owner : ByStr20, token : ByStr20 with contract field balances : Map ByStr20 Uint128 end)
+with builtin eq owner owner =>
+
+field balances : Map ByStr20 Uint128 = Emp ByStr20 Uint128
+
+transition Transfer(to : ByStr20, amount : Uint128)
+  bal <- balances[_sender];
+  rbal <- & token.balances[to];
+  ok <- exists balances[to];
+  blk <- & BLOCKNUMBER;
+  match bal with
+  | Some b =>
+    nb = builtin sub b amount;
+    balances[_sender] := nb
+  | None =>
+    e = {_exception : "NoBalance"};
+    throw e
+  end;
+  delete balances[to];
+  accept
+end
+`)
+	a, err := Parse(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Version == nil || a.Version.Value != 0 {
+		t.Fatal("Version was not parsed")
+	}
+	if len(a.Imports) != 1 || len(a.Imports[0].Names) != 2 || a.Imports[0].Names[1].Alias == nil {
+		t.Fatal("Imports were not parsed:", a.Imports)
+	}
+	if len(a.Library.Entries) != 2 {
+		t.Fatal("2 library entries are expected but actually", len(a.Library.Entries))
+	}
+	c := a.Contract
+	if len(c.Params) != 2 || c.Constraint == nil || len(c.Fields) != 1 || len(c.Components) != 1 {
+		t.Fatal("Contract was not parsed correctly")
+	}
+	if _, ok := c.Params[1].Type.(*ast.AddressType); !ok {
+		t.Fatalf("Address type is expected but actually %s", c.Params[1].Type.Name())
+	}
+
+	expected := []string{"MapGet", "RemoteMapGet", "MapGet", "ReadFromBC", "MatchStmt", "MapDelete", "Accept"}
+	body := c.Components[0].Body
+	if len(body) != len(expected) {
+		t.Fatalf("%d statements are expected but actually %d", len(expected), len(body))
+	}
+	for i, s := range body {
+		if !strings.HasPrefix(s.Name(), expected[i]) {
+			t.Errorf("Statement %d should be %s but actually %s", i, expected[i], s.Name())
+		}
+	}
+
+	end := c.Components[0].End()
+	if end.Line != 35 || end.Column != 4 {
+		t.Errorf("Transition should end at 35:4 but actually %d:%d", end.Line, end.Column)
+	}
+	if end := a.End(); end.Offset != len(s.Code) || end.Line != 36 || end.Column != 1 {
+		t.Errorf("Module should end at 36:1 but actually %d:%d", end.Line, end.Column)
+	}
+}
+
+func TestParseParenSpans(t *testing.T) {
+	s := locerr.NewDummySource(`library L
+let f = fun (x : Map ByStr20 (Option Uint32)) => fun (g : ((Uint32 -> Bool))) =>
+  match x with
+  | (Some (Pair a ((_)))) => a
+  end
+`)
+	a, err := Parse(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var spans []string
+	ast.Apply(a, func(c *ast.Cursor) bool {
+		switch n := c.Node().(type) {
+		case ast.Type, ast.Pattern:
+			spans = append(spans, string(s.Code[n.Pos().Offset:n.End().Offset]))
+		}
+		return true
+	}, nil)
+	want := []string{
+		"Map ByStr20 (Option Uint32)",
+		"ByStr20",
+		"(Option Uint32)",
+		"Uint32",
+		"((Uint32 -> Bool))",
+		"Uint32",
+		"Bool",
+		"(Some (Pair a ((_))))",
+		"(Pair a ((_)))",
+		"a",
+		"((_))",
+	}
+	if !reflect.DeepEqual(spans, want) {
+		t.Fatalf("Wanted spans %q but got %q", want, spans)
+	}
+}
+
+func TestParseError(t *testing.T) {
+	for _, tc := range []struct {
+		code string
+		line int
+		col  int
+	}{
+		{"library Foo\nlet x = ", 2, 9},
+		{"contract Foo()\nfield x : = Uint32 0", 2, 11},
+		{"transition T()\n  x <- ;\nend", 2, 8},
+		{"transition T()\n  match x with\n  | Some => \nend", 4, 4},
+		{"library Foo let x = a < b", 1, 24},
+	} {
+		t.Run(tc.code, func(t *testing.T) {
+			_, err := Parse(locerr.NewDummySource(tc.code))
+			if err == nil {
+				t.Fatal("Error did not occur")
+			}
+			errs, ok := err.(ErrorList)
+			if !ok || len(errs) == 0 {
+				t.Fatalf("Error should be ErrorList but actually %T: %s", err, err)
+			}
+			lerr := errs[0]
+			if lerr.Start.Line != tc.line || lerr.Start.Column != tc.col {
+				t.Fatalf("Error should be at %d:%d but actually %d:%d: %s", tc.line, tc.col, lerr.Start.Line, lerr.Start.Column, err)
+			}
+		})
+	}
+}
+
+func TestParsePartial(t *testing.T) {
+	code := `library Foo
+let a = Uint32 1
+let b = builtin add (
+let c = a
+type T =
+| A of
+| B
+
+contract Foo(owner : ByStr20)
+field f : Uint32 = Uint32 0
+
+transition T1()
+  x <- f;
+  y = ;
+  accept
+end
+
+transition T2(p : )
+  f := p
+end
+
+transition T3()
+  match x with
+  | Some 1 => accept
+  | None =>
+    e = { _eventname : ; };
+    event e
+  end
+end
+
+procedure P()
+  accept
+end
+`
+	a, errs := ParsePartial(locerr.NewDummySource(code))
+	if a == nil {
+		t.Fatal("Partial AST was not returned")
+	}
+
+	lines := []int{}
+	for _, err := range errs {
+		lines = append(lines, err.Start.Line)
+	}
+	expected := []int{4, 7, 14, 18, 24, 26}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Fatalf("Errors should be at lines %v but actually %v: %s", expected, lines, errs)
+	}
+
+	entries := []string{}
+	for _, e := range a.Library.Entries {
+		entries = append(entries, e.Name())
+	}
+	if !reflect.DeepEqual(entries, []string{"LetDecl (a)", "BadDecl", "LetDecl (c)", "BadDecl"}) {
+		t.Error("Unexpected library entries:", entries)
+	}
+
+	c := a.Contract
+	if c == nil || len(c.Fields) != 1 {
+		t.Fatal("Contract was not parsed")
+	}
+	comps := []string{}
+	for _, comp := range c.Components {
+		comps = append(comps, comp.Name())
+	}
+	if !reflect.DeepEqual(comps, []string{"Transition (T1)", "Transition (T2)", "Transition (T3)", "Procedure (P)"}) {
+		t.Fatal("Unexpected components:", comps)
+	}
+
+	t1 := c.Components[0].Body
+	if len(t1) != 3 || t1[1].Name() != "BadStmt" || t1[2].Name() != "Accept" {
+		t.Error("Broken statement was not recovered in T1:", t1)
+	}
+	if ps := c.Components[1].Params; len(ps) != 0 || len(c.Components[1].Body) != 1 {
+		t.Error("Broken parameter was not recovered in T2:", ps)
+	}
+	m := c.Components[2].Body[0].(*ast.MatchStmt)
+	if _, ok := m.Arms[0].Pattern.(*ast.BadPattern); !ok {
+		t.Error("Broken pattern should be BadPattern but actually", m.Arms[0].Pattern.Name())
+	}
+	if body := m.Arms[1].Body; len(body) != 2 || body[0].Name() != "BadStmt" {
+		t.Error("Broken statement was not recovered in match arm:", body)
+	}
+	if len(c.Components[3].Body) != 1 {
+		t.Error("Procedure after broken transitions was not parsed")
+	}
+}
+
+func TestParsePartialUnclosed(t *testing.T) {
+	code := `contract Foo()
+transition T1()
+  match x with
+  | A =>
+    accept
+
+transition T2()
+  accept
+end
+`
+	a, errs := ParsePartial(locerr.NewDummySource(code))
+	if len(errs) != 1 {
+		t.Fatalf("1 error are expected but actually %d: %s", len(errs), errs)
+	}
+	comps := a.Contract.Components
+	if len(comps) != 2 || comps[1].Ident.Symbol.Name != "T2" || len(comps[1].Body) != 1 {
+		t.Fatal("Transition after unclosed transition was not parsed:", comps)
+	}
+}
+
+func TestParsePartialBrokenParams(t *testing.T) {
+	code := `contract Foo()
+procedure P(
+end
+
+transition T(x : Uint32, y :
+end
+
+transition T2()
+  accept
+end
+`
+	a, errs := ParsePartial(locerr.NewDummySource(code))
+	lines := []int{}
+	for _, err := range errs {
+		lines = append(lines, err.Start.Line)
+	}
+	if !reflect.DeepEqual(lines, []int{3, 6}) {
+		t.Fatalf("Errors should be at lines 3 and 6 but actually %v: %s", lines, errs)
+	}
+	comps := []string{}
+	for _, c := range a.Contract.Components {
+		comps = append(comps, c.Name())
+	}
+	if !reflect.DeepEqual(comps, []string{"Procedure (P)", "Transition (T)", "Transition (T2)"}) {
+		t.Fatal("Components after broken parameters were not parsed:", comps)
+	}
+	if ps := a.Contract.Components[1].Params; len(ps) != 1 || ps[0].Ident.Symbol.Name != "x" {
+		t.Error("Parameter before broken one was not parsed:", ps)
+	}
+}
+
+func TestParseComments(t *testing.T) {
+	code := `(* Library doc *)
+library Foo
+
+(* Detached comment *)
+
+(* Error codes *)
+type Error =
+| CodeNotOwner (* sender is not owner *)
+(* Insufficient
+   amount *)
+| CodeAmount of Uint128
+
+let one = Uint32 1 (* one *)
+
+contract Foo(
+  (* Owner of the contract *)
+  owner : ByStr20, (* immutable *)
+  cap : Uint128
+)
+
+(* Balances of users *)
+field balances : Map ByStr20 Uint128 = Emp ByStr20 Uint128 (* initially empty *)
+
+(* Transfer tokens *)
+transition Transfer()
+  (* Not a doc *)
+  accept
+end
+`
+	a, err := Parse(locerr.NewDummySource(code))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	typ := a.Library.Entries[0].(*ast.TypeDecl)
+	let := a.Library.Entries[1].(*ast.LetDecl)
+	c := a.Contract
+	for _, tc := range []struct {
+		what  string
+		group *ast.CommentGroup
+		want  string
+	}{
+		{"library doc", a.Library.Doc, "Library doc"},
+		{"type doc", typ.Doc, "Error codes"},
+		{"constructor comment", typ.Ctors[0].Comment, "sender is not owner"},
+		{"constructor doc", typ.Ctors[1].Doc, "Insufficient\namount"},
+		{"let comment", let.Comment, "one"},
+		{"let doc", let.Doc, ""},
+		{"contract doc", c.Doc, ""},
+		{"param doc", c.Params[0].Doc, "Owner of the contract"},
+		{"param comment", c.Params[0].Comment, "immutable"},
+		{"field doc", c.Fields[0].Doc, "Balances of users"},
+		{"field comment", c.Fields[0].Comment, "initially empty"},
+		{"transition doc", c.Components[0].Doc, "Transfer tokens"},
+	} {
+		if have := tc.group.Text(); have != tc.want {
+			t.Errorf("Unexpected %s: want %q but have %q", tc.what, tc.want, have)
+		}
+	}
+}
+
+func TestParseDocCommentsInFile(t *testing.T) {
+	src, err := locerr.NewSourceFromFile(filepath.FromSlash("testdata/basic.scilla"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := Parse(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	typ := a.Library.Entries[0].(*ast.TypeDecl)
+	want := "1. last_liquidity_cumulative_index the liquidity index. Expressed in ray\n" +
+		"8. last_variable_borrow_cumulative_index variable borrow index. Expressed in ray"
+	if have := typ.Doc.Text(); have != want {
+		t.Errorf("Unexpected doc of %s: %q", typ.Ident.Symbol.Name, have)
+	}
+}
+
+// withoutPositions returns JSON representation of the AST whose positions and file path are removed
+func withoutPositions(t *testing.T, a *ast.AST) interface{} {
+	var buf bytes.Buffer
+	if err := ast.FprintJSON(&buf, a); err != nil {
+		t.Fatal(err)
+	}
+	var v interface{}
+	if err := json.Unmarshal(buf.Bytes(), &v); err != nil {
+		t.Fatal(err)
+	}
+	var strip func(v interface{})
+	strip = func(v interface{}) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			delete(v, "start")
+			delete(v, "end")
+			for _, e := range v {
+				strip(e)
+			}
+		case []interface{}:
+			for _, e := range v {
+				strip(e)
+			}
+		}
+	}
+	strip(v)
+	delete(v.(map[string]interface{}), "file")
+	return v
+}
+
+func TestFormatRoundTrip(t *testing.T) {
+	for _, n := range validFiles() {
+		t.Run(n, func(t *testing.T) {
+			s, err := locerr.NewSourceFromFile(n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := Parse(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var buf bytes.Buffer
+			if err := ast.Format(&buf, want); err != nil {
+				t.Fatal(err)
+			}
+			formatted := buf.String()
+			have, err := Parse(locerr.NewDummySource(formatted))
+			if err != nil {
+				t.Fatal(err, "\n", formatted)
+			}
+			if !reflect.DeepEqual(withoutPositions(t, have), withoutPositions(t, want)) {
+				t.Fatal("Formatted source is parsed into different AST:\n", formatted)
+			}
+
+			// Formatting is idempotent
+			buf.Reset()
+			if err := ast.Format(&buf, have); err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != formatted {
+				t.Fatal("Formatting formatted source changed it:\n", buf.String())
+			}
+		})
+	}
+}
@@ -210,7 +210,7 @@ var SeparatorTable = [...]string{
 // BuiltinADTTable from https://github.com/Zilliqa/scilla/blob/master/src/base/Datatypes.ml
 var BuiltinADTTable = [...]string{
 	BOOL:  "Bool",
-	TRUE:  "Ture",
+	TRUE:  "True",
 	FALSE: "False",
 
 	NAT:  "Nat",
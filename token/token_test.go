@@ -8,13 +8,13 @@ import (
 func TestTokenString(t *testing.T) {
 	s := locerr.NewDummySource("abcd")
 	tok := Token{
-		Kind:  IDENT,
-		Start: locerr.Pos{1, 1, 2, s},
-		End:   locerr.Pos{3, 1, 4, s},
+		Kind:  ID,
+		Start: locerr.Pos{Offset: 1, Line: 1, Column: 2, File: s},
+		End:   locerr.Pos{Offset: 3, Line: 1, Column: 4, File: s},
 		File:  s,
 	}
 	actual := tok.String()
-	expected := "<IDENT:bc>(1:2:1-1:4:3)"
+	expected := `<ID:"bc">(1:2:1-1:4:3)`
 	if actual != expected {
 		t.Fatalf("Expected '%s' but actually '%s'", expected, actual)
 	}
@@ -0,0 +1,138 @@
+package types
+
+// ADTDef is a definition of algebraic data type. Builtin ADTs (Bool, Nat, Option, List, Pair) are
+// predefined in this package. User-defined ADTs come from `type` declarations in libraries and
+// never have type parameters.
+type ADTDef struct {
+	Name    string
+	TParams []string // Type parameters like 'A
+	Ctors   []*CtorDef
+}
+
+// CtorDef is a definition of constructor of ADT.
+type CtorDef struct {
+	Name string
+	Args []Type // May contain type parameters of the ADT
+}
+
+// Builtin ADT definitions from Datatypes.ml of Zilliqa/scilla
+var (
+	BoolDef = &ADTDef{"Bool", nil, []*CtorDef{
+		{"True", nil},
+		{"False", nil},
+	}}
+	NatDef = &ADTDef{"Nat", nil, []*CtorDef{
+		{"Zero", nil},
+		{"Succ", []Type{&ADT{"Nat", nil}}},
+	}}
+	OptionDef = &ADTDef{"Option", []string{"'A"}, []*CtorDef{
+		{"Some", []Type{&TypeVar{"'A"}}},
+		{"None", nil},
+	}}
+	ListDef = &ADTDef{"List", []string{"'A"}, []*CtorDef{
+		{"Cons", []Type{&TypeVar{"'A"}, &ADT{"List", []Type{&TypeVar{"'A"}}}}},
+		{"Nil", nil},
+	}}
+	PairDef = &ADTDef{"Pair", []string{"'A", "'B"}, []*CtorDef{
+		{"Pair", []Type{&TypeVar{"'A"}, &TypeVar{"'B"}}},
+	}}
+)
+
+// BuiltinADTs is a list of definitions of builtin ADTs.
+var BuiltinADTs = []*ADTDef{BoolDef, NatDef, OptionDef, ListDef, PairDef}
+
+// Instances of builtin ADTs which have no type parameter
+var (
+	Bool = &ADT{"Bool", nil}
+	Nat  = &ADT{"Nat", nil}
+)
+
+// Option returns the type Option t.
+func Option(t Type) *ADT {
+	return &ADT{"Option", []Type{t}}
+}
+
+// List returns the type List t.
+func List(t Type) *ADT {
+	return &ADT{"List", []Type{t}}
+}
+
+// Pair returns the type Pair a b.
+func Pair(a, b Type) *ADT {
+	return &ADT{"Pair", []Type{a, b}}
+}
+
+// Type returns the ADT type with its type parameters as arguments, like List 'A.
+func (d *ADTDef) Type() *ADT {
+	if len(d.TParams) == 0 {
+		return &ADT{d.Name, nil}
+	}
+	args := make([]Type, 0, len(d.TParams))
+	for _, p := range d.TParams {
+		args = append(args, &TypeVar{p})
+	}
+	return &ADT{d.Name, args}
+}
+
+// Ctor returns the constructor definition of the name. It returns nil when the ADT does not have
+// the constructor.
+func (d *ADTDef) Ctor(name string) *CtorDef {
+	for _, c := range d.Ctors {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// CtorArgs returns the argument types of the constructor for the instance of the ADT. The number of
+// type arguments must be the same as the number of type parameters. For example, argument types of
+// Cons for List Int32 are Int32 and List Int32.
+func (d *ADTDef) CtorArgs(c *CtorDef, targs []Type) []Type {
+	if len(targs) != len(d.TParams) {
+		panic("CtorArgs: number of type arguments mismatch for " + d.Name)
+	}
+	s := make(map[string]Type, len(targs))
+	for i, p := range d.TParams {
+		s[p] = targs[i]
+	}
+	args := make([]Type, 0, len(c.Args))
+	for _, a := range c.Args {
+		args = append(args, Subst(a, s))
+	}
+	return args
+}
+
+// CtorType returns the type of the constructor as a function. For example, type of Cons is
+// forall 'A. 'A -> List ('A) -> List ('A). It returns nil when the ADT does not have the
+// constructor.
+func (d *ADTDef) CtorType(name string) Type {
+	c := d.Ctor(name)
+	if c == nil {
+		return nil
+	}
+	ts := append(append([]Type{}, c.Args...), d.Type())
+	return Forall(Fun(ts...), d.TParams...)
+}
+
+// BuiltinADT returns the definition of builtin ADT of the name. It returns nil when no builtin ADT
+// has the name.
+func BuiltinADT(name string) *ADTDef {
+	for _, d := range BuiltinADTs {
+		if d.Name == name {
+			return d
+		}
+	}
+	return nil
+}
+
+// BuiltinCtor returns the builtin ADT definition which has the constructor of the name. It returns
+// nil when no builtin ADT has the constructor.
+func BuiltinCtor(name string) (*ADTDef, *CtorDef) {
+	for _, d := range BuiltinADTs {
+		if c := d.Ctor(name); c != nil {
+			return d, c
+		}
+	}
+	return nil, nil
+}
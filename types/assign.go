@@ -0,0 +1,77 @@
+package types
+
+// Assignable returns whether a value of type have can be used where type want is expected. In
+// addition to equality, it implements subtyping of address types:
+//
+//	ByStr20 with contract field f : T, g : U end  <:  ByStr20 with contract field f : T end
+//	ByStr20 with contract ... end                 <:  ByStr20 with end
+//	ByStr20 with library end                      <:  ByStr20 with end
+//	ByStr20 with ... end                          <:  ByStr20
+//
+// Compound types are covariant in their components except for parameters of functions, which are
+// contravariant. Polymorphic types must be equal.
+func Assignable(want, have Type) bool {
+	switch w := want.(type) {
+	case *ByStrNType:
+		if _, ok := have.(*AddressType); ok {
+			return w.Size == 20
+		}
+	case *AddressType:
+		h, ok := have.(*AddressType)
+		if !ok {
+			return false
+		}
+		switch w.Kind {
+		case AnyAddr:
+			return true
+		case LibAddr:
+			return h.Kind == LibAddr
+		}
+		if h.Kind != ContrAddr {
+			return false
+		}
+		for _, f := range w.Fields {
+			ht := h.Field(f.Name)
+			if ht == nil || !Assignable(f.Type, ht) {
+				return false
+			}
+		}
+		return true
+	case *MapType:
+		h, ok := have.(*MapType)
+		return ok && Assignable(w.Key, h.Key) && Assignable(w.Value, h.Value)
+	case *FunType:
+		h, ok := have.(*FunType)
+		return ok && Assignable(h.Param, w.Param) && Assignable(w.Ret, h.Ret)
+	case *ADT:
+		h, ok := have.(*ADT)
+		if !ok || w.Name != h.Name || len(w.Args) != len(h.Args) {
+			return false
+		}
+		for i := range w.Args {
+			if !Assignable(w.Args[i], h.Args[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return Equal(want, have)
+}
+
+// AddressFields are fields which every address has regardless of its kind. They can be read
+// remotely via any address type.
+var AddressFields = []*AddressField{
+	{"_balance", Uint128},
+	{"_nonce", Uint64},
+}
+
+// RemoteField returns the type of the field which can be read remotely via the address type,
+// including implicit fields in AddressFields. It returns nil when the field cannot be read.
+func (t *AddressType) RemoteField(name string) Type {
+	for _, f := range AddressFields {
+		if f.Name == name {
+			return f.Type
+		}
+	}
+	return t.Field(name)
+}
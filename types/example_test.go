@@ -0,0 +1,22 @@
+package types
+
+import (
+	"fmt"
+)
+
+func Example() {
+	// forall 'A. forall 'B. ('A -> 'B) -> List 'A -> List 'B
+	a, b := Var("'A"), Var("'B")
+	listMap := Forall(Fun(Fun(a, b), List(a), List(b)), "'A", "'B")
+	fmt.Println(listMap)
+
+	// Instantiate the type variables like @list_map Uint32 Bool
+	t := listMap.(*PolyType).Instantiate(Uint32).(*PolyType).Instantiate(Bool)
+	fmt.Println(t)
+	fmt.Println(FreeTypeVars(t), Equal(t, Fun(Fun(Uint32, Bool), List(Uint32), List(Bool))))
+
+	// Output:
+	// forall 'A. forall 'B. ('A -> 'B) -> List ('A) -> List ('B)
+	// (Uint32 -> Bool) -> List (Uint32) -> List (Bool)
+	// [] true
+}
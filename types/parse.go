@@ -0,0 +1,260 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Parse parses the type written as a string such as "Map ByStr20 (List Uint128)". It accepts the
+// types in the format of String() and types in JSON files like init.json of Scilla. ADTs other than
+// builtin ones are looked up in adts by their names. A name qualified with an address or a library
+// like "0x1234.MyType" is looked up by the last component.
+func Parse(s string, adts map[string]*ADTDef) (Type, error) {
+	p := &typeParser{src: s, toks: tokenizeType(s), adts: adts}
+	t, err := p.parse()
+	if err != nil {
+		return nil, fmt.Errorf("Invalid type %q: %s", s, err)
+	}
+	return t, nil
+}
+
+// tokenizeType splits the type string into words and punctuations.
+func tokenizeType(s string) []string {
+	var toks []string
+	for i := 0; i < len(s); {
+		c := rune(s[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case strings.HasPrefix(s[i:], "->"):
+			toks = append(toks, "->")
+			i += 2
+		case strings.ContainsRune("(),:.", c):
+			toks = append(toks, string(c))
+			i++
+		default:
+			j := i
+			for j < len(s) && isTypeNameChar(s, j) {
+				j++
+			}
+			if j == i {
+				j++ // Unknown character is a token by itself and rejected by parser
+			}
+			toks = append(toks, s[i:j])
+			i = j
+		}
+	}
+	return toks
+}
+
+func isTypeNameChar(s string, i int) bool {
+	c := rune(s[i])
+	if c == '.' {
+		// Qualifier like 0x1234.MyType or Lib.MyType. `forall 'A.` is followed by a space
+		return i+1 < len(s) && (unicode.IsLetter(rune(s[i+1])) || unicode.IsDigit(rune(s[i+1])))
+	}
+	return c == '_' || c == '\'' || unicode.IsLetter(c) || unicode.IsDigit(c)
+}
+
+type typeParser struct {
+	src  string
+	toks []string
+	pos  int
+	adts map[string]*ADTDef
+}
+
+func (p *typeParser) peek() string {
+	if p.pos < len(p.toks) {
+		return p.toks[p.pos]
+	}
+	return ""
+}
+
+func (p *typeParser) next() string {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+func (p *typeParser) expect(want string) error {
+	if t := p.next(); t != want {
+		return p.unexpected(t, want)
+	}
+	return nil
+}
+
+func (p *typeParser) unexpected(t, want string) error {
+	if t == "" {
+		return fmt.Errorf("expected %s but reached end of type", want)
+	}
+	return fmt.Errorf("expected %s but got %q", want, t)
+}
+
+func (p *typeParser) parse() (Type, error) {
+	t, err := p.typ()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.toks) {
+		return nil, p.unexpected(p.peek(), "end of type")
+	}
+	return t, nil
+}
+
+// typ parses `forall 'A. T`, `T1 -> T2` or application of ADT.
+func (p *typeParser) typ() (Type, error) {
+	if p.peek() == "forall" {
+		p.next()
+		tv := p.next()
+		if !strings.HasPrefix(tv, "'") {
+			return nil, p.unexpected(tv, "type variable")
+		}
+		if err := p.expect("."); err != nil {
+			return nil, err
+		}
+		body, err := p.typ()
+		if err != nil {
+			return nil, err
+		}
+		return &PolyType{tv, body}, nil
+	}
+
+	t, err := p.app()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek() != "->" {
+		return t, nil
+	}
+	p.next()
+	ret, err := p.typ()
+	if err != nil {
+		return nil, err
+	}
+	return &FunType{t, ret}, nil
+}
+
+// app parses an ADT with type arguments or a type argument.
+func (p *typeParser) app() (Type, error) {
+	name := p.peek()
+	if name == "" || !isADTName(name) || Prim(name) != nil || name == "Map" {
+		return p.targ()
+	}
+	p.next()
+	var args []Type
+	for p.atTArg() {
+		a, err := p.targ()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, a)
+	}
+	return p.adt(name, args)
+}
+
+func (p *typeParser) atTArg() bool {
+	switch t := p.peek(); t {
+	case "", ")", ",", "->", "end", "with", "field":
+		return false
+	default:
+		return t != ":" && t != "."
+	}
+}
+
+func isADTName(name string) bool {
+	c := rune(name[strings.LastIndexByte(name, '.')+1])
+	return unicode.IsUpper(c)
+}
+
+func (p *typeParser) adt(name string, args []Type) (Type, error) {
+	short := name[strings.LastIndexByte(name, '.')+1:]
+	def, ok := p.adts[short]
+	if !ok {
+		def = BuiltinADT(short)
+	}
+	if def == nil {
+		return nil, fmt.Errorf("type %s is not defined", name)
+	}
+	if len(args) != len(def.TParams) {
+		return nil, fmt.Errorf("type %s takes %d type arguments but %d given", name, len(def.TParams), len(args))
+	}
+	return &ADT{def.Name, args}, nil
+}
+
+// targ parses a type which can be an argument of ADT or Map without parentheses.
+func (p *typeParser) targ() (Type, error) {
+	t := p.next()
+	switch {
+	case t == "(":
+		ty, err := p.typ()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return ty, nil
+	case t == "Map":
+		k, err := p.targ()
+		if err != nil {
+			return nil, err
+		}
+		v, err := p.targ()
+		if err != nil {
+			return nil, err
+		}
+		return &MapType{k, v}, nil
+	case t == "ByStr20" && p.peek() == "with":
+		return p.address()
+	case strings.HasPrefix(t, "'"):
+		return &TypeVar{t}, nil
+	case t != "" && Prim(t) != nil:
+		return Prim(t), nil
+	case t != "" && isADTName(t):
+		return p.adt(t, nil)
+	}
+	return nil, p.unexpected(t, "type")
+}
+
+// address parses the rest of `ByStr20 with ... end`.
+func (p *typeParser) address() (Type, error) {
+	p.next() // with
+	switch p.next() {
+	case "end":
+		return &AddressType{Kind: AnyAddr}, nil
+	case "library":
+		if err := p.expect("end"); err != nil {
+			return nil, err
+		}
+		return &AddressType{Kind: LibAddr}, nil
+	case "contract":
+	default:
+		p.pos--
+		return nil, p.unexpected(p.peek(), "end, library or contract")
+	}
+	var fields []*AddressField
+	for p.peek() == "field" {
+		p.next()
+		name := p.next()
+		if name == "" || !unicode.IsLetter(rune(name[0])) && name[0] != '_' {
+			return nil, p.unexpected(name, "field name")
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		t, err := p.typ()
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, &AddressField{name, t})
+		if p.peek() != "," {
+			break
+		}
+		p.next()
+	}
+	if err := p.expect("end"); err != nil {
+		return nil, err
+	}
+	return ContractAddress(fields...), nil
+}
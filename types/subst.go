@@ -0,0 +1,210 @@
+package types
+
+import (
+	"sort"
+	"strconv"
+)
+
+// Equal returns whether the two types are the same. Polymorphic types are compared modulo renaming
+// of bound type variables (e.g. forall 'A. 'A equals to forall 'B. 'B) and fields of contract
+// address types are compared regardless of their order.
+func Equal(a, b Type) bool {
+	return equal(a, b, nil, nil)
+}
+
+// lastIndex returns the index of the innermost binder of the type variable, or -1 when it is free
+func lastIndex(bound []string, name string) int {
+	for i := len(bound) - 1; i >= 0; i-- {
+		if bound[i] == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// equal compares types with stacks of bound type variables. Binders of a and b are pushed at the
+// same time, so bound type variables are equal when their binders are at the same depth.
+func equal(a, b Type, boundA, boundB []string) bool {
+	switch a := a.(type) {
+	case *IntType:
+		b, ok := b.(*IntType)
+		return ok && a.Signed == b.Signed && a.Bits == b.Bits
+	case *ByStrNType:
+		b, ok := b.(*ByStrNType)
+		return ok && a.Size == b.Size
+	case *StringType, *BNumType, *ByStrType, *MessageType, *EventType, *ExceptionType:
+		return a == b || a.String() == b.String()
+	case *MapType:
+		b, ok := b.(*MapType)
+		return ok && equal(a.Key, b.Key, boundA, boundB) && equal(a.Value, b.Value, boundA, boundB)
+	case *FunType:
+		b, ok := b.(*FunType)
+		return ok && equal(a.Param, b.Param, boundA, boundB) && equal(a.Ret, b.Ret, boundA, boundB)
+	case *PolyType:
+		b, ok := b.(*PolyType)
+		return ok && equal(a.Body, b.Body, append(boundA, a.TVar), append(boundB, b.TVar))
+	case *TypeVar:
+		b, ok := b.(*TypeVar)
+		if !ok {
+			return false
+		}
+		i, j := lastIndex(boundA, a.Name), lastIndex(boundB, b.Name)
+		if i >= 0 || j >= 0 {
+			return i == j
+		}
+		return a.Name == b.Name
+	case *ADT:
+		b, ok := b.(*ADT)
+		if !ok || a.Name != b.Name || len(a.Args) != len(b.Args) {
+			return false
+		}
+		for i := range a.Args {
+			if !equal(a.Args[i], b.Args[i], boundA, boundB) {
+				return false
+			}
+		}
+		return true
+	case *AddressType:
+		b, ok := b.(*AddressType)
+		if !ok || a.Kind != b.Kind || len(a.Fields) != len(b.Fields) {
+			return false
+		}
+		for _, f := range a.Fields {
+			t := b.Field(f.Name)
+			if t == nil || !equal(f.Type, t, boundA, boundB) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// FreeTypeVars returns the names of type variables which are not bound by forall in the type. The
+// names are sorted and unique.
+func FreeTypeVars(t Type) []string {
+	seen := map[string]struct{}{}
+	freeTypeVars(t, nil, seen)
+	vs := make([]string, 0, len(seen))
+	for v := range seen {
+		vs = append(vs, v)
+	}
+	sort.Strings(vs)
+	return vs
+}
+
+func freeTypeVars(t Type, bound []string, seen map[string]struct{}) {
+	switch t := t.(type) {
+	case *MapType:
+		freeTypeVars(t.Key, bound, seen)
+		freeTypeVars(t.Value, bound, seen)
+	case *FunType:
+		freeTypeVars(t.Param, bound, seen)
+		freeTypeVars(t.Ret, bound, seen)
+	case *PolyType:
+		freeTypeVars(t.Body, append(bound, t.TVar), seen)
+	case *TypeVar:
+		if lastIndex(bound, t.Name) < 0 {
+			seen[t.Name] = struct{}{}
+		}
+	case *ADT:
+		for _, a := range t.Args {
+			freeTypeVars(a, bound, seen)
+		}
+	case *AddressType:
+		for _, f := range t.Fields {
+			freeTypeVars(f.Type, bound, seen)
+		}
+	}
+}
+
+// IsGround returns whether the type has no free type variable.
+func IsGround(t Type) bool {
+	return len(FreeTypeVars(t)) == 0
+}
+
+// Subst replaces free type variables in the type with the types mapped from their names. Bound type
+// variables are renamed when they would capture free type variables of the substituted types.
+func Subst(t Type, s map[string]Type) Type {
+	if len(s) == 0 {
+		return t
+	}
+	return subst(t, s)
+}
+
+func subst(t Type, s map[string]Type) Type {
+	switch t := t.(type) {
+	case *MapType:
+		return &MapType{subst(t.Key, s), subst(t.Value, s)}
+	case *FunType:
+		return &FunType{subst(t.Param, s), subst(t.Ret, s)}
+	case *PolyType:
+		return substPoly(t, s)
+	case *TypeVar:
+		if r, ok := s[t.Name]; ok {
+			return r
+		}
+		return t
+	case *ADT:
+		if len(t.Args) == 0 {
+			return t
+		}
+		args := make([]Type, 0, len(t.Args))
+		for _, a := range t.Args {
+			args = append(args, subst(a, s))
+		}
+		return &ADT{t.Name, args}
+	case *AddressType:
+		if len(t.Fields) == 0 {
+			return t
+		}
+		fs := make([]*AddressField, 0, len(t.Fields))
+		for _, f := range t.Fields {
+			fs = append(fs, &AddressField{f.Name, subst(f.Type, s)})
+		}
+		return &AddressType{t.Kind, fs}
+	}
+	return t // Primitive types
+}
+
+func substPoly(t *PolyType, s map[string]Type) Type {
+	// Only substitutions of type variables which are free in the body are effective
+	free := FreeTypeVars(t)
+	inner := make(map[string]Type, len(free))
+	for _, v := range free {
+		if r, ok := s[v]; ok {
+			inner[v] = r
+		}
+	}
+	if len(inner) == 0 {
+		return t
+	}
+
+	// Rename the bound type variable when it captures free type variables of substituted types
+	avoid := map[string]struct{}{}
+	captured := false
+	for _, v := range free {
+		avoid[v] = struct{}{}
+	}
+	for _, r := range inner {
+		for _, v := range FreeTypeVars(r) {
+			avoid[v] = struct{}{}
+			if v == t.TVar {
+				captured = true
+			}
+		}
+	}
+	if !captured {
+		return &PolyType{t.TVar, subst(t.Body, inner)}
+	}
+
+	var fresh string
+	for i := 1; ; i++ {
+		fresh = t.TVar + strconv.Itoa(i)
+		if _, ok := avoid[fresh]; !ok {
+			break
+		}
+	}
+	inner[t.TVar] = &TypeVar{fresh}
+	return &PolyType{fresh, subst(t.Body, inner)}
+}
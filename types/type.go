@@ -0,0 +1,310 @@
+// Package types provides representation of Scilla types.
+package types
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Type is a type of Scilla (see Type.ml of Zilliqa/scilla).
+// Types are immutable. Operations which modify a type such as Subst() return a new type.
+type Type interface {
+	// String returns the type in the canonical format which scilla-checker uses in its outputs,
+	// such as "Map (ByStr20) (Uint128)" and "forall 'A. List ('A) -> Uint32".
+	String() string
+	typ()
+}
+
+// Primitive types
+type (
+	// Int32, Int64, Int128, Int256, Uint32, Uint64, Uint128, Uint256
+	IntType struct {
+		Signed bool
+		Bits   int
+	}
+
+	// String
+	StringType struct{}
+
+	// BNum
+	BNumType struct{}
+
+	// ByStr (byte string of arbitrary length)
+	ByStrType struct{}
+
+	// ByStr20, ByStr32, ...
+	ByStrNType struct {
+		Size int
+	}
+
+	// Message
+	MessageType struct{}
+
+	// Event
+	EventType struct{}
+
+	// Exception
+	ExceptionType struct{}
+)
+
+// Compound types
+type (
+	// Map K V
+	MapType struct {
+		Key   Type
+		Value Type
+	}
+
+	// T1 -> T2
+	FunType struct {
+		Param Type
+		Ret   Type
+	}
+
+	// forall 'A. T
+	PolyType struct {
+		TVar string // Including the leading quote like 'A
+		Body Type
+	}
+
+	// 'A
+	TypeVar struct {
+		Name string // Including the leading quote like 'A
+	}
+
+	// Bool, Option Uint32, List 'A, MyType. Both builtin and user-defined ADTs are represented
+	// by their names. Definitions of them are described by ADTDef.
+	ADT struct {
+		Name string
+		Args []Type
+	}
+
+	// ByStr20 with end, ByStr20 with library end, ByStr20 with contract field f : T end
+	AddressType struct {
+		Kind   AddressKind
+		Fields []*AddressField // Only for ContractAddr
+	}
+
+	// field f : T in address type
+	AddressField struct {
+		Name string
+		Type Type
+	}
+)
+
+// AddressKind is a kind of address type.
+type AddressKind int
+
+const (
+	// AnyAddr is an address of any account: ByStr20 with end
+	AnyAddr AddressKind = iota
+	// LibAddr is an address of library: ByStr20 with library end
+	LibAddr
+	// ContrAddr is an address of contract which has the fields: ByStr20 with contract ... end
+	ContrAddr
+)
+
+// Instances of primitive types
+var (
+	Int32     = &IntType{true, 32}
+	Int64     = &IntType{true, 64}
+	Int128    = &IntType{true, 128}
+	Int256    = &IntType{true, 256}
+	Uint32    = &IntType{false, 32}
+	Uint64    = &IntType{false, 64}
+	Uint128   = &IntType{false, 128}
+	Uint256   = &IntType{false, 256}
+	String    = &StringType{}
+	BNum      = &BNumType{}
+	ByStr     = &ByStrType{}
+	ByStr20   = &ByStrNType{20}
+	ByStr32   = &ByStrNType{32}
+	Message   = &MessageType{}
+	Event     = &EventType{}
+	Exception = &ExceptionType{}
+)
+
+// IntBits is a list of bit widths of integer types.
+var IntBits = []int{32, 64, 128, 256}
+
+// ByStrN returns the type of byte string of n bytes such as ByStr20.
+func ByStrN(n int) *ByStrNType {
+	switch n {
+	case 20:
+		return ByStr20
+	case 32:
+		return ByStr32
+	}
+	return &ByStrNType{n}
+}
+
+// Prim returns the primitive type of the name such as "Uint128" or "ByStr20". It returns nil when
+// the name is not a primitive type.
+func Prim(name string) Type {
+	switch name {
+	case "String":
+		return String
+	case "BNum":
+		return BNum
+	case "ByStr":
+		return ByStr
+	case "Message":
+		return Message
+	case "Event":
+		return Event
+	case "Exception":
+		return Exception
+	}
+
+	if s := strings.TrimPrefix(name, "ByStr"); s != name {
+		// Reject leading zeros and signs which strconv.Atoi accepts
+		if s[0] < '1' || s[0] > '9' {
+			return nil
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return nil
+		}
+		return ByStrN(n)
+	}
+
+	signed := true
+	s := strings.TrimPrefix(name, "Int")
+	if s == name {
+		signed = false
+		s = strings.TrimPrefix(name, "Uint")
+		if s == name {
+			return nil
+		}
+	}
+	for _, b := range IntBits {
+		if s == strconv.Itoa(b) {
+			return &IntType{signed, b}
+		}
+	}
+	return nil
+}
+
+// Fun returns the function type which takes the parameters in order. The last type is the return
+// type. For example, Fun(A, B, C) is A -> B -> C.
+func Fun(ts ...Type) Type {
+	if len(ts) == 0 {
+		panic("Fun: return type is missing")
+	}
+	ret := ts[len(ts)-1]
+	for i := len(ts) - 2; i >= 0; i-- {
+		ret = &FunType{ts[i], ret}
+	}
+	return ret
+}
+
+// Forall returns the polymorphic type which binds the type variables in order. For example,
+// Forall(T, "'A", "'B") is forall 'A. forall 'B. T.
+func Forall(body Type, tvars ...string) Type {
+	for i := len(tvars) - 1; i >= 0; i-- {
+		body = &PolyType{tvars[i], body}
+	}
+	return body
+}
+
+// Var returns the type variable of the name such as "'A".
+func Var(name string) *TypeVar {
+	return &TypeVar{name}
+}
+
+// ContractAddress returns the address type of contract which has the fields. Fields are sorted by
+// their names since the order of fields is not significant.
+func ContractAddress(fields ...*AddressField) *AddressType {
+	fs := make([]*AddressField, len(fields))
+	copy(fs, fields)
+	sort.SliceStable(fs, func(i, j int) bool { return fs[i].Name < fs[j].Name })
+	return &AddressType{ContrAddr, fs}
+}
+
+// Field returns the type of the field of contract address type. It returns nil when the field is
+// not found.
+func (t *AddressType) Field(name string) Type {
+	for _, f := range t.Fields {
+		if f.Name == name {
+			return f.Type
+		}
+	}
+	return nil
+}
+
+// Instantiate applies the type argument to the polymorphic type. For example, instantiating
+// forall 'A. List 'A -> 'A with Int32 results in List Int32 -> Int32.
+func (t *PolyType) Instantiate(arg Type) Type {
+	return Subst(t.Body, map[string]Type{t.TVar: arg})
+}
+
+func (t *IntType) String() string {
+	if t.Signed {
+		return fmt.Sprintf("Int%d", t.Bits)
+	}
+	return fmt.Sprintf("Uint%d", t.Bits)
+}
+func (t *StringType) String() string    { return "String" }
+func (t *BNumType) String() string      { return "BNum" }
+func (t *ByStrType) String() string     { return "ByStr" }
+func (t *ByStrNType) String() string    { return fmt.Sprintf("ByStr%d", t.Size) }
+func (t *MessageType) String() string   { return "Message" }
+func (t *EventType) String() string     { return "Event" }
+func (t *ExceptionType) String() string { return "Exception" }
+func (t *MapType) String() string {
+	return fmt.Sprintf("Map (%s) (%s)", t.Key.String(), t.Value.String())
+}
+func (t *FunType) String() string {
+	param := t.Param.String()
+	switch t.Param.(type) {
+	case *FunType, *PolyType:
+		param = "(" + param + ")"
+	}
+	return fmt.Sprintf("%s -> %s", param, t.Ret.String())
+}
+func (t *PolyType) String() string { return fmt.Sprintf("forall %s. %s", t.TVar, t.Body.String()) }
+func (t *TypeVar) String() string  { return t.Name }
+func (t *ADT) String() string {
+	var b strings.Builder
+	b.WriteString(t.Name)
+	for _, a := range t.Args {
+		b.WriteString(" (")
+		b.WriteString(a.String())
+		b.WriteByte(')')
+	}
+	return b.String()
+}
+func (t *AddressType) String() string {
+	switch t.Kind {
+	case AnyAddr:
+		return "ByStr20 with end"
+	case LibAddr:
+		return "ByStr20 with library end"
+	}
+	if len(t.Fields) == 0 {
+		return "ByStr20 with contract end"
+	}
+	fs := make([]string, 0, len(t.Fields))
+	for _, f := range t.Fields {
+		fs = append(fs, fmt.Sprintf("field %s : %s", f.Name, f.Type.String()))
+	}
+	sort.Strings(fs)
+	return fmt.Sprintf("ByStr20 with contract %s end", strings.Join(fs, ", "))
+}
+
+func (*IntType) typ()       {}
+func (*StringType) typ()    {}
+func (*BNumType) typ()      {}
+func (*ByStrType) typ()     {}
+func (*ByStrNType) typ()    {}
+func (*MessageType) typ()   {}
+func (*EventType) typ()     {}
+func (*ExceptionType) typ() {}
+func (*MapType) typ()       {}
+func (*FunType) typ()       {}
+func (*PolyType) typ()      {}
+func (*TypeVar) typ()       {}
+func (*ADT) typ()           {}
+func (*AddressType) typ()   {}
@@ -0,0 +1,321 @@
+package types
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTypeString(t *testing.T) {
+	a, b := Var("'A"), Var("'B")
+	for _, tc := range []struct {
+		what string
+		typ  Type
+		want string
+	}{
+		{"int", Int32, "Int32"},
+		{"uint", Uint256, "Uint256"},
+		{"string", String, "String"},
+		{"bnum", BNum, "BNum"},
+		{"bystr", ByStr, "ByStr"},
+		{"bystrn", ByStrN(33), "ByStr33"},
+		{"message", Message, "Message"},
+		{"event", Event, "Event"},
+		{"exception", Exception, "Exception"},
+		{"map", &MapType{ByStr20, &MapType{ByStr20, Uint128}}, "Map (ByStr20) (Map (ByStr20) (Uint128))"},
+		{"fun", Fun(Uint32, Uint32, Bool), "Uint32 -> Uint32 -> Bool"},
+		{"higher order fun", Fun(Fun(a, b), List(a), List(b)), "('A -> 'B) -> List ('A) -> List ('B)"},
+		{"poly param", Fun(Forall(a, "'A"), Int32), "(forall 'A. 'A) -> Int32"},
+		{"poly", Forall(Fun(a, b, Pair(a, b)), "'A", "'B"), "forall 'A. forall 'B. 'A -> 'B -> Pair ('A) ('B)"},
+		{"nested adt", Option(List(Nat)), "Option (List (Nat))"},
+		{"user adt", &ADT{"Error", nil}, "Error"},
+		{"any address", &AddressType{Kind: AnyAddr}, "ByStr20 with end"},
+		{"library address", &AddressType{Kind: LibAddr}, "ByStr20 with library end"},
+		{"empty contract address", ContractAddress(), "ByStr20 with contract end"},
+		{
+			"contract address",
+			ContractAddress(&AddressField{"owner", ByStr20}, &AddressField{"balances", &MapType{ByStr20, Uint128}}),
+			"ByStr20 with contract field balances : Map (ByStr20) (Uint128), field owner : ByStr20 end",
+		},
+	} {
+		t.Run(tc.what, func(t *testing.T) {
+			if have := tc.typ.String(); have != tc.want {
+				t.Fatalf("Wanted %q but got %q", tc.want, have)
+			}
+		})
+	}
+}
+
+func TestPrim(t *testing.T) {
+	for name, want := range map[string]Type{
+		"Int32":     Int32,
+		"Int256":    Int256,
+		"Uint64":    Uint64,
+		"String":    String,
+		"BNum":      BNum,
+		"ByStr":     ByStr,
+		"ByStr20":   ByStr20,
+		"ByStr1":    ByStrN(1),
+		"Message":   Message,
+		"Event":     Event,
+		"Exception": Exception,
+		"Int16":     nil,
+		"Uint":      nil,
+		"ByStr0":    nil,
+		"ByStr020":  nil,
+		"ByStr+1":   nil,
+		"Bool":      nil,
+		"Map":       nil,
+	} {
+		have := Prim(name)
+		if want == nil {
+			if have != nil {
+				t.Errorf("%q should not be a primitive type but got %s", name, have)
+			}
+			continue
+		}
+		if have == nil || !Equal(have, want) {
+			t.Errorf("Wanted %s for %q but got %v", want, name, have)
+		}
+	}
+}
+
+func TestEqual(t *testing.T) {
+	a, b, c := Var("'A"), Var("'B"), Var("'C")
+	for _, tc := range []struct {
+		what string
+		x, y Type
+		want bool
+	}{
+		{"same prim", Uint32, &IntType{false, 32}, true},
+		{"different sign", Int32, Uint32, false},
+		{"different width", Int32, Int64, false},
+		{"different bystrn", ByStr20, ByStrN(32), false},
+		{"bystr and bystrn", ByStr, ByStr20, false},
+		{"same map", &MapType{String, Bool}, &MapType{String, Bool}, true},
+		{"different map", &MapType{String, Bool}, &MapType{String, Nat}, false},
+		{"same fun", Fun(a, b), Fun(a, b), true},
+		{"free type vars", a, b, false},
+		{"alpha equivalent", Forall(Fun(a, b), "'A"), Forall(Fun(c, b), "'C"), true},
+		{"bound and free", Forall(Fun(a, b), "'A"), Forall(Fun(b, b), "'B"), false},
+		{"binder order", Forall(Fun(a, b), "'A", "'B"), Forall(Fun(b, a), "'A", "'B"), false},
+		{"shadowing", Forall(Forall(a, "'A"), "'A"), Forall(Forall(b, "'B"), "'A"), true},
+		{"adt args", List(Int32), List(Int32), true},
+		{"different adt", List(Int32), Option(Int32), false},
+		{"user adt", &ADT{"T", nil}, &ADT{"T", nil}, true},
+		{"address kind", &AddressType{Kind: AnyAddr}, &AddressType{Kind: LibAddr}, false},
+		{
+			"address field order",
+			&AddressType{ContrAddr, []*AddressField{{"a", Int32}, {"b", String}}},
+			&AddressType{ContrAddr, []*AddressField{{"b", String}, {"a", Int32}}},
+			true,
+		},
+		{
+			"address field type",
+			ContractAddress(&AddressField{"a", Int32}),
+			ContractAddress(&AddressField{"a", Uint32}),
+			false,
+		},
+		{"kinds of types", Message, Event, false},
+	} {
+		t.Run(tc.what, func(t *testing.T) {
+			if have := Equal(tc.x, tc.y); have != tc.want {
+				t.Fatalf("Equal(%s, %s) should be %v", tc.x, tc.y, tc.want)
+			}
+			if have := Equal(tc.y, tc.x); have != tc.want {
+				t.Fatalf("Equal(%s, %s) should be %v", tc.y, tc.x, tc.want)
+			}
+		})
+	}
+}
+
+func TestFreeTypeVars(t *testing.T) {
+	a, b, c := Var("'A"), Var("'B"), Var("'C")
+	for _, tc := range []struct {
+		typ  Type
+		want []string
+	}{
+		{Int32, []string{}},
+		{Fun(b, a, b), []string{"'A", "'B"}},
+		{Forall(Fun(a, b), "'A"), []string{"'B"}},
+		{Fun(a, Forall(a, "'A")), []string{"'A"}},
+		{&MapType{c, Option(a)}, []string{"'A", "'C"}},
+		{ContractAddress(&AddressField{"f", List(b)}), []string{"'B"}},
+	} {
+		if have := FreeTypeVars(tc.typ); !reflect.DeepEqual(have, tc.want) {
+			t.Errorf("Wanted %v for %s but got %v", tc.want, tc.typ, have)
+		}
+	}
+	if !IsGround(Forall(a, "'A")) || IsGround(List(a)) {
+		t.Error("IsGround returned wrong result")
+	}
+}
+
+func TestSubst(t *testing.T) {
+	a, b := Var("'A"), Var("'B")
+	for _, tc := range []struct {
+		what string
+		typ  Type
+		s    map[string]Type
+		want string
+	}{
+		{"type var", a, map[string]Type{"'A": Int32}, "Int32"},
+		{"compound", Fun(a, &MapType{b, List(a)}), map[string]Type{"'A": Int32, "'B": String}, "Int32 -> Map (String) (List (Int32))"},
+		{"bound var", Forall(Fun(a, b), "'A"), map[string]Type{"'A": Int32, "'B": String}, "forall 'A. 'A -> String"},
+		{"capture", Forall(Fun(a, b), "'A"), map[string]Type{"'B": List(a)}, "forall 'A1. 'A1 -> List ('A)"},
+		{"capture avoids free vars", Forall(Fun(a, b, Var("'A1")), "'A"), map[string]Type{"'B": a}, "forall 'A2. 'A2 -> 'A -> 'A1"},
+		{"address", ContractAddress(&AddressField{"f", a}), map[string]Type{"'A": Bool}, "ByStr20 with contract field f : Bool end"},
+		{"empty", Fun(a, a), nil, "'A -> 'A"},
+	} {
+		t.Run(tc.what, func(t *testing.T) {
+			if have := Subst(tc.typ, tc.s).String(); have != tc.want {
+				t.Fatalf("Wanted %q but got %q", tc.want, have)
+			}
+		})
+	}
+}
+
+func TestInstantiate(t *testing.T) {
+	a := Var("'A")
+	p := Forall(Fun(List(a), a), "'A").(*PolyType)
+	if have := p.Instantiate(Uint128); !Equal(have, Fun(List(Uint128), Uint128)) {
+		t.Fatal("Unexpected instantiation", have)
+	}
+}
+
+func TestBuiltinADT(t *testing.T) {
+	for name, want := range map[string]string{
+		"True":  "Bool",
+		"Succ":  "Nat -> Nat",
+		"Some":  "forall 'A. 'A -> Option ('A)",
+		"None":  "forall 'A. Option ('A)",
+		"Cons":  "forall 'A. 'A -> List ('A) -> List ('A)",
+		"Nil":   "forall 'A. List ('A)",
+		"Pair":  "forall 'A. forall 'B. 'A -> 'B -> Pair ('A) ('B)",
+		"Other": "",
+	} {
+		d, c := BuiltinCtor(name)
+		if want == "" {
+			if d != nil || c != nil {
+				t.Errorf("%q should not be a builtin constructor", name)
+			}
+			continue
+		}
+		if have := d.CtorType(name).String(); have != want {
+			t.Errorf("Wanted %q for constructor %q but got %q", want, name, have)
+		}
+	}
+
+	args := ListDef.CtorArgs(ListDef.Ctor("Cons"), []Type{Int32})
+	if len(args) != 2 || !Equal(args[0], Int32) || !Equal(args[1], List(Int32)) {
+		t.Fatal("Unexpected argument types of Cons", args)
+	}
+	if BuiltinADT("Option") != OptionDef || BuiltinADT("Foo") != nil {
+		t.Fatal("BuiltinADT returned wrong definition")
+	}
+	if ListDef.CtorType("Foo") != nil {
+		t.Fatal("List does not have constructor Foo")
+	}
+}
+
+func TestAssignable(t *testing.T) {
+	any, lib := &AddressType{Kind: AnyAddr}, &AddressType{Kind: LibAddr}
+	f := ContractAddress(&AddressField{"f", Uint32})
+	fg := ContractAddress(&AddressField{"f", Uint32}, &AddressField{"g", String})
+	ff := ContractAddress(&AddressField{"f", fg})
+	for _, tc := range []struct {
+		what       string
+		want, have Type
+		ok         bool
+	}{
+		{"equal", Uint32, Uint32, true},
+		{"different", Uint32, Int32, false},
+		{"address as bystr20", ByStr20, any, true},
+		{"contract address as bystr20", ByStr20, fg, true},
+		{"address as bystr32", ByStr32, any, false},
+		{"bystr20 as address", any, ByStr20, false},
+		{"library as any", any, lib, true},
+		{"contract as any", any, f, true},
+		{"any as library", lib, any, false},
+		{"contract as library", lib, ContractAddress(), false},
+		{"library as contract", ContractAddress(), lib, false},
+		{"more fields", f, fg, true},
+		{"less fields", fg, f, false},
+		{"field type", f, ContractAddress(&AddressField{"f", Int32}), false},
+		{"nested address field", ContractAddress(&AddressField{"f", f}), ff, true},
+		{"list covariance", List(ByStr20), List(fg), true},
+		{"list contravariance", List(fg), List(ByStr20), false},
+		{"map value", &MapType{ByStr20, any}, &MapType{ByStr20, lib}, true},
+		{"function parameter", Fun(fg, Bool), Fun(ByStr20, Bool), true},
+		{"function parameter contravariance", Fun(ByStr20, Bool), Fun(fg, Bool), false},
+		{"function result", Fun(Bool, ByStr20), Fun(Bool, any), true},
+	} {
+		t.Run(tc.what, func(t *testing.T) {
+			if have := Assignable(tc.want, tc.have); have != tc.ok {
+				t.Errorf("Assignable(%s, %s) should be %v", tc.want, tc.have, tc.ok)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	a := Var("'A")
+	myADT := &ADTDef{"MyType", nil, []*CtorDef{{"MyCtor", nil}}}
+	adts := map[string]*ADTDef{"MyType": myADT}
+	for _, tc := range []struct {
+		src  string
+		want Type
+	}{
+		{"Uint128", Uint128},
+		{"ByStr20", ByStr20},
+		{"Map ByStr20 (Map ByStr20 Uint128)", &MapType{ByStr20, &MapType{ByStr20, Uint128}}},
+		{"Map (ByStr20) (Map (ByStr20) (Uint128))", &MapType{ByStr20, &MapType{ByStr20, Uint128}}},
+		{"List (Pair ByStr20 Uint32)", List(Pair(ByStr20, Uint32))},
+		{"Option (List (Nat))", Option(List(Nat))},
+		{"Bool", Bool},
+		{"MyType", &ADT{"MyType", nil}},
+		{"0x1234567890123456789012345678901234567890.MyType", &ADT{"MyType", nil}},
+		{"forall 'A. ('A -> Bool) -> List 'A -> Option 'A", Forall(Fun(Fun(a, Bool), List(a), Option(a)), "'A")},
+		{"ByStr20 with end", &AddressType{Kind: AnyAddr}},
+		{"ByStr20 with library end", &AddressType{Kind: LibAddr}},
+		{
+			"ByStr20 with contract field owner : ByStr20, field balances : Map ByStr20 Uint128 end",
+			ContractAddress(&AddressField{"owner", ByStr20}, &AddressField{"balances", &MapType{ByStr20, Uint128}}),
+		},
+	} {
+		t.Run(tc.src, func(t *testing.T) {
+			have, err := Parse(tc.src, adts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !Equal(have, tc.want) {
+				t.Fatalf("Wanted %s but got %s", tc.want, have)
+			}
+			// String() of parsed type can be parsed again
+			again, err := Parse(have.String(), adts)
+			if err != nil || !Equal(again, have) {
+				t.Fatalf("%s was not parsed again: %v", have, err)
+			}
+		})
+	}
+
+	for src, want := range map[string]string{
+		"":                      "expected type but reached end of type",
+		"List":                  "type List takes 1 type arguments but 0 given",
+		"Unknown":               "type Unknown is not defined",
+		"Map Uint32":            "expected type but reached end of type",
+		"Uint32 Uint32":         `expected end of type but got "Uint32"`,
+		"(Uint32":               "expected ) but reached end of type",
+		"ByStr20 with contract": "expected end but reached end of type",
+		"ByStr20 with foo end":  `expected end, library or contract but got "foo"`,
+	} {
+		_, err := Parse(src, adts)
+		if err == nil {
+			t.Errorf("%q was parsed without error", src)
+			continue
+		}
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Error for %q should contain %q but got %q", src, want, err)
+		}
+	}
+}
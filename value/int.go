@@ -0,0 +1,131 @@
+package value
+
+import (
+	"fmt"
+	"goscilla/types"
+	"math/big"
+	"strings"
+)
+
+// IntErrorKind is a kind of failure of integer arithmetic.
+type IntErrorKind int
+
+const (
+	// IntOverflow means the result is greater than the maximum value of the type
+	IntOverflow IntErrorKind = iota
+	// IntUnderflow means the result is less than the minimum value of the type
+	IntUnderflow
+	// DivisionByZero means the divisor of div or rem is zero
+	DivisionByZero
+)
+
+// IntError is a failure of integer arithmetic. Scilla raises a runtime error instead of wrapping
+// around when the result is out of range of the fixed-width integer type.
+type IntError struct {
+	Kind IntErrorKind
+	Op   string // Name of the operation such as "add" or "to_uint32"
+	Typ  *types.IntType
+}
+
+func (e *IntError) Error() string {
+	switch e.Kind {
+	case IntOverflow:
+		return fmt.Sprintf("Integer overflow in %s of %s", e.Op, e.Typ)
+	case IntUnderflow:
+		return fmt.Sprintf("Integer underflow in %s of %s", e.Op, e.Typ)
+	}
+	return fmt.Sprintf("Division by zero in %s of %s", e.Op, e.Typ)
+}
+
+// intResult returns the integer of the type. When it is out of range, IntError is returned.
+func intResult(op string, t *types.IntType, i *big.Int) (*Int, error) {
+	min, max := Bounds(t)
+	if i.Cmp(max) > 0 {
+		return nil, &IntError{IntOverflow, op, t}
+	}
+	if i.Cmp(min) < 0 {
+		return nil, &IntError{IntUnderflow, op, t}
+	}
+	return &Int{t, i}, nil
+}
+
+func (x *Int) binary(op string, y *Int, f func(z, x, y *big.Int) *big.Int) (*Int, error) {
+	if !types.Equal(x.Typ, y.Typ) {
+		return nil, fmt.Errorf("Operands of %s must have the same type but got %s and %s", op, x.Typ, y.Typ)
+	}
+	return intResult(op, x.Typ, f(new(big.Int), x.Value, y.Value))
+}
+
+// Add returns x + y.
+func (x *Int) Add(y *Int) (*Int, error) {
+	return x.binary("add", y, (*big.Int).Add)
+}
+
+// Sub returns x - y.
+func (x *Int) Sub(y *Int) (*Int, error) {
+	return x.binary("sub", y, (*big.Int).Sub)
+}
+
+// Mul returns x * y.
+func (x *Int) Mul(y *Int) (*Int, error) {
+	return x.binary("mul", y, (*big.Int).Mul)
+}
+
+// Div returns x / y truncated toward zero like OCaml and Solidity. Dividing the minimum value of
+// signed integer type by -1 overflows.
+func (x *Int) Div(y *Int) (*Int, error) {
+	if y.Value.Sign() == 0 {
+		return nil, &IntError{DivisionByZero, "div", x.Typ}
+	}
+	return x.binary("div", y, (*big.Int).Quo)
+}
+
+// Rem returns the remainder of x / y, which has the same sign as x.
+func (x *Int) Rem(y *Int) (*Int, error) {
+	if y.Value.Sign() == 0 {
+		return nil, &IntError{DivisionByZero, "rem", x.Typ}
+	}
+	return x.binary("rem", y, (*big.Int).Rem)
+}
+
+// Pow returns x to the power of e. e must not be negative.
+func (x *Int) Pow(e *Int) (*Int, error) {
+	if e.Value.Sign() < 0 {
+		return nil, fmt.Errorf("Exponent of pow must not be negative but got %s", e.Value)
+	}
+	if x.Value.CmpAbs(big.NewInt(1)) <= 0 {
+		// 0, 1 and -1 never grow so that huge exponents are computed immediately
+		z := new(big.Int).Set(x.Value)
+		switch {
+		case e.Value.Sign() == 0:
+			z.SetInt64(1)
+		case z.Sign() < 0 && e.Value.Bit(0) == 0:
+			z.Neg(z)
+		}
+		return intResult("pow", x.Typ, z)
+	}
+	// Check bounds while multiplying to avoid computing a huge number. It takes at most the
+	// number of bits of the type
+	z := big.NewInt(1)
+	for n := new(big.Int).Set(e.Value); n.Sign() > 0; n.Sub(n, big.NewInt(1)) {
+		z.Mul(z, x.Value)
+		if _, err := intResult("pow", x.Typ, z); err != nil {
+			return nil, err
+		}
+	}
+	return &Int{x.Typ, z}, nil
+}
+
+// Isqrt returns the floor of the square root of x. x must not be negative.
+func (x *Int) Isqrt() (*Int, error) {
+	if x.Value.Sign() < 0 {
+		return nil, fmt.Errorf("Square root of negative integer %s cannot be computed", x)
+	}
+	return &Int{x.Typ, new(big.Int).Sqrt(x.Value)}, nil
+}
+
+// Convert returns the same integer of the type t such as `builtin to_uint256 x`. When x is out of
+// range of t, IntError is returned.
+func (x *Int) Convert(t *types.IntType) (*Int, error) {
+	return intResult("to_"+strings.ToLower(t.String()), t, new(big.Int).Set(x.Value))
+}
@@ -0,0 +1,289 @@
+package value
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"goscilla/types"
+	"strings"
+)
+
+// FromJSON converts the JSON value decoded by encoding/json into the value of the type. The JSON
+// value is encoded in the same way as init.json and state files of Scilla:
+//
+//	Integers and BNum: decimal number in string like "42"
+//	String: string
+//	ByStr, ByStrN and addresses: hex string like "0x1234"
+//	Map: array of objects like {"key": k, "val": v}
+//	List: array of elements
+//	Other ADTs: object like {"constructor": "Some", "argtypes": ["Uint32"], "arguments": ["1"]}
+//
+// ADTs other than builtin ones are looked up in adts.
+func FromJSON(t types.Type, j interface{}, adts map[string]*types.ADTDef) (Value, error) {
+	switch t := t.(type) {
+	case *types.IntType:
+		s, err := jsonString(t, j)
+		if err != nil {
+			return nil, err
+		}
+		return ParseInt(t, s)
+	case *types.StringType:
+		s, err := jsonString(t, j)
+		if err != nil {
+			return nil, err
+		}
+		return String(s), nil
+	case *types.BNumType:
+		s, err := jsonString(t, j)
+		if err != nil {
+			return nil, err
+		}
+		return ParseBNum(s)
+	case *types.ByStrType:
+		s, err := jsonString(t, j)
+		if err != nil {
+			return nil, err
+		}
+		if s == "0x" {
+			return ByStr{}, nil
+		}
+		b, err := ParseHex(s)
+		if err != nil {
+			return nil, err
+		}
+		return ByStr(b), nil
+	case *types.ByStrNType:
+		return byStrFromJSON(t, t.Size, j)
+	case *types.AddressType:
+		return byStrFromJSON(t, 20, j)
+	case *types.MapType:
+		return mapFromJSON(t, j, adts)
+	case *types.ADT:
+		return adtFromJSON(t, j, adts)
+	}
+	return nil, fmt.Errorf("Value of type %s cannot be represented in JSON", t)
+}
+
+func describeJSON(j interface{}) string {
+	b, err := json.Marshal(j)
+	if err != nil {
+		return fmt.Sprint(j)
+	}
+	return string(b)
+}
+
+func jsonString(t types.Type, j interface{}) (string, error) {
+	s, ok := j.(string)
+	if !ok {
+		return "", fmt.Errorf("Value of type %s must be a string but got %s", t, describeJSON(j))
+	}
+	return s, nil
+}
+
+func byStrFromJSON(t types.Type, size int, j interface{}) (Value, error) {
+	s, err := jsonString(t, j)
+	if err != nil {
+		return nil, err
+	}
+	b, err := ParseHex(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) != size {
+		return nil, fmt.Errorf("Value of type %s must have %d bytes but %s has %d bytes", t, size, s, len(b))
+	}
+	return b, nil
+}
+
+func mapFromJSON(t *types.MapType, j interface{}, adts map[string]*types.ADTDef) (Value, error) {
+	entries, ok := j.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("Value of type %s must be an array of key-value objects but got %s", t, describeJSON(j))
+	}
+	m := NewMap(t)
+	for _, e := range entries {
+		o, ok := e.(map[string]interface{})
+		if !ok || len(o) != 2 || o["key"] == nil || o["val"] == nil {
+			return nil, fmt.Errorf("Entry of map of type %s must be an object like {\"key\": k, \"val\": v} but got %s", t, describeJSON(e))
+		}
+		k, err := FromJSON(t.Key, o["key"], adts)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := m.Get(k); ok {
+			return nil, fmt.Errorf("Key %s is duplicated in map of type %s", k, t)
+		}
+		v, err := FromJSON(t.Value, o["val"], adts)
+		if err != nil {
+			return nil, err
+		}
+		m.Set(k, v)
+	}
+	return m, nil
+}
+
+func adtFromJSON(t *types.ADT, j interface{}, adts map[string]*types.ADTDef) (Value, error) {
+	if t.Name == "List" && len(t.Args) == 1 {
+		elems, ok := j.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("Value of type %s must be an array but got %s", t, describeJSON(j))
+		}
+		vs := make([]Value, 0, len(elems))
+		for _, e := range elems {
+			v, err := FromJSON(t.Args[0], e, adts)
+			if err != nil {
+				return nil, err
+			}
+			vs = append(vs, v)
+		}
+		return List(t.Args[0], vs...), nil
+	}
+
+	def, ok := adts[t.Name]
+	if !ok {
+		def = types.BuiltinADT(t.Name)
+	}
+	if def == nil {
+		return nil, fmt.Errorf("Type %s is not defined", t.Name)
+	}
+	o, ok := j.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("Value of type %s must be an object with constructor, argtypes and arguments but got %s", t, describeJSON(j))
+	}
+	name, ok := o["constructor"].(string)
+	if !ok {
+		return nil, fmt.Errorf("Value of type %s must have constructor name as string but got %s", t, describeJSON(j))
+	}
+	// Constructors may be qualified with the address of contract like "0x1234.Foo"
+	ctor := def.Ctor(name[strings.LastIndexByte(name, '.')+1:])
+	if ctor == nil {
+		return nil, fmt.Errorf("Constructor %s is not defined for type %s", name, t)
+	}
+	if ts, ok := o["argtypes"].([]interface{}); ok {
+		if len(ts) != len(t.Args) {
+			return nil, fmt.Errorf("Constructor %s of type %s must have %d argtypes but got %d", name, t, len(t.Args), len(ts))
+		}
+		for i, a := range ts {
+			s, _ := a.(string)
+			at, err := types.Parse(s, adts)
+			if err != nil {
+				return nil, err
+			}
+			if !types.Equal(at, t.Args[i]) {
+				return nil, fmt.Errorf("Argtype %s of constructor %s does not match type %s", s, name, t)
+			}
+		}
+	} else if o["argtypes"] != nil {
+		return nil, fmt.Errorf("Argtypes of constructor %s must be an array but got %s", name, describeJSON(o["argtypes"]))
+	}
+
+	params := def.CtorArgs(ctor, t.Args)
+	var args []interface{}
+	if a, ok := o["arguments"]; ok {
+		if args, ok = a.([]interface{}); !ok {
+			return nil, fmt.Errorf("Arguments of constructor %s must be an array but got %s", name, describeJSON(a))
+		}
+	}
+	if len(args) != len(params) {
+		return nil, fmt.Errorf("Constructor %s takes %d arguments but %d given", name, len(params), len(args))
+	}
+	vs := make([]Value, 0, len(args))
+	for i, a := range args {
+		v, err := FromJSON(params[i], a, adts)
+		if err != nil {
+			return nil, err
+		}
+		vs = append(vs, v)
+	}
+	return &ADT{def.Name, ctor.Name, t.Args, vs}, nil
+}
+
+// Object is a JSON object which keeps the order of its members. Scilla outputs objects such as
+// ADT values and states with members in fixed order.
+type Object []*Member
+
+// Member is a key-value pair in JSON object.
+type Member struct {
+	Key   string
+	Value interface{}
+}
+
+// MarshalJSON encodes the object with its members in order.
+func (o Object) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, m := range o {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		k, err := json.Marshal(m.Key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(m.Value)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(k)
+		b.WriteByte(':')
+		b.Write(v)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// ToJSON converts the value into the JSON value in the encoding described at FromJSON. Objects are
+// represented as Object. Values which cannot be represented in JSON such as messages cause an
+// error.
+func ToJSON(v Value) (interface{}, error) {
+	switch v := v.(type) {
+	case *Int:
+		return v.Value.String(), nil
+	case String:
+		return string(v), nil
+	case *BNum:
+		return v.Value.String(), nil
+	case ByStr, ByStrN:
+		return v.String(), nil
+	case *Map:
+		es := make([]interface{}, 0, v.Len())
+		for _, e := range v.Entries() {
+			k, err := ToJSON(e.Key)
+			if err != nil {
+				return nil, err
+			}
+			x, err := ToJSON(e.Value)
+			if err != nil {
+				return nil, err
+			}
+			es = append(es, Object{{"key", k}, {"val", x}})
+		}
+		return es, nil
+	case *ADT:
+		if elems, ok := v.Elems(); ok && v.Name == "List" {
+			js := make([]interface{}, 0, len(elems))
+			for _, e := range elems {
+				j, err := ToJSON(e)
+				if err != nil {
+					return nil, err
+				}
+				js = append(js, j)
+			}
+			return js, nil
+		}
+		ts := make([]interface{}, 0, len(v.TArgs))
+		for _, t := range v.TArgs {
+			ts = append(ts, t.String())
+		}
+		args := make([]interface{}, 0, len(v.Args))
+		for _, a := range v.Args {
+			j, err := ToJSON(a)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, j)
+		}
+		return Object{{"constructor", v.Ctor}, {"argtypes", ts}, {"arguments", args}}, nil
+	}
+	return nil, fmt.Errorf("Value %s of type %s cannot be represented in JSON", v, v.Type())
+}
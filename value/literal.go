@@ -0,0 +1,49 @@
+package value
+
+import (
+	"encoding/hex"
+	"fmt"
+	"goscilla/types"
+	"math/big"
+	"strings"
+)
+
+// ParseInt parses the integer literal such as "42" or "-1" as a value of the integer type. It
+// returns an error when the literal is out of range of the type.
+func ParseInt(t *types.IntType, lit string) (*Int, error) {
+	i, ok := new(big.Int).SetString(lit, 10)
+	if !ok {
+		return nil, fmt.Errorf("Invalid integer literal %s", lit)
+	}
+	if v := NewInt(t, i); v != nil {
+		return v, nil
+	}
+	min, max := Bounds(t)
+	return nil, fmt.Errorf("Integer literal %s is out of range of %s. It must be from %s to %s", lit, t, min, max)
+}
+
+// ParseBNum parses the literal of block number such as "100". Block numbers are not negative.
+func ParseBNum(lit string) (*BNum, error) {
+	i, ok := new(big.Int).SetString(lit, 10)
+	if !ok {
+		return nil, fmt.Errorf("Invalid block number literal %s", lit)
+	}
+	if i.Sign() < 0 {
+		return nil, fmt.Errorf("Block number literal %s must not be negative", lit)
+	}
+	return &BNum{i}, nil
+}
+
+// ParseHex parses the hex literal such as "0x1234" as a value of ByStrN type. N is the number of
+// bytes, so the literal must have even number of hex digits.
+func ParseHex(lit string) (ByStrN, error) {
+	digits := strings.TrimPrefix(lit, "0x")
+	if len(digits) == 0 || len(digits)%2 != 0 {
+		return nil, fmt.Errorf("Hex literal %s must have even number of hex digits since each byte is represented by 2 digits but it has %d digits", lit, len(digits))
+	}
+	b, err := hex.DecodeString(digits)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid hex literal %s: %s", lit, err)
+	}
+	return ByStrN(b), nil
+}
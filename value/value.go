@@ -0,0 +1,343 @@
+// Package value provides representation of Scilla values.
+package value
+
+import (
+	"encoding/hex"
+	"fmt"
+	"goscilla/types"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Value is a runtime value of Scilla. Packages evaluating expressions may define their own values
+// such as closures.
+type Value interface {
+	// Type returns the type of the value.
+	Type() types.Type
+	// String returns the value in a format like Scilla literals such as "Uint32 1" or
+	// "Some {Uint32} (Uint32 1)".
+	String() string
+}
+
+type (
+	// Int32 1, Uint128 42
+	Int struct {
+		Typ   *types.IntType
+		Value *big.Int
+	}
+
+	// "foo"
+	String string
+
+	// BNum 100
+	BNum struct {
+		Value *big.Int
+	}
+
+	// Byte string of arbitrary length
+	ByStr []byte
+
+	// Byte string of fixed length such as ByStr20. Its length is the N of ByStrN
+	ByStrN []byte
+
+	// Some {Uint32} x, True, Cons {Message} m ms
+	ADT struct {
+		Name  string // Name of the ADT such as "Option"
+		Ctor  string
+		TArgs []types.Type
+		Args  []Value
+	}
+
+	// {_tag : "Foo"; x : y}. Typ is types.Message, types.Event or types.Exception.
+	Msg struct {
+		Typ     types.Type
+		Entries []*MsgEntry
+	}
+
+	// x : y in message
+	MsgEntry struct {
+		Key   string
+		Value Value
+	}
+)
+
+// NewInt returns the integer value of the type. It returns nil when i is out of range of the type.
+func NewInt(t *types.IntType, i *big.Int) *Int {
+	if !InRange(t, i) {
+		return nil
+	}
+	return &Int{t, i}
+}
+
+// InRange returns whether the integer can be a value of the integer type.
+func InRange(t *types.IntType, i *big.Int) bool {
+	min, max := Bounds(t)
+	return i.Cmp(min) >= 0 && i.Cmp(max) <= 0
+}
+
+// Bounds returns the minimum and maximum values of the integer type.
+func Bounds(t *types.IntType) (min, max *big.Int) {
+	one := big.NewInt(1)
+	if t.Signed {
+		max = new(big.Int).Lsh(one, uint(t.Bits-1))
+		min = new(big.Int).Neg(max)
+		max.Sub(max, one)
+		return
+	}
+	max = new(big.Int).Lsh(one, uint(t.Bits))
+	max.Sub(max, one)
+	return new(big.Int), max
+}
+
+// Instances of boolean values
+var (
+	True  = &ADT{"Bool", "True", nil, nil}
+	False = &ADT{"Bool", "False", nil, nil}
+)
+
+// Bool returns True or False.
+func Bool(b bool) *ADT {
+	if b {
+		return True
+	}
+	return False
+}
+
+// Some returns the value Some {t} v.
+func Some(t types.Type, v Value) *ADT {
+	return &ADT{"Option", "Some", []types.Type{t}, []Value{v}}
+}
+
+// None returns the value None {t}.
+func None(t types.Type) *ADT {
+	return &ADT{"Option", "None", []types.Type{t}, nil}
+}
+
+// Pair returns the value Pair {a b} x y.
+func Pair(a, b types.Type, x, y Value) *ADT {
+	return &ADT{"Pair", "Pair", []types.Type{a, b}, []Value{x, y}}
+}
+
+// List returns the list of the elements of type t.
+func List(t types.Type, elems ...Value) *ADT {
+	l := &ADT{"List", "Nil", []types.Type{t}, nil}
+	for i := len(elems) - 1; i >= 0; i-- {
+		l = &ADT{"List", "Cons", []types.Type{t}, []Value{elems[i], l}}
+	}
+	return l
+}
+
+// Nat returns the natural number value such as Succ (Succ Zero).
+func Nat(n uint64) *ADT {
+	v := &ADT{"Nat", "Zero", nil, nil}
+	for ; n > 0; n-- {
+		v = &ADT{"Nat", "Succ", nil, []Value{v}}
+	}
+	return v
+}
+
+// Elems returns elements of the list value. It returns false when the value is not a list.
+func (v *ADT) Elems() ([]Value, bool) {
+	var elems []Value
+	for l := v; ; l = l.Args[1].(*ADT) {
+		switch l.Ctor {
+		case "Nil":
+			return elems, true
+		case "Cons":
+			elems = append(elems, l.Args[0])
+		default:
+			return nil, false
+		}
+	}
+}
+
+// Field returns the value of the key in the message. It returns nil when the key is not found.
+func (v *Msg) Field(key string) Value {
+	for _, e := range v.Entries {
+		if e.Key == key {
+			return e.Value
+		}
+	}
+	return nil
+}
+
+func (v *Int) Type() types.Type    { return v.Typ }
+func (v String) Type() types.Type  { return types.String }
+func (v *BNum) Type() types.Type   { return types.BNum }
+func (v ByStr) Type() types.Type   { return types.ByStr }
+func (v ByStrN) Type() types.Type  { return types.ByStrN(len(v)) }
+func (v *ADT) Type() types.Type    { return &types.ADT{Name: v.Name, Args: v.TArgs} }
+func (v *Msg) Type() types.Type    { return v.Typ }
+func (v *Int) String() string      { return fmt.Sprintf("%s %s", v.Typ, v.Value) }
+func (v String) String() string    { return strconv.Quote(string(v)) }
+func (v *BNum) String() string     { return "BNum " + v.Value.String() }
+func (v ByStr) String() string     { return "0x" + hex.EncodeToString(v) }
+func (v ByStrN) String() string    { return "0x" + hex.EncodeToString(v) }
+func (v *MsgEntry) String() string { return fmt.Sprintf("%s : %s", v.Key, v.Value) }
+
+func (v *ADT) String() string {
+	var b strings.Builder
+	b.WriteString(v.Ctor)
+	if len(v.TArgs) > 0 {
+		ts := make([]string, 0, len(v.TArgs))
+		for _, t := range v.TArgs {
+			ts = append(ts, "("+t.String()+")")
+		}
+		fmt.Fprintf(&b, " {%s}", strings.Join(ts, " "))
+	}
+	for _, a := range v.Args {
+		s := a.String()
+		if strings.ContainsRune(s, ' ') {
+			s = "(" + s + ")"
+		}
+		b.WriteByte(' ')
+		b.WriteString(s)
+	}
+	return b.String()
+}
+
+func (v *Msg) String() string {
+	es := make([]string, 0, len(v.Entries))
+	for _, e := range v.Entries {
+		es = append(es, e.String())
+	}
+	return "{" + strings.Join(es, "; ") + "}"
+}
+
+// Map is a value of map type. Keys are primitive values and compared by their values.
+// A map value is mutable. Use Copy to get a map which is not affected by modification.
+type Map struct {
+	Typ     *types.MapType
+	entries map[string]*MapEntry
+}
+
+// MapEntry is a pair of key and value in map.
+type MapEntry struct {
+	Key   Value
+	Value Value
+}
+
+// NewMap returns an empty map of the type.
+func NewMap(t *types.MapType) *Map {
+	return &Map{t, map[string]*MapEntry{}}
+}
+
+func (m *Map) Type() types.Type { return m.Typ }
+
+func (m *Map) String() string {
+	if len(m.entries) == 0 {
+		return fmt.Sprintf("Emp (%s) (%s)", m.Typ.Key, m.Typ.Value)
+	}
+	es := make([]string, 0, len(m.entries))
+	for _, e := range m.Entries() {
+		es = append(es, fmt.Sprintf("%s => %s", e.Key, e.Value))
+	}
+	return "[" + strings.Join(es, "; ") + "]"
+}
+
+// Len returns the number of entries in the map.
+func (m *Map) Len() int {
+	return len(m.entries)
+}
+
+// Get returns the value of the key. It returns false when the key is not found.
+func (m *Map) Get(k Value) (Value, bool) {
+	e, ok := m.entries[k.String()]
+	if !ok {
+		return nil, false
+	}
+	return e.Value, true
+}
+
+// Set sets the value of the key in place.
+func (m *Map) Set(k, v Value) {
+	m.entries[k.String()] = &MapEntry{k, v}
+}
+
+// Delete removes the key from the map in place.
+func (m *Map) Delete(k Value) {
+	delete(m.entries, k.String())
+}
+
+// Copy returns a shallow copy of the map. Nested maps are shared.
+func (m *Map) Copy() *Map {
+	c := &Map{m.Typ, make(map[string]*MapEntry, len(m.entries))}
+	for k, e := range m.entries {
+		c.entries[k] = e
+	}
+	return c
+}
+
+// Entries returns the entries in the map sorted by their keys.
+func (m *Map) Entries() []*MapEntry {
+	ks := make([]string, 0, len(m.entries))
+	for k := range m.entries {
+		ks = append(ks, k)
+	}
+	sort.Strings(ks)
+	es := make([]*MapEntry, 0, len(ks))
+	for _, k := range ks {
+		es = append(es, m.entries[k])
+	}
+	return es
+}
+
+// Equal returns whether the two values are equal. Maps are equal when they have the same entries.
+// Values which cannot be compared (e.g. closures) are not equal.
+func Equal(a, b Value) bool {
+	switch a := a.(type) {
+	case *Int:
+		b, ok := b.(*Int)
+		return ok && types.Equal(a.Typ, b.Typ) && a.Value.Cmp(b.Value) == 0
+	case String:
+		b, ok := b.(String)
+		return ok && a == b
+	case *BNum:
+		b, ok := b.(*BNum)
+		return ok && a.Value.Cmp(b.Value) == 0
+	case ByStr:
+		b, ok := b.(ByStr)
+		return ok && string(a) == string(b)
+	case ByStrN:
+		b, ok := b.(ByStrN)
+		return ok && string(a) == string(b)
+	case *ADT:
+		b, ok := b.(*ADT)
+		if !ok || a.Name != b.Name || a.Ctor != b.Ctor || len(a.Args) != len(b.Args) {
+			return false
+		}
+		for i := range a.Args {
+			if !Equal(a.Args[i], b.Args[i]) {
+				return false
+			}
+		}
+		return true
+	case *Map:
+		b, ok := b.(*Map)
+		if !ok || a.Len() != b.Len() {
+			return false
+		}
+		for k, e := range a.entries {
+			f, ok := b.entries[k]
+			if !ok || !Equal(e.Value, f.Value) {
+				return false
+			}
+		}
+		return true
+	case *Msg:
+		b, ok := b.(*Msg)
+		if !ok || len(a.Entries) != len(b.Entries) {
+			return false
+		}
+		for _, e := range a.Entries {
+			v := b.Field(e.Key)
+			if v == nil || !Equal(e.Value, v) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,331 @@
+package value
+
+import (
+	"encoding/json"
+	"goscilla/types"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestString(t *testing.T) {
+	m := NewMap(&types.MapType{Key: types.String, Value: types.Uint32})
+	for _, tc := range []struct {
+		v    Value
+		want string
+	}{
+		{NewInt(types.Int32, big.NewInt(-1)), "Int32 -1"},
+		{String("a\"b"), `"a\"b"`},
+		{&BNum{big.NewInt(10)}, "BNum 10"},
+		{ByStrN{0xab, 0x01}, "0xab01"},
+		{Some(types.Uint32, NewInt(types.Uint32, big.NewInt(1))), "Some {(Uint32)} (Uint32 1)"},
+		{List(types.Bool, True), "Cons {(Bool)} True (Nil {(Bool)})"},
+		{Nat(1), "Succ Zero"},
+		{m, "Emp (String) (Uint32)"},
+		{&Msg{types.Event, []*MsgEntry{{"_eventname", String("E")}}}, `{_eventname : "E"}`},
+	} {
+		if have := tc.v.String(); have != tc.want {
+			t.Errorf("Wanted %s but got %s", tc.want, have)
+		}
+	}
+}
+
+func TestNewInt(t *testing.T) {
+	if NewInt(types.Int32, big.NewInt(-2147483648)) == nil {
+		t.Error("Minimum Int32 is out of range")
+	}
+	if NewInt(types.Int32, big.NewInt(2147483648)) != nil {
+		t.Error("Int32 overflow is in range")
+	}
+	if NewInt(types.Uint32, big.NewInt(-1)) != nil {
+		t.Error("Negative Uint32 is in range")
+	}
+}
+
+func TestMap(t *testing.T) {
+	m := NewMap(&types.MapType{Key: types.String, Value: types.Uint32})
+	m.Set(String("b"), NewInt(types.Uint32, big.NewInt(2)))
+	m.Set(String("a"), NewInt(types.Uint32, big.NewInt(1)))
+	c := m.Copy()
+	m.Delete(String("b"))
+	if v, ok := c.Get(String("b")); !ok || v.String() != "Uint32 2" {
+		t.Error("Copy is affected by deletion", c)
+	}
+	if have := c.String(); have != `["a" => Uint32 1; "b" => Uint32 2]` {
+		t.Error("Unexpected map", have)
+	}
+	if Equal(m, c) {
+		t.Error("Different maps are equal")
+	}
+	m.Set(String("b"), NewInt(types.Uint32, big.NewInt(2)))
+	if !Equal(m, c) {
+		t.Error("Same maps are not equal")
+	}
+}
+
+func TestElems(t *testing.T) {
+	l := List(types.Uint32, NewInt(types.Uint32, big.NewInt(1)), NewInt(types.Uint32, big.NewInt(2)))
+	es, ok := l.Elems()
+	if !ok || len(es) != 2 || es[1].String() != "Uint32 2" {
+		t.Error("Unexpected elements", es)
+	}
+	if _, ok := True.Elems(); ok {
+		t.Error("Bool is a list")
+	}
+}
+
+func TestParseLiteral(t *testing.T) {
+	for _, tc := range []struct {
+		typ  *types.IntType
+		lit  string
+		want string
+	}{
+		{types.Uint256, "115792089237316195423570985008687907853269984665640564039457584007913129639935", "Uint256 115792089237316195423570985008687907853269984665640564039457584007913129639935"},
+		{types.Int64, "-9223372036854775808", "Int64 -9223372036854775808"},
+		{types.Uint32, "4294967296", ""},
+		{types.Int128, "170141183460469231731687303715884105728", ""},
+		{types.Uint64, "-0", "Uint64 0"},
+		{types.Int32, "1a", ""},
+	} {
+		v, err := ParseInt(tc.typ, tc.lit)
+		if tc.want == "" {
+			if err == nil {
+				t.Errorf("%s %s should be invalid but got %s", tc.typ, tc.lit, v)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s %s: %s", tc.typ, tc.lit, err)
+		} else if v.String() != tc.want {
+			t.Errorf("Wanted %s but got %s", tc.want, v)
+		}
+	}
+
+	if b, err := ParseHex("0xABcd"); err != nil || b.String() != "0xabcd" || b.Type().String() != "ByStr2" {
+		t.Error("Unexpected hex value", b, err)
+	}
+	for _, lit := range []string{"0x", "0xabc"} {
+		if _, err := ParseHex(lit); err == nil {
+			t.Error("Invalid hex literal was parsed:", lit)
+		}
+	}
+	if _, err := ParseBNum("-1"); err == nil {
+		t.Error("Negative block number was parsed")
+	}
+}
+
+func TestFromJSON(t *testing.T) {
+	mode := &types.ADTDef{Name: "Mode", Ctors: []*types.CtorDef{{Name: "Open"}, {Name: "Closed", Args: []types.Type{types.Uint32}}}}
+	adts := map[string]*types.ADTDef{"Mode": mode}
+	addr := "0x1234567890123456789012345678901234567890"
+	for _, tc := range []struct {
+		typ  types.Type
+		json string
+		want string
+	}{
+		{types.Uint128, `"42"`, "Uint128 42"},
+		{types.Int32, `"-1"`, "Int32 -1"},
+		{types.String, `"hello"`, `"hello"`},
+		{types.BNum, `"100"`, "BNum 100"},
+		{types.ByStr, `"0x"`, "0x"},
+		{types.ByStr, `"0xabcd"`, "0xabcd"},
+		{types.ByStr20, `"` + addr + `"`, addr},
+		{&types.AddressType{Kind: types.AnyAddr}, `"` + addr + `"`, addr},
+		{types.List(types.Uint32), `["1", "2"]`, "Cons {(Uint32)} (Uint32 1) (Cons {(Uint32)} (Uint32 2) (Nil {(Uint32)}))"},
+		{types.Option(types.Uint32), `{"constructor": "Some", "argtypes": ["Uint32"], "arguments": ["1"]}`, "Some {(Uint32)} (Uint32 1)"},
+		{types.Bool, `{"constructor": "True", "argtypes": [], "arguments": []}`, "True"},
+		{types.Pair(types.String, types.Bool), `{"constructor": "Pair", "argtypes": ["String", "Bool"], "arguments": ["a", {"constructor": "False", "argtypes": [], "arguments": []}]}`, `Pair {(String) (Bool)} "a" False`},
+		{&types.ADT{Name: "Mode"}, `{"constructor": "0xabcd.Closed", "argtypes": [], "arguments": ["3"]}`, "Closed (Uint32 3)"},
+		{&types.MapType{Key: types.String, Value: types.Uint32}, `[{"key": "b", "val": "2"}, {"key": "a", "val": "1"}]`, `["a" => Uint32 1; "b" => Uint32 2]`},
+		{&types.MapType{Key: types.String, Value: types.Uint32}, `[]`, "Emp (String) (Uint32)"},
+	} {
+		var j interface{}
+		if err := json.Unmarshal([]byte(tc.json), &j); err != nil {
+			panic(err)
+		}
+		v, err := FromJSON(tc.typ, j, adts)
+		if err != nil {
+			t.Errorf("%s of %s: %s", tc.json, tc.typ, err)
+			continue
+		}
+		if v.String() != tc.want {
+			t.Errorf("Wanted %s but got %s", tc.want, v)
+		}
+	}
+
+	for _, tc := range []struct {
+		typ  types.Type
+		json string
+		want string
+	}{
+		{types.Uint32, `42`, "Value of type Uint32 must be a string but got 42"},
+		{types.Uint32, `"-1"`, "Integer literal -1 is out of range of Uint32"},
+		{types.ByStr20, `"0x1234"`, "Value of type ByStr20 must have 20 bytes but 0x1234 has 2 bytes"},
+		{types.List(types.Uint32), `{}`, "Value of type List (Uint32) must be an array but got {}"},
+		{types.Option(types.Uint32), `{"constructor": "Just", "argtypes": ["Uint32"], "arguments": ["1"]}`, "Constructor Just is not defined for type Option (Uint32)"},
+		{types.Option(types.Uint32), `{"constructor": "Some", "argtypes": ["Int32"], "arguments": ["1"]}`, "Argtype Int32 of constructor Some does not match type Option (Uint32)"},
+		{types.Option(types.Uint32), `{"constructor": "Some", "argtypes": ["Uint32"], "arguments": []}`, "Constructor Some takes 1 arguments but 0 given"},
+		{&types.MapType{Key: types.String, Value: types.Uint32}, `[{"key": "a"}]`, `Entry of map of type Map (String) (Uint32) must be an object like {"key": k, "val": v}`},
+		{&types.MapType{Key: types.String, Value: types.Uint32}, `[{"key": "a", "val": "1"}, {"key": "a", "val": "2"}]`, `Key "a" is duplicated in map`},
+		{types.Message, `{}`, "Value of type Message cannot be represented in JSON"},
+	} {
+		var j interface{}
+		if err := json.Unmarshal([]byte(tc.json), &j); err != nil {
+			panic(err)
+		}
+		_, err := FromJSON(tc.typ, j, adts)
+		if err == nil {
+			t.Errorf("%s of %s was converted without error", tc.json, tc.typ)
+			continue
+		}
+		if !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("Error should contain %q but got %q", tc.want, err)
+		}
+	}
+}
+
+func TestToJSON(t *testing.T) {
+	m := NewMap(&types.MapType{Key: types.String, Value: types.List(types.Uint32)})
+	m.Set(String("b"), List(types.Uint32))
+	m.Set(String("a"), List(types.Uint32, NewInt(types.Uint32, big.NewInt(1))))
+	for _, tc := range []struct {
+		val  Value
+		want string
+	}{
+		{NewInt(types.Int32, big.NewInt(-1)), `"-1"`},
+		{String("a\"b"), `"a\"b"`},
+		{ByStr{}, `"0x"`},
+		{ByStrN{0xab, 0xcd}, `"0xabcd"`},
+		{True, `{"constructor":"True","argtypes":[],"arguments":[]}`},
+		{Some(types.BNum, &BNum{big.NewInt(3)}), `{"constructor":"Some","argtypes":["BNum"],"arguments":["3"]}`},
+		{m, `[{"key":"a","val":["1"]},{"key":"b","val":[]}]`},
+	} {
+		j, err := ToJSON(tc.val)
+		if err != nil {
+			t.Errorf("%s: %s", tc.val, err)
+			continue
+		}
+		b, err := json.Marshal(j)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != tc.want {
+			t.Errorf("Wanted %s but got %s", tc.want, b)
+		}
+	}
+
+	if _, err := ToJSON(&Msg{Typ: types.Message}); err == nil {
+		t.Error("Message was converted into JSON")
+	}
+}
+
+func TestIntArith(t *testing.T) {
+	num := func(ty *types.IntType, s string) *Int {
+		i, ok := new(big.Int).SetString(s, 10)
+		if !ok {
+			panic(s)
+		}
+		return &Int{ty, i}
+	}
+	const (
+		maxU256 = "115792089237316195423570985008687907853269984665640564039457584007913129639935"
+		minI256 = "-57896044618658097711785492504343953926634992332820282019728792003956564819968"
+	)
+	ops := map[string]func(x, y *Int) (*Int, error){
+		"add": (*Int).Add,
+		"sub": (*Int).Sub,
+		"mul": (*Int).Mul,
+		"div": (*Int).Div,
+		"rem": (*Int).Rem,
+		"pow": (*Int).Pow,
+	}
+	for _, tc := range []struct {
+		op   string
+		x, y *Int
+		want string // Result or error message
+	}{
+		{"add", num(types.Uint32, "4294967294"), num(types.Uint32, "1"), "Uint32 4294967295"},
+		{"add", num(types.Uint32, "4294967295"), num(types.Uint32, "1"), "Integer overflow in add of Uint32"},
+		{"add", num(types.Int32, "-2147483648"), num(types.Int32, "-1"), "Integer underflow in add of Int32"},
+		{"add", num(types.Uint256, maxU256), num(types.Uint256, "0"), "Uint256 " + maxU256},
+		{"add", num(types.Uint256, maxU256), num(types.Uint256, "1"), "Integer overflow in add of Uint256"},
+		{"sub", num(types.Uint64, "0"), num(types.Uint64, "1"), "Integer underflow in sub of Uint64"},
+		{"sub", num(types.Int64, "9223372036854775807"), num(types.Int64, "-1"), "Integer overflow in sub of Int64"},
+		{"sub", num(types.Int128, "-5"), num(types.Int128, "7"), "Int128 -12"},
+		{"mul", num(types.Int256, minI256), num(types.Int256, "-1"), "Integer overflow in mul of Int256"},
+		{"mul", num(types.Int64, "-4294967296"), num(types.Int64, "2147483648"), "Int64 -9223372036854775808"},
+		{"mul", num(types.Int64, "4294967296"), num(types.Int64, "-2147483649"), "Integer underflow in mul of Int64"},
+		{"div", num(types.Int32, "-7"), num(types.Int32, "2"), "Int32 -3"},
+		{"div", num(types.Int32, "7"), num(types.Int32, "-2"), "Int32 -3"},
+		{"div", num(types.Int256, minI256), num(types.Int256, "-1"), "Integer overflow in div of Int256"},
+		{"div", num(types.Uint128, "1"), num(types.Uint128, "0"), "Division by zero in div of Uint128"},
+		{"rem", num(types.Int32, "-7"), num(types.Int32, "2"), "Int32 -1"},
+		{"rem", num(types.Int32, "7"), num(types.Int32, "-2"), "Int32 1"},
+		{"rem", num(types.Int256, minI256), num(types.Int256, "-1"), "Int256 0"},
+		{"rem", num(types.Uint32, "1"), num(types.Uint32, "0"), "Division by zero in rem of Uint32"},
+		{"pow", num(types.Uint128, "2"), num(types.Uint32, "127"), "Uint128 170141183460469231731687303715884105728"},
+		{"pow", num(types.Uint128, "2"), num(types.Uint32, "128"), "Integer overflow in pow of Uint128"},
+		{"pow", num(types.Int32, "-2"), num(types.Uint32, "31"), "Int32 -2147483648"},
+		{"pow", num(types.Int32, "-2"), num(types.Uint32, "32"), "Integer overflow in pow of Int32"},
+		{"pow", num(types.Int32, "-1"), num(types.Uint32, "4294967295"), "Int32 -1"},
+		{"pow", num(types.Uint32, "0"), num(types.Uint32, "0"), "Uint32 1"},
+		{"add", num(types.Uint32, "1"), num(types.Int32, "1"), "Operands of add must have the same type but got Uint32 and Int32"},
+	} {
+		z, err := ops[tc.op](tc.x, tc.y)
+		have := ""
+		if err != nil {
+			have = err.Error()
+		} else {
+			have = z.String()
+		}
+		if have != tc.want {
+			t.Errorf("%s %s %s: wanted %q but got %q", tc.op, tc.x, tc.y, tc.want, have)
+		}
+	}
+
+	if _, err := num(types.Uint32, "1").Div(num(types.Uint32, "0")); err.(*IntError).Kind != DivisionByZero {
+		t.Errorf("Unexpected error kind: %v", err)
+	}
+
+	for _, tc := range []struct {
+		x    *Int
+		want string
+	}{
+		{num(types.Uint256, maxU256), "Uint256 340282366920938463463374607431768211455"},
+		{num(types.Uint32, "15"), "Uint32 3"},
+		{num(types.Uint32, "16"), "Uint32 4"},
+		{num(types.Uint32, "0"), "Uint32 0"},
+	} {
+		z, err := tc.x.Isqrt()
+		if err != nil || z.String() != tc.want {
+			t.Errorf("isqrt %s: wanted %s but got %v (%v)", tc.x, tc.want, z, err)
+		}
+	}
+	if _, err := num(types.Int32, "-1").Isqrt(); err == nil {
+		t.Error("Square root of negative integer was computed")
+	}
+
+	for _, tc := range []struct {
+		x    *Int
+		to   *types.IntType
+		want string
+	}{
+		{num(types.Uint128, "18446744073709551615"), types.Uint64, "Uint64 18446744073709551615"},
+		{num(types.Uint128, "18446744073709551616"), types.Uint64, "Integer overflow in to_uint64 of Uint64"},
+		{num(types.Int32, "-1"), types.Uint256, "Integer underflow in to_uint256 of Uint256"},
+		{num(types.Uint256, "9223372036854775808"), types.Int64, "Integer overflow in to_int64 of Int64"},
+		{num(types.Int256, "-9223372036854775808"), types.Int64, "Int64 -9223372036854775808"},
+		{num(types.Uint32, "42"), types.Int256, "Int256 42"},
+	} {
+		z, err := tc.x.Convert(tc.to)
+		have := ""
+		if err != nil {
+			have = err.Error()
+		} else {
+			have = z.String()
+		}
+		if have != tc.want {
+			t.Errorf("Converting %s to %s: wanted %q but got %q", tc.x, tc.to, tc.want, have)
+		}
+	}
+}